@@ -2,7 +2,9 @@ package add
 
 import (
 	"strings"
+	"time"
 
+	"github.com/negadras/tada/internal/output"
 	"github.com/negadras/tada/internal/todo"
 	"github.com/spf13/cobra"
 )
@@ -17,6 +19,18 @@ Priority levels:
   medium, m  - Medium priority
   high, h    - High priority
 
+Tokens in the description set the other fields and are taken out of it:
+  !high, !h          - Priority, using the same words as --priority
+  #tag, +project     - Tag; given both, the project is the tag and the
+                       #tag is kept in the notes
+  @tomorrow, due:fri - Due date, as for --due
+  ~30m, ~2h          - Estimate, kept at the top of the notes
+
+Words that only look like tokens, such as #42, @alice or due:someday, stay
+in the description. Tokens for a field given by a flag, such as !h with
+--priority, are not read and stay in the description too, and --raw keeps
+the description as typed.
+
 💡 Tip: For interactive task management, try 'tada --tui'`,
 		Example: `  # Add a high priority task
   tada add "Fix the login bug" --priority high
@@ -25,9 +39,24 @@ Priority levels:
   tada add "Write documentation" -p medium
   
   # Add low priority task (default)
-  tada add "Clean up code"`,
+  tada add "Clean up code"
+
+  # Add a task due in three days
+  tada add "Submit expense report" --due 3d
+
+  # Give the priority, tag, due date and an estimate in the description
+  tada add "deploy hotfix !h #oncall @today ~30m"
+
+  # Print only the new todo's id, for scripts
+  tada add "Rotate API keys" --format 'template={{.ID}}\n'`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := output.FromCommand(cmd)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
 			// Get database connection
 			db, cleanup, err := todo.GetDB(cmd)
 			if err != nil {
@@ -35,8 +64,27 @@ Priority levels:
 			}
 			defer cleanup()
 
+			quick := &todo.QuickAdd{Description: strings.TrimSpace(args[0])}
+			if raw, _ := cmd.Flags().GetBool("raw"); !raw {
+				// Tokens for fields given by flags stay in the description
+				var keep []string
+				if cmd.Flags().Changed("priority") {
+					keep = append(keep, "priority")
+				}
+				if cmd.Flags().Changed("tag") {
+					keep = append(keep, "tag", "project")
+				}
+				if cmd.Flags().Changed("due") {
+					keep = append(keep, "due date")
+				}
+				if quick, err = todo.ParseQuickAdd(args[0], time.Now(), keep...); err != nil {
+					todo.PrintError(cmd, err)
+					return nil
+				}
+			}
+
 			// Validate description
-			description := strings.TrimSpace(args[0])
+			description := quick.Description
 			if err := todo.ValidateDescription(description); err != nil {
 				todo.PrintError(cmd, err)
 				return nil
@@ -49,16 +97,60 @@ Priority levels:
 				todo.PrintError(cmd, err)
 				return nil
 			}
+			if quick.Priority != nil {
+				priority = *quick.Priority
+			}
 
 			tagFlag, _ := cmd.Flags().GetString("tag")
+			if tag := quick.TodoTag(); tag != "" {
+				tagFlag = tag
+			}
+
+			dueFlag, _ := cmd.Flags().GetString("due")
+			due, err := todo.ParseDueDate(dueFlag)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			if quick.Due != nil {
+				due = quick.Due
+			}
 
-			// Create todo
-			newTodo, err := db.Create(description, priority, tagFlag)
+			// Create todo with its due date and notes, or not at all
+			notesFlag, _ := cmd.Flags().GetString("notes")
+			notes := quick.Notes(notesFlag)
+			var newTodo *todo.Todo
+			err = db.Transaction(func(db *todo.DB) error {
+				var err error
+				if newTodo, err = db.Create(description, priority, tagFlag); err != nil {
+					return err
+				}
+				if due != nil {
+					if err := db.UpdateDue(newTodo.ID, due); err != nil {
+						return err
+					}
+					newTodo.DueAt = due
+				}
+				if notes != "" {
+					if err := db.UpdateNotes(newTodo.ID, notes); err != nil {
+						return err
+					}
+					newTodo.Notes = notes
+				}
+				return nil
+			})
 			if err != nil {
 				todo.PrintError(cmd, err)
 				return nil
 			}
 
+			if format != nil && !format.IsText() {
+				return format.WriteTodo(cmd.OutOrStdout(), newTodo)
+			}
+
+			if understood := quick.Understood(); understood != "" {
+				cmd.Printf("💡 Understood: %s\n", understood)
+			}
 			todo.PrintCreated(cmd, newTodo)
 			return nil
 		},
@@ -66,5 +158,9 @@ Priority levels:
 
 	cmd.Flags().StringP("priority", "p", "medium", "Priority level (low/l, medium/m, high/h)")
 	cmd.Flags().StringP("tag", "g", "", "Tag to categorise the todo (e.g. personal, platform-engineering)")
+	cmd.Flags().String("due", "", "Due date (YYYY-MM-DD, today, tomorrow, a day like fri or days ahead like 3d)")
+	cmd.Flags().StringP("notes", "n", "", "Longer notes about the todo")
+	cmd.Flags().Bool("raw", false, "Keep the description as typed, without reading !priority, #tag, +project, @due or ~estimate tokens")
+	output.AddFlag(cmd)
 	return cmd
 }
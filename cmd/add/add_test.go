@@ -42,3 +42,19 @@ func TestAddCommand_Arguments(t *testing.T) {
 		t.Errorf("Expected no error with valid arguments, got %v", err)
 	}
 }
+
+func TestNewCommand_FormatFlag(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Flags().Lookup("format") == nil {
+		t.Error("NewCommand() should have flag 'format'")
+	}
+}
+
+func TestNewCommand_RawFlag(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Flags().Lookup("raw") == nil {
+		t.Error("NewCommand() should have flag 'raw'")
+	}
+}
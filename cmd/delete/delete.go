@@ -1,54 +1,177 @@
 package delete
 
 import (
+	"bufio"
+	"fmt"
+	"strings"
+
 	"github.com/negadras/tada/internal/todo"
 	"github.com/spf13/cobra"
-	"strconv"
 )
 
 func NewCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "delete [id]",
-		Short: "Delete a todo",
+		Use:   "delete [id...]",
+		Short: "Delete todos",
+		Long: `Delete todos by ID, or every todo matching a filter with --where.
+
+Deleting more than one todo asks for confirmation first, unless --yes is
+given. IDs read from stdin with - need --yes, as stdin is taken by them.`,
 		Example: `  # Delete todo #5
-  tada delete 5`,
-		Args: cobra.ExactArgs(1),
+  tada delete 5
+
+  # Delete several todos, in a single transaction
+  tada delete 3-9 12
+  tada list --status done --ids | tada delete --yes -
+
+  # Delete every completed todo older than 30 days
+  tada delete --where 'status:done and completed>30d'`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("where") {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			id, err := strconv.Atoi(args[0])
-			if err != nil {
-				todo.PrintError(cmd, err)
-				return nil
+			if cmd.Flags().Changed("where") {
+				return deleteWhere(cmd)
 			}
 
-			dbPath, err := todo.GetDatabasePath()
+			ids, err := todo.ParseIDs(args, cmd.InOrStdin())
 			if err != nil {
 				todo.PrintError(cmd, err)
 				return nil
 			}
 
-			db, err := todo.NewDB(dbPath)
+			db, cleanup, err := todo.GetDB(cmd)
 			if err != nil {
-				todo.PrintError(cmd, err)
 				return nil
 			}
-			defer db.Close()
+			defer cleanup()
 
-			// Get todo before deletion
-			todoItem, err := db.Get(id)
-			if err != nil {
-				todo.PrintError(cmd, err)
-				return nil
+			if len(ids) > 1 {
+				if yes, _ := cmd.Flags().GetBool("yes"); !yes && readsStdin(args) {
+					todo.PrintError(cmd, fmt.Errorf("deleting %d todos read from stdin needs --yes", len(ids)))
+					return nil
+				}
+				var found []*todo.Todo
+				for _, id := range ids {
+					if t, err := db.Get(id); err == nil {
+						found = append(found, t)
+					}
+				}
+				if !confirm(cmd, found) {
+					return nil
+				}
 			}
 
-			if err := db.Delete(id); err != nil {
+			results, err := deleteAll(db, ids)
+			if results == nil {
 				todo.PrintError(cmd, err)
 				return nil
 			}
-
-			cmd.Printf("🗑️  Deleted todo #%d: %s\n", id, todoItem.Description)
-			return nil
+			for _, r := range results {
+				if r.Err != nil {
+					todo.PrintError(cmd, r.Err)
+				} else {
+					cmd.Printf("🗑️  Deleted todo #%d: %s\n", r.ID, r.Todo.Description)
+				}
+			}
+			return err
 		},
 	}
 
+	cmd.Flags().StringP("where", "w", "", "Delete every todo matching a filter expression instead of ids")
+	cmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation when deleting more than one todo")
+
 	return cmd
 }
+
+// deleteWhere deletes every todo matching the --where filter after confirmation
+func deleteWhere(cmd *cobra.Command) error {
+	where, _ := cmd.Flags().GetString("where")
+	filter, err := todo.ParseFilter(where)
+	if err != nil {
+		todo.PrintError(cmd, err)
+		return nil
+	}
+
+	db, cleanup, err := todo.GetDB(cmd)
+	if err != nil {
+		return nil
+	}
+	defer cleanup()
+
+	matches, err := db.Find(filter)
+	if err != nil {
+		todo.PrintError(cmd, err)
+		return nil
+	}
+
+	if len(matches) == 0 {
+		cmd.Println("📝 No todos found matching your criteria.")
+		return nil
+	}
+
+	if !confirm(cmd, matches) {
+		return nil
+	}
+
+	ids := make([]int, 0, len(matches))
+	for _, t := range matches {
+		ids = append(ids, t.ID)
+	}
+	results, err := deleteAll(db, ids)
+	if results == nil {
+		todo.PrintError(cmd, err)
+		return nil
+	}
+	deleted := 0
+	for _, r := range results {
+		if r.Err != nil {
+			todo.PrintError(cmd, r.Err)
+		} else {
+			deleted++
+		}
+	}
+
+	cmd.Printf("🗑️  Deleted %d todos\n", deleted)
+	return err
+}
+
+// confirm lists the todos about to be deleted and asks whether to delete
+// them, unless --yes was given
+func confirm(cmd *cobra.Command, todos []*todo.Todo) bool {
+	if yes, _ := cmd.Flags().GetBool("yes"); yes {
+		return true
+	}
+
+	for _, t := range todos {
+		cmd.Printf("   #%d: %s\n", t.ID, t.Description)
+	}
+	cmd.Printf("Delete these %d todos? [y/N] ", len(todos))
+	answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer != "y" && answer != "yes" {
+		cmd.Println("Aborted.")
+		return false
+	}
+	return true
+}
+
+// readsStdin reports whether the IDs are read from stdin
+func readsStdin(args []string) bool {
+	for _, arg := range args {
+		if arg == "-" {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteAll deletes todos in a single transaction
+func deleteAll(db *todo.DB, ids []int) ([]todo.Result, error) {
+	return db.Batch(ids, func(db *todo.DB, t *todo.Todo) (*todo.Todo, error) {
+		return t, db.Delete(t.ID)
+	})
+}
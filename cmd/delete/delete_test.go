@@ -5,26 +5,34 @@ import "testing"
 func TestNewCommand(t *testing.T) {
 	cmd := NewCommand()
 
-	if cmd.Use != "delete [id]" {
+	if cmd.Use != "delete [id...]" {
 		t.Errorf("NewCommand.Use == %s, want %s", cmd.Use, "delete")
 	}
 
-	if cmd.Short != "Delete a todo" {
-		t.Errorf("NewCommand() Short = %v, want 'Delete a todo'", cmd.Short)
+	if cmd.Short != "Delete todos" {
+		t.Errorf("NewCommand() Short = %v, want 'Delete todos'", cmd.Short)
 	}
 }
 
 func TestDeleteCommand_Arguments(t *testing.T) {
 	cmd := NewCommand()
 
-	// Test that command requires exactly 1 argument
+	// Test that command requires at least 1 argument
 	cmd.SetArgs([]string{})
 	if err := cmd.Execute(); err == nil {
 		t.Error("Expected error when no arguments provided")
 	}
 
-	cmd.SetArgs([]string{"arg1", "arg2"})
-	if err := cmd.Execute(); err == nil {
-		t.Error("Expected error when no arguments provided")
+	if err := cmd.Args(cmd, []string{"3", "5-9", "-"}); err != nil {
+		t.Errorf("Expected several IDs to be accepted, got %v", err)
+	}
+}
+
+func TestReadsStdin(t *testing.T) {
+	if !readsStdin([]string{"3", "-"}) {
+		t.Error("readsStdin([3 -]) = false, want true")
+	}
+	if readsStdin([]string{"3-9", "12"}) {
+		t.Error("readsStdin([3-9 12]) = true, want false")
 	}
 }
@@ -0,0 +1,241 @@
+package edit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/negadras/tada/internal/edit"
+	"github.com/negadras/tada/internal/todo"
+	"github.com/spf13/cobra"
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "edit [filter]",
+		Short: "Edit many todos at once in your editor",
+		Long: `Write the todos matching a filter (open todos by default) to a file, one per
+line, and open it in $VISUAL or $EDITOR:
+
+  #12 [H] {work} Review the budget
+  #15 [M] {} Call the plumber
+
+Change priorities ([H], [M] or [L]), tags ({} for none) and descriptions in
+place, remove a line to delete its todo, and add a line without an #ID to
+create one. After saving and quitting, the changes are applied in a single
+transaction; deleting asks for confirmation first. A file with a mistake can be edited
+again, and saving an empty file changes nothing.
+
+The filter is the same as for 'tada list'.`,
+		Example: `  # Triage every open todo
+  tada edit
+
+  # Only work todos, in vim
+  EDITOR=vim tada edit tag:work
+
+  # Review everything done this week
+  tada edit 'status:done and completed<7d'`,
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter, err := todo.ParseFilter(strings.Join(args, " "))
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			if !filter.Uses("status") {
+				open := todo.Open
+				filter = todo.NewFieldFilter(&open, nil, nil).And(filter)
+			}
+
+			db, cleanup, err := todo.GetDB(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			todos, err := db.Find(filter)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			todo.SortTodos(todos, []todo.SortKey{{Field: "priority"}, {Field: "id"}})
+
+			in := bufio.NewReader(cmd.InOrStdin())
+			plan, err := editTodos(cmd, in, todos)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			if plan == nil {
+				cmd.Println("Aborted, nothing changed.")
+				return nil
+			}
+			if plan.Empty() {
+				cmd.Println("Nothing changed.")
+				return nil
+			}
+
+			if len(plan.Delete) > 0 && !confirmDelete(cmd, in, plan.Delete) {
+				cmd.Printf("Kept %d todos whose lines were removed\n", len(plan.Delete))
+				plan.Delete = nil
+				if plan.Empty() {
+					return nil
+				}
+			}
+
+			if err := apply(db, todos, plan); err != nil {
+				todo.PrintError(cmd, fmt.Errorf("nothing changed: %w", err))
+				return nil
+			}
+			todo.PrintSuccess(cmd, "Edited todos: "+summary(plan))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation before deleting")
+
+	return cmd
+}
+
+// editTodos has the todos edited until the file can be read, and returns
+// the changes, or nil if the user gave up
+func editTodos(cmd *cobra.Command, in *bufio.Reader, todos []*todo.Todo) (*edit.Plan, error) {
+	f, err := os.CreateTemp("", "tada-edit-*.txt")
+	if err != nil {
+		return nil, err
+	}
+	path := f.Name()
+	defer os.Remove(path)
+
+	_, err = f.WriteString(edit.Format(todos))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if err := runEditor(path); err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		lines, err := edit.Parse(string(data))
+		var plan *edit.Plan
+		if err == nil {
+			plan, err = edit.PlanEdit(todos, lines)
+		}
+		if err == nil {
+			return plan, nil
+		}
+
+		cmd.Println("❌ The file could not be read:")
+		for _, line := range strings.Split(err.Error(), "\n") {
+			cmd.Printf("   %s\n", line)
+		}
+		cmd.Print("Edit it again? [Y/n] ")
+		answer, _ := in.ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer != "" && answer != "y" && answer != "yes" {
+			return nil, nil
+		}
+	}
+}
+
+// runEditor opens a file in $VISUAL or $EDITOR, which may include
+// arguments, such as "code --wait"
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	c := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("%s exited with status %d, nothing changed", editor, exitErr.ExitCode())
+		}
+		return fmt.Errorf("failed to run %s: %w", editor, err)
+	}
+	return nil
+}
+
+// confirmDelete lists the todos whose lines were removed and asks whether
+// to delete them
+func confirmDelete(cmd *cobra.Command, in *bufio.Reader, todos []*todo.Todo) bool {
+	if yes, _ := cmd.Flags().GetBool("yes"); yes {
+		return true
+	}
+	for _, t := range todos {
+		cmd.Printf("   #%d: %s\n", t.ID, t.Description)
+	}
+	cmd.Printf("Delete these %d todos? [y/N] ", len(todos))
+	answer, _ := in.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// apply makes the changes of an edit in a single transaction
+func apply(db *todo.DB, todos []*todo.Todo, plan *edit.Plan) error {
+	byID := make(map[int]*todo.Todo, len(todos))
+	for _, t := range todos {
+		byID[t.ID] = t
+	}
+
+	return db.Transaction(func(db *todo.DB) error {
+		for _, l := range plan.Create {
+			if _, err := db.Create(l.Description, l.Priority, l.Tag); err != nil {
+				return err
+			}
+		}
+
+		for _, l := range plan.Update {
+			old := byID[l.ID]
+			if l.Priority != old.Priority {
+				if err := db.UpdatePriority(l.ID, l.Priority); err != nil {
+					return err
+				}
+			}
+			if l.Description != old.Description {
+				if err := db.UpdateDescription(l.ID, l.Description); err != nil {
+					return err
+				}
+			}
+			if l.Tag != old.Tag {
+				if err := db.UpdateTag(l.ID, l.Tag); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, t := range plan.Delete {
+			if err := db.Delete(t.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// summary describes the changes of an edit
+func summary(plan *edit.Plan) string {
+	var tally todo.Tally
+	tally.Add(len(plan.Create), "created")
+	tally.Add(len(plan.Update), "updated")
+	tally.Add(len(plan.Delete), "deleted")
+	return tally.Describe("nothing changed")
+}
@@ -0,0 +1,23 @@
+package edit
+
+import "testing"
+
+func TestNewCommand(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Use != "edit [filter]" {
+		t.Errorf("NewCommand() Use = %v, want 'edit [filter]'", cmd.Use)
+	}
+
+	if cmd.Short != "Edit many todos at once in your editor" {
+		t.Errorf("NewCommand() Short = %v, want 'Edit many todos at once in your editor'", cmd.Short)
+	}
+
+	yes := cmd.Flags().Lookup("yes")
+	if yes == nil {
+		t.Fatal("NewCommand() should have a yes flag")
+	}
+	if yes.Shorthand != "y" {
+		t.Errorf("yes flag shorthand = %v, want 'y'", yes.Shorthand)
+	}
+}
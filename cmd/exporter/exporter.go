@@ -0,0 +1,137 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/negadras/tada/internal/backup"
+	"github.com/negadras/tada/internal/ical"
+	"github.com/negadras/tada/internal/markdown"
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/todo"
+	"github.com/negadras/tada/internal/todotxt"
+	"github.com/spf13/cobra"
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export [filter]",
+		Short: "Export a backup or todos for another todo app",
+		Long: `Export a full backup of every todo and quote, or todos in a format other todo
+apps understand. Writes to stdout unless --file is given.
+
+Supported formats:
+  backup   - tada JSON backup of all todos and quotes, read by 'tada import' (default)
+  todotxt  - todo.txt, one task per line, oldest first
+  ics      - iCalendar VTODOs for calendar and task apps, keeping each todo's UID
+  markdown - GitHub-flavoured "- [ ]"/"- [x]" checklist grouped by tag, with #tag,
+             !high/!low and due: markers that 'tada import --from markdown' reads back
+
+For all formats but backup an optional filter expression limits the export.`,
+		Example: `  # Back up everything before moving machines
+  tada export > backup.json
+
+  # Keep a plain-text todo.txt mirror
+  tada export --to todotxt --file ~/todo.txt
+
+  # Export only open work todos
+  tada export --to todotxt 'status:open tag:work'
+
+  # Open todos for a calendar app
+  tada export --to ics status:open --file tasks.ics
+
+  # Paste open work todos into a PR description
+  tada export --to markdown 'status:open tag:work'`,
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			to, _ := cmd.Flags().GetString("to")
+			switch to {
+			case "backup", "todotxt", "ics", "markdown":
+			default:
+				todo.PrintError(cmd, fmt.Errorf("unknown export format %q (must be one of: backup, todotxt, ics, markdown)", to))
+				return nil
+			}
+			if to == "backup" && len(args) > 0 {
+				todo.PrintError(cmd, fmt.Errorf("a backup always contains every todo and quote; filters need another --to format"))
+				return nil
+			}
+
+			filter, err := todo.ParseFilter(strings.Join(args, " "))
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			db, cleanup, err := todo.GetDB(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			todos, err := db.Find(filter)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			todo.SortTodos(todos, []todo.SortKey{{Field: "created"}, {Field: "id"}})
+
+			var quotes []*quote.Quote
+			if to == "backup" {
+				quoteDB, quoteCleanup, err := quote.GetDB(cmd)
+				if err != nil {
+					return nil
+				}
+				defer quoteCleanup()
+
+				quotes, err = quoteDB.List(nil, nil)
+				if err != nil {
+					todo.PrintError(cmd, err)
+					return nil
+				}
+			}
+
+			var out io.Writer = cmd.OutOrStdout()
+			file, _ := cmd.Flags().GetString("file")
+			if file != "" {
+				f, err := os.Create(file)
+				if err != nil {
+					todo.PrintError(cmd, err)
+					return nil
+				}
+				defer f.Close()
+				out = f
+			}
+
+			switch to {
+			case "backup":
+				err = backup.New(todos, quotes).Write(out)
+			case "todotxt":
+				err = todotxt.Write(out, todos)
+			case "ics":
+				err = ical.Write(out, todos)
+			case "markdown":
+				err = markdown.Write(out, todos)
+			}
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			if file != "" {
+				if to == "backup" {
+					todo.PrintSuccess(cmd, fmt.Sprintf("Exported %d todos and %d quotes to %s", len(todos), len(quotes), file))
+				} else {
+					todo.PrintSuccess(cmd, fmt.Sprintf("Exported %d todos to %s", len(todos), file))
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("to", "backup", "Format to export (backup, todotxt, ics, markdown)")
+	cmd.Flags().StringP("file", "f", "", "Write to a file instead of stdout")
+
+	return cmd
+}
@@ -0,0 +1,23 @@
+package exporter
+
+import "testing"
+
+func TestNewCommand(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Use != "export [filter]" {
+		t.Errorf("NewCommand() Use = %v, want 'export [filter]'", cmd.Use)
+	}
+
+	if cmd.Short != "Export a backup or todos for another todo app" {
+		t.Errorf("NewCommand() Short = %v, want 'Export a backup or todos for another todo app'", cmd.Short)
+	}
+
+	if cmd.Flags().Lookup("to") == nil {
+		t.Error("NewCommand() should have flag 'to'")
+	}
+
+	if cmd.Flags().ShorthandLookup("f") == nil {
+		t.Error("NewCommand() should have short file flag 'f'")
+	}
+}
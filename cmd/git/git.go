@@ -0,0 +1,423 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/negadras/tada/internal/gitstore"
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/todo"
+	"github.com/negadras/tada/internal/watch"
+	"github.com/spf13/cobra"
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "git",
+		Short: "Keep todos and quotes in a git repository",
+		Long: `Keep a copy of every todo and quote as plain-text files in a git repository at
+~/.tada/repo, one JSON file per todo or quote named after its UID. Once the
+repository is created, every tada command that changes a todo or quote commits
+the change, giving a history of every edit that can be reviewed with git and
+shared through any git remote.
+
+Pulling merges the other side's commits record by record: a todo or quote
+changed on both sides keeps the most recently updated version, and one
+deleted on one side but changed on the other is kept. Each such conflict is
+reported.`,
+		Example: `  # Start keeping history
+  tada git init
+
+  # Share through a remote, then push after changes
+  git -C ~/.tada/repo remote add origin git@example.com:me/todos.git
+  tada git push
+
+  # On another machine, start from the remote and keep in step
+  tada git init git@example.com:me/todos.git
+  tada git pull`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(newInitCommand())
+	cmd.AddCommand(newCommitCommand())
+	cmd.AddCommand(newPullCommand())
+	cmd.AddCommand(newPushCommand())
+	cmd.AddCommand(newInstallHooksCommand())
+	cmd.AddCommand(newLinkCommand())
+	cmd.AddCommand(newHookCommand())
+
+	return cmd
+}
+
+func newInitCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "init [remote]",
+		Short: "Create the git repository, or clone it from a remote",
+		Long: `Create the git repository and commit every todo and quote to it. Given a
+remote, the repository is cloned from it instead, and its todos and quotes are
+added to this database's.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := gitstore.GetPath()
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			remote := ""
+			if len(args) > 0 {
+				remote = args[0]
+			}
+
+			// Fail before creating the repository if the todos cannot go in it
+			if _, _, err := readDatabase(); err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			if err := gitstore.Init(dir, remote); err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			if err := combine(dir); err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			_, err = gitstore.Commit(dir, "tada git init")
+			if err == nil {
+				err = watch.Stamp(savedPath(dir))
+			}
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			if remote != "" {
+				todo.PrintSuccess(cmd, fmt.Sprintf("Cloned %s into %s", remote, dir))
+			} else {
+				todo.PrintSuccess(cmd, "Created git repository "+dir)
+			}
+			return nil
+		},
+	}
+}
+
+func newCommitCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "commit",
+		Short: "Commit changes not committed yet",
+		Long: `Commit changes not committed yet. tada commits after each command on its own,
+so this is only needed to commit changes made while the repository was missing
+or to give them a message of your own.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := storePath()
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			message, _ := cmd.Flags().GetString("message")
+			committed, err := save(dir, message)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			if !committed {
+				cmd.Println("Nothing to commit")
+				return nil
+			}
+			todo.PrintSuccess(cmd, "Committed")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("message", "m", "tada git commit", "Commit message")
+
+	return cmd
+}
+
+func newPullCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "pull",
+		Short: "Merge todos and quotes from the remote",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := storePath()
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			if _, err := save(dir, "tada git pull"); err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			merged, conflicts, err := gitstore.Pull(dir)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			if !merged {
+				cmd.Println("Already up to date")
+				return nil
+			}
+			if err := load(dir); err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			for _, c := range conflicts {
+				printConflict(cmd, c)
+			}
+			todo.PrintSuccess(cmd, "Pulled from the remote")
+			if len(conflicts) > 0 {
+				cmd.Printf("   %d conflicts\n", len(conflicts))
+			}
+			return nil
+		},
+	}
+}
+
+func newPushCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "push",
+		Short: "Push committed todos and quotes to the remote",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := storePath()
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			if _, err := save(dir, "tada git push"); err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			if err := gitstore.Push(dir); err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			todo.PrintSuccess(cmd, "Pushed to the remote")
+			return nil
+		},
+	}
+}
+
+// AutoCommit commits the changes a command made, if the git repository has
+// been created and the database changed since it was last saved. The git
+// commands commit on their own.
+func AutoCommit(cmd *cobra.Command) {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Name() == "git" && c.HasParent() {
+			return
+		}
+	}
+
+	autoSave(cmd, "tada "+strings.Join(os.Args[1:], " "))
+}
+
+// autoSave commits the changes a command made with message, if the git
+// repository has been created and the database changed since it was last
+// saved
+func autoSave(cmd *cobra.Command, message string) {
+	dir, err := gitstore.GetPath()
+	if err != nil || !gitstore.Enabled(dir) {
+		return
+	}
+	if dbPath, err := todo.GetDatabasePath(); err == nil && !watch.Changed(dbPath, savedPath(dir)) {
+		return
+	}
+	if _, err := save(dir, message); err != nil {
+		todo.PrintError(cmd, fmt.Errorf("failed to commit to %s: %w", dir, err))
+	}
+}
+
+// storePath returns the path of the git repository, which must exist
+func storePath() (string, error) {
+	dir, err := gitstore.GetPath()
+	if err != nil {
+		return "", err
+	}
+	if !gitstore.Enabled(dir) {
+		return "", fmt.Errorf("no git repository yet, run 'tada git init' first")
+	}
+	return dir, nil
+}
+
+// save writes every todo and quote to the repository and commits them if
+// anything changed
+func save(dir, message string) (bool, error) {
+	todos, quotes, err := readDatabase()
+	if err != nil {
+		return false, err
+	}
+	if err := gitstore.Write(dir, todos, quotes); err != nil {
+		return false, err
+	}
+	committed, err := gitstore.Commit(dir, message)
+	if err != nil {
+		return false, err
+	}
+	return committed, watch.Stamp(savedPath(dir))
+}
+
+// savedPath returns the path of the file whose time is when the database
+// was last saved to the repository in dir, kept out of the records in .git
+func savedPath(dir string) string {
+	return filepath.Join(dir, ".git", "tada-saved")
+}
+
+// combine adds the todos and quotes of the repository to the database and
+// those of the database to the repository, keeping the most recently
+// updated version of any in both
+func combine(dir string) error {
+	todos, quotes, err := readDatabase()
+	if err != nil {
+		return err
+	}
+	fileTodos, fileQuotes, err := gitstore.Read(dir)
+	if err != nil {
+		return err
+	}
+
+	todoIndex := make(map[string]int)
+	for i, t := range todos {
+		todoIndex[t.UID] = i
+	}
+	for _, t := range fileTodos {
+		if i, ok := todoIndex[t.UID]; !ok {
+			todos = append(todos, t)
+		} else if t.UpdatedAt.After(todos[i].UpdatedAt) {
+			todos[i] = t
+		}
+	}
+
+	quoteIndex := make(map[string]int)
+	for i, q := range quotes {
+		quoteIndex[q.UID] = i
+	}
+	for _, q := range fileQuotes {
+		if i, ok := quoteIndex[q.UID]; !ok {
+			quotes = append(quotes, q)
+		} else if q.UpdatedAt.After(quotes[i].UpdatedAt) {
+			quotes[i] = q
+		}
+	}
+
+	if err := gitstore.Write(dir, todos, quotes); err != nil {
+		return err
+	}
+	return load(dir)
+}
+
+// load brings the todos and quotes of the database in line with those of the
+// repository. Todos and quotes already in the database keep their IDs.
+func load(dir string) error {
+	todos, quotes, err := gitstore.Read(dir)
+	if err != nil {
+		return err
+	}
+	oldTodos, oldQuotes, err := readDatabase()
+	if err != nil {
+		return err
+	}
+
+	todoIDs := make(map[string]int)
+	for _, t := range oldTodos {
+		todoIDs[t.UID] = t.ID
+	}
+	for _, t := range todos {
+		t.ID = todoIDs[t.UID]
+	}
+	quoteIDs := make(map[string]int)
+	for _, q := range oldQuotes {
+		quoteIDs[q.UID] = q.ID
+	}
+	for _, q := range quotes {
+		q.ID = quoteIDs[q.UID]
+	}
+
+	db, quoteDB, cleanup, err := openDatabases()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// Todos and quotes are loaded together or not at all
+	return db.Transaction(func(db *todo.DB) error {
+		if err := db.Load(todos); err != nil {
+			return err
+		}
+		return quoteDB.Join(db.Tx()).Load(quotes)
+	})
+}
+
+// openDatabases opens the todo and quote databases
+func openDatabases() (*todo.DB, *quote.DB, func(), error) {
+	dbPath, err := todo.GetDatabasePath()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	db, err := todo.NewDB(dbPath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	quoteDB, err := quote.NewDB(dbPath)
+	if err != nil {
+		db.Close()
+		return nil, nil, nil, err
+	}
+
+	cleanup := func() {
+		quoteDB.Close()
+		db.Close()
+	}
+	return db, quoteDB, cleanup, nil
+}
+
+// errEncrypted refuses to write the todos of an encrypted database to the
+// repository, whose files are plain text
+var errEncrypted = errors.New("todos are encrypted and the git repository would hold them in plain text; run 'tada decrypt' to use it")
+
+// readDatabase reads every todo and quote of the database
+func readDatabase() ([]*todo.Todo, []*quote.Quote, error) {
+	db, quoteDB, cleanup, err := openDatabases()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cleanup()
+	if db.Encrypted() {
+		return nil, nil, errEncrypted
+	}
+
+	todos, err := db.Find(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	quotes, err := quoteDB.List(nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return todos, quotes, nil
+}
+
+// printConflict reports a todo or quote changed on both sides of a pull
+func printConflict(cmd *cobra.Command, c gitstore.Conflict) {
+	name := fmt.Sprintf("%s %q", c.Kind, c.Label)
+	switch {
+	case c.Deleted && c.KeptLocal:
+		cmd.Printf("⚠️  %s was deleted on the remote but changed here; kept it\n", name)
+	case c.Deleted:
+		cmd.Printf("⚠️  %s was deleted here but changed on the remote; restored it\n", name)
+	case c.KeptLocal:
+		cmd.Printf("⚠️  %s changed on both sides; kept this version, which is newer\n", name)
+	default:
+		cmd.Printf("⚠️  %s changed on both sides; kept the remote's version, which is newer\n", name)
+	}
+}
@@ -0,0 +1,37 @@
+package git
+
+import "testing"
+
+func TestNewCommand(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Use != "git" {
+		t.Errorf("NewCommand() Use = %v, want 'git'", cmd.Use)
+	}
+
+	if cmd.Short != "Keep todos and quotes in a git repository" {
+		t.Errorf("NewCommand() Short = %v, want 'Keep todos and quotes in a git repository'", cmd.Short)
+	}
+
+	for _, name := range []string{"init", "commit", "pull", "push", "install-hooks", "link", "hook"} {
+		if _, _, err := cmd.Find([]string{name}); err != nil {
+			t.Errorf("NewCommand() should have subcommand '%s': %v", name, err)
+		}
+	}
+}
+
+func TestNewCommitCommand(t *testing.T) {
+	cmd := newCommitCommand()
+
+	if cmd.Flags().Lookup("message") == nil {
+		t.Error("newCommitCommand() should have flag 'message'")
+	}
+}
+
+func TestNewLinkCommand(t *testing.T) {
+	cmd := newLinkCommand()
+
+	if cmd.Flags().Lookup("repo") == nil {
+		t.Error("newLinkCommand() should have flag 'repo'")
+	}
+}
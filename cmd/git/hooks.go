@@ -0,0 +1,155 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/negadras/tada/internal/gitstore"
+	"github.com/negadras/tada/internal/todo"
+	"github.com/spf13/cobra"
+)
+
+func newInstallHooksCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "install-hooks",
+		Short: "Close todos from commit messages in the current repository",
+		Long: `Install a post-commit hook in the git repository holding the current
+directory. After each commit, the hook marks done the todos the message
+references, with a trailer or anywhere in the message, and links the commit to
+them:
+
+  Closes-Todo: 42
+  Closes-Todo: 42, 43
+  Fix the login redirect (tada#42)
+
+The hook runs 'tada git hook post-commit', which uses the todos of the
+repository's .tada store if it has one. A post-commit hook of another tool is
+left alone; add that command to it instead.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := gitstore.InstallHook(".")
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			todo.PrintSuccess(cmd, "Installed "+path)
+			return nil
+		},
+	}
+}
+
+func newHookCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:       "hook post-commit",
+		Short:     "Run by the hook installed with 'tada git install-hooks'",
+		Hidden:    true,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"post-commit"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if args[0] != "post-commit" {
+				todo.PrintError(cmd, fmt.Errorf("unknown hook %q", args[0]))
+				return nil
+			}
+
+			commit, err := gitstore.ResolveCommit(".", "HEAD")
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			ids := gitstore.ParseTodoRefs(commit.Message)
+			if len(ids) == 0 {
+				return nil
+			}
+
+			db, cleanup, err := todo.GetDB(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			link := linkedCommit(commit)
+			for _, id := range ids {
+				t, err := db.Get(id)
+				if err != nil {
+					todo.PrintError(cmd, fmt.Errorf("todo #%d not found", id))
+					continue
+				}
+				if _, err := db.LinkCommit(id, link); err != nil {
+					todo.PrintError(cmd, err)
+					continue
+				}
+				if t.Status == todo.Done {
+					cmd.Printf("🔗 Linked todo #%d to %s\n", id, link.ShortSHA())
+					continue
+				}
+				if err := db.UpdateStatus(id, todo.Done); err != nil {
+					todo.PrintError(cmd, err)
+					continue
+				}
+				todo.PrintSuccess(cmd, fmt.Sprintf("Closed todo #%d: %s", id, t.Description))
+			}
+
+			autoSave(cmd, "tada git hook post-commit "+link.ShortSHA())
+			return nil
+		},
+	}
+}
+
+func newLinkCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "link <id> <commit>",
+		Short: "Link a commit to a todo",
+		Long: `Link a commit of the git repository holding the current directory, or the
+one given with --repo, to a todo, without changing the todo's status. The
+commit can be given as anything git understands, such as a hash, a branch or
+HEAD. Linked commits are listed by 'tada show'.`,
+		Example: `  tada git link 42 3f9c2ab
+  tada git link 42 HEAD~1 --repo ~/src/api`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.Atoi(args[0])
+			if err != nil {
+				todo.PrintError(cmd, fmt.Errorf("invalid todo ID: %s", args[0]))
+				return nil
+			}
+			repo, _ := cmd.Flags().GetString("repo")
+			commit, err := gitstore.ResolveCommit(repo, args[1])
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			db, cleanup, err := todo.GetDB(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			link := linkedCommit(commit)
+			linked, err := db.LinkCommit(id, link)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			if !linked {
+				cmd.Printf("Todo #%d is already linked to %s\n", id, link.ShortSHA())
+				return nil
+			}
+			cmd.Printf("🔗 Linked todo #%d to %s %s\n", id, link.ShortSHA(), link.Subject)
+
+			autoSave(cmd, "tada "+strings.Join(os.Args[1:], " "))
+			return nil
+		},
+	}
+
+	cmd.Flags().String("repo", ".", "Directory in the commit's repository")
+
+	return cmd
+}
+
+// linkedCommit returns the link to a todo recorded for a commit
+func linkedCommit(c *gitstore.RepoCommit) *todo.Commit {
+	return &todo.Commit{SHA: c.SHA, Repo: c.Repo, Subject: c.Subject}
+}
@@ -0,0 +1,26 @@
+package hooks
+
+import (
+	"github.com/negadras/tada/cmd/git"
+	"github.com/negadras/tada/cmd/prompt"
+	"github.com/negadras/tada/cmd/webhook"
+	"github.com/negadras/tada/internal/todo"
+	"github.com/negadras/tada/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+// AfterChange passes on changes made to todos and quotes: it commits them to
+// the git repository, sends them to webhooks and refreshes the prompt cache.
+// It runs after every command and after each change made through a server.
+func AfterChange(cmd *cobra.Command) {
+	// Nothing can have changed in a database that is locked, and each of
+	// them would report that it is
+	if dbPath, err := todo.GetDatabasePath(); err != nil || vault.Locked(dbPath) {
+		return
+	}
+
+	git.AutoCommit(cmd)
+	webhook.Notify(cmd)
+	// Last, as the others may write to the database
+	prompt.RefreshCache(cmd)
+}
@@ -0,0 +1,334 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/negadras/tada/internal/backup"
+	"github.com/negadras/tada/internal/ical"
+	"github.com/negadras/tada/internal/markdown"
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/taskwarrior"
+	"github.com/negadras/tada/internal/todo"
+	"github.com/negadras/tada/internal/todotxt"
+	"github.com/spf13/cobra"
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import [file]",
+		Short: "Import todos from a backup or another todo app",
+		Long: `Import a backup written by 'tada export', or todos from a file written by
+another todo app. Reads from stdin when no file or "-" is given. Either every
+item in the file is imported or none is.
+
+Supported formats:
+  backup       - tada JSON backup of todos and quotes (default)
+  todotxt      - todo.txt (priorities, completion and creation dates, +project/@context tags, due:)
+  taskwarrior  - output of 'task export' (priorities, status, project/tags, dates, annotations as notes)
+  ics          - iCalendar VTODOs (SUMMARY, PRIORITY, STATUS, CATEGORIES, CREATED/COMPLETED/DUE)
+  markdown     - "- [ ]" and "- [x]" task list items, with #tag, !high/!medium/!low and due: markers
+
+Tasks from Taskwarrior and calendars keep their UID, and tasks whose UID is
+already in tada are skipped, so importing the same file again adds nothing.
+
+Backups are merged by default: todos with the same description and creation
+time, and quotes with the same text and author, are skipped, so importing the
+same backup twice changes nothing. --replace deletes all todos and quotes
+first and restores the backup exactly, IDs included.`,
+		Example: `  # Restore a backup on a new machine
+  tada import backup.json --replace
+
+  # Merge a colleague's quotes and todos into yours, previewing first
+  tada import their-backup.json --dry-run
+  tada import their-backup.json
+
+  # Import a todo.txt file
+  tada import --from todotxt ~/todo.txt
+
+  # Import everything from Taskwarrior
+  task export | tada import --from taskwarrior
+
+  # Import tasks exported from a calendar app
+  tada import --from ics tasks.ics
+
+  # Turn the action items of meeting notes into todos
+  tada import --from markdown notes.md`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, _ := cmd.Flags().GetString("from")
+			replace, _ := cmd.Flags().GetBool("replace")
+			if replace && from != "backup" {
+				todo.PrintError(cmd, fmt.Errorf("--replace is only supported when importing a backup"))
+				return nil
+			}
+
+			var in io.Reader = cmd.InOrStdin()
+			if len(args) == 1 && args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					todo.PrintError(cmd, err)
+					return nil
+				}
+				defer f.Close()
+				in = f
+			}
+
+			switch from {
+			case "backup":
+				return importBackup(cmd, in)
+			case "todotxt":
+				return importTodoTxt(cmd, in)
+			case "taskwarrior":
+				return importTaskwarrior(cmd, in)
+			case "ics":
+				return importICS(cmd, in)
+			case "markdown":
+				return importMarkdown(cmd, in)
+			default:
+				todo.PrintError(cmd, fmt.Errorf("unknown import format %q (must be one of: backup, todotxt, taskwarrior, ics, markdown)", from))
+				return nil
+			}
+		},
+	}
+
+	cmd.Flags().String("from", "backup", "Format of the file to import (backup, todotxt, taskwarrior, ics, markdown)")
+	cmd.Flags().Bool("dry-run", false, "Show what would be imported without saving anything")
+	cmd.Flags().Bool("merge", false, "Add the backup's todos and quotes, skipping ones you already have (default)")
+	cmd.Flags().Bool("replace", false, "Delete all todos and quotes, then restore the backup")
+	cmd.MarkFlagsMutuallyExclusive("merge", "replace")
+
+	return cmd
+}
+
+// importBackup merges a backup into the database or replaces it
+func importBackup(cmd *cobra.Command, in io.Reader) error {
+	b, err := backup.Read(in)
+	if err != nil {
+		todo.PrintError(cmd, err)
+		return nil
+	}
+
+	db, cleanup, err := todo.GetDB(cmd)
+	if err != nil {
+		return nil
+	}
+	defer cleanup()
+
+	quoteDB, quoteCleanup, err := quote.GetDB(cmd)
+	if err != nil {
+		return nil
+	}
+	defer quoteCleanup()
+
+	existingTodos, err := db.Find(nil)
+	if err != nil {
+		todo.PrintError(cmd, err)
+		return nil
+	}
+	existingQuotes, err := quoteDB.List(nil, nil)
+	if err != nil {
+		todo.PrintError(cmd, err)
+		return nil
+	}
+
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	if replace, _ := cmd.Flags().GetBool("replace"); replace {
+		summary := fmt.Sprintf("%d todos and %d quotes with the backup's %d todos and %d quotes",
+			len(existingTodos), len(existingQuotes), len(b.Todos), len(b.Quotes))
+		if dryRun {
+			cmd.Printf("Would replace %s\n", summary)
+			return nil
+		}
+
+		// Todos and quotes are restored together or not at all
+		err := db.Transaction(func(db *todo.DB) error {
+			if err := quoteDB.Join(db.Tx()).Replace(b.Quotes); err != nil {
+				return err
+			}
+			return db.Replace(b.Todos)
+		})
+		if err != nil {
+			todo.PrintError(cmd, err)
+			return nil
+		}
+
+		todo.PrintSuccess(cmd, "Replaced "+summary)
+		return nil
+	}
+
+	merge := backup.PlanMerge(b, existingTodos, existingQuotes)
+	summary := fmt.Sprintf("%d todos (%d already present) and %d quotes (%d already present)",
+		len(merge.Todos), merge.SkippedTodos, len(merge.Quotes), merge.SkippedQuotes)
+	if dryRun {
+		cmd.Printf("Would import %s\n", summary)
+		return nil
+	}
+
+	err = db.Transaction(func(db *todo.DB) error {
+		if err := quoteDB.Join(db.Tx()).Insert(merge.Quotes); err != nil {
+			return err
+		}
+		return db.Insert(merge.Todos)
+	})
+	if err != nil {
+		todo.PrintError(cmd, err)
+		return nil
+	}
+
+	todo.PrintSuccess(cmd, "Imported "+summary)
+	return nil
+}
+
+// importTodoTxt adds the todos of a todo.txt file
+func importTodoTxt(cmd *cobra.Command, in io.Reader) error {
+	todos, err := todotxt.Parse(in)
+	if err != nil {
+		todo.PrintError(cmd, err)
+		return nil
+	}
+
+	insertAll(cmd, todos)
+	return nil
+}
+
+// importMarkdown adds the task list items of a markdown file
+func importMarkdown(cmd *cobra.Command, in io.Reader) error {
+	todos, err := markdown.Parse(in)
+	if err != nil {
+		todo.PrintError(cmd, err)
+		return nil
+	}
+
+	// Items of a file kept in sync carry their UID
+	insertNew(cmd, todos)
+	return nil
+}
+
+// insertAll inserts todos, or prints them with --dry-run
+func insertAll(cmd *cobra.Command, todos []*todo.Todo) {
+	if len(todos) == 0 {
+		cmd.Println("📝 Nothing to import.")
+		return
+	}
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		for _, t := range todos {
+			todo.PrintTodo(cmd, t)
+		}
+		cmd.Printf("Would import %d todos\n", len(todos))
+		return
+	}
+
+	db, cleanup, err := todo.GetDB(cmd)
+	if err != nil {
+		return
+	}
+	defer cleanup()
+
+	if err := db.Insert(todos); err != nil {
+		todo.PrintError(cmd, err)
+		return
+	}
+
+	todo.PrintSuccess(cmd, fmt.Sprintf("Imported %d todos", len(todos)))
+}
+
+// importTaskwarrior adds the tasks of a Taskwarrior export and reports what
+// could not be carried over
+func importTaskwarrior(cmd *cobra.Command, in io.Reader) error {
+	result, err := taskwarrior.Parse(in)
+	if err != nil {
+		todo.PrintError(cmd, err)
+		return nil
+	}
+
+	if !insertNew(cmd, result.Todos) {
+		return nil
+	}
+
+	if result.Deleted > 0 || result.Recurring > 0 {
+		cmd.Printf("   Skipped %d deleted tasks and %d recurring task templates\n", result.Deleted, result.Recurring)
+	}
+	if len(result.SkippedFields) > 0 {
+		cmd.Printf("   Not imported: %s\n", result.SkippedSummary())
+	}
+	if result.Shortened > 0 {
+		cmd.Printf("   Shortened %d descriptions longer than 255 characters (full text kept in notes)\n", result.Shortened)
+	}
+	return nil
+}
+
+// importICS adds the VTODOs of an iCalendar file
+func importICS(cmd *cobra.Command, in io.Reader) error {
+	result, err := ical.Parse(in)
+	if err != nil {
+		todo.PrintError(cmd, err)
+		return nil
+	}
+
+	if !insertNew(cmd, result.Todos) {
+		return nil
+	}
+
+	if result.Cancelled > 0 {
+		cmd.Printf("   Skipped %d cancelled tasks\n", result.Cancelled)
+	}
+	return nil
+}
+
+// insertNew inserts the todos whose UID is not in the database yet, or
+// prints them with --dry-run, and reports how many were skipped. It returns
+// false if the import failed.
+func insertNew(cmd *cobra.Command, todos []*todo.Todo) bool {
+	db, cleanup, err := todo.GetDB(cmd)
+	if err != nil {
+		return false
+	}
+	defer cleanup()
+
+	existing, err := db.Find(nil)
+	if err != nil {
+		todo.PrintError(cmd, err)
+		return false
+	}
+	uids := map[string]bool{}
+	for _, t := range existing {
+		uids[t.UID] = true
+	}
+
+	var fresh []*todo.Todo
+	for _, t := range todos {
+		if t.UID != "" && uids[t.UID] {
+			continue
+		}
+		if t.UID != "" {
+			uids[t.UID] = true
+		}
+		fresh = append(fresh, t)
+	}
+	skipped := len(todos) - len(fresh)
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		for _, t := range fresh {
+			todo.PrintTodo(cmd, t)
+		}
+		cmd.Printf("Would import %d todos (%d already present)\n", len(fresh), skipped)
+		return true
+	}
+
+	if len(fresh) == 0 {
+		cmd.Printf("📝 Nothing to import (%d already present).\n", skipped)
+		return true
+	}
+
+	if err := db.Insert(fresh); err != nil {
+		todo.PrintError(cmd, err)
+		return false
+	}
+
+	todo.PrintSuccess(cmd, fmt.Sprintf("Imported %d todos (%d already present)", len(fresh), skipped))
+	return true
+}
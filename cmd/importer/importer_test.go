@@ -0,0 +1,44 @@
+package importer
+
+import "testing"
+
+func TestNewCommand(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Use != "import [file]" {
+		t.Errorf("NewCommand() Use = %v, want 'import [file]'", cmd.Use)
+	}
+
+	if cmd.Short != "Import todos from a backup or another todo app" {
+		t.Errorf("NewCommand() Short = %v, want 'Import todos from a backup or another todo app'", cmd.Short)
+	}
+
+	if cmd.Flags().Lookup("from") == nil {
+		t.Error("NewCommand() should have flag 'from'")
+	}
+
+	if cmd.Flags().Lookup("dry-run") == nil {
+		t.Error("NewCommand() should have flag 'dry-run'")
+	}
+}
+
+func TestImportCommand_MergeAndReplaceFlags(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Flags().Lookup("merge") == nil {
+		t.Error("NewCommand() should have flag 'merge'")
+	}
+
+	if cmd.Flags().Lookup("replace") == nil {
+		t.Error("NewCommand() should have flag 'replace'")
+	}
+
+	if from := cmd.Flags().Lookup("from"); from == nil || from.DefValue != "backup" {
+		t.Error("NewCommand() flag 'from' should default to 'backup'")
+	}
+
+	cmd.SetArgs([]string{"backup.json", "--merge", "--replace"})
+	if err := cmd.Execute(); err == nil {
+		t.Error("Expected error when both --merge and --replace are provided")
+	}
+}
@@ -1,9 +1,14 @@
 package list
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/negadras/tada/internal/output"
+	"github.com/negadras/tada/internal/profile"
 	"github.com/negadras/tada/internal/todo"
 	"github.com/negadras/tada/internal/ui"
 	"github.com/spf13/cobra"
@@ -19,10 +24,18 @@ func isatty() bool {
 
 func NewCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "list",
+		Use:   "list [filter]",
 		Short: "List todo tasks",
 		Long: `List all the todo tasks with optional filtering.
 
+Filter expressions combine field comparisons with and, or, not and
+parentheses:
+  priority>=medium          tag:work          status:done
+  created<7d                completed:today   desc~"deploy"
+Fields: id, priority, status, tag, desc, created, updated, completed.
+A bare word searches the description. The default --status open is
+not applied when the expression already filters on status.
+
 Status filtering: 
   open, o - tasks that are open (default)
   done, d - tasks that are completed
@@ -48,17 +61,62 @@ Priority filtering:
   tada list --priority high
   
   # List all high priority tasks (open and done)
-  tada list --status all --priority high`,
+  tada list --status all --priority high
+
+  # Highest priority first, oldest first within a priority
+  tada list --sort priority,created
+
+  # Group by tag with a subtotal per tag
+  tada list --group-by tag --sort priority
+
+  # Export open todos as CSV, or render a custom line per todo
+  tada list --format csv > todos.csv
+  tada list --format 'template={{icon .Priority}} #{{.ID}} {{.Description}} ({{age .CreatedAt}})'
+
+  # Close every open todo of a sprint in one go
+  tada list 'tag:sprint-12' --ids | tada done -
+
+  # List with a filter expression
+  tada list 'priority>=medium and (tag:work or tag:oncall) and created<7d and desc~"deploy"'`,
+		Args: cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			db, cleanup, err := todo.GetDB(cmd)
+			exprFilter, err := todo.ParseFilter(strings.Join(args, " "))
 			if err != nil {
+				todo.PrintError(cmd, err)
 				return nil
 			}
-			defer cleanup()
+
+			sortFlag, _ := cmd.Flags().GetString("sort")
+			sortKeys, err := todo.ParseSort(sortFlag)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			groupBy, _ := cmd.Flags().GetString("group-by")
+			if groupBy != "" {
+				if err := todo.ValidateGroupBy(groupBy); err != nil {
+					todo.PrintError(cmd, fmt.Errorf("invalid --group-by: %w", err))
+					return nil
+				}
+			}
+
+			format, err := output.FromCommand(cmd)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			if jsonFlag, _ := cmd.Flags().GetBool("json"); jsonFlag {
+				format, _ = output.Parse(output.JSON)
+			}
 
 			statusFlag, _ := cmd.Flags().GetString("status")
 			var statusFilter *todo.Status
 
+			if exprFilter.Uses("status") && !cmd.Flags().Changed("status") {
+				statusFlag = "all"
+			}
+
 			if statusFlag != "all" && statusFlag != "a" {
 				status, err := todo.ParseStatus(statusFlag)
 				if err != nil {
@@ -85,33 +143,147 @@ Priority filtering:
 				tagFilter = &tagFlag
 			}
 
-			tasks, err := db.List(statusFilter, priorityFilter, tagFilter)
+			filter := todo.NewFieldFilter(statusFilter, priorityFilter, tagFilter).And(exprFilter)
+
+			if allStores, _ := cmd.Flags().GetBool("all-stores"); allStores {
+				if format != nil && !format.IsText() {
+					todo.PrintError(cmd, errors.New("--all-stores only prints text"))
+					return nil
+				}
+				return listStores(cmd, filter, sortKeys, groupBy)
+			}
+
+			db, cleanup, err := todo.GetDB(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			tasks, err := db.Find(filter)
 			if err != nil {
 				todo.PrintError(cmd, err)
 				return nil
 			}
 
-			jsonFlag, _ := cmd.Flags().GetBool("json")
+			todo.SortTodos(tasks, sortKeys)
+
+			if ids, _ := cmd.Flags().GetBool("ids"); ids {
+				for _, t := range tasks {
+					fmt.Fprintln(cmd.OutOrStdout(), t.ID)
+				}
+				return nil
+			}
+
+			if groupBy != "" {
+				groups, err := todo.GroupTodos(tasks, groupBy)
+				if err != nil {
+					todo.PrintError(cmd, err)
+					return nil
+				}
+
+				if format != nil && !format.IsText() {
+					return format.WriteTodoGroups(cmd.OutOrStdout(), groups)
+				}
 
-			if jsonFlag {
-				return json.NewEncoder(os.Stdout).Encode(tasks)
+				printProfile(cmd)
+				if len(groups) == 0 {
+					cmd.Println("📝 No todos found matching your criteria.")
+					return nil
+				}
+
+				todo.PrintGroups(cmd, groups)
+				return nil
 			}
 
-			if !isatty() {
+			if format != nil && !format.IsText() {
+				return format.WriteTodos(cmd.OutOrStdout(), tasks)
+			}
+
+			if format != nil && format.Name == output.Text || format == nil && !isatty() {
+				printProfile(cmd)
 				for _, t := range tasks {
 					todo.PrintTodo(cmd, t)
 				}
 				return nil
 			}
 
-			return ui.ShowTable(tasks)
+			return ui.ShowTable(tasks, profile.Label())
 		},
 	}
 
 	cmd.Flags().StringP("status", "s", "open", "Status filter (open/o, done/d, all/a)")
 	cmd.Flags().StringP("priority", "p", "all", "Priority filter (low/l, medium/m, high/h, all/a)")
 	cmd.Flags().StringP("tag", "g", "", "Filter by tag (e.g. personal, platform-engineering)")
-	cmd.Flags().Bool("json", false, "Output todos as JSON (for scripting)")
+	cmd.Flags().Bool("json", false, "Output todos as JSON (for scripting, same as --format json)")
+	output.AddFlag(cmd)
+	cmd.Flags().String("sort", "", "Sort keys, comma separated (priority, created, updated, due, tag, id, status); priority sorts highest first, dates oldest first, and a - prefix reverses a key")
+	cmd.Flags().String("group-by", "", "Group todos into sections (tag, priority, status, day)")
+	cmd.Flags().Bool("ids", false, "Print only the IDs, one per line, for 'tada done -' and other batch commands")
+	cmd.Flags().Bool("all-stores", false, "List the todos of the project's .tada store and of the profile")
 
 	return cmd
 }
+
+// printProfile prints a header naming the store in use, unless it is the
+// default profile
+func printProfile(cmd *cobra.Command) {
+	if s, err := profile.CurrentStore(); err == nil && (s.Local || s.Name != profile.Default) {
+		printHeader(cmd, s)
+	}
+}
+
+// printHeader prints a header naming a store
+func printHeader(cmd *cobra.Command, s profile.Store) {
+	if s.Local {
+		cmd.Printf("📂 Project: %s\n\n", s.Name)
+	} else {
+		cmd.Printf("📂 Profile: %s\n\n", s.Name)
+	}
+}
+
+// listStores prints the todos of every store in use, each under a header
+func listStores(cmd *cobra.Command, filter *todo.Filter, sortKeys []todo.SortKey, groupBy string) error {
+	stores, err := profile.Stores()
+	if err != nil {
+		todo.PrintError(cmd, err)
+		return nil
+	}
+
+	for i, s := range stores {
+		if i > 0 {
+			cmd.Println()
+		}
+		printHeader(cmd, s)
+
+		db, err := todo.NewDB(filepath.Join(s.Dir, "todos.db"))
+		if err != nil {
+			todo.PrintError(cmd, err)
+			continue
+		}
+		tasks, err := db.Find(filter)
+		db.Close()
+		if err != nil {
+			todo.PrintError(cmd, err)
+			continue
+		}
+		todo.SortTodos(tasks, sortKeys)
+
+		if len(tasks) == 0 {
+			cmd.Println("📝 No todos found matching your criteria.")
+			continue
+		}
+		if groupBy == "" {
+			for _, t := range tasks {
+				todo.PrintTodo(cmd, t)
+			}
+			continue
+		}
+		groups, err := todo.GroupTodos(tasks, groupBy)
+		if err != nil {
+			todo.PrintError(cmd, err)
+			continue
+		}
+		todo.PrintGroups(cmd, groups)
+	}
+	return nil
+}
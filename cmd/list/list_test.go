@@ -5,8 +5,8 @@ import "testing"
 func TestNewCommand(t *testing.T) {
 	cmd := NewCommand()
 
-	if cmd.Use != "list" {
-		t.Errorf("NewCommand() Use = %v, want 'list'", cmd.Use)
+	if cmd.Use != "list [filter]" {
+		t.Errorf("NewCommand() Use = %v, want 'list [filter]'", cmd.Use)
 	}
 
 	if cmd.Short != "List todo tasks" {
@@ -40,3 +40,43 @@ func TestNewCommand_Flags(t *testing.T) {
 	}
 
 }
+
+func TestNewCommand_SortAndGroupFlags(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Flags().Lookup("sort") == nil {
+		t.Error("NewCommand() should have flag 'sort'")
+	}
+
+	if cmd.Flags().Lookup("group-by") == nil {
+		t.Error("NewCommand() should have flag 'group-by'")
+	}
+}
+
+func TestNewCommand_FormatFlag(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Flags().Lookup("format") == nil {
+		t.Error("NewCommand() should have flag 'format'")
+	}
+
+	if cmd.Flags().ShorthandLookup("o") == nil {
+		t.Error("NewCommand() should have short format flag 'o'")
+	}
+}
+
+func TestNewCommand_AllStoresFlag(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Flags().Lookup("all-stores") == nil {
+		t.Error("NewCommand() should have flag 'all-stores'")
+	}
+}
+
+func TestNewCommand_IDsFlag(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Flags().Lookup("ids") == nil {
+		t.Error("NewCommand() should have flag 'ids'")
+	}
+}
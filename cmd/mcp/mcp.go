@@ -0,0 +1,61 @@
+package mcp
+
+import (
+	"github.com/negadras/tada/cmd/hooks"
+	"github.com/negadras/tada/cmd/version"
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/server"
+	"github.com/negadras/tada/internal/todo"
+	"github.com/spf13/cobra"
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mcp",
+		Short: "Serve todos and quotes to assistants over the Model Context Protocol",
+		Long: `Run a Model Context Protocol server on stdin and stdout, so that assistants
+and other MCP clients can manage todos. Configure the client to start
+'tada mcp' as a stdio server.
+
+Tools:
+  list_todos     list todos with the options of 'tada list'
+  search         search todos, open or done, and quotes
+  random_quote   show a random quote
+  add_todo       add a todo
+  complete_todo  mark a todo as done
+  update_todo    change a todo
+
+The open todos are also available as the resource tada://todos/open.
+
+With --read-only, only list_todos, search and random_quote are offered.`,
+		Example: `  # Let an assistant read todos but not change them
+  tada mcp --read-only`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, cleanup, err := todo.GetDB(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			quoteDB, quoteCleanup, err := quote.GetDB(cmd)
+			if err != nil {
+				return nil
+			}
+			defer quoteCleanup()
+
+			srv := server.New(db, quoteDB, "")
+			srv.OnChange = func() { hooks.AfterChange(cmd) }
+
+			readOnly, _ := cmd.Flags().GetBool("read-only")
+			if err := srv.ServeMCP(cmd.Context(), cmd.InOrStdin(), cmd.OutOrStdout(), version.Version, readOnly); err != nil {
+				todo.PrintError(cmd, err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("read-only", false, "Only offer tools that do not change todos or quotes")
+
+	return cmd
+}
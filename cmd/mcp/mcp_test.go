@@ -0,0 +1,19 @@
+package mcp
+
+import "testing"
+
+func TestNewCommand(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Use != "mcp" {
+		t.Errorf("NewCommand() Use = %v, want 'mcp'", cmd.Use)
+	}
+
+	if cmd.Short != "Serve todos and quotes to assistants over the Model Context Protocol" {
+		t.Errorf("NewCommand() Short = %v, want 'Serve todos and quotes to assistants over the Model Context Protocol'", cmd.Short)
+	}
+
+	if cmd.Flags().Lookup("read-only") == nil {
+		t.Error("NewCommand() should have flag 'read-only'")
+	}
+}
@@ -0,0 +1,229 @@
+package profile
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/negadras/tada/internal/profile"
+	"github.com/negadras/tada/internal/todo"
+	"github.com/spf13/cobra"
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Keep separate todos for work, personal and other projects",
+		Long: `Profiles keep separate todos, quotes and settings, such as webhooks, the git
+repository and the prompt cache, each in its own database. The default
+profile lives in ~/.tada and the others in ~/.tada/profiles/<name>.
+
+The profile used is, in order: the --profile flag, the TADA_PROFILE
+environment variable, then the one chosen with 'tada profile use'.`,
+		Example: `  tada profile create work
+  tada profile use work
+  tada --profile personal add "Book the dentist"
+  tada profile use default`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(newListCommand())
+	cmd.AddCommand(newCreateCommand())
+	cmd.AddCommand(newUseCommand())
+	cmd.AddCommand(newDeleteCommand())
+
+	return cmd
+}
+
+func newListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the profiles, marking the one in use",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names, err := profile.List()
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			current, err := profile.Current()
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			for _, name := range names {
+				if name == current {
+					cmd.Printf("* %s\n", name)
+				} else {
+					cmd.Printf("  %s\n", name)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func newCreateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create an empty profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if err := profile.Create(name); err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			todo.PrintSuccess(cmd, "Created profile "+name)
+
+			if use, _ := cmd.Flags().GetBool("use"); use {
+				if err := profile.Use(name); err != nil {
+					todo.PrintError(cmd, err)
+					return nil
+				}
+				cmd.Printf("📂 Now using profile %s\n", name)
+			} else {
+				cmd.Printf("Switch to it with 'tada profile use %s'.\n", name)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("use", false, "Use the new profile from now on")
+
+	return cmd
+}
+
+func newUseCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "use <name>",
+		Short: "Use a profile from now on",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if err := profile.Use(name); err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			cmd.Printf("📂 Now using profile %s\n", name)
+			if env := os.Getenv(profile.Env); env != "" && env != name {
+				cmd.Printf("⚠️  %s=%s still selects %s in this shell\n", profile.Env, env, env)
+			}
+			return nil
+		},
+	}
+}
+
+func newDeleteCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "delete <name>",
+		Aliases: []string{"rm"},
+		Short:   "Delete a profile with its todos and quotes",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if err := profile.CanDelete(name); err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+				cmd.Printf("Delete profile %s with all its todos and quotes? [y/N] ", name)
+				answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+				answer = strings.ToLower(strings.TrimSpace(answer))
+				if answer != "y" && answer != "yes" {
+					cmd.Println("Aborted.")
+					return nil
+				}
+			}
+
+			if err := profile.Delete(name); err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			cmd.Printf("🗑️  Deleted profile %s\n", name)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
+
+	return cmd
+}
+
+func NewInitCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "init [dir]",
+		Short: "Keep todos for a project in a .tada store inside it",
+		Long: `Create a .tada directory in a project, by default the current directory,
+holding its own database. Like git, every command run in the project or any
+directory below it uses the nearest .tada store instead of the profile's
+todos, so the todos travel with the code and can be committed and shared.
+
+Use --global to reach the profile's todos from inside a project, and
+'tada list --all-stores' to list both.`,
+		Example: `  cd ~/src/api && tada init
+  tada add "Document the rate limits"
+  tada list --global
+  tada list --all-stores`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := "."
+			if len(args) == 1 {
+				dir = args[0]
+			}
+			dir, err := filepath.Abs(dir)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			store, err := profile.InitLocal(dir)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			db, err := todo.NewDB(filepath.Join(store, "todos.db"))
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			db.Close()
+
+			todo.PrintSuccess(cmd, "Created a todo store in "+store)
+			cmd.Println("Commands run below it now use it; commit it to share the todos.")
+			return nil
+		},
+	}
+}
+
+// AddFlag adds the persistent --profile and --global flags to the root
+// command
+func AddFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("profile", "", "Profile to use instead of the current one (see 'tada profile')")
+	cmd.PersistentFlags().Bool("global", false, "Use the profile's todos even inside a project with a .tada store")
+}
+
+// Select makes the command use the profile given with --profile, if any, or
+// the profile instead of the project store with --global. It runs before
+// every command.
+func Select(cmd *cobra.Command) error {
+	if global, _ := cmd.Flags().GetBool("global"); global {
+		profile.SetGlobal(true)
+	}
+	name, _ := cmd.Flags().GetString("profile")
+	if name == "" {
+		return nil
+	}
+	if err := profile.Validate(name); err != nil {
+		return err
+	}
+	profile.SetOverride(name)
+	return nil
+}
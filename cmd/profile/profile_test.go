@@ -0,0 +1,38 @@
+package profile
+
+import "testing"
+
+func TestNewCommand(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Use != "profile" {
+		t.Errorf("NewCommand() Use = %v, want 'profile'", cmd.Use)
+	}
+
+	if cmd.Short != "Keep separate todos for work, personal and other projects" {
+		t.Errorf("NewCommand() Short = %v, want 'Keep separate todos for work, personal and other projects'", cmd.Short)
+	}
+
+	for _, name := range []string{"list", "create", "use", "delete"} {
+		if sub, _, err := cmd.Find([]string{name}); err != nil || sub.Name() != name {
+			t.Errorf("NewCommand() should have subcommand '%s'", name)
+		}
+	}
+
+	del, _, _ := cmd.Find([]string{"delete"})
+	if del.Flags().Lookup("yes") == nil {
+		t.Error("profile delete should have flag 'yes'")
+	}
+}
+
+func TestNewInitCommand(t *testing.T) {
+	cmd := NewInitCommand()
+
+	if cmd.Use != "init [dir]" {
+		t.Errorf("NewInitCommand() Use = %v, want 'init [dir]'", cmd.Use)
+	}
+
+	if cmd.Short != "Keep todos for a project in a .tada store inside it" {
+		t.Errorf("NewInitCommand() Short = %v, want 'Keep todos for a project in a .tada store inside it'", cmd.Short)
+	}
+}
@@ -0,0 +1,70 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/negadras/tada/internal/todo"
+	"github.com/spf13/cobra"
+)
+
+// snippets add the summary to the prompt of each shell
+var snippets = map[string]string{
+	"zsh": `# Add to ~/.zshrc: eval "$(tada prompt init zsh)"
+__tada_prompt() {
+  local summary
+  summary=$(tada prompt 2>/dev/null)
+  [[ -n $summary ]] && print -rn -- "$summary "
+}
+setopt PROMPT_SUBST
+RPROMPT='$(__tada_prompt)'"$RPROMPT"
+`,
+	"bash": `# Add to ~/.bashrc: eval "$(tada prompt init bash)"
+__tada_prompt() {
+  local summary
+  summary=$(tada prompt 2>/dev/null)
+  [[ -n $summary ]] && printf '%s ' "$summary"
+}
+PS1='$(__tada_prompt)'"$PS1"
+`,
+	"fish": `# Add to ~/.config/fish/config.fish: tada prompt init fish | source
+if functions -q fish_right_prompt; and not functions -q __tada_original_right_prompt
+    functions -c fish_right_prompt __tada_original_right_prompt
+end
+function fish_right_prompt
+    set -l summary (tada prompt 2>/dev/null)
+    test -n "$summary"; and echo -n "$summary "
+    functions -q __tada_original_right_prompt; and __tada_original_right_prompt
+end
+`,
+	"starship": `# Add to ~/.config/starship.toml, and $custom to your format if it is set
+[custom.tada]
+command = "tada prompt"
+when = true
+shell = ["sh"]
+format = "[$output]($style) "
+style = "bold purple"
+`,
+}
+
+// shells lists the shells with a snippet, in the order shown in help
+var shells = []string{"zsh", "bash", "fish", "starship"}
+
+func newInitCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:       "init <" + strings.Join(shells, "|") + ">",
+		Short:     "Print the snippet that adds the summary to a shell prompt",
+		Example:   `  eval "$(tada prompt init zsh)"`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: shells,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snippet, ok := snippets[args[0]]
+			if !ok {
+				todo.PrintError(cmd, fmt.Errorf("unknown shell %q, must be one of %s", args[0], strings.Join(shells, ", ")))
+				return nil
+			}
+			fmt.Fprint(cmd.OutOrStdout(), snippet)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,128 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/negadras/tada/internal/prompt"
+	"github.com/negadras/tada/internal/todo"
+	"github.com/negadras/tada/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prompt",
+		Short: "Print a summary of open todos for the shell prompt",
+		Long: `Print a compact summary of the open todos, such as "3🔴 5 open 1⚠ overdue",
+for a shell prompt. It reads a small cache that every tada command refreshes
+after changing todos, so it does not open the database at every prompt.
+
+Placeholders in --format:
+  {open}     open todos
+  {high}     open todos of high, medium and low priority
+  {medium}
+  {low}
+  {overdue}  open todos due before today
+  {today}    open todos due today
+
+A part in square brackets is left out when all its placeholders are zero.
+Use 'tada prompt init <shell>' to add the summary to a prompt.`,
+		Example: `  tada prompt
+  tada prompt --format '{open} todos[, {today} due today]'
+  TADA_PROMPT_FORMAT='[{high}!]' tada prompt`,
+		Args: cobra.NoArgs,
+		// Printing the prompt must stay fast, so skip the git auto-commit
+		// and cache refresh that follow other commands
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("format")
+			if format == "" {
+				format = os.Getenv("TADA_PROMPT_FORMAT")
+			}
+			if format == "" {
+				format = prompt.DefaultFormat
+			}
+
+			summary, err := readSummary()
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			text, err := prompt.Format(format, summary.Values(time.Now()))
+			if err != nil {
+				todo.PrintError(cmd, fmt.Errorf("invalid format: %w", err))
+				return nil
+			}
+			if text != "" {
+				fmt.Fprintln(cmd.OutOrStdout(), text)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("format", "f", "", "Format of the summary (default $TADA_PROMPT_FORMAT or \""+prompt.DefaultFormat+"\")")
+
+	cmd.AddCommand(newInitCommand())
+
+	return cmd
+}
+
+// readSummary reads the cache, refreshing it first if todos were changed
+// without it, for example through 'tada serve'
+func readSummary() (*prompt.Summary, error) {
+	dbPath, err := todo.GetDatabasePath()
+	if err != nil {
+		return nil, err
+	}
+	cachePath := prompt.GetCachePath(dbPath)
+
+	if !prompt.Stale(dbPath, cachePath) {
+		if summary, err := prompt.Read(cachePath); err == nil {
+			return summary, nil
+		}
+	}
+
+	db, err := todo.NewDB(dbPath)
+	if vault.IsLocked(err) {
+		// Show what was last known rather than an error at every prompt
+		if summary, err := prompt.Read(cachePath); err == nil {
+			return summary, nil
+		}
+		return &prompt.Summary{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return prompt.Refresh(db, cachePath)
+}
+
+// RefreshCache refreshes the prompt cache if the todos changed since it was
+// written. It runs after every command.
+func RefreshCache(cmd *cobra.Command) {
+	dbPath, err := todo.GetDatabasePath()
+	if err != nil {
+		return
+	}
+	cachePath := prompt.GetCachePath(dbPath)
+	if !prompt.Stale(dbPath, cachePath) {
+		return
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		// Nothing to summarize until the first todo is added
+		return
+	}
+
+	db, err := todo.NewDB(dbPath)
+	if err != nil {
+		todo.PrintError(cmd, err)
+		return
+	}
+	defer db.Close()
+	if _, err := prompt.Refresh(db, cachePath); err != nil {
+		todo.PrintError(cmd, err)
+	}
+}
@@ -0,0 +1,40 @@
+package prompt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNewCommand(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Use != "prompt" {
+		t.Errorf("NewCommand() Use = %v, want 'prompt'", cmd.Use)
+	}
+
+	if cmd.Short != "Print a summary of open todos for the shell prompt" {
+		t.Errorf("NewCommand() Short = %v, want 'Print a summary of open todos for the shell prompt'", cmd.Short)
+	}
+
+	if cmd.Flags().Lookup("format") == nil {
+		t.Error("NewCommand() should have flag 'format'")
+	}
+}
+
+func TestInitCommand(t *testing.T) {
+	for _, shell := range shells {
+		t.Run(shell, func(t *testing.T) {
+			cmd := newInitCommand()
+			var out bytes.Buffer
+			cmd.SetOut(&out)
+			cmd.SetArgs([]string{shell})
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if !strings.Contains(out.String(), "tada prompt") {
+				t.Errorf("init %s = %q, want it to run tada prompt", shell, out.String())
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package quote
 import (
 	"strings"
 
+	"github.com/negadras/tada/internal/output"
 	"github.com/negadras/tada/internal/quote"
 	"github.com/spf13/cobra"
 )
@@ -23,6 +24,12 @@ You can specify the author and category for better organization.`,
   tada quote add "Be yourself; everyone else is already taken." --author "Oscar Wilde" --category "inspiration"`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := output.FromCommand(cmd)
+			if err != nil {
+				quote.PrintError(cmd, err)
+				return nil
+			}
+
 			db, cleanup, err := quote.GetDB(cmd)
 			if err != nil {
 				return nil
@@ -54,6 +61,10 @@ You can specify the author and category for better organization.`,
 				return nil
 			}
 
+			if format != nil && !format.IsText() {
+				return format.WriteQuote(cmd.OutOrStdout(), newQuote)
+			}
+
 			quote.PrintQuoteCreated(cmd, newQuote)
 			return nil
 		},
@@ -61,5 +72,6 @@ You can specify the author and category for better organization.`,
 
 	cmd.Flags().StringP("author", "a", "", "Author of the quote")
 	cmd.Flags().StringP("category", "c", "", "Category of the quote")
+	output.AddFlag(cmd)
 	return cmd
 }
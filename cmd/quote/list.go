@@ -1,6 +1,7 @@
 package quote
 
 import (
+	"github.com/negadras/tada/internal/output"
 	"github.com/negadras/tada/internal/quote"
 	"github.com/spf13/cobra"
 )
@@ -20,8 +21,17 @@ func newListCommand() *cobra.Command {
   tada quote list --category "motivation"
   
   # List quotes by author and category
-  tada quote list --author "Oscar Wilde" --category "inspiration"`,
+  tada quote list --author "Oscar Wilde" --category "inspiration"
+
+  # Export quotes as a markdown table
+  tada quote list --format markdown`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := output.FromCommand(cmd)
+			if err != nil {
+				quote.PrintError(cmd, err)
+				return nil
+			}
+
 			db, cleanup, err := quote.GetDB(cmd)
 			if err != nil {
 				return nil
@@ -45,6 +55,10 @@ func newListCommand() *cobra.Command {
 				return nil
 			}
 
+			if format != nil && !format.IsText() {
+				return format.WriteQuotes(cmd.OutOrStdout(), quotes)
+			}
+
 			if len(quotes) == 0 {
 				cmd.Println("No quotes found.")
 				return nil
@@ -63,6 +77,7 @@ func newListCommand() *cobra.Command {
 
 	cmd.Flags().StringP("author", "a", "", "Filter by author")
 	cmd.Flags().StringP("category", "c", "", "Filter by category")
+	output.AddFlag(cmd)
 
 	return cmd
 }
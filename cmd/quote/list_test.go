@@ -51,3 +51,16 @@ func TestListCommand_Arguments(t *testing.T) {
 		t.Error("newListCommand() returned nil")
 	}
 }
+
+func TestListCommand_FormatFlag(t *testing.T) {
+	cmd := newListCommand()
+
+	formatFlag := cmd.Flags().Lookup("format")
+	if formatFlag == nil {
+		t.Fatal("newListCommand() should have a format flag")
+	}
+
+	if formatFlag.Shorthand != "o" {
+		t.Errorf("newListCommand() format flag shorthand = %v, want 'o'", formatFlag.Shorthand)
+	}
+}
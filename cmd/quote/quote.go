@@ -1,6 +1,7 @@
 package quote
 
 import (
+	"github.com/negadras/tada/internal/output"
 	"github.com/negadras/tada/internal/quote"
 	"github.com/spf13/cobra"
 )
@@ -11,6 +12,12 @@ func NewCommand() *cobra.Command {
 		Short: "Manage and display motivational quotes",
 		Long:  "Manage your collection of motivational quotes with subcommands for adding, listing, updating, and deleting quotes. Running 'quote' without subcommands displays a random quote.\n\n💡 Tip: For interactive quote browsing and management, try 'tada --tui'",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := output.FromCommand(cmd)
+			if err != nil {
+				quote.PrintError(cmd, err)
+				return err
+			}
+
 			db, cleanup, err := quote.GetDB(cmd)
 			if err != nil {
 				return err
@@ -30,11 +37,17 @@ func NewCommand() *cobra.Command {
 				return err
 			}
 
+			if format != nil && !format.IsText() {
+				return format.WriteQuote(cmd.OutOrStdout(), randomQuote)
+			}
+
 			quote.PrintQuote(cmd, randomQuote)
 			return nil
 		},
 	}
 
+	output.AddFlag(cmd)
+
 	// Add subcommands
 	cmd.AddCommand(newAddCommand())
 	cmd.AddCommand(newListCommand())
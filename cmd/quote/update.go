@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/negadras/tada/internal/output"
 	"github.com/negadras/tada/internal/quote"
 	"github.com/negadras/tada/internal/tui"
 	"github.com/spf13/cobra"
@@ -39,6 +40,13 @@ At least one flag must be provided to specify what to update.
 			if tuiMode {
 				return tui.RunWithScreen("quotes")
 			}
+
+			format, err := output.FromCommand(cmd)
+			if err != nil {
+				quote.PrintError(cmd, err)
+				return nil
+			}
+
 			id, err := strconv.Atoi(args[0])
 			if err != nil {
 				quote.PrintError(cmd, err)
@@ -103,6 +111,10 @@ At least one flag must be provided to specify what to update.
 				return nil
 			}
 
+			if format != nil && !format.IsText() {
+				return format.WriteQuote(cmd.OutOrStdout(), updatedQuote)
+			}
+
 			quote.PrintSuccess(cmd, "Updated quote:")
 			quote.PrintQuote(cmd, updatedQuote)
 			return nil
@@ -113,6 +125,7 @@ At least one flag must be provided to specify what to update.
 	cmd.Flags().StringP("author", "a", "", "Update author")
 	cmd.Flags().StringP("category", "c", "", "Update category")
 	cmd.Flags().BoolP("tui", "", false, "Launch interactive TUI mode for editing")
+	output.AddFlag(cmd)
 
 	return cmd
 }
@@ -1,16 +1,35 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/fatih/color"
 	"github.com/negadras/tada/cmd/add"
 	"github.com/negadras/tada/cmd/delete"
+	"github.com/negadras/tada/cmd/edit"
+	"github.com/negadras/tada/cmd/exporter"
+	"github.com/negadras/tada/cmd/git"
+	"github.com/negadras/tada/cmd/hooks"
+	"github.com/negadras/tada/cmd/importer"
 	"github.com/negadras/tada/cmd/list"
+	"github.com/negadras/tada/cmd/mcp"
+	"github.com/negadras/tada/cmd/profile"
+	"github.com/negadras/tada/cmd/prompt"
 	"github.com/negadras/tada/cmd/quote"
+	"github.com/negadras/tada/cmd/rpc"
+	"github.com/negadras/tada/cmd/scan"
+	"github.com/negadras/tada/cmd/serve"
+	"github.com/negadras/tada/cmd/show"
+	"github.com/negadras/tada/cmd/syncer"
 	"github.com/negadras/tada/cmd/update"
+	"github.com/negadras/tada/cmd/vault"
 	"github.com/negadras/tada/cmd/version"
+	"github.com/negadras/tada/cmd/web"
+	"github.com/negadras/tada/cmd/webhook"
+	"github.com/negadras/tada/internal/output"
+	"github.com/negadras/tada/internal/todo"
 	"github.com/negadras/tada/internal/tui"
 	"github.com/spf13/cobra"
 )
@@ -22,8 +41,12 @@ func newRootCommand() *cobra.Command {
 		Long: `tada is a CLI that will help you add todo list, list your 
 todo list, edit, close ...`,
 		SilenceErrors: true,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			cmd.SilenceUsage = true
+			return profile.Select(cmd)
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			hooks.AfterChange(cmd)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Check if TUI mode is requested
@@ -50,6 +73,26 @@ todo list, edit, close ...`,
 	cmd.AddCommand(listCmd)
 	cmd.AddCommand(updateCmd)
 	cmd.AddCommand(deleteCmd)
+	cmd.AddCommand(show.NewCommand())
+	cmd.AddCommand(edit.NewCommand())
+	cmd.AddCommand(importer.NewCommand())
+	cmd.AddCommand(exporter.NewCommand())
+	cmd.AddCommand(syncer.NewCommand())
+	cmd.AddCommand(scan.NewCommand())
+	cmd.AddCommand(git.NewCommand())
+	cmd.AddCommand(serve.NewCommand())
+	cmd.AddCommand(web.NewCommand())
+	cmd.AddCommand(rpc.NewCommand())
+	cmd.AddCommand(mcp.NewCommand())
+	cmd.AddCommand(prompt.NewCommand())
+	cmd.AddCommand(webhook.NewCommand())
+	cmd.AddCommand(vault.NewEncryptCommand())
+	cmd.AddCommand(vault.NewDecryptCommand())
+	cmd.AddCommand(vault.NewUnlockCommand())
+	cmd.AddCommand(vault.NewLockCommand())
+	cmd.AddCommand(vault.NewAgentCommand())
+	cmd.AddCommand(profile.NewCommand())
+	cmd.AddCommand(profile.NewInitCommand())
 
 	// Aliases
 	cmd.AddCommand(createAlias("ls", listCmd))
@@ -62,6 +105,8 @@ todo list, edit, close ...`,
 	// Help for aliases
 	cmd.AddCommand(createAliasesCommand())
 
+	profile.AddFlag(cmd)
+
 	// Add TUI flags
 	cmd.Flags().BoolP("tui", "t", false, "Launch interactive TUI mode")
 	cmd.Flags().StringP("screen", "s", "", "Launch TUI at specific screen (dashboard, todos, quotes)")
@@ -86,36 +131,69 @@ func createAlias(alias string, originalCmd *cobra.Command) *cobra.Command {
 
 // createDoneCommand creates a convenience command for marking todos as done
 func createDoneCommand() *cobra.Command {
-	return &cobra.Command{
-		Use:   "done [id]",
+	cmd := &cobra.Command{
+		Use:   "done [id...]",
 		Short: "Mark a todo as done (alias for 'update [id] --status done')",
 		Example: `  # Mark todo #5 as done
-  tada done 5`,
-		Args: cobra.ExactArgs(1),
+  tada done 5
+
+  # Mark todos #3 to #9 and #12 as done
+  tada done 3-9 12
+
+  # Mark every open todo tagged "sprint-12" as done
+  tada list 'tag:sprint-12' --ids | tada done -`,
+		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Create update command and set the status flag
 			updateCmd := update.NewCommand()
-			updateCmd.SetArgs(append(args, "--status", "done"))
+			updateArgs := append(args, "--status", "done")
+			if format, _ := cmd.Flags().GetString("format"); format != "" {
+				updateArgs = append(updateArgs, "--format", format)
+			}
+			updateCmd.SetArgs(updateArgs)
+			updateCmd.SetIn(cmd.InOrStdin())
+			// This command reports the error of a batch
+			updateCmd.SilenceErrors = true
+			updateCmd.SilenceUsage = true
 			return updateCmd.Execute()
 		},
 	}
+
+	output.AddFlag(cmd)
+
+	return cmd
 }
 
 // createOpenCommand creates a convenience command for marking todos as open
 func createOpenCommand() *cobra.Command {
-	return &cobra.Command{
-		Use:   "open [id]",
+	cmd := &cobra.Command{
+		Use:   "open [id...]",
 		Short: "Mark a todo as open (alias for 'update [id] --status open')",
 		Example: `  # Mark todo #5 as open
-  tada open 5`,
-		Args: cobra.ExactArgs(1),
+  tada open 5
+
+  # Reopen todos #3 to #9
+  tada open 3-9`,
+		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Create update command and set the status flag
 			updateCmd := update.NewCommand()
-			updateCmd.SetArgs(append(args, "--status", "open"))
+			updateArgs := append(args, "--status", "open")
+			if format, _ := cmd.Flags().GetString("format"); format != "" {
+				updateArgs = append(updateArgs, "--format", format)
+			}
+			updateCmd.SetArgs(updateArgs)
+			updateCmd.SetIn(cmd.InOrStdin())
+			// This command reports the error of a batch
+			updateCmd.SilenceErrors = true
+			updateCmd.SilenceUsage = true
 			return updateCmd.Execute()
 		},
 	}
+
+	output.AddFlag(cmd)
+
+	return cmd
 }
 
 // createAliasesCommand shows all available aliases
@@ -143,8 +221,13 @@ func createAliasesCommand() *cobra.Command {
 func Execute() {
 	cmd := newRootCommand()
 
-	err := cmd.Execute()
+	executed, err := cmd.ExecuteC()
 	if err != nil {
+		// The other todos of a batch were changed
+		var batchErr *todo.BatchError
+		if errors.As(err, &batchErr) {
+			hooks.AfterChange(executed)
+		}
 		fmt.Fprintf(os.Stderr, "%s %v\n", color.RedString("error:"), err)
 		os.Exit(1)
 	}
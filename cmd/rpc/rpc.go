@@ -0,0 +1,145 @@
+package rpc
+
+import (
+	"errors"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/negadras/tada/cmd/hooks"
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/server"
+	"github.com/negadras/tada/internal/todo"
+	"github.com/spf13/cobra"
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rpc",
+		Short: "Serve todos and quotes over JSON-RPC for editor integrations",
+		Long: `Serve todos and quotes over JSON-RPC 2.0, on stdin and stdout or on a unix
+socket with --socket, so editor extensions can show and tick off todos without
+starting tada for every action. Requests and responses are JSON values, one per
+line; batches are supported.
+
+Methods:
+  todos.list      {status, priority, tag, filter, sort}, as for 'tada list'
+  todos.get       {id}
+  todos.create    {description, priority, tag, due, notes}
+  todos.update    {id, ...fields to change, updated_at}
+  todos.delete    {id, updated_at}
+  quotes.list     {author, category}
+  quotes.get      {id}
+  quotes.random
+  quotes.create   {text, author, category}
+  quotes.update   {id, ...fields to change}
+  quotes.delete   {id}
+  subscribe       send a "changed" notification whenever todos or quotes
+                  change, in this connection or anywhere else
+  unsubscribe
+
+Given the updated_at read with a todo, todos.update and todos.delete fail with
+error -32002 if the todo was changed since. A missing todo or quote is error
+-32001 and invalid params are -32602.`,
+		Example: `  # Talk over stdin and stdout
+  echo '{"jsonrpc": "2.0", "id": 1, "method": "todos.list", "params": {"priority": "high"}}' | tada rpc
+
+  # Listen on a unix socket for any number of clients
+  tada rpc --socket ~/.tada/rpc.sock`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dbPath, err := todo.GetDatabasePath()
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			db, cleanup, err := todo.GetDB(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			quoteDB, quoteCleanup, err := quote.GetDB(cmd)
+			if err != nil {
+				return nil
+			}
+			defer quoteCleanup()
+
+			srv := server.New(db, quoteDB, "")
+			srv.OnChange = func() { hooks.AfterChange(cmd) }
+
+			socket, _ := cmd.Flags().GetString("socket")
+			if socket == "" {
+				if err := srv.ServeRPC(cmd.Context(), cmd.InOrStdin(), cmd.OutOrStdout(), dbPath); err != nil {
+					todo.PrintError(cmd, err)
+				}
+				return nil
+			}
+
+			if err := serveSocket(cmd, srv, socket, dbPath); err != nil {
+				todo.PrintError(cmd, err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("socket", "", "Listen on a unix socket at this path instead of stdin and stdout")
+
+	return cmd
+}
+
+// serveSocket serves each client connecting to a unix socket until
+// interrupted, then removes the socket
+func serveSocket(cmd *cobra.Command, srv *server.Server, path, dbPath string) error {
+	// A socket left behind by a tada that was killed would block Listen
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return errors.New(path + " is in use by another tada rpc")
+		}
+		os.Remove(path)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+
+	var conns sync.Map
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+		conns.Range(func(key, _ interface{}) bool {
+			key.(net.Conn).Close()
+			return true
+		})
+	}()
+
+	cmd.Printf("🔌 Serving JSON-RPC on %s (Ctrl+C to stop)\n", path)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		conns.Store(conn, nil)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conns.Delete(conn)
+			defer conn.Close()
+			_ = srv.ServeRPC(ctx, conn, conn, dbPath)
+		}()
+	}
+}
@@ -0,0 +1,19 @@
+package rpc
+
+import "testing"
+
+func TestNewCommand(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Use != "rpc" {
+		t.Errorf("NewCommand() Use = %v, want 'rpc'", cmd.Use)
+	}
+
+	if cmd.Short != "Serve todos and quotes over JSON-RPC for editor integrations" {
+		t.Errorf("NewCommand() Short = %v, want 'Serve todos and quotes over JSON-RPC for editor integrations'", cmd.Short)
+	}
+
+	if cmd.Flags().Lookup("socket") == nil {
+		t.Error("NewCommand() should have flag 'socket'")
+	}
+}
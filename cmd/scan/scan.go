@@ -0,0 +1,165 @@
+package scan
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/negadras/tada/internal/profile"
+	"github.com/negadras/tada/internal/scan"
+	"github.com/negadras/tada/internal/todo"
+	"github.com/spf13/cobra"
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scan [paths...]",
+		Short: "Turn TODO, FIXME and HACK comments in source code into todos",
+		Long: `Scan source files for TODO, FIXME and HACK comments and keep a todo for each,
+so that code debt shows up in the same list as everything else. Files git
+ignores, binary files and files over 1 MB are skipped.
+
+  // TODO: retry on timeouts
+  // FIXME(alice): crashes on an empty config
+  # HACK[high]: remove once the API is fixed
+
+An owner in parentheses is kept on the first line of the todo's notes, below
+which notes can be added, and a priority in brackets (low, medium, high) sets
+the todo's priority. Each todo is tagged
+with its comment's file:line.
+
+Scanning again brings the todos up to date: new comments are added, todos
+whose comment moved get the new file:line, and todos whose comment is gone
+are marked done. A comment is recognised by a fingerprint of its file, kind
+and text, so editing its text replaces its todo. Deleting a todo in tada
+keeps it from being added again.
+
+Without paths, the project of the .tada store in use is scanned (see 'tada
+init'), or else the current directory. Each path is kept in sync separately.`,
+		Example: `  # See what a scan would change
+  tada scan --dry-run
+
+  # Scan two directories of a project
+  tada scan cmd internal
+
+  # Code debt in Go files, highest priority first
+  tada list 'tag~".go:"' --sort priority`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = []string{"."}
+				if dir, ok := profile.Local(); ok {
+					args = []string{filepath.Dir(dir)}
+				}
+			}
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+			db, cleanup, err := todo.GetDB(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			for _, arg := range args {
+				if err := scanPath(cmd, db, arg, dryRun); err != nil {
+					todo.PrintError(cmd, fmt.Errorf("%s: %w", arg, err))
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", false, "Show what would change without changing anything")
+
+	return cmd
+}
+
+// scanPath brings the todos of one path in line with its comments
+func scanPath(cmd *cobra.Command, db *todo.DB, path string, dryRun bool) error {
+	root, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	comments, err := scan.Walk(root)
+	if err != nil {
+		return err
+	}
+
+	todos, err := db.Find(nil)
+	if err != nil {
+		return err
+	}
+	target := "scan:" + root
+	state, err := db.SyncState(target)
+	if err != nil {
+		return err
+	}
+
+	plan := scan.PlanSync(comments, todos, state, time.Now())
+	printPlan(cmd, plan)
+
+	if dryRun {
+		cmd.Printf("Would sync %s: %s\n", path, summary(plan))
+		return nil
+	}
+
+	err = db.Transaction(func(db *todo.DB) error {
+		if err := apply(db, plan, todos); err != nil {
+			return err
+		}
+		return db.SaveSyncState(target, plan.State)
+	})
+	if err != nil {
+		return err
+	}
+	todo.PrintSuccess(cmd, fmt.Sprintf("Scanned %s: %s", path, summary(plan)))
+	return nil
+}
+
+// apply makes the changes the plan needs in tada, given the todos it was
+// planned from
+func apply(db *todo.DB, plan *scan.SyncPlan, todos []*todo.Todo) error {
+	create := make([]*todo.Todo, 0, len(plan.Create))
+	for _, c := range plan.Create {
+		create = append(create, c.Todo)
+	}
+	if err := db.Insert(create); err != nil {
+		return err
+	}
+
+	byID := make(map[int]*todo.Todo, len(todos))
+	for _, t := range todos {
+		byID[t.ID] = t
+	}
+	for _, c := range append(plan.Update, plan.Close...) {
+		if err := todo.Diff(byID[c.Todo.ID], c.Todo).Apply(db, c.Todo.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printPlan prints the changes of a scan as a diff
+func printPlan(cmd *cobra.Command, plan *scan.SyncPlan) {
+	for _, c := range plan.Create {
+		cmd.Printf("+ %s %s: %s\n", c.Comment.Location(), c.Comment.Kind, c.Todo.Description)
+	}
+	for _, c := range plan.Update {
+		location := c.Todo.Tag
+		if c.From != c.Todo.Tag {
+			location = c.From + " → " + c.Todo.Tag
+		}
+		cmd.Printf("~ #%d %s %s: %s\n", c.Todo.ID, location, c.Comment.Kind, c.Todo.Description)
+	}
+	for _, c := range plan.Close {
+		cmd.Printf("- #%d %s: %s\n", c.Todo.ID, c.From, c.Todo.Description)
+	}
+}
+
+// summary describes the changes of a scan
+func summary(plan *scan.SyncPlan) string {
+	var tally todo.Tally
+	tally.Add(len(plan.Create), "added")
+	tally.Add(len(plan.Update), "updated")
+	tally.Add(len(plan.Close), "closed")
+	return tally.Describe("already in sync")
+}
@@ -0,0 +1,19 @@
+package scan
+
+import "testing"
+
+func TestNewCommand(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Use != "scan [paths...]" {
+		t.Errorf("NewCommand() Use = %v, want 'scan [paths...]'", cmd.Use)
+	}
+
+	if cmd.Short != "Turn TODO, FIXME and HACK comments in source code into todos" {
+		t.Errorf("NewCommand() Short = %v, want 'Turn TODO, FIXME and HACK comments in source code into todos'", cmd.Short)
+	}
+
+	if cmd.Flags().Lookup("dry-run") == nil {
+		t.Error("NewCommand() should have flag 'dry-run'")
+	}
+}
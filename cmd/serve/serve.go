@@ -0,0 +1,99 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/negadras/tada/cmd/hooks"
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/server"
+	"github.com/negadras/tada/internal/todo"
+	"github.com/spf13/cobra"
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve todos and quotes over a JSON REST API",
+		Long: `Serve todos and quotes over a JSON REST API, for dashboards, editor plugins and
+bots:
+
+  GET    /todos           list todos; status, priority, tag, filter and sort
+                          query parameters work like the flags of 'tada list'
+  POST   /todos           create a todo
+  GET    /todos/{id}      get a todo
+  PATCH  /todos/{id}      change some fields of a todo
+  DELETE /todos/{id}      delete a todo
+  GET    /quotes          list quotes, by author and category
+  GET    /quotes/random   get a random quote
+  GET    /openapi.json    the OpenAPI document describing all of the above
+
+Todos carry an ETag. Send it back as If-Match when changing or deleting a
+todo, or send the updated_at read with it in the body, and the request fails
+with 412 Precondition Failed if someone changed the todo in the meantime.
+
+With --token, or TADA_TOKEN set, every request but /openapi.json must send
+"Authorization: Bearer <token>". Requests must name the address listened on
+as their Host, and requests sent by web pages of other sites are refused.`,
+		Example: `  # Serve on the default address
+  tada serve
+
+  # Require a token
+  TADA_TOKEN=s3cret tada serve --addr 127.0.0.1:8080
+
+  # Create and list todos
+  curl -X POST localhost:7070/todos -H 'Content-Type: application/json' -d '{"description": "Fix login bug", "priority": "high"}'
+  curl 'localhost:7070/todos?priority=high&filter=tag:work'`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, _ := cmd.Flags().GetString("addr")
+			token, _ := cmd.Flags().GetString("token")
+			if token == "" {
+				token = os.Getenv("TADA_TOKEN")
+			}
+
+			db, cleanup, err := todo.GetDB(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			quoteDB, quoteCleanup, err := quote.GetDB(cmd)
+			if err != nil {
+				return nil
+			}
+			defer quoteCleanup()
+
+			srv := server.New(db, quoteDB, token)
+			srv.OnChange = func() { hooks.AfterChange(cmd) }
+			httpServer := &http.Server{Addr: addr, Handler: server.Guard(addr, srv.Handler())}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				_ = httpServer.Shutdown(shutdownCtx)
+			}()
+
+			cmd.Printf("🚀 Serving the tada API on http://%s (Ctrl+C to stop)\n", addr)
+			if token == "" {
+				cmd.Println("   No token set: anyone who can reach the address can change your todos")
+			}
+			if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				todo.PrintError(cmd, err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("addr", "127.0.0.1:7070", "Address to listen on")
+	cmd.Flags().String("token", "", "Bearer token required on every request (default $TADA_TOKEN)")
+
+	return cmd
+}
@@ -0,0 +1,25 @@
+package serve
+
+import "testing"
+
+func TestNewCommand(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Use != "serve" {
+		t.Errorf("NewCommand() Use = %v, want 'serve'", cmd.Use)
+	}
+
+	if cmd.Short != "Serve todos and quotes over a JSON REST API" {
+		t.Errorf("NewCommand() Short = %v, want 'Serve todos and quotes over a JSON REST API'", cmd.Short)
+	}
+
+	for _, flag := range []string{"addr", "token"} {
+		if cmd.Flags().Lookup(flag) == nil {
+			t.Errorf("NewCommand() should have flag '%s'", flag)
+		}
+	}
+
+	if got := cmd.Flags().Lookup("addr").DefValue; got != "127.0.0.1:7070" {
+		t.Errorf("addr default = %v, want '127.0.0.1:7070'", got)
+	}
+}
@@ -0,0 +1,52 @@
+package show
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/negadras/tada/internal/todo"
+	"github.com/spf13/cobra"
+)
+
+func NewCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "show <id>",
+		Short:   "Show a todo with its notes and linked commits",
+		Example: `  tada show 42`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.Atoi(args[0])
+			if err != nil {
+				todo.PrintError(cmd, fmt.Errorf("invalid todo ID: %s", args[0]))
+				return nil
+			}
+
+			db, cleanup, err := todo.GetDB(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			t, err := db.Get(id)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			commits, err := db.Commits(id)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			todo.PrintTodo(cmd, t)
+			cmd.Printf("   Created: %s\n", t.CreatedAt.Local().Format("2006-01-02 15:04"))
+			if len(commits) > 0 {
+				cmd.Println("   Commits:")
+				for _, c := range commits {
+					cmd.Printf("     %s %s (%s)\n", c.ShortSHA(), c.Subject, c.Repo)
+				}
+			}
+			return nil
+		},
+	}
+}
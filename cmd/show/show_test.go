@@ -0,0 +1,15 @@
+package show
+
+import "testing"
+
+func TestNewCommand(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Use != "show <id>" {
+		t.Errorf("NewCommand() Use = %v, want 'show <id>'", cmd.Use)
+	}
+
+	if cmd.Short != "Show a todo with its notes and linked commits" {
+		t.Errorf("NewCommand() Short = %v, want 'Show a todo with its notes and linked commits'", cmd.Short)
+	}
+}
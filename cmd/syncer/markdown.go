@@ -0,0 +1,219 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/negadras/tada/internal/markdown"
+	"github.com/negadras/tada/internal/todo"
+	"github.com/spf13/cobra"
+)
+
+func newMarkdownCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "markdown <file> [filter]",
+		Short: "Sync todos with a markdown task list in both directions",
+		Long: `Sync todos with the "- [ ]" task list items of a markdown file, such as a note
+in a notes app. The file is created when there are todos to write to it.
+
+  - Items ticked or edited in the file are updated in tada
+  - New items in the file become todos
+  - Todos changed or deleted in tada are rewritten or removed in the file
+  - Todos matching the filter (open todos by default) are added to the file
+
+tada is the source of truth: deleting an item from the file does not delete
+its todo. A todo that still matches the filter is written back, and the
+others are no longer synced; delete todos with 'tada delete' instead.
+
+Each item is linked to its todo by a <!-- tada:UID --> comment at the end of
+the line, which notes apps hide when rendering. Items use the same #tag,
+!high/!low and due: markers as 'tada export --to markdown'. Other lines of the
+file are left untouched.
+
+If an item changed both in the file and in tada since the last sync, tada's
+version is kept and the conflict is reported with the file's version.`,
+		Example: `  # Keep open todos in a note
+  tada sync markdown ~/notes/tasks.md
+
+  # Only work todos
+  tada sync markdown ~/notes/work.md 'status:open tag:work'
+
+  # See what would change
+  tada sync markdown ~/notes/tasks.md --dry-run`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			expr := "status:open"
+			if len(args) > 1 {
+				expr = strings.Join(args[1:], " ")
+			}
+			filter, err := todo.ParseFilter(expr)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			path, err := filepath.Abs(args[0])
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			lines, err := readLines(path)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			db, cleanup, err := todo.GetDB(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			todos, err := db.Find(nil)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			todo.SortTodos(todos, []todo.SortKey{{Field: "created"}, {Field: "id"}})
+
+			state, err := db.SyncState(path)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			plan, err := markdown.PlanSync(lines, todos, state, filter, time.Now())
+			if err != nil {
+				todo.PrintError(cmd, fmt.Errorf("%s: %w", args[0], err))
+				return nil
+			}
+
+			printConflicts(cmd, plan.Conflicts)
+
+			if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+				cmd.Printf("Would sync %s: %s\n", args[0], summary(plan))
+				return nil
+			}
+
+			// The file is written last, so that tada and its sync state are
+			// left as they were if anything fails
+			err = db.Transaction(func(db *todo.DB) error {
+				if err := apply(db, plan, todos); err != nil {
+					return err
+				}
+				if err := db.SaveSyncState(path, plan.State); err != nil {
+					return err
+				}
+				if plan.FileChanged {
+					return writeLines(path, plan.Lines)
+				}
+				return nil
+			})
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			todo.PrintSuccess(cmd, fmt.Sprintf("Synced %s: %s", args[0], summary(plan)))
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", false, "Show what would change without changing anything")
+
+	return cmd
+}
+
+// apply makes the changes the plan needs in tada
+func apply(db *todo.DB, plan *markdown.SyncPlan, todos []*todo.Todo) error {
+	if err := db.Insert(plan.Create); err != nil {
+		return err
+	}
+
+	byID := make(map[int]*todo.Todo, len(todos))
+	for _, t := range todos {
+		byID[t.ID] = t
+	}
+	for _, t := range plan.Update {
+		if err := todo.Diff(byID[t.ID], t).Apply(db, t.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// summary describes the changes of a sync
+func summary(plan *markdown.SyncPlan) string {
+	var tally todo.Tally
+	tally.Add(len(plan.Create), "added to tada")
+	tally.Add(len(plan.Update), "updated in tada")
+	tally.Add(plan.Written, "written to the file")
+	tally.Add(plan.Removed, "removed from the file")
+	tally.Add(plan.Unlinked, "no longer synced")
+	tally.Add(len(plan.Conflicts), "conflicts")
+	return tally.Describe("already in sync")
+}
+
+// printConflicts shows the file's version of each item whose tada version
+// was kept
+func printConflicts(cmd *cobra.Command, conflicts []markdown.Conflict) {
+	for _, c := range conflicts {
+		cmd.Println("⚠️  Changed in the file and in tada since the last sync, keeping tada's version:")
+		cmd.Printf("   file: %s\n", orDeleted(c.File))
+		cmd.Printf("   tada: %s\n", orDeleted(c.Tada))
+	}
+}
+
+func orDeleted(item string) string {
+	if item == "" {
+		return "(deleted)"
+	}
+	return item
+}
+
+// readLines reads the lines of a file; a missing file has none
+func readLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	content := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if content == "" {
+		return nil, nil
+	}
+	return strings.Split(content, "\n"), nil
+}
+
+// writeLines replaces a file with the given lines. The new content is
+// written to a temporary file first so the file is never left half written.
+func writeLines(path string, lines []string) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,177 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/replica"
+	"github.com/negadras/tada/internal/todo"
+	"github.com/spf13/cobra"
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync [other.db]",
+		Short: "Keep todos in sync with files edited by other tools",
+		Long: `Keep todos in sync with files edited by other tools, in both directions.
+Changes made on either side since the last sync are carried over to the other.
+
+Given another tada database, such as the todos.db of another machine shared
+through a file-sync tool, merges it with this one so both end up with the same
+todos and quotes:
+
+  - Todos and quotes are matched by their UID, never by ID
+  - For each field the most recent change wins, wherever it was made
+  - Deleting a todo or quote deletes it on the other side too, unless it was
+    changed there after it was deleted
+  - Fields changed on both sides since the last merge are reported as
+    conflicts, showing the value that was replaced
+
+IDs are local to each database, so a todo may have a different ID on each
+machine. Set up the second machine with 'tada export' and 'tada import'
+rather than by copying the database file.`,
+		Example: `  # Merge with the workstation's database, shared through Syncthing
+  tada sync ~/Sync/workstation/todos.db
+
+  # See what would change
+  tada sync ~/Sync/workstation/todos.db --dry-run
+
+  # Sync a markdown note
+  tada sync markdown ~/notes/tasks.md`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return cmd.Help()
+			}
+			return mergeDatabase(cmd, args[0])
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", false, "Show what would change without changing anything")
+
+	cmd.AddCommand(newMarkdownCommand())
+
+	return cmd
+}
+
+// mergeDatabase merges another tada database with this one
+func mergeDatabase(cmd *cobra.Command, other string) error {
+	localPath, err := todo.GetDatabasePath()
+	if err != nil {
+		todo.PrintError(cmd, err)
+		return nil
+	}
+	otherPath, err := filepath.Abs(other)
+	if err != nil {
+		todo.PrintError(cmd, err)
+		return nil
+	}
+	if _, err := os.Stat(otherPath); err != nil {
+		todo.PrintError(cmd, err)
+		return nil
+	}
+
+	for _, path := range []string{localPath, otherPath} {
+		if err := migrate(path); err != nil {
+			todo.PrintError(cmd, fmt.Errorf("%s: %w", path, err))
+			return nil
+		}
+	}
+
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	result, err := replica.MergeFiles(localPath, otherPath, dryRun)
+	if err != nil {
+		todo.PrintError(cmd, err)
+		return nil
+	}
+
+	for _, c := range result.Conflicts {
+		printConflict(cmd, c)
+	}
+
+	if dryRun {
+		cmd.Printf("Would merge with %s\n", other)
+	} else {
+		todo.PrintSuccess(cmd, "Merged with "+other)
+	}
+	cmd.Printf("   This database:  %s\n", describeChanges(result.Local))
+	cmd.Printf("   Other database: %s\n", describeChanges(result.Other))
+	if len(result.Conflicts) > 0 {
+		cmd.Printf("   %d conflicts\n", len(result.Conflicts))
+	}
+	return nil
+}
+
+// migrate brings a database file up to the current schema
+func migrate(path string) error {
+	todoDB, err := todo.NewDB(path)
+	if err != nil {
+		return err
+	}
+	todoDB.Close()
+
+	quoteDB, err := quote.NewDB(path)
+	if err != nil {
+		return err
+	}
+	return quoteDB.Close()
+}
+
+func describeChanges(c replica.Changes) string {
+	if c.Added+c.Updated+c.Deleted == 0 {
+		return "no changes"
+	}
+	return fmt.Sprintf("%d added, %d updated, %d deleted", c.Added, c.Updated, c.Deleted)
+}
+
+// printConflict reports a todo or quote changed in both databases
+func printConflict(cmd *cobra.Command, c replica.Conflict) {
+	name := fmt.Sprintf("%s %q", c.Kind, c.Label)
+
+	if c.Field == "deleted" {
+		deletedHere := c.Local == ""
+		switch {
+		case deletedHere && c.KeptLocal:
+			cmd.Printf("⚠️  %s was deleted here after it was changed in the other database; deleted it there too\n", name)
+		case deletedHere:
+			cmd.Printf("⚠️  %s was deleted here but changed in the other database later; restored it\n", name)
+		case c.KeptLocal:
+			cmd.Printf("⚠️  %s was deleted in the other database but changed here later; kept it\n", name)
+		default:
+			cmd.Printf("⚠️  %s was changed here but deleted in the other database later; deleted it\n", name)
+		}
+		return
+	}
+
+	kept, replaced, where := c.Other, c.Local, "this database"
+	if c.KeptLocal {
+		kept, replaced, where = c.Local, c.Other, "the other database"
+	}
+	cmd.Printf("⚠️  %s: %s changed in both databases; kept %s, replacing %s from %s\n",
+		name, c.Field, formatValue(c, kept), formatValue(c, replaced), where)
+}
+
+// formatValue shows a stored field value the way tada prints it
+func formatValue(c replica.Conflict, v string) string {
+	if c.Kind == "todo" {
+		switch c.Field {
+		case "priority":
+			n, _ := strconv.Atoi(v)
+			return todo.Priority(n).String()
+		case "status":
+			n, _ := strconv.Atoi(v)
+			return todo.Status(n).String()
+		case "due":
+			if len(v) >= 10 {
+				return v[:10]
+			}
+		}
+	}
+	if v == "" {
+		return "(empty)"
+	}
+	return strconv.Quote(v)
+}
@@ -0,0 +1,35 @@
+package syncer
+
+import "testing"
+
+func TestNewCommand(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Use != "sync [other.db]" {
+		t.Errorf("NewCommand() Use = %v, want 'sync [other.db]'", cmd.Use)
+	}
+
+	if cmd.Short != "Keep todos in sync with files edited by other tools" {
+		t.Errorf("NewCommand() Short = %v, want 'Keep todos in sync with files edited by other tools'", cmd.Short)
+	}
+
+	if cmd.Flags().Lookup("dry-run") == nil {
+		t.Error("NewCommand() should have flag 'dry-run'")
+	}
+
+	if _, _, err := cmd.Find([]string{"markdown"}); err != nil {
+		t.Errorf("NewCommand() should have subcommand 'markdown': %v", err)
+	}
+}
+
+func TestNewMarkdownCommand(t *testing.T) {
+	cmd := newMarkdownCommand()
+
+	if cmd.Use != "markdown <file> [filter]" {
+		t.Errorf("newMarkdownCommand() Use = %v, want 'markdown <file> [filter]'", cmd.Use)
+	}
+
+	if cmd.Flags().Lookup("dry-run") == nil {
+		t.Error("newMarkdownCommand() should have flag 'dry-run'")
+	}
+}
@@ -2,8 +2,8 @@ package update
 
 import (
 	"fmt"
-	"strconv"
 
+	"github.com/negadras/tada/internal/output"
 	"github.com/negadras/tada/internal/todo"
 	"github.com/negadras/tada/internal/tui"
 	"github.com/spf13/cobra"
@@ -11,12 +11,17 @@ import (
 
 func NewCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "update [id]",
+		Use:   "update [id...]",
 		Short: "Update a todo task",
 		Long: `Update various properties of a todo task including status, priority, and description.
 		
 At least one flag must be provided to specify what to update.
 
+Several todos can be updated at once, in a single transaction: give several
+IDs, ranges such as 3-9, or - to read IDs from stdin, as printed by
+'tada list --ids'. A todo that fails is reported and left unchanged, the
+others are updated, and the command exits with an error.
+
 💡 Tip: Use --tui flag to launch interactive edit mode`,
 		Example: `  # Mark todo #5 as done
   tada update 5 --status done
@@ -31,91 +36,135 @@ At least one flag must be provided to specify what to update.
   tada update 5 --status done --priority low
   
   # Using short flags
-  tada update 5 -s done -p high -d "Updated task"`,
-		Args: cobra.ExactArgs(1),
+  tada update 5 -s done -p high -d "Updated task"
+
+  # Raise the priority of several todos, in a single transaction
+  tada update 3-9 12 --priority high
+
+  # Close every open todo tagged "sprint-12"
+  tada update --where 'tag:sprint-12 and status:open' --status done`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("where") {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Check if TUI mode is requested
 			tuiMode, _ := cmd.Flags().GetBool("tui")
 			if tuiMode {
 				return tui.RunWithScreen("todos")
 			}
-			id, err := strconv.Atoi(args[0])
-			if err != nil {
-				todo.PrintError(cmd, err)
-				return nil
-			}
 
 			// Check if at least one flag is provided
 			if !cmd.Flags().Changed("status") &&
 				!cmd.Flags().Changed("priority") &&
 				!cmd.Flags().Changed("description") &&
-				!cmd.Flags().Changed("tag") {
-				todo.PrintError(cmd, fmt.Errorf("at least one flag (--status, --priority, --description, or --tag) must be provided"))
+				!cmd.Flags().Changed("tag") &&
+				!cmd.Flags().Changed("due") &&
+				!cmd.Flags().Changed("notes") {
+				todo.PrintError(cmd, fmt.Errorf("at least one flag (--status, --priority, --description, --tag, --due, or --notes) must be provided"))
 				return nil
 			}
 
-			// Get database connection
-			db, cleanup, err := todo.GetDB(cmd)
+			changes, err := parseChanges(cmd)
 			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			format, err := output.FromCommand(cmd)
+			if err != nil {
+				todo.PrintError(cmd, err)
 				return nil
 			}
-			defer cleanup()
 
-			if cmd.Flags().Changed("status") {
-				statusFlag, _ := cmd.Flags().GetString("status")
-				status, err := todo.ParseStatus(statusFlag)
+			var ids []int
+			var filter *todo.Filter
+			if cmd.Flags().Changed("where") {
+				where, _ := cmd.Flags().GetString("where")
+				filter, err = todo.ParseFilter(where)
 				if err != nil {
 					todo.PrintError(cmd, err)
 					return nil
 				}
-				if err := db.UpdateStatus(id, status); err != nil {
+			} else {
+				ids, err = todo.ParseIDs(args, cmd.InOrStdin())
+				if err != nil {
 					todo.PrintError(cmd, err)
 					return nil
 				}
 			}
 
-			if cmd.Flags().Changed("priority") {
-				priorityFlag, _ := cmd.Flags().GetString("priority")
-				priority, err := todo.ParsePriority(priorityFlag)
+			// Get database connection
+			db, cleanup, err := todo.GetDB(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			if filter != nil {
+				matches, err := db.Find(filter)
 				if err != nil {
 					todo.PrintError(cmd, err)
 					return nil
 				}
-				if err := db.UpdatePriority(id, priority); err != nil {
-					todo.PrintError(cmd, err)
+				if len(matches) == 0 {
+					cmd.Println("📝 No todos found matching your criteria.")
 					return nil
 				}
+				for _, t := range matches {
+					ids = append(ids, t.ID)
+				}
 			}
 
-			if cmd.Flags().Changed("description") {
-				description, _ := cmd.Flags().GetString("description")
-				if err := todo.ValidateDescription(description); err != nil {
-					todo.PrintError(cmd, err)
-					return nil
+			results, batchErr := db.Batch(ids, func(db *todo.DB, t *todo.Todo) (*todo.Todo, error) {
+				if err := changes.Apply(db, t.ID); err != nil {
+					return nil, err
 				}
-				if err := db.UpdateDescription(id, description); err != nil {
-					todo.PrintError(cmd, err)
-					return nil
+				return db.Get(t.ID)
+			})
+			if results == nil {
+				todo.PrintError(cmd, batchErr)
+				return nil
+			}
+
+			var updated []*todo.Todo
+			for _, r := range results {
+				if r.Err != nil {
+					todo.PrintError(cmd, r.Err)
+				} else {
+					updated = append(updated, r.Todo)
 				}
 			}
 
-			if cmd.Flags().Changed("tag") {
-				tag, _ := cmd.Flags().GetString("tag")
-				if err := db.UpdateTag(id, tag); err != nil {
-					todo.PrintError(cmd, err)
-					return nil
+			// A single todo is written as before batches existed
+			single := filter == nil && len(ids) == 1
+			if format != nil && !format.IsText() {
+				if single && len(updated) == 1 {
+					err = format.WriteTodo(cmd.OutOrStdout(), updated[0])
+				} else if !single {
+					err = format.WriteTodos(cmd.OutOrStdout(), updated)
 				}
+				if err != nil {
+					return err
+				}
+				return batchErr
 			}
 
-			updatedTodo, err := db.Get(id)
-			if err != nil {
-				todo.PrintError(cmd, err)
-				return nil
+			if len(updated) == 0 {
+				return batchErr
+			}
+			if single {
+				todo.PrintSuccess(cmd, "Updated todo:")
+			} else {
+				todo.PrintSuccess(cmd, fmt.Sprintf("Updated %d todos:", len(updated)))
 			}
 
-			todo.PrintSuccess(cmd, "Updated todo:")
-			todo.PrintTodo(cmd, updatedTodo)
-			return nil
+			for _, t := range updated {
+				todo.PrintTodo(cmd, t)
+			}
+			return batchErr
 		},
 	}
 
@@ -123,7 +172,64 @@ At least one flag must be provided to specify what to update.
 	cmd.Flags().StringP("priority", "p", "", "Update priority (low/l, medium/m, high/h)")
 	cmd.Flags().StringP("description", "d", "", "Update description")
 	cmd.Flags().StringP("tag", "g", "", "Update tag (e.g. personal, platform-engineering)")
+	cmd.Flags().String("due", "", "Update due date (YYYY-MM-DD, today, tomorrow, a day like fri, days ahead like 3d, or none)")
+	cmd.Flags().StringP("notes", "n", "", "Update notes (use \"\" to clear)")
 	cmd.Flags().BoolP("tui", "t", false, "Launch interactive TUI mode for editing")
+	cmd.Flags().StringP("where", "w", "", "Update every todo matching a filter expression instead of ids")
+	output.AddFlag(cmd)
 
 	return cmd
 }
+
+// parseChanges validates the update flags before any todo is modified
+func parseChanges(cmd *cobra.Command) (*todo.Changes, error) {
+	c := &todo.Changes{}
+
+	if cmd.Flags().Changed("status") {
+		statusFlag, _ := cmd.Flags().GetString("status")
+		status, err := todo.ParseStatus(statusFlag)
+		if err != nil {
+			return nil, err
+		}
+		c.Status = &status
+	}
+
+	if cmd.Flags().Changed("priority") {
+		priorityFlag, _ := cmd.Flags().GetString("priority")
+		priority, err := todo.ParsePriority(priorityFlag)
+		if err != nil {
+			return nil, err
+		}
+		c.Priority = &priority
+	}
+
+	if cmd.Flags().Changed("description") {
+		description, _ := cmd.Flags().GetString("description")
+		if err := todo.ValidateDescription(description); err != nil {
+			return nil, err
+		}
+		c.Description = &description
+	}
+
+	if cmd.Flags().Changed("tag") {
+		tag, _ := cmd.Flags().GetString("tag")
+		c.Tag = &tag
+	}
+
+	if cmd.Flags().Changed("due") {
+		dueFlag, _ := cmd.Flags().GetString("due")
+		due, err := todo.ParseDueDate(dueFlag)
+		if err != nil {
+			return nil, err
+		}
+		c.Due = due
+		c.DueSet = true
+	}
+
+	if cmd.Flags().Changed("notes") {
+		notes, _ := cmd.Flags().GetString("notes")
+		c.Notes = &notes
+	}
+
+	return c, nil
+}
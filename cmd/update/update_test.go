@@ -5,7 +5,7 @@ import "testing"
 func TestNewCommand(t *testing.T) {
 	cmd := NewCommand()
 
-	if cmd.Use != "update [id]" {
+	if cmd.Use != "update [id...]" {
 		t.Errorf("NewCommand.Use = %s, want update", cmd.Use)
 	}
 
@@ -33,17 +33,15 @@ func TestNewCommand(t *testing.T) {
 func TestUpdateCommand_Arguments(t *testing.T) {
 	cmd := NewCommand()
 
-	// Test that command requires exactly 1 argument
+	// Test that command requires at least 1 argument
 	cmd.SetArgs([]string{})
 	err := cmd.Execute()
 	if err == nil {
 		t.Error("Expected error when no arguments provided")
 	}
 
-	cmd.SetArgs([]string{"arg1", "arg2"})
-	err = cmd.Execute()
-	if err == nil {
-		t.Error("Expected error when too many arguments provided")
+	if err := cmd.Args(cmd, []string{"3", "5-9", "-"}); err != nil {
+		t.Errorf("Expected several IDs to be accepted, got %v", err)
 	}
 }
 
@@ -65,4 +63,8 @@ func TestUpdateCommand_Flags(t *testing.T) {
 	if descFlag == nil {
 		t.Error("NewCommand() should have short description flag 'd'")
 	}
+	notesFlag := cmd.Flags().ShorthandLookup("n")
+	if notesFlag == nil {
+		t.Error("NewCommand() should have short notes flag 'n'")
+	}
 }
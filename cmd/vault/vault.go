@@ -0,0 +1,331 @@
+package vault
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/negadras/tada/internal/gitstore"
+	"github.com/negadras/tada/internal/todo"
+	"github.com/negadras/tada/internal/vault"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// minPassphrase is the length of the shortest passphrase accepted
+const minPassphrase = 8
+
+func NewEncryptCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "encrypt",
+		Short: "Encrypt todos and quotes with a passphrase",
+		Long: `Encrypt the descriptions, tags and notes of todos and the text of quotes
+in the database with a key derived from a passphrase (Argon2id, then
+AES-256-GCM). Priorities, statuses, dates and quote authors stay readable, so
+that todos can still be sorted and filtered quickly.
+
+Once encrypted, tada needs the passphrase to open the database: either from
+'tada unlock', which keeps it in a background agent for the session, or from
+the TADA_PASSPHRASE environment variable, for scripts. There is no way to
+recover the todos without it.`,
+		Example: `  tada encrypt
+  TADA_PASSPHRASE=... tada list`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conn, cleanup, err := openDatabase(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			// Every change would still be committed to the repository in plain text
+			if dir, err := gitstore.GetPath(); err == nil && gitstore.Enabled(dir) {
+				todo.PrintError(cmd, fmt.Errorf("the git repository in %s keeps todos in plain text; move it away before encrypting", dir))
+				return nil
+			}
+
+			if encrypted, err := vault.Encrypted(conn); err != nil || encrypted {
+				if err == nil {
+					err = errors.New("todos are already encrypted")
+				}
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			passphrase, err := readNewPassphrase(cmd)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			if err := vault.Enable(conn, passphrase); err != nil {
+				todo.PrintError(cmd, fmt.Errorf("failed to encrypt: %w", err))
+				return nil
+			}
+			todo.PrintSuccess(cmd, "Encrypted todos and quotes")
+
+			if err := startAgent(passphrase, vault.DefaultTimeout); err != nil {
+				todo.PrintError(cmd, err)
+			} else {
+				cmd.Printf("🔓 Unlocked for %s; run 'tada lock' to lock now\n", formatTimeout(vault.DefaultTimeout))
+			}
+			return nil
+		},
+	}
+}
+
+func NewDecryptCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "decrypt",
+		Short: "Decrypt todos and quotes and stop asking for a passphrase",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conn, cleanup, err := openDatabase(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			if err := unlock(cmd, conn); err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			if err := vault.Disable(conn); err != nil {
+				todo.PrintError(cmd, fmt.Errorf("failed to decrypt: %w", err))
+				return nil
+			}
+			vault.StopAgent()
+			todo.PrintSuccess(cmd, "Decrypted todos and quotes")
+			return nil
+		},
+	}
+}
+
+func NewUnlockCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unlock",
+		Short: "Remember the passphrase of encrypted todos for a while",
+		Long: `Ask for the passphrase of encrypted todos and keep it in a background agent,
+reachable only by the current user, until the timeout or 'tada lock'.`,
+		Example: `  tada unlock
+  tada unlock --timeout 30m`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			if timeout <= 0 {
+				todo.PrintError(cmd, errors.New("timeout must be positive"))
+				return nil
+			}
+
+			conn, cleanup, err := openDatabase(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			passphrase, err := readPassphrase(cmd, "Passphrase: ")
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			if err := vault.Check(conn, passphrase); err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			// A new unlock replaces the agent, and its timeout
+			vault.StopAgent()
+			if err := startAgent(passphrase, timeout); err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			cmd.Printf("🔓 Unlocked for %s\n", formatTimeout(timeout))
+			return nil
+		},
+	}
+
+	cmd.Flags().Duration("timeout", vault.DefaultTimeout, "How long to remember the passphrase")
+
+	return cmd
+}
+
+func NewLockCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "lock",
+		Short: "Forget the passphrase given to 'tada unlock'",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !vault.StopAgent() {
+				cmd.Println("Already locked.")
+			} else {
+				cmd.Println("🔒 Locked")
+			}
+			if os.Getenv(vault.PassphraseEnv) != "" {
+				cmd.Printf("⚠️  %s is set, so todos can still be read from this shell\n", vault.PassphraseEnv)
+			}
+			return nil
+		},
+	}
+}
+
+// NewAgentCommand returns the command run in the background by 'tada unlock'.
+// It reads the passphrase from stdin.
+func NewAgentCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:    "agent",
+		Short:  "Keep the passphrase of encrypted todos",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		// The agent changes nothing
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			passphrase, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+			if err != nil {
+				return fmt.Errorf("failed to read passphrase: %w", err)
+			}
+
+			l, err := vault.ListenAgent()
+			if err != nil {
+				return err
+			}
+			// Tell 'tada unlock' that the agent is listening, then let it go
+			fmt.Fprintln(os.Stdout, "ready")
+			os.Stdout.Close()
+			// Keep running when the terminal that started it is closed
+			signal.Ignore(os.Interrupt, syscall.SIGHUP)
+
+			return vault.ServeAgent(l, strings.TrimSuffix(passphrase, "\n"), timeout)
+		},
+	}
+
+	cmd.Flags().Duration("timeout", vault.DefaultTimeout, "How long to remember the passphrase")
+
+	return cmd
+}
+
+// openDatabase opens the todo database without unlocking it
+func openDatabase(cmd *cobra.Command) (*sql.DB, func(), error) {
+	dbPath, err := todo.GetDatabasePath()
+	if err != nil {
+		todo.PrintError(cmd, err)
+		return nil, nil, err
+	}
+	conn, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		todo.PrintError(cmd, fmt.Errorf("failed to open database: %w", err))
+		return nil, nil, err
+	}
+	return conn, func() { conn.Close() }, nil
+}
+
+// unlock makes sure the database can be decrypted, asking for the
+// passphrase if neither the agent nor TADA_PASSPHRASE has it
+func unlock(cmd *cobra.Command, conn *sql.DB) error {
+	_, err := vault.Unlock(conn)
+	if !errors.Is(err, vault.ErrLocked) {
+		return err
+	}
+	passphrase, err := readPassphrase(cmd, "Passphrase: ")
+	if err != nil {
+		return err
+	}
+	return vault.Check(conn, passphrase)
+}
+
+// readNewPassphrase asks for a new passphrase twice
+func readNewPassphrase(cmd *cobra.Command) (string, error) {
+	passphrase, err := readPassphrase(cmd, "New passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if len(passphrase) < minPassphrase {
+		return "", fmt.Errorf("passphrase must be at least %d characters", minPassphrase)
+	}
+	again, err := readPassphrase(cmd, "Repeat passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if again != passphrase {
+		return "", errors.New("passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+// readPassphrase reads a passphrase without echoing it, or a line of stdin
+// when it is not a terminal
+func readPassphrase(cmd *cobra.Command, prompt string) (string, error) {
+	in := cmd.InOrStdin()
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		cmd.Print(prompt)
+		passphrase, err := term.ReadPassword(int(f.Fd()))
+		cmd.Println()
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		return string(passphrase), nil
+	}
+
+	// One byte at a time, so that the next line is left for the next read
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := in.Read(b)
+		if n == 1 && b[0] != '\n' {
+			line = append(line, b[0])
+			continue
+		}
+		if n == 1 || len(line) > 0 {
+			break
+		}
+		if err != nil {
+			return "", errors.New("failed to read passphrase")
+		}
+	}
+	return strings.TrimSuffix(string(line), "\r"), nil
+}
+
+// startAgent runs 'tada agent' in the background with the passphrase and
+// waits until it is listening
+func startAgent(passphrase string, timeout time.Duration) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to start agent: %w", err)
+	}
+
+	agent := exec.Command(exe, "agent", "--timeout", timeout.String())
+	agent.Stdin = strings.NewReader(passphrase + "\n")
+	stdout, err := agent.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to start agent: %w", err)
+	}
+	if err := agent.Start(); err != nil {
+		return fmt.Errorf("failed to start agent: %w", err)
+	}
+
+	line, _ := bufio.NewReader(stdout).ReadString('\n')
+	if strings.TrimSpace(line) != "ready" {
+		_ = agent.Wait()
+		return errors.New("failed to start agent")
+	}
+	// The agent outlives this command
+	return agent.Process.Release()
+}
+
+// formatTimeout formats a timeout such as 8h0m0s as 8h
+func formatTimeout(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
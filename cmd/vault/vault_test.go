@@ -0,0 +1,86 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestNewCommands(t *testing.T) {
+	tests := []struct {
+		cmd   *cobra.Command
+		use   string
+		short string
+	}{
+		{NewEncryptCommand(), "encrypt", "Encrypt todos and quotes with a passphrase"},
+		{NewDecryptCommand(), "decrypt", "Decrypt todos and quotes and stop asking for a passphrase"},
+		{NewUnlockCommand(), "unlock", "Remember the passphrase of encrypted todos for a while"},
+		{NewLockCommand(), "lock", "Forget the passphrase given to 'tada unlock'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.use, func(t *testing.T) {
+			if tt.cmd.Use != tt.use {
+				t.Errorf("Use = %v, want '%s'", tt.cmd.Use, tt.use)
+			}
+			if tt.cmd.Short != tt.short {
+				t.Errorf("Short = %v, want '%s'", tt.cmd.Short, tt.short)
+			}
+		})
+	}
+}
+
+func TestNewUnlockCommand(t *testing.T) {
+	cmd := NewUnlockCommand()
+
+	flag := cmd.Flags().Lookup("timeout")
+	if flag == nil {
+		t.Fatal("unlock should have flag 'timeout'")
+	}
+	if flag.DefValue != "8h0m0s" {
+		t.Errorf("timeout default = %v, want 8h0m0s", flag.DefValue)
+	}
+}
+
+func TestNewAgentCommand(t *testing.T) {
+	if cmd := NewAgentCommand(); !cmd.Hidden {
+		t.Error("agent should be hidden")
+	}
+}
+
+func TestReadPassphrase(t *testing.T) {
+	cmd := NewEncryptCommand()
+	cmd.SetIn(strings.NewReader("correct horse\r\ncorrect horse\n"))
+
+	got, err := readNewPassphrase(cmd)
+	if err != nil || got != "correct horse" {
+		t.Errorf("readNewPassphrase() = %q, %v, want the passphrase", got, err)
+	}
+
+	cmd.SetIn(strings.NewReader("short\nshort\n"))
+	if _, err := readNewPassphrase(cmd); err == nil {
+		t.Error("readNewPassphrase() of a short passphrase should fail")
+	}
+	cmd.SetIn(strings.NewReader("correct horse\nbattery staple\n"))
+	if _, err := readNewPassphrase(cmd); err == nil {
+		t.Error("readNewPassphrase() of different passphrases should fail")
+	}
+}
+
+func TestFormatTimeout(t *testing.T) {
+	tests := []struct {
+		timeout time.Duration
+		want    string
+	}{
+		{8 * time.Hour, "8h"},
+		{30 * time.Minute, "30m"},
+		{90 * time.Minute, "1h30m"},
+		{45 * time.Second, "45s"},
+	}
+	for _, tt := range tests {
+		if got := formatTimeout(tt.timeout); got != tt.want {
+			t.Errorf("formatTimeout(%v) = %q, want %q", tt.timeout, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/negadras/tada/cmd/hooks"
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/server"
+	"github.com/negadras/tada/internal/todo"
+	"github.com/negadras/tada/internal/web"
+	"github.com/spf13/cobra"
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "web",
+		Short: "Manage todos and quotes in the browser",
+		Long: `Serve a web interface for todos and quotes, with the same features as the TUI:
+list todos filtered by status, priority and tag, add, edit, tick and delete
+them, and browse, add, edit and delete quotes or show a random one.
+
+The page updates live whenever the database changes, including changes made
+with other tada commands, so it can stay open in a browser tab. The REST API
+of 'tada serve' is available under /api.
+
+The web interface has no login: keep the default address on 127.0.0.1 unless
+the network it listens on is trusted.`,
+		Example: `  # Open http://127.0.0.1:7071 in a browser
+  tada web
+
+  # Another port
+  tada web --addr 127.0.0.1:8080`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, _ := cmd.Flags().GetString("addr")
+
+			dbPath, err := todo.GetDatabasePath()
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			db, cleanup, err := todo.GetDB(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			quoteDB, quoteCleanup, err := quote.GetDB(cmd)
+			if err != nil {
+				return nil
+			}
+			defer quoteCleanup()
+
+			api := server.New(db, quoteDB, "")
+			api.OnChange = func() { hooks.AfterChange(cmd) }
+			httpServer := &http.Server{Addr: addr, Handler: web.Handler(api.Handler(), dbPath, addr)}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				_ = httpServer.Shutdown(shutdownCtx)
+			}()
+
+			cmd.Printf("🌐 Open http://%s in your browser (Ctrl+C to stop)\n", addr)
+			if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				todo.PrintError(cmd, err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("addr", "127.0.0.1:7071", "Address to listen on")
+
+	return cmd
+}
@@ -0,0 +1,19 @@
+package web
+
+import "testing"
+
+func TestNewCommand(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Use != "web" {
+		t.Errorf("NewCommand() Use = %v, want 'web'", cmd.Use)
+	}
+
+	if cmd.Short != "Manage todos and quotes in the browser" {
+		t.Errorf("NewCommand() Short = %v, want 'Manage todos and quotes in the browser'", cmd.Short)
+	}
+
+	if cmd.Flags().Lookup("addr") == nil {
+		t.Error("NewCommand() should have flag 'addr'")
+	}
+}
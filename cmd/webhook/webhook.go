@@ -0,0 +1,372 @@
+package webhook
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/todo"
+	"github.com/negadras/tada/internal/watch"
+	"github.com/negadras/tada/internal/webhook"
+	"github.com/spf13/cobra"
+)
+
+// client sends deliveries; a slow webhook must not hold up tada for long
+var client = &http.Client{Timeout: 5 * time.Second}
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "webhook",
+		Short: "Send todo and quote changes to webhook URLs",
+		Long: `Send a signed JSON payload to webhook URLs whenever a todo or quote is added,
+updated, completed or deleted, by any tada command, the TUI or the servers.
+
+Events:
+  add, update, done, delete                 todos
+  quote.add, quote.update, quote.delete     quotes
+
+Each payload is POSTed with the headers X-Tada-Event, X-Tada-Delivery and
+X-Tada-Signature, "sha256=" followed by the hex HMAC-SHA256 of the body keyed
+with the webhook's secret. Changes are queued in the database and sent in the
+background after the command that made them; a failed delivery is retried
+after 30 seconds, then twice as long each time up to an hour, and given up
+after 8 attempts.
+Retries happen when a later tada command runs, or with 'tada webhook deliver'.`,
+		Example: `  # Send completed and new todos to a local relay
+  tada webhook add http://127.0.0.1:9000/tada --events add,done
+
+  # See why deliveries failed, then retry them
+  tada webhook deliveries --failed
+  tada webhook deliver`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(newAddCommand())
+	cmd.AddCommand(newListCommand())
+	cmd.AddCommand(newRemoveCommand())
+	cmd.AddCommand(newDeliveriesCommand())
+	cmd.AddCommand(newDeliverCommand())
+
+	return cmd
+}
+
+func newAddCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add <url>",
+		Short: "Register a webhook URL",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			eventList, _ := cmd.Flags().GetString("events")
+			events, err := webhook.ParseEvents(eventList)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			secret, _ := cmd.Flags().GetString("secret")
+
+			store, cleanup, err := openStore(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			// Only changes made from now on are sent to the new webhook
+			if err := record(store); err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			w, err := store.Add(args[0], events, secret)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+
+			todo.PrintSuccess(cmd, fmt.Sprintf("Added webhook #%d: %s (%s)", w.ID, w.URL, strings.Join(w.Events, ",")))
+			cmd.Printf("🔑 Secret: %s\n", w.Secret)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("events", "e", "", "Comma-separated events to send (default all)")
+	cmd.Flags().String("secret", "", "Secret that signs the payloads (default a random one)")
+
+	return cmd
+}
+
+func newListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the webhooks",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, cleanup, err := openStore(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			webhooks, err := store.List()
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			if len(webhooks) == 0 {
+				cmd.Println("No webhooks yet. Add one with 'tada webhook add <url>'.")
+				return nil
+			}
+			for _, w := range webhooks {
+				cmd.Printf("#%d %s (%s)\n", w.ID, w.URL, strings.Join(w.Events, ","))
+			}
+			return nil
+		},
+	}
+}
+
+func newRemoveCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "remove <id>",
+		Aliases: []string{"rm"},
+		Short:   "Remove a webhook and its queued deliveries",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.Atoi(args[0])
+			if err != nil {
+				todo.PrintError(cmd, fmt.Errorf("invalid webhook ID %q", args[0]))
+				return nil
+			}
+
+			store, cleanup, err := openStore(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			if err := store.Remove(id); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					err = fmt.Errorf("webhook #%d not found", id)
+				}
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			cmd.Printf("🗑️  Removed webhook #%d\n", id)
+			return nil
+		},
+	}
+}
+
+func newDeliveriesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deliveries",
+		Short: "Show recent deliveries and why they failed",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			failed, _ := cmd.Flags().GetBool("failed")
+			limit, _ := cmd.Flags().GetInt("limit")
+
+			store, cleanup, err := openStore(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			deliveries, err := store.Deliveries(limit, failed)
+			if err != nil {
+				todo.PrintError(cmd, err)
+				return nil
+			}
+			if len(deliveries) == 0 {
+				cmd.Println("No deliveries.")
+				return nil
+			}
+			for _, d := range deliveries {
+				printDelivery(cmd, d)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolP("failed", "f", false, "Only show deliveries that were not delivered yet")
+	cmd.Flags().IntP("limit", "n", 20, "Number of deliveries to show")
+
+	return cmd
+}
+
+func newDeliverCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deliver",
+		Short: "Send every undelivered change now, including given up ones",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dbPath, _ := cmd.Flags().GetString("background"); dbPath != "" {
+				// Started by Notify: send what is due, with no one to tell
+				store, err := webhook.Open(dbPath)
+				if err != nil {
+					return nil
+				}
+				defer store.Close()
+				_, _ = store.Deliver(client, false)
+				return nil
+			}
+
+			store, cleanup, err := openStore(cmd)
+			if err != nil {
+				return nil
+			}
+			defer cleanup()
+
+			attempts, err := store.Deliver(client, true)
+			if err != nil {
+				todo.PrintError(cmd, err)
+			}
+			if len(attempts) == 0 && err == nil {
+				cmd.Println("Nothing to deliver.")
+			}
+			for _, a := range attempts {
+				if a.Err != nil {
+					cmd.Printf("⚠️  Delivery #%d of %s to %s failed: %v\n", a.Delivery.ID, a.Delivery.Event, a.Delivery.URL, a.Err)
+				} else {
+					cmd.Printf("📨 Delivered #%d of %s to %s\n", a.Delivery.ID, a.Delivery.Event, a.Delivery.URL)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("background", "", "Send only the due deliveries of the database at this path, quietly")
+	_ = cmd.Flags().MarkHidden("background")
+
+	return cmd
+}
+
+// Notify queues the changes made by a command for the webhooks, if the
+// database changed since they were last queued, and starts sending what is
+// due in the background, so that a slow or unreachable webhook never holds
+// up the command. It runs after every command but the webhook ones, which
+// change no todos.
+func Notify(cmd *cobra.Command) {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Name() == "webhook" && c.HasParent() {
+			return
+		}
+	}
+
+	dbPath, err := todo.GetDatabasePath()
+	if err != nil {
+		return
+	}
+	store, err := webhook.Open(dbPath)
+	if err != nil {
+		return
+	}
+	defer store.Close()
+
+	webhooks, err := store.List()
+	if err != nil || len(webhooks) == 0 {
+		return
+	}
+
+	if recorded := recordedPath(dbPath); watch.Changed(dbPath, recorded) {
+		if err := record(store); err != nil {
+			todo.PrintError(cmd, fmt.Errorf("failed to queue webhook deliveries: %w", err))
+			return
+		}
+		if err := watch.Stamp(recorded); err != nil {
+			todo.PrintError(cmd, err)
+		}
+	}
+
+	if due, err := store.Due(); err != nil || !due {
+		return
+	}
+	if err := deliverInBackground(dbPath); err != nil {
+		todo.PrintError(cmd, fmt.Errorf("failed to send webhook deliveries: %w", err))
+	}
+}
+
+// recordedPath returns the path of the file whose time is when the changes
+// of the database at dbPath were last queued
+func recordedPath(dbPath string) string {
+	return filepath.Join(filepath.Dir(dbPath), "webhooks.recorded")
+}
+
+// deliverInBackground starts 'tada webhook deliver --background' to send
+// the deliveries of the database at dbPath that are due, without waiting
+// for it
+func deliverInBackground(dbPath string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	child := exec.Command(exe, "webhook", "deliver", "--background", dbPath)
+	if err := child.Start(); err != nil {
+		return err
+	}
+	return child.Process.Release()
+}
+
+// openStore opens the webhooks of the default database, printing errors
+func openStore(cmd *cobra.Command) (*webhook.Store, func(), error) {
+	dbPath, err := todo.GetDatabasePath()
+	if err != nil {
+		todo.PrintError(cmd, err)
+		return nil, nil, err
+	}
+	store, err := webhook.Open(dbPath)
+	if err != nil {
+		todo.PrintError(cmd, err)
+		return nil, nil, err
+	}
+	return store, func() { store.Close() }, nil
+}
+
+// record queues a delivery of every change since the last record
+func record(store *webhook.Store) error {
+	dbPath, err := todo.GetDatabasePath()
+	if err != nil {
+		return err
+	}
+	todoDB, err := todo.NewDB(dbPath)
+	if err != nil {
+		return err
+	}
+	defer todoDB.Close()
+	quoteDB, err := quote.NewDB(dbPath)
+	if err != nil {
+		return err
+	}
+	defer quoteDB.Close()
+
+	todos, err := todoDB.List(nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	quotes, err := quoteDB.List(nil, nil)
+	if err != nil {
+		return err
+	}
+	_, err = store.Record(todos, quotes)
+	return err
+}
+
+func printDelivery(cmd *cobra.Command, d *webhook.Delivery) {
+	icon := map[string]string{"delivered": "✅", "pending": "⏳", "failed": "❌"}[d.State()]
+	cmd.Printf("%s #%d %s to %s, %s, %d attempt(s)\n", icon, d.ID, d.Event, d.URL, d.State(), d.Attempts)
+	if d.LastError != "" {
+		cmd.Printf("   Last error: %s\n", d.LastError)
+	}
+	if d.State() == "pending" && d.Attempts > 0 {
+		cmd.Printf("   Next attempt: %s\n", d.NextAttemptAt.Local().Format("2006-01-02 15:04:05"))
+	}
+}
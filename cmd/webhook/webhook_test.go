@@ -0,0 +1,26 @@
+package webhook
+
+import "testing"
+
+func TestNewCommand(t *testing.T) {
+	cmd := NewCommand()
+
+	if cmd.Use != "webhook" {
+		t.Errorf("NewCommand() Use = %v, want 'webhook'", cmd.Use)
+	}
+
+	if cmd.Short != "Send todo and quote changes to webhook URLs" {
+		t.Errorf("NewCommand() Short = %v, want 'Send todo and quote changes to webhook URLs'", cmd.Short)
+	}
+
+	for _, name := range []string{"add", "list", "remove", "deliveries", "deliver"} {
+		if sub, _, err := cmd.Find([]string{name}); err != nil || sub.Name() != name {
+			t.Errorf("NewCommand() should have subcommand '%s'", name)
+		}
+	}
+
+	add, _, _ := cmd.Find([]string{"add"})
+	if add.Flags().Lookup("events") == nil {
+		t.Error("webhook add should have flag 'events'")
+	}
+}
@@ -0,0 +1,188 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/todo"
+)
+
+// FormatName identifies tada backup files
+const FormatName = "tada-backup"
+
+// Version is the version of the backup format written by this build. Backups
+// with a higher version were written by a newer tada and are rejected.
+const Version = 1
+
+// Backup is the envelope of a full backup of todos and quotes
+type Backup struct {
+	Format     string         `json:"format"`
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Todos      []*todo.Todo   `json:"todos"`
+	Quotes     []*quote.Quote `json:"quotes"`
+}
+
+// New returns a backup of the given todos and quotes
+func New(todos []*todo.Todo, quotes []*quote.Quote) *Backup {
+	if todos == nil {
+		todos = []*todo.Todo{}
+	}
+	if quotes == nil {
+		quotes = []*quote.Quote{}
+	}
+
+	return &Backup{
+		Format:     FormatName,
+		Version:    Version,
+		ExportedAt: time.Now().UTC(),
+		Todos:      todos,
+		Quotes:     quotes,
+	}
+}
+
+// Write writes the backup as indented JSON
+func (b *Backup) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// Read reads and validates a backup
+func Read(r io.Reader) (*Backup, error) {
+	var b Backup
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, fmt.Errorf("not a tada backup (use --from for other formats): %w", err)
+	}
+
+	if b.Format != FormatName {
+		return nil, fmt.Errorf("not a tada backup: format is %q, want %q", b.Format, FormatName)
+	}
+	if b.Version < 1 || b.Version > Version {
+		return nil, fmt.Errorf("unsupported backup version %d (this tada reads versions 1 to %d)", b.Version, Version)
+	}
+
+	todoIDs, todoUIDs := map[int]bool{}, map[string]bool{}
+	for i, t := range b.Todos {
+		if err := checkUnique(t.ID, t.UID, todoIDs, todoUIDs); err != nil {
+			return nil, fmt.Errorf("todo %d: %w", i+1, err)
+		}
+		if err := todo.ValidateDescription(t.Description); err != nil {
+			return nil, fmt.Errorf("todo %d: %w", i+1, err)
+		}
+		if t.Priority < todo.Low || t.Priority > todo.High {
+			return nil, fmt.Errorf("todo %d: invalid priority %d", i+1, t.Priority)
+		}
+		if t.Status != todo.Open && t.Status != todo.Done {
+			return nil, fmt.Errorf("todo %d: invalid status %d", i+1, t.Status)
+		}
+	}
+	quoteIDs, quoteUIDs := map[int]bool{}, map[string]bool{}
+	for i, q := range b.Quotes {
+		if err := checkUnique(q.ID, q.UID, quoteIDs, quoteUIDs); err != nil {
+			return nil, fmt.Errorf("quote %d: %w", i+1, err)
+		}
+		if err := quote.ValidateQuoteText(q.Text); err != nil {
+			return nil, fmt.Errorf("quote %d: %w", i+1, err)
+		}
+	}
+
+	return &b, nil
+}
+
+// checkUnique reports an ID or UID already seen in the backup, which would
+// make a restore fail part-way or merge two items into one
+func checkUnique(id int, uid string, ids map[int]bool, uids map[string]bool) error {
+	if id != 0 {
+		if ids[id] {
+			return fmt.Errorf("duplicate ID %d", id)
+		}
+		ids[id] = true
+	}
+	if uid != "" {
+		if uids[uid] {
+			return fmt.Errorf("duplicate UID %s", uid)
+		}
+		uids[uid] = true
+	}
+	return nil
+}
+
+// TodoKey returns the identity used to recognise the same todo across
+// databases: its description and creation time. IDs are not used because
+// two databases assign them independently.
+func TodoKey(t *todo.Todo) string {
+	return strings.TrimSpace(t.Description) + "\x00" + t.CreatedAt.UTC().Truncate(time.Second).Format(time.RFC3339)
+}
+
+// QuoteKey returns the identity used to recognise the same quote across
+// databases: its text and author, ignoring case and surrounding whitespace
+func QuoteKey(q *quote.Quote) string {
+	return strings.ToLower(strings.TrimSpace(q.Text)) + "\x00" + strings.ToLower(strings.TrimSpace(q.Author))
+}
+
+// Merge is the result of merging a backup into existing data
+type Merge struct {
+	Todos         []*todo.Todo
+	Quotes        []*quote.Quote
+	SkippedTodos  int
+	SkippedQuotes int
+}
+
+// PlanMerge returns the todos and quotes of the backup that are not already
+// present, by UID or else by TodoKey for todos and QuoteKey for quotes.
+// Duplicates within the backup itself are skipped too, so importing the same
+// backup twice changes nothing. The returned items have no ID and are given
+// new ones when inserted, but keep their UID.
+func PlanMerge(b *Backup, todos []*todo.Todo, quotes []*quote.Quote) *Merge {
+	m := &Merge{}
+
+	seenTodos := map[string]bool{}
+	seenUIDs := map[string]bool{}
+	for _, t := range todos {
+		seenTodos[TodoKey(t)] = true
+		seenUIDs[t.UID] = true
+	}
+	for _, t := range b.Todos {
+		key := TodoKey(t)
+		if seenTodos[key] || (t.UID != "" && seenUIDs[t.UID]) {
+			m.SkippedTodos++
+			continue
+		}
+		seenTodos[key] = true
+		if t.UID != "" {
+			seenUIDs[t.UID] = true
+		}
+
+		copied := *t
+		copied.ID = 0
+		m.Todos = append(m.Todos, &copied)
+	}
+
+	seenQuotes := map[string]bool{}
+	for _, q := range quotes {
+		seenQuotes[QuoteKey(q)] = true
+		seenUIDs[q.UID] = true
+	}
+	for _, q := range b.Quotes {
+		key := QuoteKey(q)
+		if seenQuotes[key] || (q.UID != "" && seenUIDs[q.UID]) {
+			m.SkippedQuotes++
+			continue
+		}
+		seenQuotes[key] = true
+		if q.UID != "" {
+			seenUIDs[q.UID] = true
+		}
+
+		copied := *q
+		copied.ID = 0
+		m.Quotes = append(m.Quotes, &copied)
+	}
+
+	return m
+}
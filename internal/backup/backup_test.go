@@ -0,0 +1,118 @@
+package backup
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/todo"
+)
+
+func TestBackup_WriteRead(t *testing.T) {
+	created := time.Date(2024, 4, 20, 9, 0, 0, 0, time.UTC)
+	completed := created.Add(time.Hour)
+
+	b := New(
+		[]*todo.Todo{{ID: 4, Description: "Ship release", Priority: todo.High, Status: todo.Done, Tag: "launch", CreatedAt: created, UpdatedAt: completed, CompletedAt: &completed}},
+		[]*quote.Quote{{ID: 9, Text: "Stay hungry", Author: "Steve Jobs", CreatedAt: created, UpdatedAt: created}},
+	)
+
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `"format": "tada-backup"`) || !strings.Contains(buf.String(), `"version": 1`) {
+		t.Errorf("Write() should include the format and version, got %s", buf.String())
+	}
+
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	if len(got.Todos) != 1 || got.Todos[0].ID != 4 || got.Todos[0].Tag != "launch" || !got.Todos[0].CompletedAt.Equal(completed) {
+		t.Errorf("Read() todos = %+v", got.Todos)
+	}
+	if len(got.Quotes) != 1 || got.Quotes[0].ID != 9 || got.Quotes[0].Author != "Steve Jobs" {
+		t.Errorf("Read() quotes = %+v", got.Quotes)
+	}
+}
+
+func TestRead_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		errContains string
+	}{
+		{"not json", "(A) Fix login bug", "not a tada backup"},
+		{"wrong format", `{"format": "other", "version": 1}`, "not a tada backup"},
+		{"newer version", `{"format": "tada-backup", "version": 99}`, "unsupported backup version"},
+		{"empty description", `{"format": "tada-backup", "version": 1, "todos": [{"description": " ", "priority": 2, "status": 1}]}`, "todo 1"},
+		{"invalid priority", `{"format": "tada-backup", "version": 1, "todos": [{"description": "x", "priority": 7, "status": 1}]}`, "invalid priority"},
+		{"empty quote", `{"format": "tada-backup", "version": 1, "quotes": [{"text": ""}]}`, "quote 1"},
+		{"duplicate todo ID", `{"format": "tada-backup", "version": 1, "todos": [{"id": 3, "description": "a", "priority": 2, "status": 1}, {"id": 3, "description": "b", "priority": 2, "status": 1}]}`, "todo 2: duplicate ID 3"},
+		{"duplicate todo UID", `{"format": "tada-backup", "version": 1, "todos": [{"uid": "u1", "description": "a", "priority": 2, "status": 1}, {"uid": "u1", "description": "b", "priority": 2, "status": 1}]}`, "todo 2: duplicate UID u1"},
+		{"duplicate quote ID", `{"format": "tada-backup", "version": 1, "quotes": [{"id": 1, "text": "a"}, {"id": 1, "text": "b"}]}`, "quote 2: duplicate ID 1"},
+		{"duplicate quote UID", `{"format": "tada-backup", "version": 1, "quotes": [{"uid": "q", "text": "a"}, {"uid": "q", "text": "b"}]}`, "quote 2: duplicate UID q"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(strings.NewReader(tt.input))
+			if err == nil {
+				t.Fatal("Read() expected error")
+			}
+			if !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("Read() error = %v, want error containing %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestPlanMerge(t *testing.T) {
+	created := time.Date(2024, 4, 20, 9, 0, 0, 0, time.UTC)
+
+	existingTodos := []*todo.Todo{{ID: 1, Description: "Fix login bug", CreatedAt: created, UID: "uid-1"}}
+	existingQuotes := []*quote.Quote{{ID: 1, Text: "Stay hungry", Author: "Steve Jobs", UID: "uid-2"}}
+
+	b := New(
+		[]*todo.Todo{
+			{ID: 1, Description: "Fix login bug", CreatedAt: created},
+			{ID: 2, Description: "Fix login bug", CreatedAt: created.Add(time.Hour)},
+			{ID: 3, Description: "Write docs", CreatedAt: created},
+			{ID: 4, Description: "Write docs", CreatedAt: created},
+			{ID: 5, Description: "Fix login bug (renamed)", CreatedAt: created, UID: "uid-1"},
+		},
+		[]*quote.Quote{
+			{ID: 5, Text: "stay hungry ", Author: "steve jobs"},
+			{ID: 6, Text: "Stay hungry", Author: "Someone else"},
+			{ID: 7, Text: "Stay hungry, stay foolish", Author: "Steve Jobs", UID: "uid-2"},
+		},
+	)
+
+	m := PlanMerge(b, existingTodos, existingQuotes)
+
+	if len(m.Todos) != 2 || m.SkippedTodos != 3 {
+		t.Errorf("PlanMerge() todos = %d new, %d skipped; want 2 new, 3 skipped", len(m.Todos), m.SkippedTodos)
+	}
+	if len(m.Quotes) != 1 || m.SkippedQuotes != 2 {
+		t.Errorf("PlanMerge() quotes = %d new, %d skipped; want 1 new, 2 skipped", len(m.Quotes), m.SkippedQuotes)
+	}
+
+	for _, td := range m.Todos {
+		if td.ID != 0 {
+			t.Errorf("PlanMerge() should clear IDs, got %d", td.ID)
+		}
+	}
+	if b.Todos[1].ID != 2 {
+		t.Error("PlanMerge() should not modify the backup")
+	}
+
+	// Merging the same backup again once it has been applied adds nothing
+	again := PlanMerge(b, append(existingTodos, m.Todos...), append(existingQuotes, m.Quotes...))
+	if len(again.Todos) != 0 || len(again.Quotes) != 0 {
+		t.Errorf("second PlanMerge() = %d todos, %d quotes; want none", len(again.Todos), len(again.Quotes))
+	}
+}
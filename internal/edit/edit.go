@@ -0,0 +1,187 @@
+package edit
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/negadras/tada/internal/todo"
+)
+
+// header explains the format at the top of the file, like git rebase -i
+const header = `# Edit the todos below, then save and quit.
+#
+#   #12 [H] {tag} description
+#
+# Change a priority ([H], [M] or [L]), a tag or a description in place;
+# {} is no tag.
+# Remove a line to delete its todo. Add a line without an #ID to create
+# a todo, such as "[H] {work} Review the budget". Lines starting with
+# "# " are ignored; an empty file changes nothing.
+`
+
+// linePattern is a line of the file: an optional #ID, an optional [P]
+// priority and an optional {tag} before the description
+var linePattern = regexp.MustCompile(`^(?:#(\d+)\s+)?(?:\[([^\]]*)\]\s*)?(?:\{([^}]*)\}\s*)?(.*)$`)
+
+// Line is a todo as edited in the file
+type Line struct {
+	// ID is 0 for a new todo
+	ID          int
+	Priority    todo.Priority
+	Tag         string
+	Description string
+}
+
+// Format writes todos in the format of the file, after the header
+func Format(todos []*todo.Todo) string {
+	var b strings.Builder
+	b.WriteString(header)
+	b.WriteString("\n")
+	for _, t := range todos {
+		b.WriteString(FormatLine(t))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// FormatLine returns the line of a todo. The braces of the tag are written
+// even without one, so that a description starting with {...} is not read
+// back as the tag.
+func FormatLine(t *todo.Todo) string {
+	return fmt.Sprintf("#%d [%s] {%s} %s", t.ID, priorityLetter(t.Priority), t.Tag, t.Description)
+}
+
+// priorityLetter returns the letter of a priority in the file
+func priorityLetter(p todo.Priority) string {
+	switch p {
+	case todo.High:
+		return "H"
+	case todo.Low:
+		return "L"
+	default:
+		return "M"
+	}
+}
+
+// Parse reads the edited file. Every line with a problem is reported, with
+// its number, so that they can all be fixed in one go.
+func Parse(text string) ([]Line, error) {
+	var lines []Line
+	var problems []string
+	seen := map[int]bool{}
+
+	s := bufio.NewScanner(strings.NewReader(text))
+	lineNo := 0
+	for s.Scan() {
+		lineNo++
+		raw := strings.TrimSpace(s.Text())
+		if raw == "" || raw == "#" || strings.HasPrefix(raw, "# ") {
+			continue
+		}
+
+		l, err := ParseLine(raw)
+		if err == nil && l.ID != 0 && seen[l.ID] {
+			err = fmt.Errorf("todo #%d appears twice", l.ID)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %v", lineNo, err))
+			continue
+		}
+		seen[l.ID] = true
+		lines = append(lines, l)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, "\n"))
+	}
+	return lines, nil
+}
+
+// ParseLine parses a line of the file. New todos default to medium priority.
+func ParseLine(raw string) (Line, error) {
+	m := linePattern.FindStringSubmatch(raw)
+	l := Line{Priority: todo.Medium, Tag: strings.TrimSpace(m[3]), Description: strings.TrimSpace(m[4])}
+
+	if m[1] != "" {
+		id, err := strconv.Atoi(m[1])
+		if err != nil || id <= 0 {
+			return Line{}, fmt.Errorf("invalid todo ID #%s", m[1])
+		}
+		l.ID = id
+	} else if strings.HasPrefix(raw, "#") {
+		return Line{}, fmt.Errorf("invalid todo ID in %q", raw)
+	}
+
+	if m[2] != "" {
+		p, err := todo.ParsePriority(m[2])
+		if err != nil {
+			return Line{}, fmt.Errorf("invalid priority [%s], use [H], [M] or [L]", m[2])
+		}
+		l.Priority = p
+	}
+
+	if err := todo.ValidateDescription(l.Description); err != nil {
+		return Line{}, err
+	}
+	return l, nil
+}
+
+// Plan is the changes an edit makes
+type Plan struct {
+	// Create are the new todos
+	Create []Line
+	// Update are the todos changed, with their new fields
+	Update []Line
+	// Delete are the todos whose lines were removed
+	Delete []*todo.Todo
+}
+
+// Empty reports whether the edit changes nothing
+func (p *Plan) Empty() bool {
+	return len(p.Create) == 0 && len(p.Update) == 0 && len(p.Delete) == 0
+}
+
+// PlanEdit works out the changes between the todos written to the file and
+// its edited lines. A line may only keep the ID of a todo that was written
+// to the file. If no lines are left, the edit is taken as abandoned and
+// nothing is deleted.
+func PlanEdit(todos []*todo.Todo, lines []Line) (*Plan, error) {
+	plan := &Plan{}
+	if len(lines) == 0 {
+		return plan, nil
+	}
+
+	byID := make(map[int]*todo.Todo, len(todos))
+	for _, t := range todos {
+		byID[t.ID] = t
+	}
+
+	kept := map[int]bool{}
+	for _, l := range lines {
+		if l.ID == 0 {
+			plan.Create = append(plan.Create, l)
+			continue
+		}
+		t, ok := byID[l.ID]
+		if !ok {
+			return nil, fmt.Errorf("todo #%d was not in the file; remove its ID to create a new todo", l.ID)
+		}
+		kept[l.ID] = true
+		if l.Priority != t.Priority || l.Tag != t.Tag || l.Description != t.Description {
+			plan.Update = append(plan.Update, l)
+		}
+	}
+
+	for _, t := range todos {
+		if !kept[t.ID] {
+			plan.Delete = append(plan.Delete, t)
+		}
+	}
+	return plan, nil
+}
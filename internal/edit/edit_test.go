@@ -0,0 +1,140 @@
+package edit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/negadras/tada/internal/todo"
+)
+
+func TestFormatLine(t *testing.T) {
+	tests := []struct {
+		name string
+		todo *todo.Todo
+		want string
+	}{
+		{"with tag", &todo.Todo{ID: 12, Priority: todo.High, Tag: "work", Description: "Review the budget"}, "#12 [H] {work} Review the budget"},
+		{"without tag", &todo.Todo{ID: 3, Priority: todo.Low, Description: "Water plants"}, "#3 [L] {} Water plants"},
+		{"medium", &todo.Todo{ID: 7, Priority: todo.Medium, Description: "Call"}, "#7 [M] {} Call"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatLine(tt.todo); got != tt.want {
+				t.Errorf("FormatLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    Line
+		wantErr bool
+	}{
+		{"full line", "#12 [H] {work} Review the budget", Line{ID: 12, Priority: todo.High, Tag: "work", Description: "Review the budget"}, false},
+		{"no tag", "#3 [l] Water plants", Line{ID: 3, Priority: todo.Low, Description: "Water plants"}, false},
+		{"new todo", "[H] {home} Fix the tap", Line{Priority: todo.High, Tag: "home", Description: "Fix the tap"}, false},
+		{"new todo with defaults", "Fix the tap", Line{Priority: todo.Medium, Description: "Fix the tap"}, false},
+		{"empty tag", "#4 [M] {} Untagged", Line{ID: 4, Priority: todo.Medium, Description: "Untagged"}, false},
+		{"invalid priority", "#4 [Q] Nope", Line{}, true},
+		{"invalid ID", "#x [H] Nope", Line{}, true},
+		{"no description", "#4 [H] {work}", Line{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLine(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLine(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLine(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	todos := []*todo.Todo{
+		{ID: 1, Priority: todo.High, Tag: "work", Description: "alpha"},
+		{ID: 2, Priority: todo.Medium, Description: "beta"},
+	}
+
+	lines, err := Parse(Format(todos))
+	if err != nil {
+		t.Fatalf("Parse(Format()) error = %v", err)
+	}
+	if len(lines) != 2 || lines[0].ID != 1 || lines[1].Description != "beta" {
+		t.Errorf("Parse(Format()) = %+v, want the two todos back", lines)
+	}
+
+	_, err = Parse("#1 [H] alpha\n#1 [M] again\n\n#2 [Q] beta\n")
+	if err == nil {
+		t.Fatal("Parse() error = nil, want the problems")
+	}
+	for _, want := range []string{"line 2: todo #1 appears twice", "line 4: invalid priority [Q]"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse() error = %q, want it to contain %q", err, want)
+		}
+	}
+}
+
+func TestPlanEdit(t *testing.T) {
+	todos := []*todo.Todo{
+		{ID: 1, Priority: todo.High, Tag: "work", Description: "alpha"},
+		{ID: 2, Priority: todo.Medium, Description: "beta"},
+		{ID: 3, Priority: todo.Medium, Description: "gamma"},
+	}
+
+	lines, err := Parse("#1 [H] {work} alpha\n#2 [L] {home} beta renamed\n[H] delta\n")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	plan, err := PlanEdit(todos, lines)
+	if err != nil {
+		t.Fatalf("PlanEdit() error = %v", err)
+	}
+	if len(plan.Update) != 1 || plan.Update[0].ID != 2 || plan.Update[0].Tag != "home" {
+		t.Errorf("PlanEdit() Update = %+v, want #2 only", plan.Update)
+	}
+	if len(plan.Create) != 1 || plan.Create[0].Description != "delta" {
+		t.Errorf("PlanEdit() Create = %+v, want delta", plan.Create)
+	}
+	if len(plan.Delete) != 1 || plan.Delete[0].ID != 3 {
+		t.Errorf("PlanEdit() Delete = %+v, want #3", plan.Delete)
+	}
+
+	// An empty file is an abandoned edit
+	plan, err = PlanEdit(todos, nil)
+	if err != nil || !plan.Empty() {
+		t.Errorf("PlanEdit() of no lines = %+v, %v, want no changes", plan, err)
+	}
+
+	if _, err := PlanEdit(todos, []Line{{ID: 9, Priority: todo.Medium, Description: "other"}}); err == nil {
+		t.Error("PlanEdit() with an unknown ID error = nil, want an error")
+	}
+}
+
+func TestFormat_RoundTrip(t *testing.T) {
+	todos := []*todo.Todo{
+		{ID: 1, Priority: todo.High, Tag: "work", Description: "Review the budget"},
+		{ID: 2, Priority: todo.Medium, Description: "{wip} refactor"},
+		{ID: 3, Priority: todo.Low, Description: "[draft] notes"},
+		{ID: 4, Priority: todo.Medium, Tag: "home", Description: "{x} [y] both"},
+	}
+
+	lines, err := Parse(Format(todos))
+	if err != nil {
+		t.Fatalf("Parse(Format()) error = %v", err)
+	}
+	plan, err := PlanEdit(todos, lines)
+	if err != nil {
+		t.Fatalf("PlanEdit() error = %v", err)
+	}
+	if !plan.Empty() {
+		t.Errorf("PlanEdit() of the unedited file = %+v, want no changes", plan)
+	}
+}
@@ -0,0 +1,194 @@
+package gitstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Conflict is a record changed on both sides of a pull. The newer version
+// is kept; a record deleted on one side and changed on the other is kept.
+type Conflict struct {
+	// Kind is "todo" or "quote"
+	Kind string
+	// Label is the todo's description or the quote's text
+	Label string
+	// KeptLocal tells whether this side's version was kept
+	KeptLocal bool
+	// Deleted tells whether the record was deleted on the side not kept
+	Deleted bool
+}
+
+// git runs a git command in dir and returns its trimmed output
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	if err != nil {
+		if output == "" {
+			return "", fmt.Errorf("git %s: %w", args[0], err)
+		}
+		return output, fmt.Errorf("git %s: %s", args[0], output)
+	}
+	return output, nil
+}
+
+// Init creates an empty store in dir. Given a remote, dir is cloned from it
+// instead, so the store starts with the records already pushed there.
+func Init(dir, remote string) error {
+	if Enabled(dir) {
+		return fmt.Errorf("%s is already a git store", dir)
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+
+	if remote != "" {
+		_, err := git(filepath.Dir(dir), "clone", "--quiet", remote, dir)
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	_, err := git(dir, "init", "--quiet")
+	return err
+}
+
+// Commit commits every change in dir with the given message, reporting
+// whether there was anything to commit
+func Commit(dir, message string) (bool, error) {
+	if _, err := git(dir, "add", "--all"); err != nil {
+		return false, err
+	}
+	status, err := git(dir, "status", "--porcelain")
+	if err != nil || status == "" {
+		return false, err
+	}
+	if _, err := git(dir, "commit", "--quiet", "--message", message); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Push pushes the current branch to origin, setting it as the upstream
+func Push(dir string) error {
+	_, err := git(dir, "push", "--quiet", "--set-upstream", "origin", "HEAD")
+	if err != nil && strings.Contains(err.Error(), "rejected") {
+		return fmt.Errorf("the remote has changes that are not here yet, run 'tada git pull' first")
+	}
+	return err
+}
+
+// Pull fetches the current branch from origin and merges it. Records
+// changed on both sides are resolved by keeping one version of the whole
+// record, and reported. It reports false if there was nothing to merge.
+func Pull(dir string) (bool, []Conflict, error) {
+	if _, err := git(dir, "fetch", "--quiet", "origin"); err != nil {
+		return false, nil, err
+	}
+	branch, err := git(dir, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return false, nil, err
+	}
+	upstream := "origin/" + branch
+	if _, err := git(dir, "rev-parse", "--verify", "--quiet", upstream); err != nil {
+		// Nothing has been pushed to this branch yet
+		return false, nil, nil
+	}
+
+	before, _ := git(dir, "rev-parse", "--verify", "--quiet", "HEAD")
+	if _, err := git(dir, "merge", "--quiet", "--no-edit", upstream); err == nil {
+		after, _ := git(dir, "rev-parse", "HEAD")
+		return before != after, nil, nil
+	}
+
+	conflicted, err := git(dir, "diff", "--name-only", "--diff-filter=U")
+	if err != nil || conflicted == "" {
+		_, _ = git(dir, "merge", "--abort")
+		return false, nil, fmt.Errorf("failed to merge %s: %w", upstream, err)
+	}
+
+	var conflicts []Conflict
+	for _, name := range strings.Split(conflicted, "\n") {
+		c, err := resolve(dir, name)
+		if err != nil {
+			_, _ = git(dir, "merge", "--abort")
+			return false, nil, fmt.Errorf("failed to resolve %s: %w", name, err)
+		}
+		conflicts = append(conflicts, c)
+	}
+
+	if _, err := git(dir, "commit", "--quiet", "--no-edit"); err != nil {
+		return false, nil, err
+	}
+	return true, conflicts, nil
+}
+
+// resolve settles a conflicted record file by keeping either this side's or
+// the other side's version of it
+func resolve(dir, name string) (Conflict, error) {
+	c := Conflict{Kind: "todo"}
+	if strings.HasPrefix(name, quotesDir+"/") {
+		c.Kind = "quote"
+	}
+
+	// Stage 2 is this side's version and stage 3 the other side's; a
+	// missing stage means the record was deleted on that side
+	local, localErr := git(dir, "show", ":2:"+name)
+	other, otherErr := git(dir, "show", ":3:"+name)
+	if localErr != nil && otherErr != nil {
+		return c, fmt.Errorf("no version of the record to keep")
+	}
+
+	var keep string
+	switch {
+	case otherErr != nil:
+		keep, c.KeptLocal, c.Deleted = local, true, true
+	case localErr != nil:
+		keep, c.Deleted = other, true
+	default:
+		localInfo, err := parseRecordInfo(local)
+		if err != nil {
+			return c, err
+		}
+		otherInfo, err := parseRecordInfo(other)
+		if err != nil {
+			return c, err
+		}
+		keep = other
+		if !localInfo.UpdatedAt.Before(otherInfo.UpdatedAt) {
+			keep, c.KeptLocal = local, true
+		}
+	}
+
+	info, err := parseRecordInfo(keep)
+	if err != nil {
+		return c, err
+	}
+	c.Label = info.Description + info.Text
+
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(keep+"\n"), 0644); err != nil {
+		return c, err
+	}
+	_, err = git(dir, "add", "--", name)
+	return c, err
+}
+
+// recordInfo holds the fields of a todo or quote file needed to resolve a
+// conflict
+type recordInfo struct {
+	Description string    `json:"description"`
+	Text        string    `json:"text"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func parseRecordInfo(data string) (recordInfo, error) {
+	var info recordInfo
+	err := json.Unmarshal([]byte(data), &info)
+	return info, err
+}
@@ -0,0 +1,255 @@
+package gitstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/todo"
+)
+
+// The store keeps each todo and quote in its own file, named after its UID,
+// so that git can track, diff and merge them one record at a time:
+//
+//	todos/<uid>.json
+//	quotes/<uid>.json
+const (
+	todosDir  = "todos"
+	quotesDir = "quotes"
+)
+
+// todoRecord is the file form of a todo. IDs are local to each database so
+// they are not stored, and every field is always written, one per line, so
+// that a change to one field is a one-line diff.
+type todoRecord struct {
+	UID         string     `json:"uid"`
+	Description string     `json:"description"`
+	Priority    int        `json:"priority"`
+	Status      int        `json:"status"`
+	Tag         string     `json:"tag"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	DueAt       *time.Time `json:"due_at"`
+	Notes       string     `json:"notes"`
+}
+
+// quoteRecord is the file form of a quote
+type quoteRecord struct {
+	UID       string    `json:"uid"`
+	Text      string    `json:"text"`
+	Author    string    `json:"author"`
+	Category  string    `json:"category"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GetPath returns the path of the store, a directory next to the database
+func GetPath() (string, error) {
+	dbPath, err := todo.GetDatabasePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(dbPath), "repo"), nil
+}
+
+// Enabled reports whether dir holds a store, i.e. whether 'tada git init'
+// has been run
+func Enabled(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// Write makes the files in dir match the given todos and quotes, writing
+// only the files that changed and removing those of deleted records
+func Write(dir string, todos []*todo.Todo, quotes []*quote.Quote) error {
+	files := make(map[string][]byte)
+	for _, t := range todos {
+		data, err := encode(newTodoRecord(t))
+		if err != nil {
+			return err
+		}
+		files[filepath.Join(todosDir, t.UID+".json")] = data
+	}
+	for _, q := range quotes {
+		data, err := encode(newQuoteRecord(q))
+		if err != nil {
+			return err
+		}
+		files[filepath.Join(quotesDir, q.UID+".json")] = data
+	}
+
+	for _, sub := range []string{todosDir, quotesDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return err
+		}
+		names, err := recordFiles(dir, sub)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if _, ok := files[name]; !ok {
+				if err := os.Remove(filepath.Join(dir, name)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, data) {
+			continue
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read reads the todos and quotes stored in dir, sorted by creation time.
+// They have no IDs.
+func Read(dir string) ([]*todo.Todo, []*quote.Quote, error) {
+	var todos []*todo.Todo
+	names, err := recordFiles(dir, todosDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, name := range names {
+		var r todoRecord
+		if err := decode(filepath.Join(dir, name), &r); err != nil {
+			return nil, nil, err
+		}
+		todos = append(todos, r.todo())
+	}
+
+	var quotes []*quote.Quote
+	names, err = recordFiles(dir, quotesDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, name := range names {
+		var r quoteRecord
+		if err := decode(filepath.Join(dir, name), &r); err != nil {
+			return nil, nil, err
+		}
+		quotes = append(quotes, r.quote())
+	}
+
+	sort.SliceStable(todos, func(i, j int) bool { return todos[i].CreatedAt.Before(todos[j].CreatedAt) })
+	sort.SliceStable(quotes, func(i, j int) bool { return quotes[i].CreatedAt.Before(quotes[j].CreatedAt) })
+	return todos, quotes, nil
+}
+
+// recordFiles lists the record files in a subdirectory of dir, relative to
+// dir and sorted by name
+func recordFiles(dir, sub string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, sub))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, filepath.Join(sub, e.Name()))
+		}
+	}
+	return names, nil
+}
+
+func encode(v interface{}) ([]byte, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func decode(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// fileTime normalizes a timestamp to what the database stores, so a record
+// read back from the database is written out unchanged
+func fileTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Second)
+}
+
+func optionalFileTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	ft := fileTime(*t)
+	return &ft
+}
+
+func newTodoRecord(t *todo.Todo) todoRecord {
+	return todoRecord{
+		UID:         t.UID,
+		Description: t.Description,
+		Priority:    int(t.Priority),
+		Status:      int(t.Status),
+		Tag:         t.Tag,
+		CreatedAt:   fileTime(t.CreatedAt),
+		UpdatedAt:   fileTime(t.UpdatedAt),
+		CompletedAt: optionalFileTime(t.CompletedAt),
+		DueAt:       optionalFileTime(t.DueAt),
+		Notes:       t.Notes,
+	}
+}
+
+func (r todoRecord) todo() *todo.Todo {
+	return &todo.Todo{
+		UID:         r.UID,
+		Description: r.Description,
+		Priority:    todo.Priority(r.Priority),
+		Status:      todo.Status(r.Status),
+		Tag:         r.Tag,
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+		CompletedAt: r.CompletedAt,
+		DueAt:       r.DueAt,
+		Notes:       r.Notes,
+	}
+}
+
+func newQuoteRecord(q *quote.Quote) quoteRecord {
+	return quoteRecord{
+		UID:       q.UID,
+		Text:      q.Text,
+		Author:    q.Author,
+		Category:  q.Category,
+		CreatedAt: fileTime(q.CreatedAt),
+		UpdatedAt: fileTime(q.UpdatedAt),
+	}
+}
+
+func (r quoteRecord) quote() *quote.Quote {
+	return &quote.Quote{
+		UID:       r.UID,
+		Text:      r.Text,
+		Author:    r.Author,
+		Category:  r.Category,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+	}
+}
@@ -0,0 +1,252 @@
+package gitstore
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/todo"
+)
+
+func TestWriteRead(t *testing.T) {
+	dir := t.TempDir()
+	created := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	due := time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC)
+	todos := []*todo.Todo{
+		{ID: 7, UID: "u1", Description: "Fix login bug", Priority: todo.High, Status: todo.Open, Tag: "backend",
+			CreatedAt: created, UpdatedAt: created, DueAt: &due, Notes: "See the logs"},
+		{ID: 8, UID: "u2", Description: "Call mom", Priority: todo.Medium, Status: todo.Open,
+			CreatedAt: created.Add(time.Hour), UpdatedAt: created.Add(time.Hour)},
+	}
+	quotes := []*quote.Quote{
+		{ID: 1, UID: "q1", Text: "Stay hungry", Author: "Steve Jobs", CreatedAt: created, UpdatedAt: created},
+	}
+
+	if err := Write(dir, todos, quotes); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	gotTodos, gotQuotes, err := Read(dir)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(gotTodos) != 2 || len(gotQuotes) != 1 {
+		t.Fatalf("Read() = %d todos, %d quotes, want 2 and 1", len(gotTodos), len(gotQuotes))
+	}
+	want := *todos[0]
+	want.ID = 0
+	if !reflect.DeepEqual(*gotTodos[0], want) {
+		t.Errorf("todo = %+v, want %+v", *gotTodos[0], want)
+	}
+	if gotQuotes[0].Text != "Stay hungry" || gotQuotes[0].UID != "q1" {
+		t.Errorf("quote = %+v", gotQuotes[0])
+	}
+
+	// Writing again without a todo removes its file
+	if err := Write(dir, todos[:1], quotes); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "todos", "u2.json")); !os.IsNotExist(err) {
+		t.Errorf("todos/u2.json should be removed, stat error = %v", err)
+	}
+}
+
+func TestPull(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(name, "tada")
+	}
+	for _, name := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(name, "tada@example.com")
+	}
+
+	root := t.TempDir()
+	remote := filepath.Join(root, "remote.git")
+	if out, err := exec.Command("git", "init", "--quiet", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare: %v: %s", err, out)
+	}
+
+	created := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	newTodos := func() []*todo.Todo {
+		return []*todo.Todo{
+			{UID: "u1", Description: "Fix login bug", Priority: todo.High, Status: todo.Open, CreatedAt: created, UpdatedAt: created},
+			{UID: "u2", Description: "Call mom", Priority: todo.Medium, Status: todo.Open, CreatedAt: created, UpdatedAt: created},
+			{UID: "u3", Description: "Write docs", Priority: todo.Low, Status: todo.Open, CreatedAt: created, UpdatedAt: created},
+		}
+	}
+
+	laptop := filepath.Join(root, "laptop")
+	if err := Init(laptop, remote); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if err := Write(laptop, newTodos(), nil); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := Commit(laptop, "first"); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if err := Push(laptop); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	workstation := filepath.Join(root, "workstation")
+	if err := Init(workstation, remote); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if todos, _, _ := Read(workstation); len(todos) != 3 {
+		t.Fatalf("cloned %d todos, want 3", len(todos))
+	}
+
+	// The workstation edits u1 first and deletes u2, then pushes
+	later := created.Add(time.Hour)
+	todos := newTodos()
+	todos[0].Description, todos[0].UpdatedAt = "Fix the login bug", later
+	if err := Write(workstation, []*todo.Todo{todos[0], todos[2]}, nil); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := Commit(workstation, "edit"); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if err := Push(workstation); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	// The laptop edits u1 later, edits u2 and edits u3's priority
+	todos = newTodos()
+	todos[0].Description, todos[0].UpdatedAt = "Fix login bug today", later.Add(time.Hour)
+	todos[1].Priority, todos[1].UpdatedAt = todo.High, later
+	todos[2].Priority, todos[2].UpdatedAt = todo.High, later
+	if err := Write(laptop, todos, nil); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := Commit(laptop, "edit"); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if err := Push(laptop); err == nil {
+		t.Error("Push() should be rejected before pulling")
+	}
+
+	merged, conflicts, err := Pull(laptop)
+	if err != nil {
+		t.Fatalf("Pull() error = %v", err)
+	}
+	if !merged || len(conflicts) != 2 {
+		t.Fatalf("Pull() = %v, %+v, want a merge with 2 conflicts", merged, conflicts)
+	}
+	for _, c := range conflicts {
+		if !c.KeptLocal || c.Kind != "todo" {
+			t.Errorf("Conflict = %+v, want this side kept", c)
+		}
+	}
+
+	got, _, err := Read(laptop)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	descriptions := map[string]string{}
+	for _, td := range got {
+		descriptions[td.UID] = td.Description
+	}
+	want := map[string]string{"u1": "Fix login bug today", "u2": "Call mom", "u3": "Write docs"}
+	if !reflect.DeepEqual(descriptions, want) {
+		t.Errorf("todos after pull = %v, want %v", descriptions, want)
+	}
+
+	if err := Push(laptop); err != nil {
+		t.Errorf("Push() after pulling error = %v", err)
+	}
+	if merged, _, err := Pull(workstation); err != nil || !merged {
+		t.Errorf("Pull() on the workstation = %v, %v, want a fast-forward", merged, err)
+	}
+}
+
+func TestParseTodoRefs(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    []int
+	}{
+		{"none", "Fix the login redirect", nil},
+		{"trailer", "Fix the login redirect\n\nCloses-Todo: 42", []int{42}},
+		{"trailer with several IDs", "Fix it\n\ncloses-todo: 42, #43 44", []int{42, 43, 44}},
+		{"inline", "Fix the login redirect (tada#42)", []int{42}},
+		{"both, once each", "Fix tada#7\n\nCloses-Todo: 42\nCloses-Todo: 7", []int{42, 7}},
+		{"not a trailer", "Mention Closes-Todo: 42 inline", nil},
+		{"not tada", "Fixes gh#42 and xtada#43", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseTodoRefs(tt.message); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTodoRefs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstallHook(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(name, "tada")
+	}
+	for _, name := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(name, "tada@example.com")
+	}
+
+	repo := t.TempDir()
+	if _, err := InstallHook(repo); err == nil {
+		t.Error("InstallHook() outside a repository should fail")
+	}
+	if _, err := git(repo, "init", "--quiet"); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := InstallHook(repo)
+	if err != nil {
+		t.Fatalf("InstallHook() error = %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode()&0111 == 0 {
+		t.Fatalf("InstallHook() should write an executable hook at %s", path)
+	}
+	if _, err := InstallHook(repo); err != nil {
+		t.Errorf("InstallHook() again error = %v, want it replaced", err)
+	}
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho other tool\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := InstallHook(repo); err == nil {
+		t.Error("InstallHook() over another tool's hook should fail")
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(repo, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git(repo, "add", "."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git(repo, "commit", "--quiet", "-m", "Fix the login redirect\n\nCloses-Todo: 42"); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := ResolveCommit(repo, "HEAD")
+	if err != nil {
+		t.Fatalf("ResolveCommit() error = %v", err)
+	}
+	if len(c.SHA) != 40 || c.Subject != "Fix the login redirect" || !strings.Contains(c.Message, "Closes-Todo: 42") {
+		t.Errorf("ResolveCommit() = %+v", c)
+	}
+	if _, err := ResolveCommit(repo, "missing"); err == nil {
+		t.Error("ResolveCommit() of a missing commit should fail")
+	}
+}
@@ -0,0 +1,102 @@
+package gitstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// hookMarker identifies a hook written by InstallHook
+const hookMarker = "# Installed by tada git install-hooks"
+
+// hookScript is the post-commit hook, which closes the todos the commit
+// references. A missing tada must not get in the way of committing.
+const hookScript = `#!/bin/sh
+` + hookMarker + `
+command -v tada >/dev/null 2>&1 || exit 0
+tada git hook post-commit || true
+`
+
+// RepoCommit is a commit in one of the user's repositories
+type RepoCommit struct {
+	SHA string
+	// Repo is the top-level directory of the repository
+	Repo    string
+	Subject string
+	Message string
+}
+
+// todoRefPatterns find todo IDs in commit messages: Closes-Todo trailers,
+// which may list several IDs, and tada#42 anywhere
+var todoRefPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?im)^closes-todo:[ \t]*([#\d, \t]+)$`),
+	regexp.MustCompile(`(?i)\btada#(\d+)\b`),
+}
+
+// ParseTodoRefs returns the IDs of the todos a commit message closes, in
+// the order they first appear
+func ParseTodoRefs(message string) []int {
+	var ids []int
+	for _, p := range todoRefPatterns {
+		for _, m := range p.FindAllStringSubmatch(message, -1) {
+			for _, field := range strings.FieldsFunc(m[1], func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t'
+			}) {
+				id, err := strconv.Atoi(strings.TrimPrefix(field, "#"))
+				if err == nil && id > 0 && !slices.Contains(ids, id) {
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+	return ids
+}
+
+// InstallHook installs the post-commit hook in the repository holding dir
+// and returns its path. A post-commit hook of another tool is left alone.
+func InstallHook(dir string) (string, error) {
+	hooks, err := git(dir, "rev-parse", "--path-format=absolute", "--git-path", "hooks")
+	if err != nil {
+		return "", fmt.Errorf("%s is not in a git repository", dir)
+	}
+	path := filepath.Join(hooks, "post-commit")
+
+	data, err := os.ReadFile(path)
+	if err == nil && !strings.Contains(string(data), hookMarker) {
+		return "", fmt.Errorf("%s already exists; add 'tada git hook post-commit' to it", path)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	if err := os.MkdirAll(hooks, 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(hookScript), 0755); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// ResolveCommit returns the commit rev names in the repository holding dir
+func ResolveCommit(dir, rev string) (*RepoCommit, error) {
+	repo, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%s is not in a git repository", dir)
+	}
+	sha, err := git(dir, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("no commit %s in %s", rev, repo)
+	}
+	message, err := git(dir, "log", "-1", "--format=%B", sha)
+	if err != nil {
+		return nil, err
+	}
+	subject, _, _ := strings.Cut(message, "\n")
+	return &RepoCommit{SHA: sha, Repo: repo, Subject: subject, Message: message}, nil
+}
@@ -0,0 +1,378 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/negadras/tada/internal/todo"
+)
+
+const (
+	utcLayout   = "20060102T150405Z"
+	localLayout = "20060102T150405"
+	dateLayout  = "20060102"
+)
+
+// Write writes todos as an iCalendar (RFC 5545) calendar of VTODO components
+func Write(w io.Writer, todos []*todo.Todo) error {
+	bw := bufio.NewWriter(w)
+	stamp := time.Now().UTC().Format(utcLayout)
+
+	writeLine(bw, "BEGIN:VCALENDAR")
+	writeLine(bw, "VERSION:2.0")
+	writeLine(bw, "PRODID:-//tada//tada//EN")
+
+	for _, t := range todos {
+		writeLine(bw, "BEGIN:VTODO")
+		writeLine(bw, "UID:"+escapeText(t.UID))
+		writeLine(bw, "DTSTAMP:"+stamp)
+		writeLine(bw, "SUMMARY:"+escapeText(t.Description))
+		if t.Notes != "" {
+			writeLine(bw, "DESCRIPTION:"+escapeText(t.Notes))
+		}
+		writeLine(bw, "PRIORITY:"+strconv.Itoa(formatPriority(t.Priority)))
+		if t.Tag != "" {
+			writeLine(bw, "CATEGORIES:"+escapeText(t.Tag))
+		}
+		writeLine(bw, "CREATED:"+t.CreatedAt.UTC().Format(utcLayout))
+		writeLine(bw, "LAST-MODIFIED:"+t.UpdatedAt.UTC().Format(utcLayout))
+		if t.DueAt != nil {
+			writeLine(bw, formatDue(*t.DueAt))
+		}
+		if t.Status == todo.Done {
+			writeLine(bw, "STATUS:COMPLETED")
+			if t.CompletedAt != nil {
+				writeLine(bw, "COMPLETED:"+t.CompletedAt.UTC().Format(utcLayout))
+			}
+		} else {
+			writeLine(bw, "STATUS:NEEDS-ACTION")
+		}
+		writeLine(bw, "END:VTODO")
+	}
+
+	writeLine(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+// Result is the outcome of parsing a calendar
+type Result struct {
+	Todos []*todo.Todo
+	// Cancelled counts the cancelled VTODOs that were not imported
+	Cancelled int
+}
+
+// Parse reads the VTODO components of an iCalendar file. Other components,
+// such as events, are ignored.
+//
+// PRIORITY 1-4 is high, 5 (or none) is medium and 6-9 is low; COMPLETED
+// todos are done and CANCELLED ones are skipped. The first of the
+// CATEGORIES becomes the tag and DESCRIPTION becomes the notes.
+func Parse(r io.Reader) (*Result, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	var current *todo.Todo
+	var cancelled bool
+	inTodo := false
+	depth := 0
+
+	for i, line := range lines {
+		name, params, value, ok := splitLine(line)
+		if !ok {
+			return nil, fmt.Errorf("line %d: invalid content line %q", i+1, line)
+		}
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VTODO") && !inTodo:
+			inTodo = true
+			depth = 0
+			cancelled = false
+			current = &todo.Todo{Priority: todo.Medium, Status: todo.Open}
+			continue
+		case !inTodo:
+			continue
+		case name == "BEGIN":
+			// Nested components such as VALARM
+			depth++
+			continue
+		case name == "END" && depth > 0:
+			depth--
+			continue
+		case name == "END" && strings.EqualFold(value, "VTODO"):
+			inTodo = false
+			if cancelled {
+				result.Cancelled++
+				continue
+			}
+			if err := finish(current); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			result.Todos = append(result.Todos, current)
+			continue
+		case depth > 0:
+			continue
+		}
+
+		if err := apply(current, name, params, value, &cancelled); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+
+	if inTodo {
+		return nil, fmt.Errorf("unterminated VTODO")
+	}
+
+	return result, nil
+}
+
+// apply sets the todo field for a VTODO property
+func apply(t *todo.Todo, name string, params map[string]string, value string, cancelled *bool) error {
+	switch name {
+	case "UID":
+		t.UID = unescapeText(value)
+	case "SUMMARY":
+		t.Description = strings.Join(strings.Fields(unescapeText(value)), " ")
+	case "DESCRIPTION":
+		t.Notes = unescapeText(value)
+	case "PRIORITY":
+		p, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || p < 0 || p > 9 {
+			return fmt.Errorf("invalid PRIORITY %q", value)
+		}
+		t.Priority = parsePriority(p)
+	case "STATUS":
+		switch strings.ToUpper(value) {
+		case "COMPLETED":
+			t.Status = todo.Done
+		case "CANCELLED":
+			*cancelled = true
+		default:
+			t.Status = todo.Open
+		}
+	case "CATEGORIES":
+		if t.Tag == "" {
+			categories := splitText(value)
+			if len(categories) > 0 {
+				t.Tag = strings.TrimSpace(categories[0])
+			}
+		}
+	case "CREATED", "LAST-MODIFIED", "COMPLETED", "DUE":
+		at, err := parseTime(value, params)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", name, value)
+		}
+		switch name {
+		case "CREATED":
+			t.CreatedAt = at
+		case "LAST-MODIFIED":
+			t.UpdatedAt = at
+		case "COMPLETED":
+			t.CompletedAt = &at
+		case "DUE":
+			t.DueAt = &at
+		}
+	}
+	return nil
+}
+
+// finish validates a parsed todo and fills in the dates a calendar app may
+// have left out
+func finish(t *todo.Todo) error {
+	if err := todo.ValidateDescription(t.Description); err != nil {
+		return fmt.Errorf("VTODO %q: %w", t.UID, err)
+	}
+
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	if t.UpdatedAt.IsZero() {
+		t.UpdatedAt = t.CreatedAt
+	}
+	if t.Status == todo.Done && t.CompletedAt == nil {
+		completed := t.UpdatedAt
+		t.CompletedAt = &completed
+	}
+	if t.Status == todo.Open {
+		t.CompletedAt = nil
+	}
+	return nil
+}
+
+// formatPriority maps a priority onto the iCalendar 1 (highest) to 9 scale
+func formatPriority(p todo.Priority) int {
+	switch p {
+	case todo.High:
+		return 1
+	case todo.Low:
+		return 9
+	default:
+		return 5
+	}
+}
+
+func parsePriority(p int) todo.Priority {
+	switch {
+	case p >= 1 && p <= 4:
+		return todo.High
+	case p >= 6:
+		return todo.Low
+	default:
+		return todo.Medium
+	}
+}
+
+// formatDue writes due dates set as a day (local midnight) as DATE values
+// and other due times in UTC
+func formatDue(due time.Time) string {
+	local := due.Local()
+	if local.Hour() == 0 && local.Minute() == 0 && local.Second() == 0 {
+		return "DUE;VALUE=DATE:" + local.Format(dateLayout)
+	}
+	return "DUE:" + due.UTC().Format(utcLayout)
+}
+
+// parseTime parses DATE and DATE-TIME values: UTC, with a TZID parameter,
+// floating (local) time or a date at local midnight
+func parseTime(value string, params map[string]string) (time.Time, error) {
+	if strings.EqualFold(params["VALUE"], "DATE") || len(value) == len(dateLayout) {
+		return time.ParseInLocation(dateLayout, value, time.Local)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse(utcLayout, value)
+	}
+
+	loc := time.Local
+	if tzid := params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	return time.ParseInLocation(localLayout, value, loc)
+}
+
+// unfold reads content lines, joining folded continuation lines
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+	if len(lines) == 0 || !strings.EqualFold(lines[0], "BEGIN:VCALENDAR") {
+		return nil, fmt.Errorf("not an iCalendar file: missing BEGIN:VCALENDAR")
+	}
+	return lines, nil
+}
+
+// splitLine splits a content line into its upper-cased name, parameters and
+// value, e.g. DUE;VALUE=DATE:20240601
+func splitLine(line string) (string, map[string]string, string, bool) {
+	// The value starts at the first colon outside a quoted parameter value
+	inQuotes := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			inQuotes = !inQuotes
+		} else if c == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	params := map[string]string{}
+	for _, p := range parts[1:] {
+		key, value, _ := strings.Cut(p, "=")
+		params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+
+	return strings.ToUpper(parts[0]), params, line[colon+1:], true
+}
+
+// writeLine writes a content line, folding it at 75 octets as required by
+// RFC 5545 without splitting a multi-byte character
+func writeLine(w *bufio.Writer, line string) {
+	first := true
+	for len(line) > 0 {
+		limit := 75
+		if !first {
+			limit = 74
+			w.WriteString(" ")
+		}
+		if len(line) <= limit {
+			w.WriteString(line)
+			break
+		}
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n")
+		line = line[cut:]
+		first = false
+	}
+	w.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+var (
+	textEscaper   = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+	textUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+)
+
+// escapeText escapes a TEXT value
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// unescapeText reverses escapeText
+func unescapeText(s string) string {
+	return textUnescaper.Replace(s)
+}
+
+// splitText splits a multi-valued TEXT property on unescaped commas
+func splitText(s string) []string {
+	var values []string
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			b.WriteByte(s[i])
+			b.WriteByte(s[i+1])
+			i++
+		case s[i] == ',':
+			values = append(values, unescapeText(b.String()))
+			b.Reset()
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return append(values, unescapeText(b.String()))
+}
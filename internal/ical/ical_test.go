@@ -0,0 +1,190 @@
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/negadras/tada/internal/todo"
+)
+
+func TestWriteParse_RoundTrip(t *testing.T) {
+	created := time.Date(2024, 4, 20, 9, 0, 0, 0, time.UTC)
+	completed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	due := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+
+	todos := []*todo.Todo{
+		{
+			UID:         "1b4e28ba-2fa1-4d2c-8a3b-2c2f5e0a9c11",
+			Description: "Fix login bug; urgent, really",
+			Priority:    todo.High,
+			Status:      todo.Open,
+			Tag:         "backend",
+			Notes:       "Repro steps:\n1. log in\n2. " + strings.Repeat("wait ", 30),
+			CreatedAt:   created,
+			UpdatedAt:   created,
+			DueAt:       &due,
+		},
+		{
+			UID:         "9f0c2a52-7c0e-4e44-9b83-5b8f1c1f0d22",
+			Description: "Ship release",
+			Priority:    todo.Low,
+			Status:      todo.Done,
+			CreatedAt:   created,
+			UpdatedAt:   completed,
+			CompletedAt: &completed,
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, todos); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Write() line longer than 75 octets: %q", line)
+		}
+	}
+	for _, want := range []string{"BEGIN:VTODO", "PRIORITY:1", "STATUS:NEEDS-ACTION", "STATUS:COMPLETED", "CATEGORIES:backend", "DUE;VALUE=DATE:20240601", "COMPLETED:20240501T120000Z"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Write() output should contain %q", want)
+		}
+	}
+
+	result, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(result.Todos) != len(todos) {
+		t.Fatalf("Parse() returned %d todos, want %d", len(result.Todos), len(todos))
+	}
+
+	for i, got := range result.Todos {
+		want := todos[i]
+		if got.UID != want.UID || got.Description != want.Description || got.Priority != want.Priority ||
+			got.Status != want.Status || got.Tag != want.Tag || got.Notes != want.Notes {
+			t.Errorf("todo %d = %+v, want %+v", i, got, want)
+		}
+		if !got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) {
+			t.Errorf("todo %d dates = %v/%v, want %v/%v", i, got.CreatedAt, got.UpdatedAt, want.CreatedAt, want.UpdatedAt)
+		}
+	}
+	if got := result.Todos[0].DueAt; got == nil || !got.Equal(due) {
+		t.Errorf("DueAt = %v, want %v", got, due)
+	}
+	if got := result.Todos[1].CompletedAt; got == nil || !got.Equal(completed) {
+		t.Errorf("CompletedAt = %v, want %v", got, completed)
+	}
+}
+
+func TestParse_CalendarApp(t *testing.T) {
+	input := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Example//Calendar//EN",
+		"BEGIN:VEVENT",
+		"UID:event-1",
+		"SUMMARY:Standup",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:task-1",
+		"SUMMARY:Book flights to Berl",
+		" in",
+		"PRIORITY:7",
+		"CATEGORIES:Travel\\, personal,Family",
+		"DUE;TZID=Europe/Berlin:20240601T170000",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"DESCRIPTION:Reminder",
+		"END:VALARM",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"UID:task-2",
+		"SUMMARY:Dropped idea",
+		"STATUS:CANCELLED",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"UID:task-3",
+		"SUMMARY:Done without a date",
+		"STATUS:COMPLETED",
+		"CREATED:20240420T090000Z",
+		"END:VTODO",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	result, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(result.Todos) != 2 || result.Cancelled != 1 {
+		t.Fatalf("Parse() = %d todos, %d cancelled; want 2 and 1", len(result.Todos), result.Cancelled)
+	}
+
+	flights := result.Todos[0]
+	if flights.Description != "Book flights to Berlin" {
+		t.Errorf("Description = %q, want the unfolded summary", flights.Description)
+	}
+	if flights.Priority != todo.Low {
+		t.Errorf("Priority = %v, want LOW", flights.Priority)
+	}
+	if flights.Tag != "Travel, personal" {
+		t.Errorf("Tag = %q, want the first category", flights.Tag)
+	}
+	if flights.Notes != "" {
+		t.Errorf("Notes = %q, the alarm description should be ignored", flights.Notes)
+	}
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	if want := time.Date(2024, 6, 1, 17, 0, 0, 0, berlin); flights.DueAt == nil || !flights.DueAt.Equal(want) {
+		t.Errorf("DueAt = %v, want %v", flights.DueAt, want)
+	}
+
+	done := result.Todos[1]
+	if done.Status != todo.Done || done.CompletedAt == nil {
+		t.Errorf("completed todo = %+v, want done with a completion date", done)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		errContains string
+	}{
+		{"not a calendar", "(A) todo.txt line", "not an iCalendar file"},
+		{"unterminated", "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nSUMMARY:x\r\n", "unterminated VTODO"},
+		{"missing summary", "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nUID:x\r\nEND:VTODO\r\nEND:VCALENDAR\r\n", "description cannot be empty"},
+		{"bad priority", "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nPRIORITY:high\r\nEND:VTODO\r\nEND:VCALENDAR\r\n", "invalid PRIORITY"},
+		{"bad date", "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nDUE:tomorrow\r\nEND:VTODO\r\nEND:VCALENDAR\r\n", "invalid DUE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.input))
+			if err == nil {
+				t.Fatal("Parse() expected error")
+			}
+			if !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("Parse() error = %v, want error containing %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestWriteLine_FoldsMultiByteCharacters(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	writeLine(w, "SUMMARY:"+strings.Repeat("é", 60))
+	w.Flush()
+
+	lines, err := unfold(strings.NewReader("BEGIN:VCALENDAR\r\n" + buf.String()))
+	if err != nil {
+		t.Fatalf("unfold() error = %v", err)
+	}
+	if lines[1] != "SUMMARY:"+strings.Repeat("é", 60) {
+		t.Errorf("folded line did not unfold to the original: %q", lines[1])
+	}
+}
@@ -0,0 +1,186 @@
+package markdown
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/negadras/tada/internal/todo"
+)
+
+const dateLayout = "2006-01-02"
+
+// itemPattern matches a task list item such as "- [ ] text" or "1. [x] text",
+// at any indentation, capturing the bullet, the checkbox and the text
+var itemPattern = regexp.MustCompile(`^(\s*(?:[-*+]|\d+[.)])\s+)\[([ xX])\]\s+(.*)$`)
+
+// markerPattern matches the marker that links an item to a todo
+var markerPattern = regexp.MustCompile(`\s*<!--\s*tada:(\S+)\s*-->\s*$`)
+
+// tagPattern matches the name of a #tag marker. Tags start with a letter so
+// issue references such as #42 stay in the description.
+var tagPattern = regexp.MustCompile(`^[\pL][\pL\pN_\-/.]*$`)
+
+// Parse reads the task list items of a markdown document into todos: "- [ ]"
+// items are open and "- [x]" items are done. Other lines, including headings
+// and plain list items, are ignored.
+//
+// The first #tag in an item becomes the tag and a !high, !medium or !low
+// marker sets the priority (medium if there is none); both are removed from
+// the description. A due:YYYY-MM-DD marker sets the due date, and a
+// <!-- tada:UID --> marker written by sync sets the UID.
+func Parse(r io.Reader) ([]*todo.Todo, error) {
+	return parse(r, time.Now())
+}
+
+func parse(r io.Reader, now time.Time) ([]*todo.Todo, error) {
+	var todos []*todo.Todo
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		m := itemPattern.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+
+		t, err := parseItem(m[3], m[2] != " ", now)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		todos = append(todos, t)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read markdown: %w", err)
+	}
+
+	return todos, nil
+}
+
+// parseItem parses the text of a task list item after its checkbox
+func parseItem(text string, checked bool, now time.Time) (*todo.Todo, error) {
+	t := &todo.Todo{Priority: todo.Medium, Status: todo.Open, CreatedAt: now, UpdatedAt: now}
+	if checked {
+		t.Status = todo.Done
+		completed := now
+		t.CompletedAt = &completed
+	}
+
+	if m := markerPattern.FindStringSubmatch(text); m != nil {
+		t.UID = m[1]
+		text = text[:len(text)-len(m[0])]
+	}
+
+	var words []string
+	for _, word := range strings.Fields(text) {
+		if name, ok := strings.CutPrefix(word, "#"); ok && t.Tag == "" && tagPattern.MatchString(name) {
+			t.Tag = name
+			continue
+		}
+		if name, ok := strings.CutPrefix(word, "!"); ok && isLetters(name) {
+			if p, err := todo.ParsePriority(name); err == nil {
+				t.Priority = p
+				continue
+			}
+		}
+		if value, ok := strings.CutPrefix(word, "due:"); ok {
+			if d, err := time.ParseInLocation(dateLayout, value, time.Local); err == nil {
+				t.DueAt = &d
+				continue
+			}
+		}
+		words = append(words, word)
+	}
+
+	t.Description = strings.Join(words, " ")
+	if err := todo.ValidateDescription(t.Description); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Format returns the task list item for a todo, e.g.
+// "- [ ] Fix login bug #backend !high due:2024-06-01"
+func Format(t *todo.Todo) string {
+	return "- " + formatItem(t)
+}
+
+// formatItem returns a task list item without its bullet
+func formatItem(t *todo.Todo) string {
+	box := "[ ]"
+	if t.Status == todo.Done {
+		box = "[x]"
+	}
+
+	parts := []string{box, t.Description}
+	if t.Tag != "" {
+		parts = append(parts, "#"+formatTag(t.Tag))
+	}
+	switch t.Priority {
+	case todo.High:
+		parts = append(parts, "!high")
+	case todo.Low:
+		parts = append(parts, "!low")
+	}
+	if t.DueAt != nil {
+		parts = append(parts, "due:"+formatDue(t.DueAt))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Write writes todos as a GitHub-flavoured task list with a heading per tag.
+// Each item also carries its #tag so it keeps it when pasted on its own.
+func Write(w io.Writer, todos []*todo.Todo) error {
+	groups, err := todo.GroupTodos(todos, "tag")
+	if err != nil {
+		return err
+	}
+
+	for i, g := range groups {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "## %s\n\n", g.Key); err != nil {
+			return err
+		}
+		for _, t := range g.Todos {
+			if _, err := fmt.Fprintln(w, Format(t)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// formatTag writes a tag as a single word
+func formatTag(tag string) string {
+	return strings.Join(strings.Fields(tag), "-")
+}
+
+func formatDue(due *time.Time) string {
+	if due == nil {
+		return ""
+	}
+	return due.Local().Format(dateLayout)
+}
+
+// isLetters reports whether s is a non-empty run of ASCII letters
+func isLetters(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
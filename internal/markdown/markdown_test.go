@@ -0,0 +1,132 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/negadras/tada/internal/todo"
+)
+
+const notes = `# Sprint planning
+
+Attendees: Ana, Ben
+
+## Action items
+
+- [ ] Fix login bug #backend !high
+- [x] Ship release #launch
+* [ ] Follow up on #42 with design !low due:2024-06-01
+  - [X] Nested item
+1. [ ] Numbered item !urgent
+- a plain bullet, not a task
+- [] not a task either
+`
+
+func TestParse(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.Local)
+
+	todos, err := parse(strings.NewReader(notes), now)
+	if err != nil {
+		t.Fatalf("parse() error = %v", err)
+	}
+
+	tests := []struct {
+		description string
+		priority    todo.Priority
+		status      todo.Status
+		tag         string
+	}{
+		{"Fix login bug", todo.High, todo.Open, "backend"},
+		{"Ship release", todo.Medium, todo.Done, "launch"},
+		{"Follow up on #42 with design", todo.Low, todo.Open, ""},
+		{"Nested item", todo.Medium, todo.Done, ""},
+		{"Numbered item !urgent", todo.Medium, todo.Open, ""},
+	}
+
+	if len(todos) != len(tests) {
+		t.Fatalf("parse() returned %d todos, want %d", len(todos), len(tests))
+	}
+
+	for i, tt := range tests {
+		got := todos[i]
+		if got.Description != tt.description || got.Priority != tt.priority || got.Status != tt.status || got.Tag != tt.tag {
+			t.Errorf("todo %d = %q %v %v %q, want %q %v %v %q", i,
+				got.Description, got.Priority, got.Status, got.Tag, tt.description, tt.priority, tt.status, tt.tag)
+		}
+		if (got.Status == todo.Done) != (got.CompletedAt != nil) {
+			t.Errorf("todo %d CompletedAt = %v with status %v", i, got.CompletedAt, got.Status)
+		}
+	}
+
+	want := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	if due := todos[2].DueAt; due == nil || !due.Equal(want) {
+		t.Errorf("DueAt = %v, want %v", due, want)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	_, err := Parse(strings.NewReader("Notes\n\n- [ ] #backend !high\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Parse() error = %v, want an error for line 3", err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	due := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	todos := []*todo.Todo{
+		{Description: "Fix login bug", Priority: todo.High, Status: todo.Open, Tag: "backend", DueAt: &due},
+		{Description: "Call mom", Priority: todo.Medium, Status: todo.Open},
+		{Description: "Ship API", Priority: todo.Low, Status: todo.Done, Tag: "backend"},
+		{Description: "Plan offsite", Priority: todo.Medium, Status: todo.Open, Tag: "team events"},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, todos); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	want := `## backend
+
+- [ ] Fix login bug #backend !high due:2024-06-01
+- [x] Ship API #backend !low
+
+## (no tag)
+
+- [ ] Call mom
+
+## team events
+
+- [ ] Plan offsite #team-events
+`
+	if buf.String() != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	todos := []*todo.Todo{
+		{Description: "Fix login bug", Priority: todo.High, Status: todo.Open, Tag: "backend"},
+		{Description: "Ship API", Priority: todo.Low, Status: todo.Done},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, todos); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	got, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(got) != len(todos) {
+		t.Fatalf("Parse() returned %d todos, want %d", len(got), len(todos))
+	}
+	for i, want := range todos {
+		if got[i].Description != want.Description || got[i].Priority != want.Priority ||
+			got[i].Status != want.Status || got[i].Tag != want.Tag {
+			t.Errorf("todo %d = %+v, want %+v", i, got[i], want)
+		}
+	}
+}
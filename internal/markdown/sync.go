@@ -0,0 +1,237 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/negadras/tada/internal/todo"
+)
+
+// Conflict is an item that changed both in the file and in tada since the
+// last sync. Sync keeps tada's version.
+type Conflict struct {
+	// File and Tada are the item in the file and in tada; an empty value
+	// means it was deleted there
+	File string
+	Tada string
+}
+
+// SyncPlan describes the changes that bring a markdown file and the todos
+// back in sync
+type SyncPlan struct {
+	// Lines is the new content of the file
+	Lines []string
+	// FileChanged reports whether Lines differs from the file
+	FileChanged bool
+
+	// Create and Update are the changes to make in tada. Updated todos
+	// carry their tada ID with the fields edited in the file applied.
+	Create []*todo.Todo
+	Update []*todo.Todo
+
+	// Written counts the items rewritten or added from tada and Removed the
+	// items taken out because their todo was deleted in tada. Unlinked
+	// counts the todos whose item was deleted from the file and that no
+	// longer match the filter, so are no longer kept in the file.
+	Written  int
+	Removed  int
+	Unlinked int
+
+	Conflicts []Conflict
+
+	// State is each linked todo's item as of this sync, by UID, to be passed
+	// to the next PlanSync
+	State map[string]string
+}
+
+// PlanSync works out how to sync the lines of a markdown file with todos in
+// both directions. state holds the items as of the previous sync, which
+// tells which side changed an item since then.
+//
+// Items are linked to todos by a <!-- tada:UID --> marker at the end of the
+// line. Items without one are matched to a todo with the same description,
+// or else become new todos. Todos matching include that are not in the file
+// yet are appended to it. A todo deleted in tada is removed from the file,
+// unless the file changed it, which is a conflict. tada is the source of
+// truth, so deleting an item from the file never deletes its todo: a todo
+// matching include is written back, and the others stop being synced.
+func PlanSync(lines []string, todos []*todo.Todo, state map[string]string, include *todo.Filter, now time.Time) (*SyncPlan, error) {
+	plan := &SyncPlan{State: map[string]string{}}
+
+	byUID := make(map[string]*todo.Todo, len(todos))
+	byDescription := map[string]*todo.Todo{}
+	for _, t := range todos {
+		byUID[t.UID] = t
+		if _, dup := byDescription[t.Description]; dup {
+			// Ambiguous, so items with this description are not matched
+			byDescription[t.Description] = nil
+		} else {
+			byDescription[t.Description] = t
+		}
+	}
+
+	type item struct {
+		index  int
+		prefix string
+		todo   *todo.Todo
+	}
+
+	// Collect the items first so a todo is never matched by description
+	// when a later line links it by its marker
+	var items []item
+	linked := map[string]bool{}
+	for i, line := range lines {
+		m := itemPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		t, err := parseItem(m[3], m[2] != " ", now)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if t.UID != "" {
+			if linked[t.UID] {
+				// A copied line becomes a todo of its own
+				t.UID = ""
+			} else {
+				linked[t.UID] = true
+			}
+		}
+		items = append(items, item{index: i, prefix: m[1], todo: t})
+	}
+
+	replace := map[int]string{}
+	remove := map[int]bool{}
+
+	for _, it := range items {
+		ft := it.todo
+		line := lines[it.index]
+		base, synced := state[ft.UID]
+
+		if ft.UID == "" {
+			if t := byDescription[ft.Description]; t != nil && !linked[t.UID] {
+				ft.UID = t.UID
+				linked[t.UID] = true
+				base, synced = formatItem(t), true
+			} else {
+				ft.UID = todo.NewUID()
+				plan.Create = append(plan.Create, ft)
+				plan.State[ft.UID] = formatItem(ft)
+				replace[it.index] = withMarker(line, ft.UID)
+				continue
+			}
+		}
+
+		fileItem := formatItem(ft)
+		t := byUID[ft.UID]
+
+		if t == nil {
+			switch {
+			case !synced:
+				// Linked by another database, e.g. on another machine
+				plan.Create = append(plan.Create, ft)
+				plan.State[ft.UID] = fileItem
+			case fileItem == base:
+				remove[it.index] = true
+				plan.Removed++
+			default:
+				plan.Conflicts = append(plan.Conflicts, Conflict{File: strings.TrimSpace(line)})
+				remove[it.index] = true
+				plan.Removed++
+			}
+			continue
+		}
+
+		tadaItem := formatItem(t)
+		switch {
+		case fileItem == tadaItem:
+			plan.State[ft.UID] = tadaItem
+			if !markerPattern.MatchString(line) {
+				replace[it.index] = withMarker(line, ft.UID)
+			}
+		case synced && fileItem == base:
+			plan.State[ft.UID] = tadaItem
+			replace[it.index] = it.prefix + tadaItem + " " + marker(ft.UID)
+			plan.Written++
+		case synced && tadaItem == base:
+			plan.Update = append(plan.Update, merge(t, ft))
+			plan.State[ft.UID] = fileItem
+			if !markerPattern.MatchString(line) {
+				replace[it.index] = withMarker(line, ft.UID)
+			}
+		default:
+			plan.Conflicts = append(plan.Conflicts, Conflict{File: strings.TrimSpace(line), Tada: "- " + tadaItem})
+			plan.State[ft.UID] = tadaItem
+			replace[it.index] = it.prefix + tadaItem + " " + marker(ft.UID)
+			plan.Written++
+		}
+	}
+
+	for i, line := range lines {
+		if remove[i] {
+			continue
+		}
+		if r, ok := replace[i]; ok {
+			plan.Lines = append(plan.Lines, r)
+			continue
+		}
+		plan.Lines = append(plan.Lines, line)
+	}
+	plan.FileChanged = len(replace) > 0 || len(remove) > 0
+
+	for _, t := range todos {
+		if linked[t.UID] {
+			continue
+		}
+
+		if !include.Match(t) {
+			if _, synced := state[t.UID]; synced {
+				// The item was deleted from the file
+				plan.Unlinked++
+			}
+			continue
+		}
+		tadaItem := formatItem(t)
+
+		if n := len(plan.Lines); n > 0 && plan.Lines[n-1] != "" && !itemPattern.MatchString(plan.Lines[n-1]) {
+			// Start the list after a blank line rather than inside a paragraph
+			plan.Lines = append(plan.Lines, "")
+		}
+		plan.Lines = append(plan.Lines, "- "+tadaItem+" "+marker(t.UID))
+		plan.State[t.UID] = tadaItem
+		plan.Written++
+		plan.FileChanged = true
+	}
+
+	return plan, nil
+}
+
+// merge returns a copy of t with the fields edited in the file item f
+// applied. Fields that only differ in how they are written, such as a tag
+// with spaces, are left alone.
+func merge(t, f *todo.Todo) *todo.Todo {
+	merged := *t
+	merged.Status = f.Status
+	merged.Priority = f.Priority
+	merged.Description = f.Description
+	if formatTag(f.Tag) != formatTag(t.Tag) {
+		merged.Tag = f.Tag
+	}
+	if formatDue(f.DueAt) != formatDue(t.DueAt) {
+		merged.DueAt = f.DueAt
+	}
+	return &merged
+}
+
+// marker returns the comment that links an item to the todo with the given UID
+func marker(uid string) string {
+	return "<!-- tada:" + uid + " -->"
+}
+
+// withMarker links a line to a todo, replacing any marker it already has
+func withMarker(line, uid string) string {
+	line = markerPattern.ReplaceAllString(line, "")
+	return strings.TrimRight(line, " \t") + " " + marker(uid)
+}
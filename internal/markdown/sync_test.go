@@ -0,0 +1,173 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/negadras/tada/internal/todo"
+)
+
+func TestPlanSync(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.Local)
+	openFilter, _ := todo.ParseFilter("status:open")
+
+	newTodos := func() []*todo.Todo {
+		return []*todo.Todo{
+			{ID: 1, UID: "u1", Description: "Fix login bug", Priority: todo.High, Status: todo.Open, Tag: "backend"},
+			{ID: 2, UID: "u2", Description: "Call mom", Priority: todo.Medium, Status: todo.Open},
+		}
+	}
+	synced := map[string]string{
+		"u1": "[ ] Fix login bug #backend !high",
+		"u2": "[ ] Call mom",
+	}
+	syncedFile := []string{
+		"# Tasks",
+		"",
+		"- [ ] Fix login bug #backend !high <!-- tada:u1 -->",
+		"- [ ] Call mom <!-- tada:u2 -->",
+	}
+
+	t.Run("first sync writes open todos", func(t *testing.T) {
+		plan, err := PlanSync([]string{"# Tasks", ""}, newTodos(), nil, openFilter, now)
+		if err != nil {
+			t.Fatalf("PlanSync() error = %v", err)
+		}
+		if strings.Join(plan.Lines, "\n") != strings.Join(syncedFile, "\n") {
+			t.Errorf("Lines =\n%s", strings.Join(plan.Lines, "\n"))
+		}
+		if plan.Written != 2 || !plan.FileChanged || len(plan.State) != 2 {
+			t.Errorf("plan = %+v", plan)
+		}
+	})
+
+	t.Run("nothing changed", func(t *testing.T) {
+		plan, err := PlanSync(syncedFile, newTodos(), synced, openFilter, now)
+		if err != nil {
+			t.Fatalf("PlanSync() error = %v", err)
+		}
+		if plan.FileChanged || len(plan.Create)+len(plan.Update)+len(plan.Conflicts) != 0 {
+			t.Errorf("plan = %+v, want no changes", plan)
+		}
+	})
+
+	t.Run("ticked and new items in the file", func(t *testing.T) {
+		lines := append([]string(nil), syncedFile...)
+		lines[3] = "- [x] Call mom <!-- tada:u2 -->"
+		lines = append(lines, "  - [ ] Buy milk #home")
+
+		plan, err := PlanSync(lines, newTodos(), synced, openFilter, now)
+		if err != nil {
+			t.Fatalf("PlanSync() error = %v", err)
+		}
+		if len(plan.Update) != 1 || plan.Update[0].ID != 2 || plan.Update[0].Status != todo.Done {
+			t.Errorf("Update = %+v, want todo 2 done", plan.Update)
+		}
+		if len(plan.Create) != 1 || plan.Create[0].Description != "Buy milk" || plan.Create[0].Tag != "home" {
+			t.Fatalf("Create = %+v, want Buy milk", plan.Create)
+		}
+		if want := "  - [ ] Buy milk #home " + marker(plan.Create[0].UID); plan.Lines[4] != want {
+			t.Errorf("new item line = %q, want %q", plan.Lines[4], want)
+		}
+		if plan.State["u2"] != "[x] Call mom" {
+			t.Errorf("State[u2] = %q", plan.State["u2"])
+		}
+	})
+
+	t.Run("changed and deleted in tada", func(t *testing.T) {
+		todos := newTodos()
+		todos[0].Status = todo.Done
+		todos = todos[:1]
+
+		plan, err := PlanSync(syncedFile, todos, synced, openFilter, now)
+		if err != nil {
+			t.Fatalf("PlanSync() error = %v", err)
+		}
+		want := []string{"# Tasks", "", "- [x] Fix login bug #backend !high <!-- tada:u1 -->"}
+		if strings.Join(plan.Lines, "\n") != strings.Join(want, "\n") {
+			t.Errorf("Lines =\n%s", strings.Join(plan.Lines, "\n"))
+		}
+		if plan.Written != 1 || plan.Removed != 1 || len(plan.Update) != 0 {
+			t.Errorf("plan = %+v", plan)
+		}
+	})
+
+	t.Run("deleted from the file", func(t *testing.T) {
+		plan, err := PlanSync(syncedFile[:3], newTodos(), synced, openFilter, now)
+		if err != nil {
+			t.Fatalf("PlanSync() error = %v", err)
+		}
+		if strings.Join(plan.Lines, "\n") != strings.Join(syncedFile, "\n") {
+			t.Errorf("Lines =\n%s\nwant the open todo written back", strings.Join(plan.Lines, "\n"))
+		}
+		if plan.Written != 1 || plan.Unlinked != 0 {
+			t.Errorf("plan = %+v", plan)
+		}
+	})
+
+	t.Run("deleted from the file and no longer matching", func(t *testing.T) {
+		todos := newTodos()
+		todos[1].Status = todo.Done
+
+		plan, err := PlanSync(syncedFile[:3], todos, synced, openFilter, now)
+		if err != nil {
+			t.Fatalf("PlanSync() error = %v", err)
+		}
+		if plan.FileChanged || plan.Unlinked != 1 || len(plan.Update) != 0 {
+			t.Errorf("plan = %+v, want todo 2 no longer synced", plan)
+		}
+		if _, ok := plan.State["u2"]; ok {
+			t.Error("State keeps todo 2, want it dropped")
+		}
+	})
+
+	t.Run("changed on both sides", func(t *testing.T) {
+		todos := newTodos()
+		todos[0].Priority = todo.Low
+		lines := append([]string(nil), syncedFile...)
+		lines[2] = "- [x] Fix login bug #backend !high <!-- tada:u1 -->"
+
+		plan, err := PlanSync(lines, todos, synced, openFilter, now)
+		if err != nil {
+			t.Fatalf("PlanSync() error = %v", err)
+		}
+		if len(plan.Conflicts) != 1 || len(plan.Update) != 0 {
+			t.Fatalf("plan = %+v, want one conflict", plan)
+		}
+		if c := plan.Conflicts[0]; c.File != lines[2] || c.Tada != "- [ ] Fix login bug #backend !low" {
+			t.Errorf("Conflict = %+v", c)
+		}
+		if plan.Lines[2] != "- [ ] Fix login bug #backend !low <!-- tada:u1 -->" {
+			t.Errorf("line = %q, want tada's version", plan.Lines[2])
+		}
+	})
+
+	t.Run("items without markers match by description", func(t *testing.T) {
+		lines := []string{"- [x] Fix login bug #backend !high", "- [ ] Call mom"}
+
+		plan, err := PlanSync(lines, newTodos(), nil, openFilter, now)
+		if err != nil {
+			t.Fatalf("PlanSync() error = %v", err)
+		}
+		if len(plan.Create) != 0 || len(plan.Update) != 1 || plan.Update[0].Status != todo.Done {
+			t.Errorf("plan = %+v, want todo 1 done and nothing created", plan)
+		}
+		if plan.Lines[1] != "- [ ] Call mom "+marker("u2") {
+			t.Errorf("line = %q, want a marker added", plan.Lines[1])
+		}
+	})
+
+	t.Run("tag written with dashes is kept", func(t *testing.T) {
+		todos := []*todo.Todo{{ID: 1, UID: "u1", Description: "Plan offsite", Priority: todo.Medium, Status: todo.Open, Tag: "team events"}}
+		lines := []string{"- [x] Plan offsite #team-events <!-- tada:u1 -->"}
+
+		plan, err := PlanSync(lines, todos, map[string]string{"u1": "[ ] Plan offsite #team-events"}, openFilter, now)
+		if err != nil {
+			t.Fatalf("PlanSync() error = %v", err)
+		}
+		if len(plan.Update) != 1 || plan.Update[0].Tag != "team events" {
+			t.Errorf("Update = %+v, want the tag unchanged", plan.Update)
+		}
+	})
+}
@@ -0,0 +1,504 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/todo"
+	"github.com/spf13/cobra"
+)
+
+// Format names accepted by Parse
+const (
+	Text      = "text"
+	Table     = "table"
+	JSON      = "json"
+	NDJSON    = "ndjson"
+	CSV       = "csv"
+	TSV       = "tsv"
+	YAML      = "yaml"
+	Markdown  = "markdown"
+	Checklist = "checklist"
+	Template  = "template"
+)
+
+// formats lists the formats in the order shown in help and error messages
+var formats = []string{Text, Table, JSON, NDJSON, CSV, TSV, YAML, Markdown, Checklist, Template + "=..."}
+
+// FlagUsage is the help text for --format flags
+var FlagUsage = "Output format (" + strings.Join(formats, ", ") + ")"
+
+// Format renders todos and quotes in one of the supported output formats.
+// The Text and Table formats are left to the caller, which already knows how
+// to print and page items interactively.
+type Format struct {
+	Name string
+	tmpl *template.Template
+}
+
+// Parse parses a --format value. Templates are written as
+// template='{{.ID}}\t{{.Description}}' and may use the helper functions age,
+// icon, date, due, upper and lower.
+func Parse(spec string) (*Format, error) {
+	if body, ok := strings.CutPrefix(spec, Template+"="); ok {
+		// Allow \t and \n escapes so templates can be typed on the command line
+		body = strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(body)
+
+		tmpl, err := template.New("format").Funcs(templateFuncs).Parse(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+		return &Format{Name: Template, tmpl: tmpl}, nil
+	}
+
+	name := strings.ToLower(strings.TrimSpace(spec))
+	switch name {
+	case Text, Table, JSON, NDJSON, CSV, TSV, YAML, Markdown, Checklist:
+		return &Format{Name: name}, nil
+	case "md":
+		return &Format{Name: Markdown}, nil
+	case Template:
+		return nil, fmt.Errorf("template format needs a template, e.g. template='{{.ID}} {{.Description}}'")
+	default:
+		return nil, fmt.Errorf("unknown format %q (must be one of: %s)", spec, strings.Join(formats, ", "))
+	}
+}
+
+// IsText reports whether the caller should print items itself
+func (f *Format) IsText() bool {
+	return f.Name == Text || f.Name == Table
+}
+
+// templateFuncs are the helper functions available to templates
+var templateFuncs = template.FuncMap{
+	// age formats how long ago a time was, e.g. {{age .CreatedAt}}
+	"age": func(t interface{}) string {
+		switch v := t.(type) {
+		case time.Time:
+			return todo.FormatAge(time.Since(v))
+		case *time.Time:
+			if v == nil {
+				return ""
+			}
+			return todo.FormatAge(time.Since(*v))
+		default:
+			return ""
+		}
+	},
+	// icon returns the emoji for a priority, e.g. {{icon .Priority}}
+	"icon": todo.GetPriorityIcon,
+	// date formats a time as YYYY-MM-DD or with an optional Go layout
+	"date": func(t interface{}, layout ...string) string {
+		l := "2006-01-02"
+		if len(layout) > 0 {
+			l = layout[0]
+		}
+		switch v := t.(type) {
+		case time.Time:
+			return v.Local().Format(l)
+		case *time.Time:
+			if v == nil {
+				return ""
+			}
+			return v.Local().Format(l)
+		default:
+			return ""
+		}
+	},
+	// due describes a due date relative to today
+	"due": func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return todo.FormatDue(*t)
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// field is a named value of a record. Numbers are written to YAML unquoted.
+type field struct {
+	name   string
+	value  string
+	number bool
+}
+
+// todoRecord flattens a todo into the fields used by tabular formats
+func todoRecord(t *todo.Todo) []field {
+	return []field{
+		{"id", strconv.Itoa(t.ID), true},
+		{"description", t.Description, false},
+		{"priority", strings.ToLower(t.Priority.String()), false},
+		{"status", strings.ToLower(t.Status.String()), false},
+		{"tag", t.Tag, false},
+		{"due_at", formatTime(t.DueAt), false},
+		{"created_at", formatTime(&t.CreatedAt), false},
+		{"updated_at", formatTime(&t.UpdatedAt), false},
+		{"completed_at", formatTime(t.CompletedAt), false},
+		{"notes", t.Notes, false},
+	}
+}
+
+// quoteRecord flattens a quote into the fields used by tabular formats
+func quoteRecord(q *quote.Quote) []field {
+	return []field{
+		{"id", strconv.Itoa(q.ID), true},
+		{"text", q.Text, false},
+		{"author", q.Author, false},
+		{"category", q.Category, false},
+		{"created_at", formatTime(&q.CreatedAt), false},
+		{"updated_at", formatTime(&q.UpdatedAt), false},
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// WriteTodos writes a list of todos
+func (f *Format) WriteTodos(w io.Writer, todos []*todo.Todo) error {
+	if todos == nil {
+		todos = []*todo.Todo{}
+	}
+
+	switch f.Name {
+	case JSON:
+		return json.NewEncoder(w).Encode(todos)
+	case Checklist:
+		for _, t := range todos {
+			if err := writeChecklistItem(w, t); err != nil {
+				return err
+			}
+		}
+		return nil
+	case Markdown:
+		return writeMarkdownTable(w, todoTable(todos))
+	}
+
+	items := make([]interface{}, len(todos))
+	records := make([][]field, len(todos))
+	for i, t := range todos {
+		items[i] = t
+		records[i] = todoRecord(t)
+	}
+	return f.writeItems(w, items, records, todoHeader)
+}
+
+// WriteTodo writes a single todo. JSON and YAML produce a single object
+// rather than a one-element list.
+func (f *Format) WriteTodo(w io.Writer, t *todo.Todo) error {
+	switch f.Name {
+	case JSON:
+		return json.NewEncoder(w).Encode(t)
+	case YAML:
+		return writeYAMLMapping(w, todoRecord(t), "")
+	default:
+		return f.WriteTodos(w, []*todo.Todo{t})
+	}
+}
+
+// WriteTodoGroups writes grouped todos. Only formats that can express
+// nesting support groups.
+func (f *Format) WriteTodoGroups(w io.Writer, groups []todo.Group) error {
+	if groups == nil {
+		groups = []todo.Group{}
+	}
+
+	switch f.Name {
+	case JSON:
+		return json.NewEncoder(w).Encode(groups)
+	case YAML:
+		if len(groups) == 0 {
+			_, err := fmt.Fprintln(w, "[]")
+			return err
+		}
+		for _, g := range groups {
+			if _, err := fmt.Fprintf(w, "- key: %s\n  count: %d\n  todos:\n", yamlScalar(g.Key), g.Count); err != nil {
+				return err
+			}
+			for _, t := range g.Todos {
+				if err := writeYAMLMapping(w, todoRecord(t), "    - "); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case Markdown, Checklist:
+		for i, g := range groups {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "### %s (%d)\n\n", g.Key, g.Count)
+			if err := f.WriteTodos(w, g.Todos); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("--group-by is not supported with the %s format", f.Name)
+	}
+}
+
+// WriteQuotes writes a list of quotes
+func (f *Format) WriteQuotes(w io.Writer, quotes []*quote.Quote) error {
+	if quotes == nil {
+		quotes = []*quote.Quote{}
+	}
+
+	switch f.Name {
+	case JSON:
+		return json.NewEncoder(w).Encode(quotes)
+	case Checklist:
+		return fmt.Errorf("the checklist format is only supported for todos")
+	case Markdown:
+		return writeMarkdownTable(w, quoteTable(quotes))
+	}
+
+	items := make([]interface{}, len(quotes))
+	records := make([][]field, len(quotes))
+	for i, q := range quotes {
+		items[i] = q
+		records[i] = quoteRecord(q)
+	}
+	return f.writeItems(w, items, records, quoteHeader)
+}
+
+// WriteQuote writes a single quote
+func (f *Format) WriteQuote(w io.Writer, q *quote.Quote) error {
+	switch f.Name {
+	case JSON:
+		return json.NewEncoder(w).Encode(q)
+	case YAML:
+		return writeYAMLMapping(w, quoteRecord(q), "")
+	default:
+		return f.WriteQuotes(w, []*quote.Quote{q})
+	}
+}
+
+var (
+	todoHeader  = recordHeader(todoRecord(&todo.Todo{}))
+	quoteHeader = recordHeader(quoteRecord(&quote.Quote{}))
+)
+
+func recordHeader(record []field) []string {
+	header := make([]string, len(record))
+	for i, f := range record {
+		header[i] = f.name
+	}
+	return header
+}
+
+// writeItems writes the formats that treat todos and quotes alike
+func (f *Format) writeItems(w io.Writer, items []interface{}, records [][]field, header []string) error {
+	switch f.Name {
+	case NDJSON:
+		enc := json.NewEncoder(w)
+		for _, item := range items {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case CSV, TSV:
+		cw := csv.NewWriter(w)
+		if f.Name == TSV {
+			cw.Comma = '\t'
+		}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		for _, record := range records {
+			row := make([]string, len(record))
+			for i, fld := range record {
+				row[i] = fld.value
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case YAML:
+		if len(records) == 0 {
+			_, err := fmt.Fprintln(w, "[]")
+			return err
+		}
+		for _, record := range records {
+			if err := writeYAMLMapping(w, record, "- "); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case Template:
+		for _, item := range items {
+			var b strings.Builder
+			if err := f.tmpl.Execute(&b, item); err != nil {
+				return fmt.Errorf("failed to render template: %w", err)
+			}
+			line := b.String()
+			if !strings.HasSuffix(line, "\n") {
+				line += "\n"
+			}
+			if _, err := io.WriteString(w, line); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("the %s format must be printed by the caller", f.Name)
+	}
+}
+
+// writeYAMLMapping writes a record as a YAML mapping. The first line is
+// prefixed with prefix (e.g. "- " for list items) and the following lines
+// are aligned with it; empty values are omitted.
+func writeYAMLMapping(w io.Writer, record []field, prefix string) error {
+	indent := strings.Repeat(" ", len(prefix))
+
+	first := true
+	for _, f := range record {
+		if f.value == "" {
+			continue
+		}
+		lead := indent
+		if first {
+			lead = prefix
+			first = false
+		}
+		value := f.value
+		if !f.number {
+			value = yamlScalar(value)
+		}
+		if _, err := fmt.Fprintf(w, "%s%s: %s\n", lead, f.name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// yamlScalar quotes a string when YAML would otherwise read it as another
+// type or misparse it. Strings starting with a digit, a sign or a dot are
+// always quoted, as YAML reads many of them as numbers or timestamps, such
+// as 0x1F, 0o17, 1_000, .5 or 2024-01-01.
+func yamlScalar(s string) string {
+	if s == "" {
+		return `""`
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.Quote(s)
+	}
+	if strings.ContainsRune("0123456789+.", rune(s[0])) {
+		return strconv.Quote(s)
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "y", "n", "on", "off", "null", "~":
+		return strconv.Quote(s)
+	}
+	if strings.ContainsAny(s, ":#{}[],&*!|>'\"%@`\n\t") || strings.TrimSpace(s) != s || strings.HasPrefix(s, "-") || strings.HasPrefix(s, "?") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// writeChecklistItem writes a todo as a GitHub-flavoured markdown task
+func writeChecklistItem(w io.Writer, t *todo.Todo) error {
+	box := "[ ]"
+	if t.Status == todo.Done {
+		box = "[x]"
+	}
+
+	line := fmt.Sprintf("- %s %s %s", box, todo.GetPriorityIcon(t.Priority), t.Description)
+	if t.Tag != "" {
+		line += fmt.Sprintf(" `%s`", t.Tag)
+	}
+	if t.DueAt != nil && t.Status == todo.Open {
+		line += fmt.Sprintf(" (due %s)", t.DueAt.Local().Format("2006-01-02"))
+	}
+
+	_, err := fmt.Fprintln(w, line)
+	return err
+}
+
+// todoTable returns the header and rows of the markdown todo table
+func todoTable(todos []*todo.Todo) [][]string {
+	rows := [][]string{{"ID", "Priority", "Status", "Tag", "Due", "Age", "Description"}}
+	for _, t := range todos {
+		due := ""
+		if t.DueAt != nil {
+			due = t.DueAt.Local().Format("2006-01-02")
+		}
+		rows = append(rows, []string{
+			fmt.Sprintf("#%d", t.ID),
+			todo.GetPriorityIcon(t.Priority) + " " + t.Priority.String(),
+			t.Status.String(),
+			t.Tag,
+			due,
+			todo.FormatAge(t.Age()),
+			t.Description,
+		})
+	}
+	return rows
+}
+
+// quoteTable returns the header and rows of the markdown quote table
+func quoteTable(quotes []*quote.Quote) [][]string {
+	rows := [][]string{{"ID", "Quote", "Author", "Category"}}
+	for _, q := range quotes {
+		rows = append(rows, []string{fmt.Sprintf("#%d", q.ID), q.Text, q.Author, q.Category})
+	}
+	return rows
+}
+
+// writeMarkdownTable writes rows as a markdown table, the first row being the header
+func writeMarkdownTable(w io.Writer, rows [][]string) error {
+	escape := strings.NewReplacer("|", `\|`, "\n", " ")
+
+	for i, row := range rows {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			cells[j] = escape.Replace(cell)
+		}
+		if _, err := fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | ")); err != nil {
+			return err
+		}
+		if i == 0 {
+			sep := make([]string, len(row))
+			for j := range sep {
+				sep[j] = "---"
+			}
+			if _, err := fmt.Fprintf(w, "|%s|\n", strings.Join(sep, "|")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AddFlag registers the --format flag on a command
+func AddFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("format", "o", "", FlagUsage)
+}
+
+// FromCommand returns the format selected with --format, or nil when the
+// flag is not set and the command should use its default output
+func FromCommand(cmd *cobra.Command) (*Format, error) {
+	spec, _ := cmd.Flags().GetString("format")
+	if spec == "" {
+		return nil, nil
+	}
+	return Parse(spec)
+}
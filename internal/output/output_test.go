@@ -0,0 +1,261 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/todo"
+)
+
+func testTodos() []*todo.Todo {
+	created := time.Date(2025, 6, 1, 9, 30, 0, 0, time.UTC)
+	completed := created.Add(2 * time.Hour)
+
+	return []*todo.Todo{
+		{
+			ID:          1,
+			Description: "Fix login bug",
+			Priority:    todo.High,
+			Status:      todo.Open,
+			Tag:         "work",
+			CreatedAt:   created,
+			UpdatedAt:   created,
+		},
+		{
+			ID:          2,
+			Description: "Buy milk, eggs",
+			Priority:    todo.Low,
+			Status:      todo.Done,
+			CreatedAt:   created,
+			UpdatedAt:   completed,
+			CompletedAt: &completed,
+		},
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec        string
+		want        string
+		errContains string
+	}{
+		{"csv", CSV, ""},
+		{"TSV", TSV, ""},
+		{"md", Markdown, ""},
+		{"markdown", Markdown, ""},
+		{"checklist", Checklist, ""},
+		{"table", Table, ""},
+		{"template={{.ID}}", Template, ""},
+		{"template", "", "needs a template"},
+		{"template={{.ID", "", "invalid template"},
+		{"xml", "", "unknown format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			f, err := Parse(tt.spec)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("Parse(%q) error = %v, want error containing %q", tt.spec, err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.spec, err)
+			}
+			if f.Name != tt.want {
+				t.Errorf("Parse(%q).Name = %v, want %v", tt.spec, f.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormat_WriteTodos(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "csv",
+			want: "id,description,priority,status,tag,due_at,created_at,updated_at,completed_at,notes\n" +
+				"1,Fix login bug,high,open,work,,2025-06-01T09:30:00Z,2025-06-01T09:30:00Z,,\n" +
+				"2,\"Buy milk, eggs\",low,done,,,2025-06-01T09:30:00Z,2025-06-01T11:30:00Z,2025-06-01T11:30:00Z,\n",
+		},
+		{
+			format: "tsv",
+			want: "id\tdescription\tpriority\tstatus\ttag\tdue_at\tcreated_at\tupdated_at\tcompleted_at\tnotes\n" +
+				"1\tFix login bug\thigh\topen\twork\t\t2025-06-01T09:30:00Z\t2025-06-01T09:30:00Z\t\t\n" +
+				"2\tBuy milk, eggs\tlow\tdone\t\t\t2025-06-01T09:30:00Z\t2025-06-01T11:30:00Z\t2025-06-01T11:30:00Z\t\n",
+		},
+		{
+			format: "yaml",
+			want: "- id: 1\n  description: Fix login bug\n  priority: high\n  status: open\n  tag: work\n" +
+				"  created_at: \"2025-06-01T09:30:00Z\"\n  updated_at: \"2025-06-01T09:30:00Z\"\n" +
+				"- id: 2\n  description: \"Buy milk, eggs\"\n  priority: low\n  status: done\n" +
+				"  created_at: \"2025-06-01T09:30:00Z\"\n  updated_at: \"2025-06-01T11:30:00Z\"\n  completed_at: \"2025-06-01T11:30:00Z\"\n",
+		},
+		{
+			format: "checklist",
+			want:   "- [ ] 🔴 Fix login bug `work`\n- [x] 🟢 Buy milk, eggs\n",
+		},
+		{
+			format: `template=#{{.ID}}\t{{upper .Description}}`,
+			want:   "#1\tFIX LOGIN BUG\n#2\tBUY MILK, EGGS\n",
+		},
+		{
+			format: `template={{.ID}} {{icon .Priority}} {{date .CreatedAt}}`,
+			want:   "1 🔴 " + testTodos()[0].CreatedAt.Local().Format("2006-01-02") + "\n2 🟢 " + testTodos()[1].CreatedAt.Local().Format("2006-01-02") + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			f, err := Parse(tt.format)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.format, err)
+			}
+
+			var buf bytes.Buffer
+			if err := f.WriteTodos(&buf, testTodos()); err != nil {
+				t.Fatalf("WriteTodos() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("WriteTodos() =\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestFormat_WriteTodos_NDJSON(t *testing.T) {
+	f, _ := Parse(NDJSON)
+
+	var buf bytes.Buffer
+	if err := f.WriteTodos(&buf, testTodos()); err != nil {
+		t.Fatalf("WriteTodos() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("WriteTodos() wrote %d lines, want 2", len(lines))
+	}
+	for _, line := range lines {
+		var decoded todo.Todo
+		if err := json.Unmarshal([]byte(line), &decoded); err != nil {
+			t.Errorf("line %q is not a JSON todo: %v", line, err)
+		}
+	}
+}
+
+func TestFormat_WriteTodos_Markdown(t *testing.T) {
+	f, _ := Parse(Markdown)
+
+	todos := testTodos()
+	todos[0].Description = "Handle a|b"
+
+	var buf bytes.Buffer
+	if err := f.WriteTodos(&buf, todos); err != nil {
+		t.Fatalf("WriteTodos() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("WriteTodos() wrote %d lines, want header, separator and 2 rows", len(lines))
+	}
+	if lines[0] != "| ID | Priority | Status | Tag | Due | Age | Description |" {
+		t.Errorf("header = %q", lines[0])
+	}
+	if !strings.Contains(lines[2], `Handle a\|b`) {
+		t.Errorf("row %q should escape the pipe in the description", lines[2])
+	}
+}
+
+func TestFormat_WriteTodo(t *testing.T) {
+	f, _ := Parse(JSON)
+
+	var buf bytes.Buffer
+	if err := f.WriteTodo(&buf, testTodos()[0]); err != nil {
+		t.Fatalf("WriteTodo() error = %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "{") {
+		t.Errorf("WriteTodo() with json should write an object, got %s", buf.String())
+	}
+
+	f, _ = Parse(YAML)
+	buf.Reset()
+	if err := f.WriteTodo(&buf, testTodos()[0]); err != nil {
+		t.Fatalf("WriteTodo() error = %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "id: 1\n") {
+		t.Errorf("WriteTodo() with yaml should write a mapping, got %s", buf.String())
+	}
+}
+
+func TestFormat_WriteTodoGroups(t *testing.T) {
+	groups, _ := todo.GroupTodos(testTodos(), "status")
+
+	f, _ := Parse(Checklist)
+	var buf bytes.Buffer
+	if err := f.WriteTodoGroups(&buf, groups); err != nil {
+		t.Fatalf("WriteTodoGroups() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "### OPEN (1)\n\n- [ ]") {
+		t.Errorf("WriteTodoGroups() = %s, want a heading per group", buf.String())
+	}
+
+	f, _ = Parse(CSV)
+	if err := f.WriteTodoGroups(&buf, groups); err == nil {
+		t.Error("WriteTodoGroups() with csv should return an error")
+	}
+}
+
+func TestFormat_WriteQuotes(t *testing.T) {
+	quotes := []*quote.Quote{
+		{ID: 3, Text: "Stay hungry, stay foolish", Author: "Steve Jobs", Category: "inspiration"},
+	}
+
+	f, _ := Parse(`template={{.Text}} — {{.Author}}`)
+	var buf bytes.Buffer
+	if err := f.WriteQuotes(&buf, quotes); err != nil {
+		t.Fatalf("WriteQuotes() error = %v", err)
+	}
+	if buf.String() != "Stay hungry, stay foolish — Steve Jobs\n" {
+		t.Errorf("WriteQuotes() = %q", buf.String())
+	}
+
+	f, _ = Parse(Checklist)
+	if err := f.WriteQuotes(&buf, quotes); err == nil {
+		t.Error("WriteQuotes() with checklist should return an error")
+	}
+}
+
+func TestYAMLScalar(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "plain"},
+		{"", `""`},
+		{"42", `"42"`},
+		{"2024-01-01", `"2024-01-01"`},
+		{"0x1F", `"0x1F"`},
+		{"0o17", `"0o17"`},
+		{"1_000", `"1_000"`},
+		{".inf", `".inf"`},
+		{"+1", `"+1"`},
+		{"v2", "v2"},
+		{"yes", `"yes"`},
+		{"key: value", `"key: value"`},
+		{"- item", `"- item"`},
+		{"multi\nline", `"multi\nline"`},
+	}
+
+	for _, tt := range tests {
+		if got := yamlScalar(tt.in); got != tt.want {
+			t.Errorf("yamlScalar(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
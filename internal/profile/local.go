@@ -0,0 +1,152 @@
+package profile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LocalDir is the directory holding a project's own todos, created by
+// 'tada init' and found from any directory below it, like .git
+const LocalDir = ".tada"
+
+// localIgnore keeps the files that belong to one machine out of the
+// project's repository
+const localIgnore = `# Written by tada; only the database is shared
+todos.db-journal
+prompt.json
+prompt.json.tmp
+repo/
+`
+
+// global is set by --global, to use the profile even inside a project
+var global bool
+
+// SetGlobal makes every command of this process ignore project stores, as
+// with --global
+func SetGlobal(g bool) {
+	global = g
+}
+
+// FindLocal returns the project store nearest to dir, walking up to the
+// root. The global ~/.tada is not a project store.
+func FindLocal(dir string) (string, bool) {
+	root, _ := GetRoot()
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+
+	for {
+		candidate := filepath.Join(dir, LocalDir)
+		if candidate != root {
+			if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+				return candidate, true
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// Local returns the project store used by commands, unless a profile was
+// asked for with --profile or --global
+func Local() (string, bool) {
+	if global || override != "" {
+		return "", false
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+	return FindLocal(wd)
+}
+
+// InitLocal creates a project store in dir
+func InitLocal(dir string) (string, error) {
+	store := filepath.Join(dir, LocalDir)
+	if root, _ := GetRoot(); store == root {
+		return "", fmt.Errorf("%s is the global store", store)
+	}
+	if _, err := os.Stat(store); err == nil {
+		return "", fmt.Errorf("%s already exists", store)
+	}
+	if err := os.MkdirAll(store, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", store, err)
+	}
+	if err := os.WriteFile(filepath.Join(store, ".gitignore"), []byte(localIgnore), 0644); err != nil {
+		return "", err
+	}
+	return store, nil
+}
+
+// Store is a place todos are kept: a project store or a profile
+type Store struct {
+	// Name is the project's directory name, or the profile
+	Name string
+	Dir  string
+	// Local is set for a project store
+	Local bool
+}
+
+// Label names the store in headers
+func (s Store) Label() string {
+	if s.Local {
+		return s.Name + " (local)"
+	}
+	return s.Name
+}
+
+// CurrentStore returns the store used by commands: the nearest project
+// store, else the profile in use
+func CurrentStore() (Store, error) {
+	if dir, ok := Local(); ok {
+		return localStore(dir), nil
+	}
+	return globalStore()
+}
+
+// Stores returns the project store in use, if any, then the profile's, for
+// commands that show both
+func Stores() ([]Store, error) {
+	var stores []Store
+	if dir, ok := Local(); ok {
+		stores = append(stores, localStore(dir))
+	}
+	s, err := globalStore()
+	if err != nil {
+		return nil, err
+	}
+	return append(stores, s), nil
+}
+
+// Label names the store in use for headers. It is empty for the default
+// profile, which is not shown.
+func Label() string {
+	s, err := CurrentStore()
+	if err != nil || !s.Local && s.Name == Default {
+		return ""
+	}
+	return s.Label()
+}
+
+// localStore returns a project store, named after its project's directory
+func localStore(dir string) Store {
+	return Store{Name: filepath.Base(filepath.Dir(dir)), Dir: dir, Local: true}
+}
+
+// globalStore returns the store of the profile in use
+func globalStore() (Store, error) {
+	name, err := Current()
+	if err != nil {
+		return Store{}, err
+	}
+	dir, err := globalDir()
+	if err != nil {
+		return Store{}, err
+	}
+	return Store{Name: name, Dir: dir}, nil
+}
@@ -0,0 +1,244 @@
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Default is the profile kept directly in ~/.tada, as before profiles existed
+const Default = "default"
+
+// Env is the environment variable that selects a profile for one shell
+const Env = "TADA_PROFILE"
+
+// namePattern is what profile names may look like, so that they are safe
+// directory names
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// override is the profile chosen with --profile, if any
+var override string
+
+// SetOverride makes every command of this process use a profile, as with
+// --profile
+func SetOverride(name string) {
+	override = name
+}
+
+// GetRoot returns ~/.tada, which holds the default profile and the others
+func GetRoot() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".tada"), nil
+}
+
+// Validate checks that a name can be used as a profile
+func Validate(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q, use lowercase letters, digits, - and _", name)
+	}
+	return nil
+}
+
+// GetDir returns the directory holding a profile's database and files
+func GetDir(name string) (string, error) {
+	root, err := GetRoot()
+	if err != nil {
+		return "", err
+	}
+	if name == Default {
+		return root, nil
+	}
+	return filepath.Join(root, "profiles", name), nil
+}
+
+// Exists reports whether a profile was created. The default profile always
+// exists.
+func Exists(name string) bool {
+	if name == Default {
+		return true
+	}
+	dir, err := GetDir(name)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
+
+// Current returns the profile in use: the one given with --profile, else
+// TADA_PROFILE, else the one chosen with 'tada profile use'
+func Current() (string, error) {
+	if override != "" {
+		return override, nil
+	}
+	if name := os.Getenv(Env); name != "" {
+		return name, nil
+	}
+	return Selected()
+}
+
+// Selected returns the profile chosen with 'tada profile use'
+func Selected() (string, error) {
+	root, err := GetRoot()
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(filepath.Join(root, "profile"))
+	if errors.Is(err, os.ErrNotExist) {
+		return Default, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read current profile: %w", err)
+	}
+	if name := strings.TrimSpace(string(data)); name != "" {
+		return name, nil
+	}
+	return Default, nil
+}
+
+// GetCurrentDir returns the directory of the store in use: the nearest
+// project store, else the profile's directory, creating it for the default
+// profile
+func GetCurrentDir() (string, error) {
+	if dir, ok := Local(); ok {
+		return dir, nil
+	}
+	return globalDir()
+}
+
+// globalDir returns the directory of the profile in use, ignoring project
+// stores
+func globalDir() (string, error) {
+	name, err := Current()
+	if err != nil {
+		return "", err
+	}
+	if err := Validate(name); err != nil {
+		return "", err
+	}
+	if !Exists(name) {
+		return "", fmt.Errorf("profile %q does not exist, create it with 'tada profile create %s'", name, name)
+	}
+
+	dir, err := GetDir(name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create .tada directory: %w", err)
+	}
+	return dir, nil
+}
+
+// List returns the profiles, the default one first
+func List() ([]string, error) {
+	names := []string{Default}
+	root, err := GetRoot()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(root, "profiles"))
+	if errors.Is(err, os.ErrNotExist) {
+		return names, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list profiles: %w", err)
+	}
+
+	var others []string
+	for _, e := range entries {
+		if e.IsDir() && Validate(e.Name()) == nil && e.Name() != Default {
+			others = append(others, e.Name())
+		}
+	}
+	sort.Strings(others)
+	return append(names, others...), nil
+}
+
+// Create creates an empty profile
+func Create(name string) error {
+	if err := Validate(name); err != nil {
+		return err
+	}
+	if Exists(name) {
+		return fmt.Errorf("profile %q already exists", name)
+	}
+	dir, err := GetDir(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create profile: %w", err)
+	}
+	return nil
+}
+
+// Use makes a profile the one used by every command that is not given
+// another
+func Use(name string) error {
+	if err := Validate(name); err != nil {
+		return err
+	}
+	if !Exists(name) {
+		return fmt.Errorf("profile %q does not exist, create it with 'tada profile create %s'", name, name)
+	}
+	root, err := GetRoot()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return fmt.Errorf("failed to create .tada directory: %w", err)
+	}
+
+	path := filepath.Join(root, "profile")
+	if name == Default {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(path, []byte(name+"\n"), 0644)
+}
+
+// CanDelete reports why a profile cannot be deleted, or nil if it can. The
+// default profile and the one chosen with 'tada profile use' cannot be.
+func CanDelete(name string) error {
+	if name == Default {
+		return errors.New("the default profile cannot be deleted")
+	}
+	if err := Validate(name); err != nil {
+		return err
+	}
+	if !Exists(name) {
+		return fmt.Errorf("profile %q does not exist", name)
+	}
+	if selected, err := Selected(); err != nil {
+		return err
+	} else if selected == name {
+		return fmt.Errorf("profile %q is in use, switch with 'tada profile use %s' first", name, Default)
+	}
+	return nil
+}
+
+// Delete deletes a profile with its todos, quotes and files
+func Delete(name string) error {
+	if err := CanDelete(name); err != nil {
+		return err
+	}
+
+	dir, err := GetDir(name)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to delete profile: %w", err)
+	}
+	return nil
+}
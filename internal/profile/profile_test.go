@@ -0,0 +1,183 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// setup gives the test an empty home directory and no selected profile
+func setup(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(Env, "")
+	SetOverride("")
+	SetGlobal(false)
+	t.Cleanup(func() {
+		SetOverride("")
+		SetGlobal(false)
+	})
+	return home
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"work", false},
+		{"side-project", false},
+		{"client_2", false},
+		{"", true},
+		{"Work", true},
+		{"../work", true},
+		{"-work", true},
+		{"my work", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.name); (err != nil) != tt.wantErr {
+				t.Errorf("Validate(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	home := setup(t)
+
+	if name, err := Current(); err != nil || name != Default {
+		t.Fatalf("Current() = %q, %v, want %q", name, err, Default)
+	}
+	dir, err := GetCurrentDir()
+	if err != nil || dir != filepath.Join(home, ".tada") {
+		t.Errorf("GetCurrentDir() = %q, %v, want ~/.tada", dir, err)
+	}
+
+	if err := Use("work"); err == nil {
+		t.Error("Use() of a missing profile should fail")
+	}
+	for _, name := range []string{"work", "personal"} {
+		if err := Create(name); err != nil {
+			t.Fatalf("Create(%q) error = %v", name, err)
+		}
+	}
+	if err := Create("work"); err == nil {
+		t.Error("Create() of an existing profile should fail")
+	}
+	if names, _ := List(); !slices.Equal(names, []string{"default", "personal", "work"}) {
+		t.Errorf("List() = %v, want default first then sorted", names)
+	}
+
+	if err := Use("work"); err != nil {
+		t.Fatalf("Use() error = %v", err)
+	}
+	if name, _ := Current(); name != "work" {
+		t.Errorf("Current() after Use() = %q, want work", name)
+	}
+	dir, err = GetCurrentDir()
+	if err != nil || dir != filepath.Join(home, ".tada", "profiles", "work") {
+		t.Errorf("GetCurrentDir() = %q, %v, want the work profile", dir, err)
+	}
+
+	t.Setenv(Env, "personal")
+	if name, _ := Current(); name != "personal" {
+		t.Errorf("Current() with %s = %q, want personal", Env, name)
+	}
+	SetOverride("default")
+	if name, _ := Current(); name != "default" {
+		t.Errorf("Current() with an override = %q, want default", name)
+	}
+	SetOverride("missing")
+	if _, err := GetCurrentDir(); err == nil {
+		t.Error("GetCurrentDir() of a missing profile should fail")
+	}
+	SetOverride("")
+
+	if err := Delete(Default); err == nil {
+		t.Error("Delete() of the default profile should fail")
+	}
+	if err := Delete("work"); err == nil {
+		t.Error("Delete() of the selected profile should fail")
+	}
+	if err := Use(Default); err != nil {
+		t.Fatalf("Use(default) error = %v", err)
+	}
+	if err := Delete("work"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".tada", "profiles", "work")); !os.IsNotExist(err) {
+		t.Error("Delete() should remove the profile's directory")
+	}
+	if selected, _ := Selected(); selected != Default {
+		t.Errorf("Selected() = %q, want default", selected)
+	}
+}
+
+func TestLocal(t *testing.T) {
+	home := setup(t)
+	project := filepath.Join(t.TempDir(), "api")
+	sub := filepath.Join(project, "cmd", "server")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(sub)
+
+	if _, ok := FindLocal(sub); ok {
+		t.Fatal("FindLocal() before init should find nothing")
+	}
+	if err := os.MkdirAll(filepath.Join(home, ".tada"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := FindLocal(home); ok {
+		t.Error("FindLocal() should not take ~/.tada for a project store")
+	}
+	if _, err := InitLocal(home); err == nil {
+		t.Error("InitLocal() of the home directory should fail")
+	}
+
+	store, err := InitLocal(project)
+	if err != nil {
+		t.Fatalf("InitLocal() error = %v", err)
+	}
+	if store != filepath.Join(project, LocalDir) {
+		t.Errorf("InitLocal() = %q, want %q", store, filepath.Join(project, LocalDir))
+	}
+	if _, err := os.Stat(filepath.Join(store, ".gitignore")); err != nil {
+		t.Errorf("InitLocal() should write a .gitignore: %v", err)
+	}
+	if _, err := InitLocal(project); err == nil {
+		t.Error("InitLocal() of an existing store should fail")
+	}
+
+	if got, ok := FindLocal(sub); !ok || got != store {
+		t.Errorf("FindLocal() = %q, %v, want %q", got, ok, store)
+	}
+	if dir, err := GetCurrentDir(); err != nil || dir != store {
+		t.Errorf("GetCurrentDir() = %q, %v, want the project store", dir, err)
+	}
+	if got := Label(); got != "api (local)" {
+		t.Errorf("Label() = %q, want 'api (local)'", got)
+	}
+	stores, err := Stores()
+	if err != nil || len(stores) != 2 || !stores[0].Local || stores[1].Name != Default {
+		t.Errorf("Stores() = %v, %v, want the project store then the default profile", stores, err)
+	}
+
+	SetGlobal(true)
+	if dir, err := GetCurrentDir(); err != nil || dir != filepath.Join(home, ".tada") {
+		t.Errorf("GetCurrentDir() with --global = %q, %v, want ~/.tada", dir, err)
+	}
+	if got := Label(); got != "" {
+		t.Errorf("Label() with --global = %q, want empty for the default profile", got)
+	}
+	SetGlobal(false)
+
+	SetOverride(Default)
+	if dir, err := GetCurrentDir(); err != nil || dir != filepath.Join(home, ".tada") {
+		t.Errorf("GetCurrentDir() with --profile = %q, %v, want ~/.tada", dir, err)
+	}
+}
@@ -0,0 +1,185 @@
+package prompt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/negadras/tada/internal/todo"
+	"github.com/negadras/tada/internal/watch"
+)
+
+// DefaultFormat shows the high priority, open and overdue counts, leaving out
+// the ones that are zero, e.g. "3🔴 5 open 1⚠ overdue"
+const DefaultFormat = "[{high}🔴 ][{open} open][ {overdue}⚠ overdue]"
+
+// dateLayout is the layout of due dates in the cache. Comparing them as
+// strings orders them by day.
+const dateLayout = "2006-01-02"
+
+// Summary counts the open todos. It is all a prompt needs, so it is cached
+// to avoid opening the database at every prompt.
+type Summary struct {
+	Open   int `json:"open"`
+	High   int `json:"high"`
+	Medium int `json:"medium"`
+	Low    int `json:"low"`
+	// Due holds the local due day of each open todo that has one, so that
+	// todos become overdue without the cache being refreshed
+	Due []string `json:"due,omitempty"`
+}
+
+// GetCachePath returns the path of the cache, next to the database
+func GetCachePath(dbPath string) string {
+	return filepath.Join(filepath.Dir(dbPath), "prompt.json")
+}
+
+// Summarize counts the open todos among todos
+func Summarize(todos []*todo.Todo) *Summary {
+	s := &Summary{}
+	for _, t := range todos {
+		if t.Status != todo.Open {
+			continue
+		}
+		s.Open++
+		switch t.Priority {
+		case todo.High:
+			s.High++
+		case todo.Medium:
+			s.Medium++
+		case todo.Low:
+			s.Low++
+		}
+		if t.DueAt != nil {
+			s.Due = append(s.Due, t.DueAt.Local().Format(dateLayout))
+		}
+	}
+	return s
+}
+
+// Stale reports whether the cache is missing or older than the database.
+// File times are coarse, so a cache written right after a change usually
+// has the same time as the database; that cache is up to date.
+func Stale(dbPath, cachePath string) bool {
+	return watch.Changed(dbPath, cachePath)
+}
+
+// Refresh writes the summary of the todos in db to the cache
+func Refresh(db *todo.DB, cachePath string) (*Summary, error) {
+	open := todo.Open
+	todos, err := db.List(&open, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	s := Summarize(todos)
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	// Write then rename, so that a prompt never reads half a cache
+	tmp := cachePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write prompt cache: %w", err)
+	}
+	if err := os.Rename(tmp, cachePath); err != nil {
+		return nil, fmt.Errorf("failed to write prompt cache: %w", err)
+	}
+	return s, nil
+}
+
+// Read reads the cache
+func Read(cachePath string) (*Summary, error) {
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		return nil, err
+	}
+	var s Summary
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid prompt cache: %w", err)
+	}
+	return &s, nil
+}
+
+// Values returns the value of each placeholder on the given day
+func (s *Summary) Values(now time.Time) map[string]int {
+	today := now.Format(dateLayout)
+	values := map[string]int{
+		"open":    s.Open,
+		"high":    s.High,
+		"medium":  s.Medium,
+		"low":     s.Low,
+		"overdue": 0,
+		"today":   0,
+	}
+	for _, due := range s.Due {
+		if due < today {
+			values["overdue"]++
+		} else if due == today {
+			values["today"]++
+		}
+	}
+	return values
+}
+
+// Format replaces the placeholders in format, such as {open}, with their
+// values. A part in square brackets is left out when all the placeholders
+// in it are zero.
+func Format(format string, values map[string]int) (string, error) {
+	var out, group strings.Builder
+	inGroup, groupNonZero := false, false
+
+	for i := 0; i < len(format); i++ {
+		w := &out
+		if inGroup {
+			w = &group
+		}
+
+		switch c := format[i]; c {
+		case '{':
+			end := strings.IndexByte(format[i:], '}')
+			if end < 0 {
+				return "", errors.New("unclosed {")
+			}
+			name := format[i+1 : i+end]
+			value, ok := values[name]
+			if !ok {
+				return "", fmt.Errorf("unknown placeholder {%s}", name)
+			}
+			if value != 0 {
+				groupNonZero = true
+			}
+			w.WriteString(strconv.Itoa(value))
+			i += end
+
+		case '[':
+			if inGroup {
+				return "", errors.New("nested [")
+			}
+			inGroup, groupNonZero = true, false
+			group.Reset()
+
+		case ']':
+			if !inGroup {
+				return "", errors.New("unmatched ]")
+			}
+			if groupNonZero {
+				out.WriteString(group.String())
+			}
+			inGroup = false
+
+		default:
+			w.WriteByte(c)
+		}
+	}
+
+	if inGroup {
+		return "", errors.New("unclosed [")
+	}
+	return out.String(), nil
+}
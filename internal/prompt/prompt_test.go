@@ -0,0 +1,114 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/negadras/tada/internal/todo"
+)
+
+func TestFormat(t *testing.T) {
+	values := map[string]int{"open": 5, "high": 3, "medium": 2, "low": 0, "overdue": 1, "today": 0}
+
+	tests := []struct {
+		name    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{"default", DefaultFormat, "3🔴 5 open 1⚠ overdue", false},
+		{"plain text", "todos: {open}", "todos: 5", false},
+		{"zero group left out", "{open}[ ({today} today)]", "5", false},
+		{"group kept if any is non-zero", "[{low}/{medium}]", "0/2", false},
+		{"unknown placeholder", "{later}", "", true},
+		{"unclosed placeholder", "{open", "", true},
+		{"unclosed group", "[{open}", "", true},
+		{"nested group", "[[{open}]]", "", true},
+		{"unmatched bracket", "{open}]", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Format(tt.format, values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Format() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got, _ := Format(DefaultFormat, (&Summary{}).Values(time.Now())); got != "" {
+		t.Errorf("Format(DefaultFormat) with no todos = %q, want empty", got)
+	}
+}
+
+func TestSummary_Values(t *testing.T) {
+	now := time.Date(2025, 6, 20, 15, 0, 0, 0, time.Local)
+	yesterday := now.AddDate(0, 0, -1)
+	tomorrow := now.AddDate(0, 0, 1)
+
+	s := Summarize([]*todo.Todo{
+		{Status: todo.Open, Priority: todo.High, DueAt: &yesterday},
+		{Status: todo.Open, Priority: todo.High, DueAt: &now},
+		{Status: todo.Open, Priority: todo.Low, DueAt: &tomorrow},
+		{Status: todo.Open, Priority: todo.Medium},
+		{Status: todo.Done, Priority: todo.High, DueAt: &yesterday},
+	})
+
+	want := map[string]int{"open": 4, "high": 2, "medium": 1, "low": 1, "overdue": 1, "today": 1}
+	got := s.Values(now)
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("Values()[%q] = %d, want %d", name, got[name], value)
+		}
+	}
+
+	// Without a refresh, todos become overdue as days pass
+	if got := s.Values(now.AddDate(0, 0, 2)); got["overdue"] != 3 {
+		t.Errorf("Values() two days later overdue = %d, want 3", got["overdue"])
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "todos.db")
+	cachePath := GetCachePath(dbPath)
+
+	db, err := todo.NewDB(dbPath)
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	defer db.Close()
+	db.Create("Write tests", todo.High)
+
+	if !Stale(dbPath, cachePath) {
+		t.Error("Stale() = false without a cache")
+	}
+	if _, err := Refresh(db, cachePath); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if Stale(dbPath, cachePath) {
+		t.Error("Stale() = true after Refresh()")
+	}
+
+	s, err := Read(cachePath)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if s.Open != 1 || s.High != 1 {
+		t.Errorf("Read() = %+v, want one open high priority todo", s)
+	}
+
+	// A change to the database makes the cache stale
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(dbPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if !Stale(dbPath, cachePath) {
+		t.Error("Stale() = false after the database changed")
+	}
+}
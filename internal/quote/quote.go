@@ -3,11 +3,13 @@ package quote
 import (
 	"database/sql"
 	"fmt"
-	"os"
 	"path/filepath"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/negadras/tada/internal/profile"
+	"github.com/negadras/tada/internal/replica"
+	"github.com/negadras/tada/internal/vault"
 )
 
 // Quote represents a motivational quote
@@ -18,6 +20,7 @@ type Quote struct {
 	Category  string    `json:"category"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	UID       string    `json:"uid"`
 }
 
 // Age returns how long ago the quote was created
@@ -25,21 +28,27 @@ func (q *Quote) Age() time.Duration {
 	return time.Since(q.CreatedAt)
 }
 
+// sqlTimeFormat is the layout used to store timestamps, matching CURRENT_TIMESTAMP
+const sqlTimeFormat = "2006-01-02 15:04:05"
+
 // DB handles all database operations for quotes
 type DB struct {
 	conn *sql.DB
+	// tx is the transaction the DB joined, if any
+	tx *sql.Tx
+	// node identifies this database in the clocks of changes made to it
+	node string
+	// cipher encrypts the text of quotes, or is nil if the database is not
+	// encrypted
+	cipher *vault.Cipher
 }
 
-// GetDatabasePath returns the path to the database file
+// GetDatabasePath returns the path to the database file of the current
+// profile
 func GetDatabasePath() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	tadaDir, err := profile.GetCurrentDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
-	}
-
-	tadaDir := filepath.Join(homeDir, ".tada")
-	if err := os.MkdirAll(tadaDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create .tada directory: %w", err)
+		return "", err
 	}
 
 	return filepath.Join(tadaDir, "todos.db"), nil
@@ -57,7 +66,18 @@ func NewDB(dbPath string) (*DB, error) {
 		return nil, fmt.Errorf("failed to create tables: %w", err)
 	}
 
-	return &DB{conn: db}, nil
+	node, err := replica.Node(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node ID: %w", err)
+	}
+
+	cipher, err := vault.Unlock(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &DB{conn: db, node: node, cipher: cipher}, nil
 }
 
 // createTables creates the database schema
@@ -76,18 +96,28 @@ func createTables(db *sql.DB) error {
 	CREATE INDEX IF NOT EXISTS idx_quotes_author ON quotes(author);
 	CREATE INDEX IF NOT EXISTS idx_quotes_category ON quotes(category);
 	CREATE INDEX IF NOT EXISTS idx_quotes_created_at ON quotes(created_at);
-	`
+	` + replica.Schema
+
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
 
-	_, err := db.Exec(schema)
+	// Migrate: add a stable unique identifier, used to match quotes when
+	// merging databases, and give one to existing quotes
+	_, _ = db.Exec(`ALTER TABLE quotes ADD COLUMN uid TEXT NOT NULL DEFAULT ''`)
+	if _, err := db.Exec(`UPDATE quotes SET uid = ` + replica.SQLNewUID + ` WHERE uid = ''`); err != nil {
+		return err
+	}
+	_, err := db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_quotes_uid ON quotes(uid)`)
 	return err
 }
 
 // Create creates a new quote
 func (db *DB) Create(text, author, category string) (*Quote, error) {
-	result, err := db.conn.Exec(`
-		INSERT INTO quotes (text, author, category)
-		VALUES (?, ?, ?)
-	`, text, author, category)
+	result, err := db.q().Exec(`
+		INSERT INTO quotes (text, author, category, uid)
+		VALUES (?, ?, ?, ?)
+	`, db.cipher.Seal(text), author, category, replica.NewUID())
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create quote: %w", err)
@@ -101,19 +131,201 @@ func (db *DB) Create(text, author, category string) (*Quote, error) {
 	return db.Get(int(id))
 }
 
-// Get retrieves a quote by ID
-func (db *DB) Get(id int) (*Quote, error) {
-	row := db.conn.QueryRow(`
-		SELECT id, text, author, category, created_at, updated_at
-		FROM quotes WHERE id = ?
-	`, id)
+// Insert stores quotes with all of their fields, including timestamps, in a
+// single transaction. Quotes with an ID or UID keep it and the others are
+// given a new one.
+func (db *DB) Insert(quotes []*Quote) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		return db.insertQuotes(tx, quotes)
+	})
+}
+
+// Replace deletes every quote and stores the given quotes in their place, in
+// a single transaction
+func (db *DB) Replace(quotes []*Quote) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		if err := replica.Forget(tx, "quotes"); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM quotes`); err != nil {
+			return fmt.Errorf("failed to delete quotes: %w", err)
+		}
+		return db.insertQuotes(tx, quotes)
+	})
+}
+
+// Load brings the quotes in line with the given quotes, the state of
+// another store such as the git repository, in a single transaction, like
+// todo.DB.Load: quotes are matched by UID and updated where they differ,
+// missing ones are added and the others are deleted with a tombstone
+func (db *DB) Load(quotes []*Quote) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		db := db.Join(tx)
+		existing, err := db.List(nil, nil)
+		if err != nil {
+			return err
+		}
+
+		keep := make(map[string]bool, len(quotes))
+		for _, q := range quotes {
+			keep[q.UID] = true
+		}
+		byUID := make(map[string]*Quote, len(existing))
+		usedIDs := map[int]bool{}
+		for _, q := range existing {
+			if !keep[q.UID] {
+				if err := db.Delete(q.ID); err != nil {
+					return err
+				}
+				continue
+			}
+			byUID[q.UID] = q
+			usedIDs[q.ID] = true
+		}
+
+		var added []*Quote
+		for _, q := range quotes {
+			old, ok := byUID[q.UID]
+			if !ok {
+				if usedIDs[q.ID] {
+					// The ID was taken by another quote here
+					q.ID = 0
+				}
+				added = append(added, q)
+				continue
+			}
+			if q.Text == old.Text && q.Author == old.Author && q.Category == old.Category {
+				continue
+			}
+			if err := db.Update(old.ID, q.Text, q.Author, q.Category); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`UPDATE quotes SET updated_at = ? WHERE id = ?`, q.UpdatedAt.UTC().Format(sqlTimeFormat), old.ID); err != nil {
+				return err
+			}
+		}
+		return db.insertQuotes(tx, added)
+	})
+}
+
+// Join returns the DB running its statements in tx, a transaction on the
+// same database file such as the one of todo.DB.Transaction, so that todos
+// and quotes change together
+func (db *DB) Join(tx *sql.Tx) *DB {
+	joined := *db
+	joined.tx = tx
+	return &joined
+}
+
+// querier runs statements on the connection or on a joined transaction
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// q returns where the DB's statements run
+func (db *DB) q() querier {
+	if db.tx != nil {
+		return db.tx
+	}
+	return db.conn
+}
+
+// withTx runs fn in a transaction, committing if it returns nil, or in the
+// joined transaction
+func (db *DB) withTx(fn func(tx *sql.Tx) error) error {
+	if db.tx != nil {
+		return fn(db.tx)
+	}
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// insertQuotes inserts quotes inside a transaction and sets their IDs
+func (db *DB) insertQuotes(tx *sql.Tx, quotes []*Quote) error {
+	stmt, err := tx.Prepare(`
+		INSERT INTO quotes (id, text, author, category, created_at, updated_at, uid)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, q := range quotes {
+		created, updated := q.CreatedAt, q.UpdatedAt
+		if created.IsZero() {
+			created = time.Now()
+		}
+		if updated.IsZero() {
+			updated = created
+		}
+
+		var id interface{}
+		if q.ID != 0 {
+			id = q.ID
+		}
+		if q.UID == "" {
+			q.UID = replica.NewUID()
+		}
 
+		result, err := stmt.Exec(
+			id, db.cipher.Seal(q.Text), q.Author, q.Category,
+			created.UTC().Format(sqlTimeFormat), updated.UTC().Format(sqlTimeFormat), q.UID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert quote %q: %w", q.Text, err)
+		}
+
+		newID, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get quote ID: %w", err)
+		}
+		q.ID = int(newID)
+	}
+
+	return nil
+}
+
+// scanQuote reads a quote from a row selected with id, text, author,
+// category, created_at, updated_at and uid
+func (db *DB) scanQuote(row interface{ Scan(...interface{}) error }) (*Quote, error) {
 	quote := &Quote{}
 	err := row.Scan(
 		&quote.ID, &quote.Text, &quote.Author, &quote.Category,
-		&quote.CreatedAt, &quote.UpdatedAt,
+		&quote.CreatedAt, &quote.UpdatedAt, &quote.UID,
 	)
+	if err != nil {
+		return nil, err
+	}
 
+	if quote.Text, err = db.cipher.Open(quote.Text); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// Get retrieves a quote by ID
+func (db *DB) Get(id int) (*Quote, error) {
+	row := db.q().QueryRow(`
+		SELECT id, text, author, category, created_at, updated_at, uid
+		FROM quotes WHERE id = ?
+	`, id)
+
+	quote, err := db.scanQuote(row)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get quote: %w", err)
 	}
@@ -124,7 +336,7 @@ func (db *DB) Get(id int) (*Quote, error) {
 // List retrieves all quotes with optional filtering
 func (db *DB) List(author, category *string) ([]*Quote, error) {
 	query := `
-		SELECT id, text, author, category, created_at, updated_at
+		SELECT id, text, author, category, created_at, updated_at, uid
 		FROM quotes WHERE 1=1
 	`
 	args := []interface{}{}
@@ -141,7 +353,7 @@ func (db *DB) List(author, category *string) ([]*Quote, error) {
 
 	query += " ORDER BY created_at DESC"
 
-	rows, err := db.conn.Query(query, args...)
+	rows, err := db.q().Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list quotes: %w", err)
 	}
@@ -149,12 +361,7 @@ func (db *DB) List(author, category *string) ([]*Quote, error) {
 
 	var quotes []*Quote
 	for rows.Next() {
-		quote := &Quote{}
-		err := rows.Scan(
-			&quote.ID, &quote.Text, &quote.Author, &quote.Category,
-			&quote.CreatedAt, &quote.UpdatedAt,
-		)
-
+		quote, err := db.scanQuote(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan quote: %w", err)
 		}
@@ -167,17 +374,12 @@ func (db *DB) List(author, category *string) ([]*Quote, error) {
 
 // GetRandom retrieves a random quote
 func (db *DB) GetRandom() (*Quote, error) {
-	row := db.conn.QueryRow(`
-		SELECT id, text, author, category, created_at, updated_at
+	row := db.q().QueryRow(`
+		SELECT id, text, author, category, created_at, updated_at, uid
 		FROM quotes ORDER BY RANDOM() LIMIT 1
 	`)
 
-	quote := &Quote{}
-	err := row.Scan(
-		&quote.ID, &quote.Text, &quote.Author, &quote.Category,
-		&quote.CreatedAt, &quote.UpdatedAt,
-	)
-
+	quote, err := db.scanQuote(row)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get random quote: %w", err)
 	}
@@ -187,19 +389,42 @@ func (db *DB) GetRandom() (*Quote, error) {
 
 // Update updates a quote
 func (db *DB) Update(id int, text, author, category string) error {
-	_, err := db.conn.Exec(`
-		UPDATE quotes 
-		SET text = ?, author = ?, category = ?, updated_at = CURRENT_TIMESTAMP
-		WHERE id = ?
-	`, text, author, category, id)
+	return db.withTx(func(tx *sql.Tx) error {
+		if db.cipher != nil {
+			// Keep the stored text if it is unchanged
+			var stored string
+			if err := tx.QueryRow(`SELECT text FROM quotes WHERE id = ?`, id).Scan(&stored); err != nil && err != sql.ErrNoRows {
+				return err
+			}
+			text = db.cipher.Reseal(stored, text)
+		}
 
-	return err
+		for _, f := range []struct{ name, value string }{{"text", text}, {"author", author}, {"category", category}} {
+			if err := replica.Touch(tx, db.node, "quotes", id, f.name, f.name, f.value); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.Exec(`
+			UPDATE quotes 
+			SET text = ?, author = ?, category = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, text, author, category, id)
+		return err
+	})
 }
 
-// Delete deletes a quote by ID
+// Delete deletes a quote by ID, leaving a tombstone so that merging with
+// another database deletes it there too
 func (db *DB) Delete(id int) error {
-	_, err := db.conn.Exec("DELETE FROM quotes WHERE id = ?", id)
-	return err
+	return db.withTx(func(tx *sql.Tx) error {
+		if err := replica.Bury(tx, db.node, "quotes", "quote", id); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec("DELETE FROM quotes WHERE id = ?", id)
+		return err
+	})
 }
 
 // Close closes the database connection
@@ -264,6 +264,37 @@ func TestDB_Integration(t *testing.T) {
 			t.Errorf("Delete() with non-existent ID should not return error, got %v", err)
 		}
 	})
+
+	t.Run("Replace quotes", func(t *testing.T) {
+		created := time.Date(2024, 4, 20, 9, 0, 0, 0, time.UTC)
+		quotes := []*Quote{
+			{ID: 42, Text: "Restored quote", Author: "Someone", Category: "backup", CreatedAt: created, UpdatedAt: created},
+			{Text: "Quote without ID"},
+		}
+
+		if err := db.Replace(quotes); err != nil {
+			t.Fatalf("Replace() error = %v", err)
+		}
+
+		all, err := db.List(nil, nil)
+		if err != nil {
+			t.Fatalf("List() after Replace() error = %v", err)
+		}
+		if len(all) != 2 {
+			t.Fatalf("Replace() left %d quotes, want 2", len(all))
+		}
+
+		restored, err := db.Get(42)
+		if err != nil {
+			t.Fatalf("Get() after Replace() error = %v", err)
+		}
+		if restored.Text != "Restored quote" || !restored.CreatedAt.Equal(created) {
+			t.Errorf("Replace() stored %+v", restored)
+		}
+		if quotes[1].ID == 0 {
+			t.Error("Replace() should assign an ID to quotes without one")
+		}
+	})
 }
 
 func TestMigrateHardcodedQuotes(t *testing.T) {
@@ -367,3 +398,30 @@ func TestParseQuote(t *testing.T) {
 		})
 	}
 }
+
+func TestDB_Join(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	defer db.Close()
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Join(tx).Insert([]*Quote{{Text: "Rolled back", Author: "Test"}}); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	quotes, err := db.List(nil, nil)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(quotes) != 0 {
+		t.Errorf("List() = %d quotes, want none after the joined transaction rolled back", len(quotes))
+	}
+}
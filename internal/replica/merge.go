@@ -0,0 +1,598 @@
+package replica
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/negadras/tada/internal/vault"
+)
+
+// field is a part of a record that is merged as a whole, such as a todo's
+// status together with its completion time
+type field struct {
+	name    string
+	columns []string
+}
+
+// table describes a table whose rows are merged
+type table struct {
+	name   string
+	kind   string
+	label  string
+	fields []field
+}
+
+var tables = []table{
+	{name: "todos", kind: "todo", label: "description", fields: []field{
+		{"description", []string{"description"}},
+		{"priority", []string{"priority"}},
+		{"status", []string{"status", "completed_at"}},
+		{"tag", []string{"tag"}},
+		{"due", []string{"due_at"}},
+		{"notes", []string{"notes"}},
+	}},
+	{name: "quotes", kind: "quote", label: "text", fields: []field{
+		{"text", []string{"text"}},
+		{"author", []string{"author"}},
+		{"category", []string{"category"}},
+	}},
+}
+
+// record is a row with the clock of each of its fields
+type record struct {
+	uid     string
+	created sql.NullString
+	updated sql.NullString
+	values  map[string][]sql.NullString
+	clocks  map[string]string
+}
+
+// newest returns the clock of the record's latest change
+func (r *record) newest() string {
+	newest := ""
+	for _, c := range r.clocks {
+		if c > newest {
+			newest = c
+		}
+	}
+	return newest
+}
+
+type tombstone struct {
+	kind  string
+	clock string
+}
+
+// snapshot is the content of a database, decrypted
+type snapshot struct {
+	node    string
+	cipher  *vault.Cipher
+	synced  map[string]string
+	records map[string]map[string]*record
+	tombs   map[string]tombstone
+}
+
+// Conflict is a todo or quote changed in both databases since they were last
+// merged, where the later change was kept
+type Conflict struct {
+	Kind  string
+	Label string
+	// Field is the field changed on both sides, or "deleted" when one side
+	// deleted what the other changed
+	Field string
+	// Local and Other are the values in each database; the first column of
+	// the field, or empty for the side that deleted it
+	Local string
+	Other string
+	// KeptLocal reports whether the local value won
+	KeptLocal bool
+}
+
+// Changes counts the todos and quotes a merge added, updated and deleted in
+// one database
+type Changes struct {
+	Added   int
+	Updated int
+	Deleted int
+}
+
+// Result describes a merge
+type Result struct {
+	Local     Changes
+	Other     Changes
+	Conflicts []Conflict
+}
+
+// Merge merges two databases in both directions, so both end up with the
+// same todos and quotes. Records are matched by UID. For each field, the
+// most recent change wins, wherever it was made. A deletion wins over the
+// changes made before it, and a change made after a deletion brings the
+// record back. Changes to the same field on both sides since the last merge
+// are reported as conflicts. With dryRun nothing is written.
+func Merge(local, other *sql.DB, dryRun bool) (*Result, error) {
+	a, err := load(local)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read this database: %w", err)
+	}
+	b, err := load(other)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the other database: %w", err)
+	}
+	if a.node == b.node {
+		return nil, fmt.Errorf("both databases have the same node ID; a database file copied from another machine cannot be merged with it (start from 'tada export' and 'tada import' instead)")
+	}
+
+	// Changes made after the last merge on both sides are conflicts. If the
+	// databases disagree on when that was, the earlier time is used.
+	last := a.synced[b.node]
+	if s := b.synced[a.node]; s < last {
+		last = s
+	}
+
+	result := &Result{}
+	records := map[string]map[string]*record{}
+	tombs := map[string]tombstone{}
+	for uid, t := range a.tombs {
+		tombs[uid] = t
+	}
+	for uid, t := range b.tombs {
+		if t.clock > tombs[uid].clock {
+			tombs[uid] = t
+		}
+	}
+
+	for _, tb := range tables {
+		merged := map[string]*record{}
+		ra, rb := a.records[tb.name], b.records[tb.name]
+
+		for _, uid := range unionKeys(ra, rb) {
+			la, lb := ra[uid], rb[uid]
+
+			var m *record
+			switch {
+			case la != nil && lb != nil:
+				m = mergeRecord(tb, la, lb, last, result)
+			case la != nil:
+				m = la
+			default:
+				m = lb
+			}
+
+			if t, ok := tombs[uid]; ok {
+				newest := m.newest()
+				if t.clock > newest {
+					// Deleted after its last change. It is a conflict if the
+					// side that still has it changed it since the last merge.
+					if newest > last {
+						result.Conflicts = append(result.Conflicts, deleted(tb, m, la != nil, true))
+					}
+					continue
+				}
+				// Changed after it was deleted, so it comes back
+				if t.clock > last {
+					result.Conflicts = append(result.Conflicts, deleted(tb, m, la != nil, false))
+				}
+				delete(tombs, uid)
+			}
+			merged[uid] = m
+		}
+		records[tb.name] = merged
+	}
+
+	syncClock := Now(a.node)
+	if result.Local, err = apply(local, a, records, tombs, b.node, syncClock, dryRun); err != nil {
+		return nil, fmt.Errorf("failed to update this database: %w", err)
+	}
+	if result.Other, err = apply(other, b, records, tombs, a.node, syncClock, dryRun); err != nil {
+		return nil, fmt.Errorf("failed to update the other database: %w", err)
+	}
+
+	return result, nil
+}
+
+// mergeRecord merges two versions of a record field by field
+func mergeRecord(tb table, a, b *record, last string, result *Result) *record {
+	m := &record{
+		uid:     a.uid,
+		created: a.created,
+		updated: a.updated,
+		values:  map[string][]sql.NullString{},
+		clocks:  map[string]string{},
+	}
+	if b.created.Valid && (!a.created.Valid || b.created.String < a.created.String) {
+		m.created = b.created
+	}
+	if b.updated.String > a.updated.String {
+		m.updated = b.updated
+	}
+
+	for _, f := range tb.fields {
+		va, vb := a.values[f.name], b.values[f.name]
+		ca, cb := a.clocks[f.name], b.clocks[f.name]
+
+		keepA := ca > cb || ca == cb && join(va) >= join(vb)
+		if keepA {
+			m.values[f.name], m.clocks[f.name] = va, ca
+		} else {
+			m.values[f.name], m.clocks[f.name] = vb, cb
+		}
+
+		if join(va) != join(vb) && ca > last && cb > last {
+			result.Conflicts = append(result.Conflicts, Conflict{
+				Kind:      tb.kind,
+				Label:     m.values[tb.label][0].String,
+				Field:     f.name,
+				Local:     va[0].String,
+				Other:     vb[0].String,
+				KeptLocal: keepA,
+			})
+		}
+	}
+	return m
+}
+
+// deleted returns the conflict of a record deleted on one side and changed
+// on the other. local reports whether the local side still has it and
+// removed whether the deletion won.
+func deleted(tb table, r *record, local, removed bool) Conflict {
+	c := Conflict{Kind: tb.kind, Label: r.values[tb.label][0].String, Field: "deleted", KeptLocal: local != removed}
+	if local {
+		c.Local = c.Label
+	} else {
+		c.Other = c.Label
+	}
+	return c
+}
+
+// load reads the records, clocks and tombstones of a database
+func load(db *sql.DB) (*snapshot, error) {
+	node, err := Node(db)
+	if err != nil {
+		return nil, err
+	}
+	cipher, err := vault.Unlock(db)
+	if err != nil {
+		return nil, err
+	}
+	s := &snapshot{
+		node:    node,
+		cipher:  cipher,
+		synced:  map[string]string{},
+		records: map[string]map[string]*record{},
+		tombs:   map[string]tombstone{},
+	}
+
+	rows, err := db.Query(`SELECT key, value FROM sync_meta WHERE key LIKE 'synced:%'`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		s.synced[strings.TrimPrefix(key, "synced:")] = value
+	}
+	rows.Close()
+
+	clocks := map[string]map[string]string{}
+	rows, err = db.Query(`SELECT uid, field, clock FROM field_clocks`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var uid, f, clock string
+		if err := rows.Scan(&uid, &f, &clock); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if clocks[uid] == nil {
+			clocks[uid] = map[string]string{}
+		}
+		clocks[uid][f] = clock
+	}
+	rows.Close()
+
+	rows, err = db.Query(`SELECT uid, kind, clock FROM tombstones`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var uid string
+		var t tombstone
+		if err := rows.Scan(&uid, &t.kind, &t.clock); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		s.tombs[uid] = t
+	}
+	rows.Close()
+
+	for _, tb := range tables {
+		records, err := loadTable(db, tb, clocks, cipher)
+		if err != nil {
+			return nil, err
+		}
+		s.records[tb.name] = records
+	}
+	return s, nil
+}
+
+// loadTable reads the rows of a table as stored, decrypting them with
+// cipher. Fields without a recorded clock were last changed when the row was
+// last updated.
+func loadTable(db *sql.DB, tb table, clocks map[string]map[string]string, cipher *vault.Cipher) (map[string]*record, error) {
+	selects := []string{"uid", "CAST(created_at AS TEXT)", "CAST(updated_at AS TEXT)"}
+	for _, f := range tb.fields {
+		for _, c := range f.columns {
+			selects = append(selects, "CAST("+c+" AS TEXT)")
+		}
+	}
+
+	rows, err := db.Query(`SELECT ` + strings.Join(selects, ", ") + ` FROM ` + tb.name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := map[string]*record{}
+	for rows.Next() {
+		r := &record{values: map[string][]sql.NullString{}, clocks: map[string]string{}}
+		dest := []interface{}{&r.uid, &r.created, &r.updated}
+		for _, f := range tb.fields {
+			values := make([]sql.NullString, len(f.columns))
+			for i := range values {
+				dest = append(dest, &values[i])
+			}
+			r.values[f.name] = values
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		for _, f := range tb.fields {
+			for i, c := range f.columns {
+				v := &r.values[f.name][i]
+				if !v.Valid || !encrypted(tb, c) {
+					continue
+				}
+				var err error
+				if v.String, err = cipher.Open(v.String); err != nil {
+					return nil, err
+				}
+			}
+			if c, ok := clocks[r.uid][f.name]; ok {
+				r.clocks[f.name] = c
+			} else {
+				r.clocks[f.name] = At(parseTime(r.updated.String))
+			}
+		}
+		records[r.uid] = r
+	}
+	return records, rows.Err()
+}
+
+// apply changes a database to hold the merged records and tombstones, and
+// returns what changed
+func apply(db *sql.DB, s *snapshot, records map[string]map[string]*record, tombs map[string]tombstone, peer, syncClock string, dryRun bool) (Changes, error) {
+	var changes Changes
+
+	tx, err := db.Begin()
+	if err != nil {
+		return changes, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, tb := range tables {
+		current := s.records[tb.name]
+		merged := records[tb.name]
+
+		for _, uid := range sortedKeys(merged) {
+			m, r := merged[uid], current[uid]
+			switch {
+			case r == nil:
+				if err := insertRecord(tx, tb, m, s.cipher); err != nil {
+					return changes, err
+				}
+				changes.Added++
+			default:
+				changed, err := updateRecord(tx, tb, r, m, s.cipher)
+				if err != nil {
+					return changes, err
+				}
+				if changed {
+					changes.Updated++
+				}
+			}
+			if err := saveClocks(tx, tb, r, m); err != nil {
+				return changes, err
+			}
+		}
+
+		for _, uid := range sortedKeys(current) {
+			if merged[uid] != nil {
+				continue
+			}
+			if _, err := tx.Exec(`DELETE FROM `+tb.name+` WHERE uid = ?`, uid); err != nil {
+				return changes, err
+			}
+			if _, err := tx.Exec(`DELETE FROM field_clocks WHERE uid = ?`, uid); err != nil {
+				return changes, err
+			}
+			changes.Deleted++
+		}
+	}
+
+	for uid, t := range tombs {
+		if s.tombs[uid] == t {
+			continue
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO tombstones (uid, kind, clock) VALUES (?, ?, ?)
+			ON CONFLICT (uid) DO UPDATE SET kind = excluded.kind, clock = excluded.clock
+		`, uid, t.kind, t.clock); err != nil {
+			return changes, err
+		}
+	}
+	for uid := range s.tombs {
+		if _, ok := tombs[uid]; !ok {
+			if _, err := tx.Exec(`DELETE FROM tombstones WHERE uid = ?`, uid); err != nil {
+				return changes, err
+			}
+		}
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO sync_meta (key, value) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value
+	`, "synced:"+peer, syncClock); err != nil {
+		return changes, err
+	}
+
+	if dryRun {
+		return changes, nil
+	}
+	return changes, tx.Commit()
+}
+
+// insertRecord inserts a record that only the other database had
+func insertRecord(tx *sql.Tx, tb table, m *record, cipher *vault.Cipher) error {
+	columns := []string{"uid", "created_at", "updated_at"}
+	args := []interface{}{m.uid, nullable(m.created), nullable(m.updated)}
+	for _, f := range tb.fields {
+		for i, c := range f.columns {
+			columns = append(columns, c)
+			args = append(args, sealed(tb, c, m.values[f.name][i], cipher))
+		}
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	_, err := tx.Exec(`INSERT INTO `+tb.name+` (`+strings.Join(columns, ", ")+`) VALUES (`+placeholders+`)`, args...)
+	return err
+}
+
+// updateRecord writes the fields whose merged value differs from the
+// stored one and reports whether there were any
+func updateRecord(tx *sql.Tx, tb table, r, m *record, cipher *vault.Cipher) (bool, error) {
+	var sets []string
+	var args []interface{}
+	for _, f := range tb.fields {
+		if join(r.values[f.name]) == join(m.values[f.name]) {
+			continue
+		}
+		for i, c := range f.columns {
+			sets = append(sets, c+" = ?")
+			args = append(args, sealed(tb, c, m.values[f.name][i], cipher))
+		}
+	}
+	if len(sets) == 0 {
+		return false, nil
+	}
+
+	sets = append(sets, "updated_at = ?")
+	args = append(args, nullable(m.updated), r.uid)
+	_, err := tx.Exec(`UPDATE `+tb.name+` SET `+strings.Join(sets, ", ")+` WHERE uid = ?`, args...)
+	return true, err
+}
+
+// saveClocks records the merged clock of each field; r is the stored
+// record, or nil if it was just inserted
+func saveClocks(tx *sql.Tx, tb table, r, m *record) error {
+	for _, f := range tb.fields {
+		if r != nil && r.clocks[f.name] == m.clocks[f.name] {
+			continue
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO field_clocks (uid, field, clock) VALUES (?, ?, ?)
+			ON CONFLICT (uid, field) DO UPDATE SET clock = excluded.clock
+		`, m.uid, f.name, m.clocks[f.name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func nullable(s sql.NullString) interface{} {
+	if !s.Valid {
+		return nil
+	}
+	return s.String
+}
+
+// encrypted reports whether a column is encrypted in encrypted databases
+func encrypted(tb table, column string) bool {
+	return slices.Contains(vault.Columns[tb.name], column)
+}
+
+// sealed returns the value to store in a column, encrypted if need be
+func sealed(tb table, column string, s sql.NullString, cipher *vault.Cipher) interface{} {
+	if !s.Valid || !encrypted(tb, column) {
+		return nullable(s)
+	}
+	return cipher.Seal(s.String)
+}
+
+// join returns a comparable form of a field's values
+func join(values []sql.NullString) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		if v.Valid {
+			parts[i] = "=" + v.String
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// parseTime parses a stored timestamp
+func parseTime(s string) time.Time {
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04:05.999999999-07:00", time.RFC3339Nano} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func unionKeys(a, b map[string]*record) []string {
+	keys := sortedKeys(a)
+	for _, k := range sortedKeys(b) {
+		if a[k] == nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeys(m map[string]*record) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// MergeFiles merges the database files at localPath and otherPath. Both
+// must already have the current schema.
+func MergeFiles(localPath, otherPath string, dryRun bool) (*Result, error) {
+	local, err := sql.Open("sqlite3", localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer local.Close()
+
+	other, err := sql.Open("sqlite3", otherPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer other.Close()
+
+	return Merge(local, other, dryRun)
+}
@@ -0,0 +1,331 @@
+package replica_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/replica"
+	"github.com/negadras/tada/internal/todo"
+	"github.com/negadras/tada/internal/vault"
+)
+
+// openPair creates two empty databases, as on two machines
+func openPair(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "laptop.db"), filepath.Join(dir, "workstation.db")}
+	for _, path := range paths {
+		todoDB, err := todo.NewDB(path)
+		if err != nil {
+			t.Fatalf("todo.NewDB() error = %v", err)
+		}
+		todoDB.Close()
+		quoteDB, err := quote.NewDB(path)
+		if err != nil {
+			t.Fatalf("quote.NewDB() error = %v", err)
+		}
+		quoteDB.Close()
+	}
+	return paths[0], paths[1]
+}
+
+func withTodos(t *testing.T, path string, fn func(db *todo.DB)) {
+	t.Helper()
+	db, err := todo.NewDB(path)
+	if err != nil {
+		t.Fatalf("todo.NewDB() error = %v", err)
+	}
+	defer db.Close()
+	fn(db)
+}
+
+func findByUID(t *testing.T, path, uid string) *todo.Todo {
+	t.Helper()
+	var found *todo.Todo
+	withTodos(t, path, func(db *todo.DB) {
+		todos, err := db.Find(nil)
+		if err != nil {
+			t.Fatalf("Find() error = %v", err)
+		}
+		for _, td := range todos {
+			if td.UID == uid {
+				found = td
+			}
+		}
+	})
+	return found
+}
+
+func merge(t *testing.T, laptop, workstation string) *replica.Result {
+	t.Helper()
+	result, err := replica.MergeFiles(laptop, workstation, false)
+	if err != nil {
+		t.Fatalf("MergeFiles() error = %v", err)
+	}
+	return result
+}
+
+func TestMerge(t *testing.T) {
+	laptop, workstation := openPair(t)
+
+	var fix, docs *todo.Todo
+	withTodos(t, laptop, func(db *todo.DB) {
+		fix, _ = db.Create("Fix login bug", todo.High)
+		docs, _ = db.Create("Write docs", todo.Medium)
+	})
+	withTodos(t, workstation, func(db *todo.DB) {
+		db.Create("Workstation task", todo.Low)
+	})
+	quoteDB, _ := quote.NewDB(workstation)
+	q, _ := quoteDB.Create("Stay hungry", "Steve Jobs", "")
+	quoteDB.Close()
+
+	result := merge(t, laptop, workstation)
+	if result.Local.Added != 2 || result.Other.Added != 2 || len(result.Conflicts) != 0 {
+		t.Fatalf("first merge = %+v", result)
+	}
+
+	quoteDB, _ = quote.NewDB(laptop)
+	quotes, _ := quoteDB.List(nil, nil)
+	quoteDB.Close()
+	if len(quotes) != 1 || quotes[0].UID != q.UID {
+		t.Errorf("laptop quotes = %+v, want the workstation's quote", quotes)
+	}
+
+	t.Run("fields changed on different sides are both kept", func(t *testing.T) {
+		withTodos(t, laptop, func(db *todo.DB) { db.UpdatePriority(fix.ID, todo.Low) })
+		other := findByUID(t, workstation, fix.UID)
+		withTodos(t, workstation, func(db *todo.DB) { db.UpdateStatus(other.ID, todo.Done) })
+
+		result := merge(t, laptop, workstation)
+		if len(result.Conflicts) != 0 {
+			t.Errorf("Conflicts = %+v, want none", result.Conflicts)
+		}
+		for _, path := range []string{laptop, workstation} {
+			got := findByUID(t, path, fix.UID)
+			if got.Priority != todo.Low || got.Status != todo.Done {
+				t.Errorf("%s: todo = %v %v, want LOW and DONE", filepath.Base(path), got.Priority, got.Status)
+			}
+		}
+	})
+
+	t.Run("same field changed on both sides is a conflict", func(t *testing.T) {
+		withTodos(t, laptop, func(db *todo.DB) { db.UpdateDescription(docs.ID, "Write the docs") })
+		time.Sleep(time.Millisecond)
+		other := findByUID(t, workstation, docs.UID)
+		withTodos(t, workstation, func(db *todo.DB) { db.UpdateDescription(other.ID, "Write the README") })
+
+		result := merge(t, laptop, workstation)
+		if len(result.Conflicts) != 1 {
+			t.Fatalf("Conflicts = %+v, want one", result.Conflicts)
+		}
+		c := result.Conflicts[0]
+		if c.Field != "description" || c.KeptLocal || c.Local != "Write the docs" || c.Other != "Write the README" {
+			t.Errorf("Conflict = %+v", c)
+		}
+		if got := findByUID(t, laptop, docs.UID); got.Description != "Write the README" {
+			t.Errorf("Description = %q, want the later change", got.Description)
+		}
+	})
+
+	t.Run("deletes are carried over", func(t *testing.T) {
+		withTodos(t, laptop, func(db *todo.DB) { db.Delete(fix.ID) })
+
+		result := merge(t, laptop, workstation)
+		if result.Other.Deleted != 1 || len(result.Conflicts) != 0 {
+			t.Errorf("merge = %+v, want one deletion on the other side", result)
+		}
+		if got := findByUID(t, workstation, fix.UID); got != nil {
+			t.Errorf("deleted todo still on the workstation: %+v", got)
+		}
+
+		// Merging again changes nothing
+		again := merge(t, laptop, workstation)
+		if again.Local != (replica.Changes{}) || again.Other != (replica.Changes{}) {
+			t.Errorf("second merge = %+v, want no changes", again)
+		}
+	})
+
+	t.Run("dry run writes nothing", func(t *testing.T) {
+		withTodos(t, laptop, func(db *todo.DB) { db.Create("Not yet merged", todo.Medium) })
+
+		result, err := replica.MergeFiles(laptop, workstation, true)
+		if err != nil {
+			t.Fatalf("MergeFiles() error = %v", err)
+		}
+		if result.Other.Added != 1 {
+			t.Errorf("dry run = %+v, want one todo to add", result)
+		}
+		withTodos(t, workstation, func(db *todo.DB) {
+			todos, _ := db.Find(nil)
+			for _, td := range todos {
+				if td.Description == "Not yet merged" {
+					t.Error("dry run added the todo")
+				}
+			}
+		})
+	})
+}
+
+func TestMerge_Load(t *testing.T) {
+	laptop, workstation := openPair(t)
+
+	var kept, removed *todo.Todo
+	withTodos(t, laptop, func(db *todo.DB) {
+		kept, _ = db.Create("Kept", todo.Medium)
+		removed, _ = db.Create("Removed", todo.Medium)
+	})
+	merge(t, laptop, workstation)
+
+	// Loading a state without a todo, as from the git repository, deletes it
+	withTodos(t, laptop, func(db *todo.DB) {
+		if err := db.Load([]*todo.Todo{kept}); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+	})
+	result := merge(t, laptop, workstation)
+	if result.Other.Deleted != 1 || result.Local.Added != 0 {
+		t.Errorf("merge = %+v, want the removed todo deleted on the other side", result)
+	}
+	if got := findByUID(t, laptop, removed.UID); got != nil {
+		t.Errorf("removed todo came back: %+v", got)
+	}
+	if got := findByUID(t, workstation, kept.UID); got == nil {
+		t.Error("kept todo is missing on the workstation")
+	}
+}
+
+func TestMerge_LoadKeepsClocks(t *testing.T) {
+	laptop, workstation := openPair(t)
+
+	var shared *todo.Todo
+	withTodos(t, laptop, func(db *todo.DB) {
+		shared, _ = db.Create("Shared", todo.Low)
+	})
+	merge(t, laptop, workstation)
+
+	withTodos(t, workstation, func(db *todo.DB) {
+		id := findByUID(t, workstation, shared.UID).ID
+		if err := db.UpdatePriority(id, todo.High); err != nil {
+			t.Fatal(err)
+		}
+	})
+	withTodos(t, laptop, func(db *todo.DB) {
+		if err := db.UpdateDescription(shared.ID, "Shared, renamed"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	before := countClocks(t, laptop)
+
+	// Load the laptop's own state back with new notes, as git init and
+	// git pull do
+	withTodos(t, laptop, func(db *todo.DB) {
+		todos, _ := db.Find(nil)
+		todos[0].Notes = "From git"
+		if err := db.Load(todos); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+	})
+	if after := countClocks(t, laptop); after != before+1 {
+		t.Errorf("field clocks = %d after Load, want %d kept and one for the notes", after, before)
+	}
+
+	result := merge(t, laptop, workstation)
+	if len(result.Conflicts) != 0 {
+		t.Errorf("Conflicts = %+v, want none", result.Conflicts)
+	}
+	for _, path := range []string{laptop, workstation} {
+		got := findByUID(t, path, shared.UID)
+		if got.Priority != todo.High || got.Description != "Shared, renamed" || got.Notes != "From git" {
+			t.Errorf("%s: todo = %+v, want every change kept", filepath.Base(path), got)
+		}
+	}
+}
+
+// countClocks returns how many field clocks a database has
+func countClocks(t *testing.T, path string) int {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM field_clocks`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestMerge_SameNode(t *testing.T) {
+	laptop, _ := openPair(t)
+
+	_, err := replica.MergeFiles(laptop, laptop, false)
+	if err == nil || !strings.Contains(err.Error(), "same node ID") {
+		t.Errorf("MergeFiles() error = %v, want a same node error", err)
+	}
+}
+
+func TestMerge_Encrypted(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(vault.PassphraseEnv, "correct horse")
+	laptop, workstation := openPair(t)
+
+	var uid string
+	withTodos(t, laptop, func(db *todo.DB) {
+		created, err := db.Create("Write up the incident", todo.High, "oncall")
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		uid = created.UID
+	})
+	for _, path := range []string{laptop, workstation} {
+		conn, err := sql.Open("sqlite3", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := vault.Enable(conn, "correct horse"); err != nil {
+			t.Fatalf("vault.Enable() error = %v", err)
+		}
+		conn.Close()
+	}
+
+	result := merge(t, laptop, workstation)
+	if result.Other.Added != 1 {
+		t.Errorf("Other.Added = %d, want 1", result.Other.Added)
+	}
+	got := findByUID(t, workstation, uid)
+	if got == nil || got.Description != "Write up the incident" || got.Tag != "oncall" {
+		t.Fatalf("merged todo = %+v, want it decrypted on the workstation", got)
+	}
+
+	conn, err := sql.Open("sqlite3", workstation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var description string
+	if err := conn.QueryRow(`SELECT description FROM todos WHERE uid = ?`, uid).Scan(&description); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(description, "enc:") {
+		t.Errorf("merged description stored as %q, want it encrypted", description)
+	}
+
+	// Nothing changed, so merging again changes nothing
+	if result := merge(t, laptop, workstation); result.Local != (replica.Changes{}) || result.Other != (replica.Changes{}) {
+		t.Errorf("second merge = %+v, want no changes", result)
+	}
+}
+
+func TestClock(t *testing.T) {
+	before := replica.At(time.Date(2024, 4, 20, 9, 0, 0, 0, time.UTC))
+	after := replica.Now("node")
+	if !(before < after) {
+		t.Errorf("At() = %q should sort before Now() = %q", before, after)
+	}
+}
@@ -0,0 +1,111 @@
+package replica
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Schema creates the tables that record when each field of a todo or quote
+// last changed and which ones were deleted, so that two databases can be
+// merged
+const Schema = `
+	-- When each field of a todo or quote last changed, by UID
+	CREATE TABLE IF NOT EXISTS field_clocks (
+		uid TEXT NOT NULL,
+		field TEXT NOT NULL,
+		clock TEXT NOT NULL,
+		PRIMARY KEY (uid, field)
+	);
+
+	-- Deleted todos and quotes, so a merge deletes them in the other database too
+	CREATE TABLE IF NOT EXISTS tombstones (
+		uid TEXT PRIMARY KEY,
+		kind TEXT NOT NULL,
+		clock TEXT NOT NULL
+	);
+
+	-- The database's node ID and when it was last merged with each other node
+	CREATE TABLE IF NOT EXISTS sync_meta (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+`
+
+// SQLNewUID is an SQL expression producing a random (version 4) UUID
+const SQLNewUID = `lower(
+	hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' ||
+	substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))
+)`
+
+// NewUID returns a random (version 4) UUID
+func NewUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// Node returns the ID that tells this database's changes apart from those
+// made in other databases, creating it on first use
+func Node(db *sql.DB) (string, error) {
+	if _, err := db.Exec(`INSERT OR IGNORE INTO sync_meta (key, value) VALUES ('node', ?)`, NewUID()); err != nil {
+		return "", err
+	}
+
+	var node string
+	err := db.QueryRow(`SELECT value FROM sync_meta WHERE key = 'node'`).Scan(&node)
+	return node, err
+}
+
+// Clocks are strings that sort in the order changes were made: the time in
+// nanoseconds as fixed-width hex, then the node that made the change, which
+// breaks ties between databases.
+
+// Now returns the clock of a change made now on node
+func Now(node string) string {
+	return fmt.Sprintf("%016x-%s", time.Now().UnixNano(), node)
+}
+
+// At returns the clock of a change made at t on an unknown node, used for
+// fields changed before clocks were recorded
+func At(t time.Time) string {
+	return fmt.Sprintf("%016x-", t.UnixNano())
+}
+
+// Touch records that a field of the row with the given id, stored in
+// column, is changing to value. Nothing is recorded if the column already holds
+// the value, so saving an unchanged field does not win over a real change
+// made elsewhere.
+func Touch(tx *sql.Tx, node, table string, id int, field, column string, value interface{}) error {
+	_, err := tx.Exec(`
+		INSERT INTO field_clocks (uid, field, clock)
+		SELECT uid, ?, ? FROM `+table+` WHERE id = ? AND `+column+` IS NOT ?
+		ON CONFLICT (uid, field) DO UPDATE SET clock = excluded.clock
+	`, field, Now(node), id, value)
+	return err
+}
+
+// Bury records that the row with the given id is being deleted and forgets
+// when its fields changed
+func Bury(tx *sql.Tx, node, table, kind string, id int) error {
+	if _, err := tx.Exec(`
+		INSERT INTO tombstones (uid, kind, clock)
+		SELECT uid, ?, ? FROM `+table+` WHERE id = ?
+		ON CONFLICT (uid) DO UPDATE SET clock = excluded.clock
+	`, kind, Now(node), id); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`DELETE FROM field_clocks WHERE uid = (SELECT uid FROM `+table+` WHERE id = ?)`, id)
+	return err
+}
+
+// Forget drops the field clocks of every row of a table, for when all rows
+// are replaced, e.g. by restoring a backup
+func Forget(tx *sql.Tx, table string) error {
+	_, err := tx.Exec(`DELETE FROM field_clocks WHERE uid IN (SELECT uid FROM ` + table + `)`)
+	return err
+}
@@ -0,0 +1,146 @@
+package scan
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is a pattern of a .gitignore file
+type ignoreRule struct {
+	// base is the directory of the .gitignore file, from the scanned
+	// directory, or "" for the scanned directory itself
+	base    string
+	pattern *regexp.Regexp
+	// anchored rules match the path from base, others the name at any depth
+	anchored bool
+	negate   bool
+	dirOnly  bool
+}
+
+// ignoreList holds the rules of the .gitignore files seen so far, parents
+// before children, so that the last rule matching a path decides, as in git
+type ignoreList []ignoreRule
+
+// load adds the rules of the .gitignore file in dir, if any
+func (l *ignoreList) load(dir, rel string) error {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if rel == "." {
+		rel = ""
+	}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if rule, ok := parseIgnoreRule(rel, s.Text()); ok {
+			*l = append(*l, rule)
+		}
+	}
+	return s.Err()
+}
+
+// parseIgnoreRule parses a line of a .gitignore file in base
+func parseIgnoreRule(base, line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+	line = strings.TrimPrefix(line, `\`)
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	// A slash anywhere but at the end ties the pattern to base
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	re, err := regexp.Compile("^" + globToRegexp(line) + "$")
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.pattern = re
+	return rule, true
+}
+
+// globToRegexp translates a gitignore glob, with ** for any directories
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**"):
+			b.WriteString("(?:/.*)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// match reports whether the .gitignore files ignore a path, given from the
+// scanned directory with forward slashes
+func (l ignoreList) match(rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range l {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		sub := rel
+		if r.base != "" {
+			var ok bool
+			if sub, ok = strings.CutPrefix(rel, r.base+"/"); !ok {
+				continue
+			}
+		}
+		if !r.anchored {
+			sub = path.Base(sub)
+		}
+		if r.pattern.MatchString(sub) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,254 @@
+package scan
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/negadras/tada/internal/todo"
+)
+
+// maxFileSize is the size of the largest file read; larger ones are most
+// likely generated or data
+const maxFileSize = 1 << 20
+
+// Comment is a TODO, FIXME or HACK comment found in a source file
+type Comment struct {
+	// Kind is TODO, FIXME or HACK
+	Kind string
+	Text string
+	// Owner is the name in TODO(owner), if any
+	Owner string
+	// Priority is the one in TODO[high], if any
+	Priority *todo.Priority
+	// File is the path of the file from the scanned directory, with
+	// forward slashes
+	File string
+	Line int
+	// Fingerprint identifies the comment across scans while it moves
+	// within its file
+	Fingerprint string
+}
+
+// Location returns the comment's file:line
+func (c *Comment) Location() string {
+	return fmt.Sprintf("%s:%d", c.File, c.Line)
+}
+
+// keywordPattern finds a keyword with its optional (owner) and [priority]
+var keywordPattern = regexp.MustCompile(`\b(TODO|FIXME|HACK)\b(?:\(([^)]*)\))?(?:\s*\[([^\]]*)\])?:?`)
+
+// commentMarkers start a comment in the languages scanned
+var commentMarkers = []string{"//", "#", "/*", "--", ";", "<!--", "%"}
+
+// ParseLine returns the comment on a line of source, if any. The keyword must
+// follow a comment marker, so that the words in strings and identifiers are
+// not taken for comments.
+func ParseLine(line string) (*Comment, bool) {
+	for _, m := range keywordPattern.FindAllStringSubmatchIndex(line, -1) {
+		if !inComment(line[:m[0]]) {
+			continue
+		}
+
+		c := &Comment{Kind: line[m[2]:m[3]]}
+		if m[4] >= 0 {
+			c.Owner = strings.TrimSpace(line[m[4]:m[5]])
+		}
+		text := line[m[1]:]
+		if m[6] >= 0 {
+			if p, err := todo.ParsePriority(line[m[6]:m[7]]); err == nil {
+				c.Priority = &p
+			} else {
+				// Not a priority, so part of the text
+				text = line[m[6]-1:]
+			}
+		}
+
+		text = strings.TrimSpace(text)
+		text = strings.TrimSuffix(text, "*/")
+		text = strings.TrimSuffix(text, "-->")
+		c.Text = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(text), ":-"))
+		return c, true
+	}
+	return nil, false
+}
+
+// inComment reports whether the text before a keyword ends with a comment
+// marker
+func inComment(prefix string) bool {
+	prefix = strings.TrimRight(prefix, " \t")
+	// The lines inside a /* */ or /** */ block start with *
+	if trimmed := strings.TrimSpace(prefix); trimmed != "" && strings.Trim(trimmed, "/*") == "" {
+		return true
+	}
+	for _, marker := range commentMarkers {
+		if !strings.HasSuffix(prefix, marker) {
+			continue
+		}
+		before := strings.TrimSuffix(prefix, marker)
+		// "a//b" or "x#y" are not comments, but "//" and "x // y" are
+		if before == "" || strings.HasSuffix(before, " ") || strings.HasSuffix(before, "\t") ||
+			strings.Trim(before, marker[:1]) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseFile returns the comments in the content of a file
+func ParseFile(file string, data []byte) []*Comment {
+	var comments []*Comment
+	seen := map[string]int{}
+	for i, line := range strings.Split(string(data), "\n") {
+		c, ok := ParseLine(strings.TrimSuffix(line, "\r"))
+		if !ok {
+			continue
+		}
+		c.File = file
+		c.Line = i + 1
+
+		// Identical comments in a file are told apart by their order
+		key := c.Kind + "\x00" + c.Text
+		c.Fingerprint = fingerprint(file, key, seen[key])
+		seen[key]++
+		comments = append(comments, c)
+	}
+	return comments
+}
+
+// fingerprint identifies a comment by its file, kind and text, so that it
+// keeps it when lines are added or removed above it
+func fingerprint(file, key string, n int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%d", file, key, n)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Walk returns the comments in the text files under root, skipping the
+// files git ignores. root can also be a single file.
+//
+// Inside a git work tree, the files are those git lists, so that every
+// ignore file applies: .gitignore files above root, .git/info/exclude and
+// the global excludes. Elsewhere, the .gitignore files under root are read.
+func Walk(root string) ([]*Comment, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		data, err := readText(root, info.Size())
+		if err != nil || data == nil {
+			return nil, err
+		}
+		return ParseFile(filepath.Base(root), data), nil
+	}
+
+	var comments []*Comment
+	if files, ok := gitFiles(root); ok {
+		for _, rel := range files {
+			if strings.HasPrefix(rel, ".tada/") || strings.Contains(rel, "/.tada/") {
+				continue
+			}
+			path := filepath.Join(root, filepath.FromSlash(rel))
+			info, err := os.Lstat(path)
+			if err != nil || !info.Mode().IsRegular() {
+				// Deleted but not yet committed, or a link
+				continue
+			}
+			data, err := readText(path, info.Size())
+			if err != nil {
+				return nil, err
+			}
+			if data != nil {
+				comments = append(comments, ParseFile(rel, data)...)
+			}
+		}
+		sort.SliceStable(comments, func(i, j int) bool {
+			return comments[i].File < comments[j].File
+		})
+		return comments, nil
+	}
+
+	var ignore ignoreList
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if rel != "." && (d.Name() == ".git" || d.Name() == ".tada" || ignore.match(rel, true)) {
+				return filepath.SkipDir
+			}
+			return ignore.load(path, rel)
+		}
+		if !d.Type().IsRegular() || ignore.match(rel, false) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := readText(path, info.Size())
+		if err != nil || data == nil {
+			return err
+		}
+		comments = append(comments, ParseFile(rel, data)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].File < comments[j].File
+	})
+	return comments, nil
+}
+
+// gitFiles returns the files under dir that git tracks or would add,
+// relative to dir, and false if dir is not in a git work tree
+func gitFiles(dir string) ([]string, bool) {
+	out, err := exec.Command("git", "-C", dir, "ls-files", "-z", "--cached", "--others", "--exclude-standard", "--", ".").Output()
+	if err != nil {
+		return nil, false
+	}
+
+	var files []string
+	seen := map[string]bool{}
+	for _, rel := range strings.Split(string(out), "\x00") {
+		// Files with unresolved conflicts are listed once per stage
+		if rel != "" && !seen[rel] {
+			seen[rel] = true
+			files = append(files, rel)
+		}
+	}
+	return files, true
+}
+
+// readText reads a file, returning nil for binary and very large files
+func readText(path string, size int64) ([]byte, error) {
+	if size > maxFileSize {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return nil, nil
+	}
+	return data, nil
+}
@@ -0,0 +1,157 @@
+package scan
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/negadras/tada/internal/todo"
+)
+
+func TestParseLine(t *testing.T) {
+	high := todo.High
+	tests := []struct {
+		name string
+		line string
+		want *Comment
+	}{
+		{"go", "\t// TODO: retry on timeouts", &Comment{Kind: "TODO", Text: "retry on timeouts"}},
+		{"after code", "x := f() // FIXME handle the error", &Comment{Kind: "FIXME", Text: "handle the error"}},
+		{"owner and priority", "# HACK(alice)[high]: remove after the migration", &Comment{Kind: "HACK", Text: "remove after the migration", Owner: "alice", Priority: &high}},
+		{"priority only", "-- TODO [h] index this column", &Comment{Kind: "TODO", Text: "index this column", Priority: &high}},
+		{"not a priority", "// TODO [later] think", &Comment{Kind: "TODO", Text: "[later] think"}},
+		{"block", "/* TODO: split this file */", &Comment{Kind: "TODO", Text: "split this file"}},
+		{"inside a block", "   * FIXME: wrong for leap years", &Comment{Kind: "FIXME", Text: "wrong for leap years"}},
+		{"html", "<!-- TODO: add the logo -->", &Comment{Kind: "TODO", Text: "add the logo"}},
+		{"doc comment", "/// TODO: document the errors", &Comment{Kind: "TODO", Text: "document the errors"}},
+		{"no text", "// TODO", &Comment{Kind: "TODO"}},
+		{"in a string", `msg := "TODO: not a comment"`, nil},
+		{"marker inside a word", "url := a//TODO", nil},
+		{"lower case", "// todo: not shouting", nil},
+		{"longer word", "// TODOS are elsewhere", nil},
+		{"no marker", "TODO: plain text", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseLine(tt.line)
+			if ok != (tt.want != nil) {
+				t.Fatalf("ParseLine(%q) = %+v, %v", tt.line, got, ok)
+			}
+			if ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLine(%q) = %+v, want %+v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	data := []byte("package a\n// TODO: check\nfunc f() {}\n// TODO: check\n")
+	comments := ParseFile("a.go", data)
+	if len(comments) != 2 {
+		t.Fatalf("ParseFile() = %d comments, want 2", len(comments))
+	}
+	if comments[0].Location() != "a.go:2" || comments[1].Location() != "a.go:4" {
+		t.Errorf("locations = %s, %s, want a.go:2 and a.go:4", comments[0].Location(), comments[1].Location())
+	}
+	if comments[0].Fingerprint == comments[1].Fingerprint {
+		t.Error("identical comments should have different fingerprints")
+	}
+
+	// Lines added above keep the fingerprints
+	moved := ParseFile("a.go", append([]byte("\n\n"), data...))
+	if moved[0].Fingerprint != comments[0].Fingerprint || moved[1].Fingerprint != comments[1].Fingerprint {
+		t.Error("fingerprints should not change when comments move")
+	}
+	if other := ParseFile("b.go", data); other[0].Fingerprint == comments[0].Fingerprint {
+		t.Error("fingerprints should differ between files")
+	}
+}
+
+func TestWalk(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".gitignore":           "*.log\nbuild/\n/generated.go\n!keep.log\n",
+		"main.go":              "// TODO: main\n",
+		"generated.go":         "// TODO: generated\n",
+		"pkg/generated.go":     "// TODO: not anchored here\n",
+		"pkg/.gitignore":       "secret.py\n",
+		"pkg/secret.py":        "# TODO: ignored by pkg\n",
+		"pkg/deep/util.py":     "# FIXME: util\n",
+		"debug.log":            "# TODO: ignored log\n",
+		"keep.log":             "# TODO: kept log\n",
+		"build/out.go":         "// TODO: build output\n",
+		"docs/build/readme.sh": "# TODO: also a build directory\n",
+		".git/config":          "# TODO: git internals\n",
+		"image.png":            "\x00\x01 // TODO: binary\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	comments, err := Walk(root)
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	var got []string
+	for _, c := range comments {
+		got = append(got, c.Location()+" "+c.Text)
+	}
+	want := []string{"keep.log:1 kept log", "main.go:1 main", "pkg/deep/util.py:1 util", "pkg/generated.go:1 not anchored here"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Walk() = %q, want %q", got, want)
+	}
+
+	comments, err = Walk(filepath.Join(root, "main.go"))
+	if err != nil || len(comments) != 1 || comments[0].File != "main.go" {
+		t.Errorf("Walk() of a file = %+v, %v", comments, err)
+	}
+}
+
+func TestWalk_GitWorkTree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root := t.TempDir()
+	if out, err := exec.Command("git", "-C", root, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	files := map[string]string{
+		".gitignore":        "gen/\n",
+		".git/info/exclude": "local.go\n",
+		"src/main.go":       "// TODO: main\n",
+		"src/gen/b.go":      "// TODO: generated\n",
+		"src/local.go":      "// TODO: excluded\n",
+		"src/.tada/x.go":    "// TODO: the store\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The ignore files of the repository apply below its top level too
+	comments, err := Walk(filepath.Join(root, "src"))
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	var got []string
+	for _, c := range comments {
+		got = append(got, c.Location()+" "+c.Text)
+	}
+	if want := []string{"main.go:1 main"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Walk() = %q, want %q", got, want)
+	}
+}
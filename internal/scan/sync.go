@@ -0,0 +1,149 @@
+package scan
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/negadras/tada/internal/todo"
+)
+
+// fallbackDescription describes a comment with a keyword and no text
+const fallbackDescription = "%s comment"
+
+// notePattern is the line a scan writes at the top of a todo's notes, as
+// written by notes
+var notePattern = regexp.MustCompile(`^(TODO|FIXME|HACK) comment(, owner .*)?$`)
+
+// Change is a todo a scan creates, updates or closes, with the comment it
+// comes from
+type Change struct {
+	Todo *todo.Todo
+	// Comment is nil for todos closed because their comment disappeared
+	Comment *Comment
+	// From is the todo's file:line before a comment moved
+	From string
+}
+
+// SyncPlan describes the changes that bring the todos back in line with the
+// comments of a scan
+type SyncPlan struct {
+	// Create, Update and Close are the changes to make in tada. Updated and
+	// closed todos carry their tada ID with the new fields.
+	Create []Change
+	Update []Change
+	Close  []Change
+
+	// State is the fingerprint of each comment's todo as of this scan, by
+	// UID, to be passed to the next PlanSync
+	State map[string]string
+}
+
+// PlanSync works out how to bring todos in line with the comments found by
+// a scan. state holds the fingerprints as of the previous scan, by UID.
+//
+// New comments become open todos tagged with their file:line. Todos whose
+// comment moved are tagged with the new line, and their owner and priority
+// follow the comment's markers; notes added below the owner are kept. Todos whose comment disappeared are closed.
+// A todo deleted in tada is not created again while its comment remains.
+func PlanSync(comments []*Comment, todos []*todo.Todo, state map[string]string, now time.Time) *SyncPlan {
+	plan := &SyncPlan{State: map[string]string{}}
+
+	byUID := make(map[string]*todo.Todo, len(todos))
+	for _, t := range todos {
+		byUID[t.UID] = t
+	}
+	uidByFingerprint := make(map[string]string, len(state))
+	for uid, fp := range state {
+		uidByFingerprint[fp] = uid
+	}
+
+	found := map[string]bool{}
+	for _, c := range comments {
+		found[c.Fingerprint] = true
+
+		uid, known := uidByFingerprint[c.Fingerprint]
+		if !known {
+			t := NewTodo(c, now)
+			plan.Create = append(plan.Create, Change{Todo: t, Comment: c})
+			plan.State[t.UID] = c.Fingerprint
+			continue
+		}
+
+		plan.State[uid] = c.Fingerprint
+		old, ok := byUID[uid]
+		if !ok {
+			continue
+		}
+		updated := *old
+		updated.Tag = c.Location()
+		updated.Notes = withNote(old.Notes, notes(c))
+		if c.Priority != nil {
+			updated.Priority = *c.Priority
+		}
+		if updated.Tag != old.Tag || updated.Notes != old.Notes || updated.Priority != old.Priority {
+			plan.Update = append(plan.Update, Change{Todo: &updated, Comment: c, From: old.Tag})
+		}
+	}
+
+	for uid, fp := range state {
+		if found[fp] {
+			continue
+		}
+		if t, ok := byUID[uid]; ok && t.Status == todo.Open {
+			closed := *t
+			closed.Status = todo.Done
+			plan.Close = append(plan.Close, Change{Todo: &closed, From: t.Tag})
+		}
+	}
+	sort.Slice(plan.Close, func(i, j int) bool {
+		return plan.Close[i].Todo.ID < plan.Close[j].Todo.ID
+	})
+	return plan
+}
+
+// NewTodo returns the todo for a new comment
+func NewTodo(c *Comment, now time.Time) *todo.Todo {
+	description := c.Text
+	if description == "" {
+		description = fmt.Sprintf(fallbackDescription, c.Kind)
+	}
+	priority := todo.Medium
+	if c.Priority != nil {
+		priority = *c.Priority
+	}
+	return &todo.Todo{
+		UID:         todo.NewUID(),
+		Description: description,
+		Priority:    priority,
+		Status:      todo.Open,
+		Tag:         c.Location(),
+		Notes:       notes(c),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// withNote returns the notes of a scanned todo with the line the scan wrote
+// at the top replaced by note, keeping the lines added below it. Notes whose
+// first line was rewritten in tada are left alone.
+func withNote(notes, note string) string {
+	first, rest, more := strings.Cut(notes, "\n")
+	if !notePattern.MatchString(first) {
+		return notes
+	}
+	if !more {
+		return note
+	}
+	return note + "\n" + rest
+}
+
+// notes describes where a todo comes from
+func notes(c *Comment) string {
+	if c.Owner != "" {
+		return fmt.Sprintf("%s comment, owner %s", c.Kind, c.Owner)
+	}
+	return c.Kind + " comment"
+}
@@ -0,0 +1,80 @@
+package scan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/negadras/tada/internal/todo"
+)
+
+func TestPlanSync(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	high := todo.High
+
+	first := ParseFile("a.go", []byte("// TODO: retry\n// FIXME(alice): crash\n// HACK: temp\n"))
+	plan := PlanSync(first, nil, nil, now)
+	if len(plan.Create) != 3 || len(plan.Update) != 0 || len(plan.Close) != 0 {
+		t.Fatalf("first PlanSync() = %d created, %d updated, %d closed, want 3 created",
+			len(plan.Create), len(plan.Update), len(plan.Close))
+	}
+	var todos []*todo.Todo
+	for i, c := range plan.Create {
+		c.Todo.ID = i + 1
+		todos = append(todos, c.Todo)
+	}
+	if t0 := todos[1]; t0.Description != "crash" || t0.Tag != "a.go:2" || t0.Notes != "FIXME comment, owner alice" || t0.Priority != todo.Medium {
+		t.Errorf("created todo = %+v", t0)
+	}
+
+	// Unchanged comments need nothing
+	if again := PlanSync(first, todos, plan.State, now); len(again.Create)+len(again.Update)+len(again.Close) != 0 {
+		t.Errorf("PlanSync() of the same comments = %+v, want no changes", again)
+	}
+
+	// The TODO moves, the FIXME gets a priority, the HACK goes away and
+	// the todo of the TODO was deleted in tada
+	second := ParseFile("a.go", []byte("\n// TODO: retry\n// FIXME(alice)[high]: crash\n"))
+	plan = PlanSync(second, todos[1:], plan.State, now)
+	if len(plan.Create) != 0 {
+		t.Errorf("PlanSync() created %d todos, want none for a todo deleted in tada", len(plan.Create))
+	}
+	if len(plan.Update) != 1 {
+		t.Fatalf("PlanSync() updated %d todos, want 1", len(plan.Update))
+	}
+	if u := plan.Update[0]; u.Todo.ID != 2 || u.Todo.Tag != "a.go:3" || u.From != "a.go:2" || u.Todo.Priority != high {
+		t.Errorf("updated todo = %+v from %s", u.Todo, u.From)
+	}
+	if len(plan.Close) != 1 || plan.Close[0].Todo.ID != 3 || plan.Close[0].Todo.Status != todo.Done {
+		t.Errorf("PlanSync() closed %+v, want todo #3", plan.Close)
+	}
+	if len(plan.State) != 2 {
+		t.Errorf("PlanSync() state has %d entries, want the 2 comments left", len(plan.State))
+	}
+}
+
+func TestPlanSync_KeepsNotes(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	first := ParseFile("a.go", []byte("// FIXME(alice): crash\n// TODO: retry\n"))
+	plan := PlanSync(first, nil, nil, now)
+	var todos []*todo.Todo
+	for i, c := range plan.Create {
+		c.Todo.ID = i + 1
+		todos = append(todos, c.Todo)
+	}
+	todos[0].Notes += "\nSee the crash report"
+	todos[1].Notes = "Waiting on the API team"
+
+	// The owner changes and both comments move
+	second := ParseFile("a.go", []byte("\n// FIXME(bob): crash\n// TODO: retry\n"))
+	plan = PlanSync(second, todos, plan.State, now)
+	if len(plan.Update) != 2 {
+		t.Fatalf("PlanSync() updated %d todos, want 2", len(plan.Update))
+	}
+	if got := plan.Update[0].Todo.Notes; got != "FIXME comment, owner bob\nSee the crash report" {
+		t.Errorf("notes = %q, want the new owner above the added notes", got)
+	}
+	if got := plan.Update[1].Todo.Notes; got != "Waiting on the API team" {
+		t.Errorf("notes = %q, want rewritten notes left alone", got)
+	}
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net"
+	"net/http"
+)
+
+// Guard refuses requests for another host than the one listened on at
+// addr, as sent by pages of other sites through DNS rebinding, and requests
+// made by pages of another origin. Any host is accepted when listening on
+// all interfaces.
+func Guard(addr string, next http.Handler) http.Handler {
+	hosts := allowedHosts(addr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hosts != nil && !hosts[r.Host] {
+			http.Error(w, "unknown host "+r.Host, http.StatusMisdirectedRequest)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" && origin != "http://"+r.Host {
+			http.Error(w, "requests from "+origin+" are not allowed", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHosts returns the Host headers that reach addr: addr itself, and
+// localhost for a loopback address. It is nil for all interfaces.
+func allowedHosts(addr string) map[string]bool {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return map[string]bool{addr: true}
+	}
+	ip := net.ParseIP(host)
+	if host == "" || ip != nil && ip.IsUnspecified() {
+		return nil
+	}
+
+	hosts := map[string]bool{addr: true}
+	if host == "localhost" || ip != nil && ip.IsLoopback() {
+		hosts[net.JoinHostPort("localhost", port)] = true
+		hosts[net.JoinHostPort("127.0.0.1", port)] = true
+		hosts[net.JoinHostPort("::1", port)] = true
+	}
+	return hosts
+}
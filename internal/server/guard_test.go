@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowedHosts(t *testing.T) {
+	tests := []struct {
+		addr    string
+		allowed []string
+		refused []string
+	}{
+		{"127.0.0.1:7071", []string{"127.0.0.1:7071", "localhost:7071", "[::1]:7071"}, []string{"evil.example:7071", "127.0.0.1:8080"}},
+		{"192.168.1.5:7071", []string{"192.168.1.5:7071"}, []string{"localhost:7071"}},
+		{":7071", []string{"anything:7071"}, nil},
+		{"0.0.0.0:7071", []string{"anything:7071"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			hosts := allowedHosts(tt.addr)
+			for _, h := range tt.allowed {
+				if hosts != nil && !hosts[h] {
+					t.Errorf("allowedHosts(%q) refuses %q", tt.addr, h)
+				}
+			}
+			for _, h := range tt.refused {
+				if hosts == nil || hosts[h] {
+					t.Errorf("allowedHosts(%q) allows %q", tt.addr, h)
+				}
+			}
+		})
+	}
+}
+
+func TestGuard(t *testing.T) {
+	handler := Guard("127.0.0.1:7070", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name   string
+		host   string
+		origin string
+		want   int
+	}{
+		{"same host", "localhost:7070", "", http.StatusOK},
+		{"same origin", "127.0.0.1:7070", "http://127.0.0.1:7070", http.StatusOK},
+		{"rebound host", "evil.example:7070", "", http.StatusMisdirectedRequest},
+		{"other origin", "127.0.0.1:7070", "http://evil.example", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("DELETE", "/todos/1", nil)
+			req.Host = tt.host
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/todo"
+)
+
+// mcpProtocolVersions are the Model Context Protocol versions spoken, newest
+// first
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// openTodosURI is the resource listing the open todos
+const openTodosURI = "tada://todos/open"
+
+// mcpTool is a tool offered to MCP clients
+type mcpTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+	Annotations mcpAnnotations  `json:"annotations"`
+
+	call func(s *Server, args json.RawMessage) (interface{}, error)
+}
+
+type mcpAnnotations struct {
+	ReadOnlyHint bool `json:"readOnlyHint"`
+}
+
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type mcpToolResult struct {
+	Content []mcpContent `json:"content"`
+	IsError bool         `json:"isError"`
+}
+
+type mcpResource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	MimeType    string `json:"mimeType"`
+}
+
+type mcpResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// todoFieldsSchema are the JSON schema properties of a todo's fields
+const todoFieldsSchema = `
+	"description": {"type": "string"},
+	"priority": {"type": "string", "enum": ["low", "medium", "high"]},
+	"tag": {"type": "string"},
+	"due": {"type": ["string", "null"], "description": "A date such as 2025-06-20, tomorrow or 3d, or null to clear it"},
+	"notes": {"type": "string"}`
+
+var mcpTools = []*mcpTool{
+	{
+		Name:        "list_todos",
+		Description: "List todos, only open ones unless a status is given. Takes the same options as 'tada list'.",
+		InputSchema: json.RawMessage(`{"type": "object", "properties": {
+	"status": {"type": "string", "enum": ["open", "done", "all"]},
+	"priority": {"type": "string", "enum": ["low", "medium", "high"]},
+	"tag": {"type": "string"},
+	"filter": {"type": "string", "description": "A filter expression such as 'priority>=medium and due<3d'"},
+	"sort": {"type": "string", "description": "Sort keys such as 'priority,due'; priority sorts highest first and a - prefix reverses a key"}}}`),
+		Annotations: mcpAnnotations{ReadOnlyHint: true},
+		call: func(s *Server, args json.RawMessage) (interface{}, error) {
+			var q listQuery
+			if err := decodeParams(args, &q); err != nil {
+				return nil, err
+			}
+			return s.listTodos(q)
+		},
+	},
+	{
+		Name:        "search",
+		Description: "Search the descriptions and notes of all todos, open or done, and the text and author of quotes.",
+		InputSchema: json.RawMessage(`{"type": "object", "properties": {"query": {"type": "string"}}, "required": ["query"]}`),
+		Annotations: mcpAnnotations{ReadOnlyHint: true},
+		call: func(s *Server, args json.RawMessage) (interface{}, error) {
+			var p struct {
+				Query string `json:"query"`
+			}
+			if err := decodeParams(args, &p); err != nil {
+				return nil, err
+			}
+			return s.search(p.Query)
+		},
+	},
+	{
+		Name:        "random_quote",
+		Description: "Show a random quote.",
+		InputSchema: json.RawMessage(`{"type": "object", "properties": {}}`),
+		Annotations: mcpAnnotations{ReadOnlyHint: true},
+		call: func(s *Server, args json.RawMessage) (interface{}, error) {
+			return s.randomQuote()
+		},
+	},
+	{
+		Name:        "add_todo",
+		Description: "Add a todo. Priority defaults to medium.",
+		InputSchema: json.RawMessage(`{"type": "object", "properties": {` + todoFieldsSchema + `}, "required": ["description"]}`),
+		call: func(s *Server, args json.RawMessage) (interface{}, error) {
+			var in todoInput
+			if err := decodeParams(args, &in); err != nil {
+				return nil, err
+			}
+			return s.createTodo(&in)
+		},
+	},
+	{
+		Name:        "complete_todo",
+		Description: "Mark a todo as done.",
+		InputSchema: json.RawMessage(`{"type": "object", "properties": {"id": {"type": "integer"}}, "required": ["id"]}`),
+		call: func(s *Server, args json.RawMessage) (interface{}, error) {
+			var p idParams
+			if err := decodeParams(args, &p); err != nil {
+				return nil, err
+			}
+			done := json.RawMessage(`"done"`)
+			return s.updateTodo(p.ID, &todoInput{Status: &done}, "")
+		},
+	},
+	{
+		Name:        "update_todo",
+		Description: "Change the fields of a todo that are given.",
+		InputSchema: json.RawMessage(`{"type": "object", "properties": {
+	"id": {"type": "integer"},
+	"status": {"type": "string", "enum": ["open", "done"]},` + todoFieldsSchema + `}, "required": ["id"]}`),
+		call: func(s *Server, args json.RawMessage) (interface{}, error) {
+			var p struct {
+				ID int `json:"id"`
+				todoInput
+			}
+			if err := decodeParams(args, &p); err != nil {
+				return nil, err
+			}
+			return s.updateTodo(p.ID, &p.todoInput, "")
+		},
+	},
+}
+
+// mcpSession is the state of one MCP client
+type mcpSession struct {
+	s        *Server
+	version  string
+	readOnly bool
+}
+
+// ServeMCP serves the Model Context Protocol on r and w, as a server named
+// tada of the given version. With readOnly, only the tools that do not change
+// todos or quotes are offered.
+func (s *Server) ServeMCP(ctx context.Context, r io.Reader, w io.Writer, version string, readOnly bool) error {
+	m := &mcpSession{s: s, version: version, readOnly: readOnly}
+	c := &rpcConn{out: w, methods: m.call}
+	return c.serve(ctx, r)
+}
+
+// call runs a method of the Model Context Protocol
+func (m *mcpSession) call(ctx context.Context, method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "initialize":
+		var p struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		// The client's capabilities and info are not used
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, badRequest(err)
+		}
+		version := mcpProtocolVersions[0]
+		if slices.Contains(mcpProtocolVersions, p.ProtocolVersion) {
+			version = p.ProtocolVersion
+		}
+		return map[string]interface{}{
+			"protocolVersion": version,
+			"capabilities":    map[string]interface{}{"tools": struct{}{}, "resources": struct{}{}},
+			"serverInfo":      map[string]string{"name": "tada", "version": m.version},
+		}, nil
+
+	case "ping":
+		return struct{}{}, nil
+
+	case "tools/list":
+		var tools []*mcpTool
+		for _, tool := range mcpTools {
+			if m.offers(tool) {
+				tools = append(tools, tool)
+			}
+		}
+		return map[string]interface{}{"tools": tools}, nil
+
+	case "tools/call":
+		var p struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, badRequest(err)
+		}
+		i := slices.IndexFunc(mcpTools, func(tool *mcpTool) bool { return tool.Name == p.Name })
+		if i < 0 || !m.offers(mcpTools[i]) {
+			return nil, badRequest(fmt.Errorf("unknown tool %q", p.Name))
+		}
+
+		// Errors of the tool are reported to the model rather than the client
+		result, err := mcpTools[i].call(m.s, p.Arguments)
+		if err != nil {
+			return &mcpToolResult{Content: []mcpContent{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+		}
+		text, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return &mcpToolResult{Content: []mcpContent{{Type: "text", Text: string(text)}}}, nil
+
+	case "resources/list":
+		return map[string]interface{}{"resources": []mcpResource{{
+			URI:         openTodosURI,
+			Name:        "Open todos",
+			Description: "The todos that are not done yet, highest priority first",
+			MimeType:    "application/json",
+		}}}, nil
+
+	case "resources/templates/list":
+		return map[string]interface{}{"resourceTemplates": []struct{}{}}, nil
+
+	case "resources/read":
+		var p struct {
+			URI string `json:"uri"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, badRequest(err)
+		}
+		if p.URI != openTodosURI {
+			return nil, notFound("resource %s not found", p.URI)
+		}
+		todos, err := m.s.listTodos(listQuery{Sort: "priority,created"})
+		if err != nil {
+			return nil, err
+		}
+		text, err := json.MarshalIndent(todos, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"contents": []mcpResourceContents{{
+			URI: openTodosURI, MimeType: "application/json", Text: string(text),
+		}}}, nil
+	}
+
+	// Notifications such as notifications/initialized need no answer
+	if strings.HasPrefix(method, "notifications/") {
+		return nil, nil
+	}
+	return nil, errMethodNotFound
+}
+
+// offers reports whether a tool is available in the session
+func (m *mcpSession) offers(tool *mcpTool) bool {
+	return !m.readOnly || tool.Annotations.ReadOnlyHint
+}
+
+// searchResult holds the todos and quotes matching a search
+type searchResult struct {
+	Todos  []*todo.Todo   `json:"todos"`
+	Quotes []*quote.Quote `json:"quotes"`
+}
+
+// search finds the todos and quotes containing query, ignoring case
+func (s *Server) search(query string) (*searchResult, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil, badRequest(errors.New("query is required"))
+	}
+	contains := func(fields ...string) bool {
+		for _, field := range fields {
+			if strings.Contains(strings.ToLower(field), query) {
+				return true
+			}
+		}
+		return false
+	}
+
+	todos, err := s.listTodos(listQuery{Status: "all"})
+	if err != nil {
+		return nil, err
+	}
+	quotes, err := s.listQuotes("", "")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &searchResult{Todos: []*todo.Todo{}, Quotes: []*quote.Quote{}}
+	for _, t := range todos {
+		if contains(t.Description, t.Notes) {
+			result.Todos = append(result.Todos, t)
+		}
+	}
+	for _, q := range quotes {
+		if contains(q.Text, q.Author) {
+			result.Quotes = append(result.Quotes, q)
+		}
+	}
+	return result, nil
+}
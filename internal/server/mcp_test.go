@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/negadras/tada/internal/todo"
+)
+
+func newMCPClient(t *testing.T, readOnly bool) (*rpcClient, *todo.DB) {
+	t.Helper()
+	return newClient(t, func(s *Server, r io.Reader, w io.Writer, _ string) {
+		s.ServeMCP(context.Background(), r, w, "1.2.3", readOnly)
+	})
+}
+
+func TestServeMCP(t *testing.T) {
+	client, db := newMCPClient(t, false)
+	db.Create("Renew passport", todo.High)
+
+	tests := []struct {
+		name    string
+		request string
+		want    string
+	}{
+		{
+			"initialize",
+			`{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": {"protocolVersion": "2024-11-05", "capabilities": {}, "clientInfo": {"name": "test", "version": "1"}}}`,
+			`"protocolVersion":"2024-11-05","serverInfo":{"name":"tada","version":"1.2.3"}`,
+		},
+		{
+			"unknown protocol version",
+			`{"jsonrpc": "2.0", "id": 2, "method": "initialize", "params": {"protocolVersion": "1999-01-01"}}`,
+			`"protocolVersion":"` + mcpProtocolVersions[0] + `"`,
+		},
+		{
+			"list tools",
+			`{"jsonrpc": "2.0", "id": 3, "method": "tools/list"}`,
+			`"name":"update_todo"`,
+		},
+		{
+			"add todo",
+			`{"jsonrpc": "2.0", "id": 4, "method": "tools/call", "params": {"name": "add_todo", "arguments": {"description": "Book flights", "notes": "Window seat"}}}`,
+			`\"description\": \"Book flights\"`,
+		},
+		{
+			"complete todo",
+			`{"jsonrpc": "2.0", "id": 5, "method": "tools/call", "params": {"name": "complete_todo", "arguments": {"id": 1}}}`,
+			`\"status\": 2`,
+		},
+		{
+			"search notes and done todos",
+			`{"jsonrpc": "2.0", "id": 6, "method": "tools/call", "params": {"name": "search", "arguments": {"query": "WINDOW"}}}`,
+			`Book flights`,
+		},
+		{
+			"tool error",
+			`{"jsonrpc": "2.0", "id": 7, "method": "tools/call", "params": {"name": "update_todo", "arguments": {"id": 42, "tag": "x"}}}`,
+			`"text":"todo #42 not found"}],"isError":true`,
+		},
+		{
+			"unknown tool",
+			`{"jsonrpc": "2.0", "id": 8, "method": "tools/call", "params": {"name": "drop_tables"}}`,
+			`"error":{"code":-32602,`,
+		},
+		{
+			"open todos resource",
+			`{"jsonrpc": "2.0", "id": 9, "method": "resources/read", "params": {"uri": "tada://todos/open"}}`,
+			`"uri":"tada://todos/open","mimeType":"application/json","text":"[\n  {\n    \"id\": 2,`,
+		},
+		{
+			"notifications are not answered",
+			`{"jsonrpc": "2.0", "method": "notifications/initialized"}` + "\n" + `{"jsonrpc": "2.0", "id": 10, "method": "ping"}`,
+			`{"jsonrpc":"2.0","id":10,"result":{}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := client.call(t, tt.request); !strings.Contains(got, tt.want) {
+				t.Errorf("reply = %s, want it to contain %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeMCP_ReadOnly(t *testing.T) {
+	client, db := newMCPClient(t, true)
+
+	got := client.call(t, `{"jsonrpc": "2.0", "id": 1, "method": "tools/list"}`)
+	for _, name := range []string{"list_todos", "search", "random_quote"} {
+		if !strings.Contains(got, `"name":"`+name+`"`) {
+			t.Errorf("tools/list = %s, want %s", got, name)
+		}
+	}
+	if strings.Contains(got, `"name":"add_todo"`) {
+		t.Errorf("tools/list = %s, want no add_todo", got)
+	}
+
+	got = client.call(t, `{"jsonrpc": "2.0", "id": 2, "method": "tools/call", "params": {"name": "add_todo", "arguments": {"description": "Sneaky"}}}`)
+	if !strings.Contains(got, `"code":-32602`) {
+		t.Errorf("add_todo reply = %s, want error -32602", got)
+	}
+	if todos, _ := db.List(nil, nil, nil); len(todos) != 0 {
+		t.Errorf("read-only add_todo created %d todos", len(todos))
+	}
+}
+
+func TestServeMCP_OpenTodosOrder(t *testing.T) {
+	client, db := newMCPClient(t, true)
+	db.Create("Water the plants", todo.Low)
+	db.Create("Renew passport", todo.High)
+	db.Create("Call the bank", todo.Medium)
+
+	got := client.call(t, `{"jsonrpc": "2.0", "id": 1, "method": "resources/read", "params": {"uri": "tada://todos/open"}}`)
+	high := strings.Index(got, "Renew passport")
+	medium := strings.Index(got, "Call the bank")
+	low := strings.Index(got, "Water the plants")
+	if high < 0 || !(high < medium && medium < low) {
+		t.Errorf("resources/read = %s, want the highest priority first", got)
+	}
+}
@@ -0,0 +1,373 @@
+package server
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/todo"
+)
+
+// The operations below are shared by the REST API and JSON-RPC.
+
+// requestError is an error caused by the request rather than by the
+// database, with the HTTP status it is reported with
+type requestError struct {
+	status int
+	err    error
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+func badRequest(err error) error {
+	return &requestError{http.StatusBadRequest, err}
+}
+
+func notFound(format string, args ...interface{}) error {
+	return &requestError{http.StatusNotFound, fmt.Errorf(format, args...)}
+}
+
+func preconditionFailed(format string, args ...interface{}) error {
+	return &requestError{http.StatusPreconditionFailed, fmt.Errorf(format, args...)}
+}
+
+// errorStatus returns the HTTP status an error is reported with
+func errorStatus(err error) int {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		return reqErr.status
+	}
+	return http.StatusInternalServerError
+}
+
+// decodeJSON decodes a request body, rejecting unknown fields
+func decodeJSON(r io.Reader, v interface{}) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return badRequest(fmt.Errorf("invalid JSON: %w", err))
+	}
+	return nil
+}
+
+// todoInput holds the fields of a todo to create or change. Priority and
+// status are names such as "high" and "done", or their numbers; due is a
+// date such as "2025-06-20", "tomorrow" or "3d", or null to clear it.
+type todoInput struct {
+	Description *string          `json:"description"`
+	Priority    *json.RawMessage `json:"priority"`
+	Status      *json.RawMessage `json:"status"`
+	Tag         *string          `json:"tag"`
+	Due         *json.RawMessage `json:"due"`
+	Notes       *string          `json:"notes"`
+	// UpdatedAt, if given, must match the todo's, so that the change fails
+	// if the todo was changed since it was read
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+// changes validates the input
+func (in *todoInput) changes() (*todo.Changes, error) {
+	c := &todo.Changes{Tag: in.Tag, Notes: in.Notes}
+	if in.Description != nil {
+		if err := todo.ValidateDescription(*in.Description); err != nil {
+			return nil, badRequest(err)
+		}
+		c.Description = in.Description
+	}
+	if in.Priority != nil {
+		priority, err := todo.ParsePriority(rawString(*in.Priority))
+		if err != nil {
+			return nil, badRequest(fmt.Errorf("invalid priority: %w", err))
+		}
+		c.Priority = &priority
+	}
+	if in.Status != nil {
+		status, err := todo.ParseStatus(rawString(*in.Status))
+		if err != nil {
+			return nil, badRequest(fmt.Errorf("invalid status: %w", err))
+		}
+		c.Status = &status
+	}
+	if in.Due != nil {
+		due, err := todo.ParseDueDate(rawString(*in.Due))
+		if err != nil {
+			return nil, badRequest(fmt.Errorf("invalid due: %w", err))
+		}
+		c.Due, c.DueSet = due, true
+	}
+	return c, nil
+}
+
+// rawString returns a JSON string's value, or the text of any other JSON
+// value such as a number. null becomes an empty string.
+func rawString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	if string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
+
+// listQuery holds the options of a todo listing, as for 'tada list'
+type listQuery struct {
+	Status   string `json:"status"`
+	Priority string `json:"priority"`
+	Tag      string `json:"tag"`
+	Filter   string `json:"filter"`
+	Sort     string `json:"sort"`
+}
+
+func (s *Server) listTodos(q listQuery) ([]*todo.Todo, error) {
+	filter, err := todo.ParseListFilter(q.Status, q.Priority, q.Tag, q.Filter)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	sortKeys, err := todo.ParseSort(q.Sort)
+	if err != nil {
+		return nil, badRequest(fmt.Errorf("invalid sort: %w", err))
+	}
+
+	todos, err := s.todos.Find(filter)
+	if err != nil {
+		return nil, err
+	}
+	todo.SortTodos(todos, sortKeys)
+	if todos == nil {
+		todos = []*todo.Todo{}
+	}
+	return todos, nil
+}
+
+func (s *Server) getTodo(id int) (*todo.Todo, error) {
+	t, err := s.todos.Get(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("todo #%d not found", id)
+	}
+	return t, err
+}
+
+func (s *Server) createTodo(in *todoInput) (*todo.Todo, error) {
+	changes, err := in.changes()
+	if err != nil {
+		return nil, err
+	}
+	if changes.Description == nil {
+		return nil, badRequest(errors.New("description is required"))
+	}
+	priority := todo.Medium
+	if changes.Priority != nil {
+		priority = *changes.Priority
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var t *todo.Todo
+	err = s.todos.Transaction(func(db *todo.DB) error {
+		if t, err = db.Create(*changes.Description, priority); err != nil {
+			return err
+		}
+		// Create takes the description and priority; the rest are updates
+		changes.Description, changes.Priority = nil, nil
+		return changes.Apply(db, t.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.changed()
+	return s.todos.Get(t.ID)
+}
+
+// updateTodo changes a todo. Unless ifMatch is empty or "*", it must be the
+// todo's current ETag.
+func (s *Server) updateTodo(id int, in *todoInput, ifMatch string) (*todo.Todo, error) {
+	changes, err := in.changes()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.getTodo(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPrecondition(t, ifMatch, in.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	if err := changes.Apply(s.todos, id); err != nil {
+		return nil, err
+	}
+	s.changed()
+	return s.todos.Get(id)
+}
+
+func (s *Server) deleteTodo(id int, ifMatch string, updatedAt *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.getTodo(id)
+	if err != nil {
+		return err
+	}
+	if err := checkPrecondition(t, ifMatch, updatedAt); err != nil {
+		return err
+	}
+
+	if err := s.todos.Delete(id); err != nil {
+		return err
+	}
+	s.changed()
+	return nil
+}
+
+// checkPrecondition makes sure the client changes the version of the todo
+// it last read, given by its ETag or its updated_at
+func checkPrecondition(t *todo.Todo, ifMatch string, updatedAt *time.Time) error {
+	if ifMatch != "" && ifMatch != "*" && ifMatch != etag(t) {
+		return preconditionFailed("todo #%d was changed since it was read", t.ID)
+	}
+	if updatedAt != nil && !updatedAt.Equal(t.UpdatedAt) {
+		return preconditionFailed("todo #%d was changed since it was read (updated_at is %s)", t.ID, t.UpdatedAt.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// etag identifies a version of a todo. It is a hash of the whole todo, so it
+// changes even when two changes are made within the same second.
+func etag(t *todo.Todo) string {
+	data, _ := json.Marshal(t)
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf(`"%x"`, sum[:8])
+}
+
+func (s *Server) changed() {
+	if s.OnChange != nil {
+		s.OnChange()
+	}
+}
+
+// quoteInput holds the fields of a quote to create or change
+type quoteInput struct {
+	Text     *string `json:"text"`
+	Author   *string `json:"author"`
+	Category *string `json:"category"`
+}
+
+// merged returns the quote with the fields given in the input replaced,
+// validated
+func (in *quoteInput) merged(q *quote.Quote) (*quote.Quote, error) {
+	out := *q
+	if in.Text != nil {
+		out.Text = strings.TrimSpace(*in.Text)
+	}
+	if in.Author != nil {
+		out.Author = strings.TrimSpace(*in.Author)
+	}
+	if in.Category != nil {
+		out.Category = strings.TrimSpace(*in.Category)
+	}
+
+	if err := quote.ValidateQuoteText(out.Text); err != nil {
+		return nil, badRequest(err)
+	}
+	if err := quote.ValidateAuthor(out.Author); err != nil {
+		return nil, badRequest(err)
+	}
+	if err := quote.ValidateCategory(out.Category); err != nil {
+		return nil, badRequest(err)
+	}
+	return &out, nil
+}
+
+func (s *Server) listQuotes(author, category string) ([]*quote.Quote, error) {
+	quotes, err := s.quotes.List(&author, &category)
+	if err != nil {
+		return nil, err
+	}
+	if quotes == nil {
+		quotes = []*quote.Quote{}
+	}
+	return quotes, nil
+}
+
+func (s *Server) randomQuote() (*quote.Quote, error) {
+	q, err := s.quotes.GetRandom()
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("no quotes yet")
+	}
+	return q, err
+}
+
+func (s *Server) getQuote(id int) (*quote.Quote, error) {
+	q, err := s.quotes.Get(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("quote #%d not found", id)
+	}
+	return q, err
+}
+
+func (s *Server) createQuote(in *quoteInput) (*quote.Quote, error) {
+	q, err := in.merged(&quote.Quote{})
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, err = s.quotes.Create(q.Text, q.Author, q.Category)
+	if err != nil {
+		return nil, err
+	}
+	s.changed()
+	return q, nil
+}
+
+func (s *Server) updateQuote(id int, in *quoteInput) (*quote.Quote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, err := s.getQuote(id)
+	if err != nil {
+		return nil, err
+	}
+	q, err = in.merged(q)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.quotes.Update(id, q.Text, q.Author, q.Category); err != nil {
+		return nil, err
+	}
+	s.changed()
+	return s.quotes.Get(id)
+}
+
+func (s *Server) deleteQuote(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.getQuote(id); err != nil {
+		return err
+	}
+	if err := s.quotes.Delete(id); err != nil {
+		return err
+	}
+	s.changed()
+	return nil
+}
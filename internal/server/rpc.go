@@ -0,0 +1,344 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/negadras/tada/internal/watch"
+)
+
+// JSON-RPC 2.0 error codes. The first five are defined by the
+// specification, the others are tada's.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+	codeNotFound       = -32001
+	codeConflict       = -32002
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      json.RawMessage  `json:"id"`
+	Result  *json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// rpcNotification is sent to subscribed clients, without an ID
+type rpcNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// rpcConn is one client connection
+type rpcConn struct {
+	// methods runs a method of the protocol spoken on the connection
+	methods func(ctx context.Context, method string, params json.RawMessage) (interface{}, error)
+
+	// mu serializes writes, which come from requests and subscriptions
+	mu  sync.Mutex
+	out io.Writer
+
+	// unsubscribe stops the subscription, if any
+	unsubscribe context.CancelFunc
+}
+
+// ServeRPC serves JSON-RPC 2.0 requests read from r, writing responses to w,
+// one JSON value per line, until r ends or ctx is done. Once a client calls
+// "subscribe", a "changed" notification is sent each time the database file
+// at dbPath changes.
+func (s *Server) ServeRPC(ctx context.Context, r io.Reader, w io.Writer, dbPath string) error {
+	c := &rpcConn{out: w}
+	c.methods = func(ctx context.Context, method string, params json.RawMessage) (interface{}, error) {
+		return s.rpcMethod(ctx, c, method, params, dbPath)
+	}
+	defer c.stopSubscription()
+	return c.serve(ctx, r)
+}
+
+// serve answers the requests read from r until r ends or ctx is done
+func (c *rpcConn) serve(ctx context.Context, r io.Reader) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				// The rest of the stream cannot be trusted after a syntax error
+				c.write(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"),
+					Error: &rpcError{codeParseError, "parse error: " + err.Error()}})
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		raw = bytes.TrimSpace(raw)
+		if len(raw) > 0 && raw[0] == '[' {
+			c.batch(ctx, raw)
+			continue
+		}
+		if resp := c.handle(ctx, raw); resp != nil {
+			c.write(resp)
+		}
+	}
+}
+
+// batch handles a batch of requests, answering with an array of responses
+func (c *rpcConn) batch(ctx context.Context, raw json.RawMessage) {
+	var requests []json.RawMessage
+	if err := json.Unmarshal(raw, &requests); err != nil || len(requests) == 0 {
+		c.write(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"),
+			Error: &rpcError{codeInvalidRequest, "invalid batch"}})
+		return
+	}
+
+	var responses []*rpcResponse
+	for _, req := range requests {
+		if resp := c.handle(ctx, req); resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	if len(responses) > 0 {
+		c.write(responses)
+	}
+}
+
+// handle runs one request, returning nil for notifications
+func (c *rpcConn) handle(ctx context.Context, raw json.RawMessage) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil || req.JSONRPC != "2.0" || req.Method == "" {
+		return &rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"),
+			Error: &rpcError{codeInvalidRequest, "invalid request"}}
+	}
+
+	result, err := c.methods(ctx, req.Method, req.Params)
+	if req.ID == nil {
+		return nil
+	}
+
+	resp := &rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	if err != nil {
+		resp.Error = toRPCError(err)
+		return resp
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		resp.Error = &rpcError{codeInternalError, err.Error()}
+		return resp
+	}
+	msg := json.RawMessage(data)
+	resp.Result = &msg
+	return resp
+}
+
+// errMethodNotFound is returned for unknown methods
+var errMethodNotFound = errors.New("method not found")
+
+// rpcMethod runs a method of tada's JSON-RPC protocol
+func (s *Server) rpcMethod(ctx context.Context, c *rpcConn, method string, params json.RawMessage, dbPath string) (interface{}, error) {
+	switch method {
+	case "todos.list":
+		var q listQuery
+		if err := decodeParams(params, &q); err != nil {
+			return nil, err
+		}
+		return s.listTodos(q)
+
+	case "todos.get":
+		var p idParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.getTodo(p.ID)
+
+	case "todos.create":
+		var in todoInput
+		if err := decodeParams(params, &in); err != nil {
+			return nil, err
+		}
+		return s.createTodo(&in)
+
+	case "todos.update":
+		var p struct {
+			ID int `json:"id"`
+			todoInput
+		}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.updateTodo(p.ID, &p.todoInput, "")
+
+	case "todos.delete":
+		var p struct {
+			ID        int        `json:"id"`
+			UpdatedAt *time.Time `json:"updated_at"`
+		}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return nil, s.deleteTodo(p.ID, "", p.UpdatedAt)
+
+	case "quotes.list":
+		var p struct {
+			Author   string `json:"author"`
+			Category string `json:"category"`
+		}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.listQuotes(p.Author, p.Category)
+
+	case "quotes.get":
+		var p idParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.getQuote(p.ID)
+
+	case "quotes.random":
+		return s.randomQuote()
+
+	case "quotes.create":
+		var in quoteInput
+		if err := decodeParams(params, &in); err != nil {
+			return nil, err
+		}
+		return s.createQuote(&in)
+
+	case "quotes.update":
+		var p struct {
+			ID int `json:"id"`
+			quoteInput
+		}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.updateQuote(p.ID, &p.quoteInput)
+
+	case "quotes.delete":
+		var p idParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return nil, s.deleteQuote(p.ID)
+
+	case "subscribe":
+		if dbPath == "" {
+			return nil, errors.New("subscriptions are not available")
+		}
+		c.subscribe(ctx, dbPath)
+		return true, nil
+
+	case "unsubscribe":
+		c.stopSubscription()
+		return true, nil
+	}
+
+	return nil, errMethodNotFound
+}
+
+// idParams are the params of methods taking a todo or quote ID
+type idParams struct {
+	ID int `json:"id"`
+}
+
+// decodeParams decodes the params of a request. Missing params are an
+// empty object.
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 || string(params) == "null" {
+		params = json.RawMessage("{}")
+	}
+	return decodeJSON(bytes.NewReader(params), v)
+}
+
+// subscribe sends a "changed" notification each time the database changes
+func (c *rpcConn) subscribe(ctx context.Context, dbPath string) {
+	c.stopSubscription()
+
+	ctx, cancel := context.WithCancel(ctx)
+	c.mu.Lock()
+	c.unsubscribe = cancel
+	c.mu.Unlock()
+
+	changes := watch.File(ctx, dbPath)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changes:
+				c.write(rpcNotification{JSONRPC: "2.0", Method: "changed", Params: struct{}{}})
+			}
+		}
+	}()
+}
+
+func (c *rpcConn) stopSubscription() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.unsubscribe != nil {
+		c.unsubscribe()
+		c.unsubscribe = nil
+	}
+}
+
+// write writes one message as a line of JSON
+func (c *rpcConn) write(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, _ = c.out.Write(append(data, '\n'))
+}
+
+// toRPCError converts an error of an operation to a JSON-RPC error
+func toRPCError(err error) *rpcError {
+	if errors.Is(err, errMethodNotFound) {
+		return &rpcError{codeMethodNotFound, err.Error()}
+	}
+
+	switch errorStatus(err) {
+	case http.StatusBadRequest:
+		return &rpcError{codeInvalidParams, err.Error()}
+	case http.StatusNotFound:
+		return &rpcError{codeNotFound, err.Error()}
+	case http.StatusPreconditionFailed:
+		return &rpcError{codeConflict, err.Error()}
+	default:
+		return &rpcError{codeInternalError, fmt.Sprintf("internal error: %v", err)}
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/negadras/tada/internal/todo"
+	"github.com/negadras/tada/internal/watch"
+)
+
+// rpcClient talks to ServeRPC through pipes
+type rpcClient struct {
+	in  io.WriteCloser
+	out *bufio.Scanner
+}
+
+func newRPCClient(t *testing.T) (*rpcClient, *todo.DB) {
+	t.Helper()
+	return newClient(t, func(s *Server, r io.Reader, w io.Writer, dbPath string) {
+		s.ServeRPC(context.Background(), r, w, dbPath)
+	})
+}
+
+// newClient starts serve on a new server and returns a client of it
+func newClient(t *testing.T, serve func(s *Server, r io.Reader, w io.Writer, dbPath string)) (*rpcClient, *todo.DB) {
+	t.Helper()
+	db, quoteDB, path := newTestDatabases(t)
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	go func() {
+		serve(New(db, quoteDB, ""), inR, outW, path)
+		outW.Close()
+	}()
+	t.Cleanup(func() { inW.Close() })
+
+	return &rpcClient{in: inW, out: bufio.NewScanner(outR)}, db
+}
+
+// call sends a line and returns the line received in reply
+func (c *rpcClient) call(t *testing.T, line string) string {
+	t.Helper()
+	if _, err := io.WriteString(c.in, line+"\n"); err != nil {
+		t.Fatal(err)
+	}
+	return c.next(t)
+}
+
+func (c *rpcClient) next(t *testing.T) string {
+	t.Helper()
+	lines := make(chan string, 1)
+	go func() {
+		if c.out.Scan() {
+			lines <- c.out.Text()
+		}
+	}()
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(2 * time.Second):
+		t.Fatal("no reply")
+		return ""
+	}
+}
+
+func TestServeRPC(t *testing.T) {
+	client, db := newRPCClient(t)
+
+	tests := []struct {
+		name    string
+		request string
+		want    string
+	}{
+		{
+			"create",
+			`{"jsonrpc": "2.0", "id": 1, "method": "todos.create", "params": {"description": "Fix login bug", "priority": "high"}}`,
+			`"result":{"id":1,"description":"Fix login bug","priority":3`,
+		},
+		{
+			"update",
+			`{"jsonrpc": "2.0", "id": "two", "method": "todos.update", "params": {"id": 1, "status": "done"}}`,
+			`"id":"two","result":{"id":1,"description":"Fix login bug","priority":3,"status":2`,
+		},
+		{
+			"list with filters",
+			`{"jsonrpc": "2.0", "id": 3, "method": "todos.list", "params": {"status": "done", "filter": "priority:high"}}`,
+			`"result":[{"id":1,`,
+		},
+		{
+			"stale update",
+			`{"jsonrpc": "2.0", "id": 4, "method": "todos.update", "params": {"id": 1, "tag": "x", "updated_at": "2001-01-01T00:00:00Z"}}`,
+			`"error":{"code":-32002,`,
+		},
+		{
+			"missing todo",
+			`{"jsonrpc": "2.0", "id": 5, "method": "todos.get", "params": {"id": 42}}`,
+			`"error":{"code":-32001,"message":"todo #42 not found"}`,
+		},
+		{
+			"invalid params",
+			`{"jsonrpc": "2.0", "id": 6, "method": "todos.create", "params": {"description": "x", "priority": "urgent"}}`,
+			`"error":{"code":-32602,`,
+		},
+		{
+			"unknown method",
+			`{"jsonrpc": "2.0", "id": 7, "method": "todos.frobnicate"}`,
+			`"error":{"code":-32601,`,
+		},
+		{
+			"invalid request",
+			`{"id": 8, "method": "todos.list"}`,
+			`"error":{"code":-32600,`,
+		},
+		{
+			"batch skips notifications",
+			`[{"jsonrpc": "2.0", "method": "todos.list"}, {"jsonrpc": "2.0", "id": 9, "method": "quotes.list"}]`,
+			`[{"jsonrpc":"2.0","id":9,"result":[]}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := client.call(t, tt.request); !strings.Contains(got, tt.want) {
+				t.Errorf("reply = %s, want it to contain %s", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("subscription", func(t *testing.T) {
+		watch.Interval = 10 * time.Millisecond
+		if got := client.call(t, `{"jsonrpc": "2.0", "id": 10, "method": "subscribe"}`); !strings.Contains(got, `"result":true`) {
+			t.Fatalf("subscribe reply = %s", got)
+		}
+
+		// A change made outside the connection, as by another tada command
+		time.Sleep(50 * time.Millisecond)
+		db.Create("Added elsewhere", todo.Low)
+
+		var notification struct {
+			Method string          `json:"method"`
+			ID     json.RawMessage `json:"id"`
+		}
+		json.Unmarshal([]byte(client.next(t)), &notification)
+		if notification.Method != "changed" || notification.ID != nil {
+			t.Errorf("notification = %+v, want changed without an ID", notification)
+		}
+	})
+}
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/todo"
+)
+
+//go:embed openapi.json
+var openAPI []byte
+
+// Server serves todos and quotes over a JSON REST API
+type Server struct {
+	todos  *todo.DB
+	quotes *quote.DB
+	token  string
+
+	// OnChange, if set, is called after each request that changed a todo or
+	// quote
+	OnChange func()
+
+	// mu serializes changes, so that checking a todo's ETag and changing the
+	// todo happen together
+	mu sync.Mutex
+}
+
+// New returns a server for the given databases. Unless token is empty,
+// every request but the OpenAPI document must carry it as a bearer token.
+func New(todos *todo.DB, quotes *quote.DB, token string) *Server {
+	return &Server{todos: todos, quotes: quotes, token: token}
+}
+
+// Handler returns the HTTP handler of the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /openapi.json", s.openAPI)
+	mux.Handle("GET /todos", s.auth(s.handleListTodos))
+	mux.Handle("POST /todos", s.auth(s.handleCreateTodo))
+	mux.Handle("GET /todos/{id}", s.auth(s.handleGetTodo))
+	mux.Handle("PATCH /todos/{id}", s.auth(s.handleUpdateTodo))
+	mux.Handle("DELETE /todos/{id}", s.auth(s.handleDeleteTodo))
+	mux.Handle("GET /quotes", s.auth(s.handleListQuotes))
+	mux.Handle("POST /quotes", s.auth(s.handleCreateQuote))
+	mux.Handle("GET /quotes/random", s.auth(s.handleRandomQuote))
+	mux.Handle("PATCH /quotes/{id}", s.auth(s.handleUpdateQuote))
+	mux.Handle("DELETE /quotes/{id}", s.auth(s.handleDeleteQuote))
+	return mux
+}
+
+// readJSON decodes the JSON body of a request. Other content types are
+// refused: a web page can send a form or text/plain body to any address
+// without asking, but a JSON request from another origin needs the
+// server's permission first.
+func readJSON(r *http.Request, v interface{}) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		return &requestError{http.StatusUnsupportedMediaType, errors.New("content type must be application/json")}
+	}
+	return decodeJSON(r.Body, v)
+}
+
+// auth checks the bearer token of a request
+func (s *Server) auth(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(s.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="tada"`)
+				writeError(w, &requestError{http.StatusUnauthorized, errors.New("missing or wrong bearer token")})
+				return
+			}
+		}
+		next(w, r)
+	})
+}
+
+func (s *Server) openAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPI)
+}
+
+// handleListTodos lists todos, filtered by the same status, priority and
+// tag parameters as 'tada list' flags, a filter expression and sort keys
+func (s *Server) handleListTodos(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	todos, err := s.listTodos(listQuery{
+		Status:   query.Get("status"),
+		Priority: query.Get("priority"),
+		Tag:      query.Get("tag"),
+		Filter:   query.Get("filter"),
+		Sort:     query.Get("sort"),
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, todos)
+}
+
+func (s *Server) handleCreateTodo(w http.ResponseWriter, r *http.Request) {
+	var in todoInput
+	if err := readJSON(r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	t, err := s.createTodo(&in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/todos/%d", t.ID))
+	w.Header().Set("ETag", etag(t))
+	writeJSON(w, http.StatusCreated, t)
+}
+
+func (s *Server) handleGetTodo(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	t, err := s.getTodo(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	tag := etag(t)
+	if r.Header.Get("If-None-Match") == tag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", tag)
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (s *Server) handleUpdateTodo(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in todoInput
+	if err := readJSON(r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	t, err := s.updateTodo(id, &in, r.Header.Get("If-Match"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(t))
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (s *Server) handleDeleteTodo(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := s.deleteTodo(id, r.Header.Get("If-Match"), nil); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListQuotes lists quotes, optionally filtered by author and category
+func (s *Server) handleListQuotes(w http.ResponseWriter, r *http.Request) {
+	quotes, err := s.listQuotes(r.URL.Query().Get("author"), r.URL.Query().Get("category"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, quotes)
+}
+
+func (s *Server) handleRandomQuote(w http.ResponseWriter, r *http.Request) {
+	q, err := s.randomQuote()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, q)
+}
+
+func (s *Server) handleCreateQuote(w http.ResponseWriter, r *http.Request) {
+	var in quoteInput
+	if err := readJSON(r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	q, err := s.createQuote(&in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/quotes/%d", q.ID))
+	writeJSON(w, http.StatusCreated, q)
+}
+
+func (s *Server) handleUpdateQuote(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var in quoteInput
+	if err := readJSON(r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	q, err := s.updateQuote(id, &in)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, q)
+}
+
+func (s *Server) handleDeleteQuote(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := s.deleteQuote(id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pathID reads the ID in a request's path
+func pathID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return 0, badRequest(fmt.Errorf("invalid ID %q", r.PathValue("id")))
+	}
+	return id, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error as {"error": "..."}, with the status of a
+// request error or 500
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, errorStatus(err), map[string]string{"error": err.Error()})
+}
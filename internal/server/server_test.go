@@ -0,0 +1,191 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/todo"
+)
+
+// newTestDatabases opens todo and quote databases in a temporary file
+func newTestDatabases(t *testing.T) (*todo.DB, *quote.DB, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := todo.NewDB(path)
+	if err != nil {
+		t.Fatalf("todo.NewDB() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	quoteDB, err := quote.NewDB(path)
+	if err != nil {
+		t.Fatalf("quote.NewDB() error = %v", err)
+	}
+	t.Cleanup(func() { quoteDB.Close() })
+	return db, quoteDB, path
+}
+
+func newTestServer(t *testing.T, token string) (*httptest.Server, *todo.DB) {
+	t.Helper()
+	db, quoteDB, _ := newTestDatabases(t)
+
+	ts := httptest.NewServer(New(db, quoteDB, token).Handler())
+	t.Cleanup(ts.Close)
+	return ts, db
+}
+
+func do(t *testing.T, ts *httptest.Server, method, path, body string, header map[string]string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestServer(t *testing.T) {
+	ts, db := newTestServer(t, "")
+	db.Create("Call mom", todo.Low)
+
+	t.Run("create", func(t *testing.T) {
+		resp := do(t, ts, "POST", "/todos", `{"description": "Fix login bug", "priority": "high", "tag": "work", "due": "2025-06-20"}`, nil)
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("status = %d, want 201", resp.StatusCode)
+		}
+		var got todo.Todo
+		json.NewDecoder(resp.Body).Decode(&got)
+		if got.Priority != todo.High || got.Tag != "work" || got.DueAt == nil || resp.Header.Get("Location") != "/todos/2" {
+			t.Errorf("created %+v at %q", got, resp.Header.Get("Location"))
+		}
+	})
+
+	t.Run("create needs a JSON content type", func(t *testing.T) {
+		resp := do(t, ts, "POST", "/todos", `{"description": "From another site"}`, map[string]string{"Content-Type": "text/plain"})
+		if resp.StatusCode != http.StatusUnsupportedMediaType {
+			t.Errorf("status = %d, want 415", resp.StatusCode)
+		}
+	})
+
+	t.Run("list with filters", func(t *testing.T) {
+		tests := []struct {
+			query string
+			want  int
+		}{
+			{"", 2},
+			{"?priority=high", 1},
+			{"?tag=work", 1},
+			{"?filter=" + "priority>=medium", 1},
+			{"?status=done", 0},
+		}
+		for _, tt := range tests {
+			resp := do(t, ts, "GET", "/todos"+tt.query, "", nil)
+			var got []todo.Todo
+			json.NewDecoder(resp.Body).Decode(&got)
+			if resp.StatusCode != http.StatusOK || len(got) != tt.want {
+				t.Errorf("GET /todos%s = %d, %d todos, want %d", tt.query, resp.StatusCode, len(got), tt.want)
+			}
+		}
+
+		if resp := do(t, ts, "GET", "/todos?priority=urgent", "", nil); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("invalid priority status = %d, want 400", resp.StatusCode)
+		}
+	})
+
+	t.Run("optimistic concurrency", func(t *testing.T) {
+		resp := do(t, ts, "GET", "/todos/1", "", nil)
+		tag := resp.Header.Get("ETag")
+		if tag == "" {
+			t.Fatal("GET /todos/1 has no ETag")
+		}
+
+		resp = do(t, ts, "PATCH", "/todos/1", `{"status": "done"}`, map[string]string{"If-Match": tag})
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") == tag {
+			t.Fatalf("PATCH status = %d, ETag = %q", resp.StatusCode, resp.Header.Get("ETag"))
+		}
+
+		// The todo changed, so the old ETag no longer matches
+		resp = do(t, ts, "PATCH", "/todos/1", `{"priority": "high"}`, map[string]string{"If-Match": tag})
+		if resp.StatusCode != http.StatusPreconditionFailed {
+			t.Errorf("stale PATCH status = %d, want 412", resp.StatusCode)
+		}
+		resp = do(t, ts, "PATCH", "/todos/1", `{"priority": "high", "updated_at": "2001-01-01T00:00:00Z"}`, nil)
+		if resp.StatusCode != http.StatusPreconditionFailed {
+			t.Errorf("stale updated_at PATCH status = %d, want 412", resp.StatusCode)
+		}
+		if got, _ := db.Get(1); got.Status != todo.Done || got.Priority != todo.Low {
+			t.Errorf("todo = %v %v, want DONE and LOW", got.Status, got.Priority)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if resp := do(t, ts, "DELETE", "/todos/1", "", nil); resp.StatusCode != http.StatusNoContent {
+			t.Errorf("DELETE status = %d, want 204", resp.StatusCode)
+		}
+		if resp := do(t, ts, "GET", "/todos/1", "", nil); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET deleted status = %d, want 404", resp.StatusCode)
+		}
+	})
+
+	t.Run("quotes", func(t *testing.T) {
+		if resp := do(t, ts, "GET", "/quotes/random", "", nil); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("random quote with no quotes status = %d, want 404", resp.StatusCode)
+		}
+
+		resp := do(t, ts, "POST", "/quotes", `{"text": "Stay hungry", "author": "Steve Jobs"}`, nil)
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("POST /quotes status = %d, want 201", resp.StatusCode)
+		}
+		if resp := do(t, ts, "POST", "/quotes", `{"author": "Nobody"}`, nil); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("POST /quotes without text status = %d, want 400", resp.StatusCode)
+		}
+
+		resp = do(t, ts, "PATCH", "/quotes/1", `{"category": "motivation"}`, nil)
+		var got quote.Quote
+		json.NewDecoder(resp.Body).Decode(&got)
+		if resp.StatusCode != http.StatusOK || got.Text != "Stay hungry" || got.Category != "motivation" {
+			t.Errorf("PATCH /quotes/1 = %d, %+v", resp.StatusCode, got)
+		}
+
+		if resp := do(t, ts, "GET", "/quotes/random", "", nil); resp.StatusCode != http.StatusOK {
+			t.Errorf("random quote status = %d, want 200", resp.StatusCode)
+		}
+		if resp := do(t, ts, "DELETE", "/quotes/1", "", nil); resp.StatusCode != http.StatusNoContent {
+			t.Errorf("DELETE /quotes/1 status = %d, want 204", resp.StatusCode)
+		}
+	})
+}
+
+func TestServer_Token(t *testing.T) {
+	ts, _ := newTestServer(t, "s3cret")
+
+	tests := []struct {
+		path   string
+		header map[string]string
+		want   int
+	}{
+		{"/todos", nil, http.StatusUnauthorized},
+		{"/todos", map[string]string{"Authorization": "Bearer wrong"}, http.StatusUnauthorized},
+		{"/todos", map[string]string{"Authorization": "Bearer s3cret"}, http.StatusOK},
+		{"/openapi.json", nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if resp := do(t, ts, "GET", tt.path, "", tt.header); resp.StatusCode != tt.want {
+			t.Errorf("GET %s with %v = %d, want %d", tt.path, tt.header, resp.StatusCode, tt.want)
+		}
+	}
+}
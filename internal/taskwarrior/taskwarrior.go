@@ -0,0 +1,290 @@
+package taskwarrior
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/negadras/tada/internal/todo"
+)
+
+// timeLayout is the timestamp format used by task export
+const timeLayout = "20060102T150405Z"
+
+// maxDescription is the longest description tada accepts
+const maxDescription = 255
+
+// task is a task as written by task export
+type task struct {
+	UUID        string       `json:"uuid"`
+	Description string       `json:"description"`
+	Status      string       `json:"status"`
+	Priority    string       `json:"priority"`
+	Project     string       `json:"project"`
+	Tags        []string     `json:"tags"`
+	Entry       string       `json:"entry"`
+	Modified    string       `json:"modified"`
+	End         string       `json:"end"`
+	Due         string       `json:"due"`
+	Annotations []annotation `json:"annotations"`
+}
+
+type annotation struct {
+	Entry       string `json:"entry"`
+	Description string `json:"description"`
+}
+
+// mappedFields are the task attributes that are imported. id and urgency
+// are computed by Taskwarrior, so losing them is not reported.
+var mappedFields = map[string]bool{
+	"uuid": true, "description": true, "status": true, "priority": true, "project": true, "tags": true,
+	"entry": true, "modified": true, "end": true, "due": true, "annotations": true,
+	"id": true, "urgency": true,
+}
+
+// Result is the outcome of parsing a Taskwarrior export
+type Result struct {
+	Todos []*todo.Todo
+	// Deleted and Recurring count the deleted tasks and recurring task
+	// templates that were not imported
+	Deleted   int
+	Recurring int
+	// SkippedFields counts, per attribute, the tasks that had a value tada
+	// has no place for. "tags" counts tasks with more than one tag and no
+	// project, and "project-tags" tasks whose tags gave way to the project.
+	SkippedFields map[string]int
+	// Shortened counts descriptions that were cut to fit; the full text is
+	// kept at the top of the notes
+	Shortened int
+}
+
+// SkippedSummary describes the skipped fields, e.g. "scheduled (12 tasks), wait (1 task)"
+func (r *Result) SkippedSummary() string {
+	names := make([]string, 0, len(r.SkippedFields))
+	for name := range r.SkippedFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		n := r.SkippedFields[name]
+		label := name
+		switch name {
+		case "tags":
+			label = "tags beyond the first"
+		case "project-tags":
+			label = "tags of tasks with a project"
+		}
+		if n == 1 {
+			parts[i] = fmt.Sprintf("%s (1 task)", label)
+		} else {
+			parts[i] = fmt.Sprintf("%s (%d tasks)", label, n)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Parse reads the output of task export, either a JSON array or one JSON
+// object per line as written by older versions of Taskwarrior.
+//
+// Priorities H, M and L map to high, medium and low (none is medium);
+// pending and waiting tasks are open, completed tasks are done, and deleted
+// tasks and recurring templates are skipped. The uuid becomes the UID, and
+// the project, or the first tag without a project, becomes the tag. entry,
+// modified, end and due set the creation, update, completion and due dates,
+// and annotations become notes.
+func Parse(r io.Reader) (*Result, error) {
+	raws, err := readTasks(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{SkippedFields: map[string]int{}}
+	for i, raw := range raws {
+		var fields map[string]json.RawMessage
+		var tw task
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, fmt.Errorf("task %d: %w", i+1, err)
+		}
+		if err := json.Unmarshal(raw, &tw); err != nil {
+			return nil, fmt.Errorf("task %d: %w", i+1, err)
+		}
+
+		switch tw.Status {
+		case "deleted":
+			result.Deleted++
+			continue
+		case "recurring":
+			result.Recurring++
+			continue
+		}
+
+		t, err := convert(&tw, result)
+		if err != nil {
+			return nil, fmt.Errorf("task %d (%q): %w", i+1, tw.Description, err)
+		}
+		result.Todos = append(result.Todos, t)
+
+		for name := range fields {
+			if !mappedFields[name] {
+				result.SkippedFields[name]++
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// readTasks splits the export into one raw JSON object per task
+func readTasks(r io.Reader) ([]json.RawMessage, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Taskwarrior export: %w", err)
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+
+	if trimmed[0] == '[' {
+		var raws []json.RawMessage
+		if err := json.Unmarshal(trimmed, &raws); err != nil {
+			return nil, fmt.Errorf("not a Taskwarrior export: %w", err)
+		}
+		return raws, nil
+	}
+
+	var raws []json.RawMessage
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(trimmed)+1)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := bytes.TrimSuffix(bytes.TrimSpace(scanner.Bytes()), []byte(","))
+		if len(line) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			return nil, fmt.Errorf("not a Taskwarrior export: line %d is not JSON", lineNo)
+		}
+		raws = append(raws, json.RawMessage(append([]byte(nil), line...)))
+	}
+	return raws, scanner.Err()
+}
+
+// convert maps a Taskwarrior task onto a todo
+func convert(tw *task, result *Result) (*todo.Todo, error) {
+	t := &todo.Todo{Status: todo.Open, UID: tw.UUID}
+
+	switch tw.Priority {
+	case "H":
+		t.Priority = todo.High
+	case "M", "":
+		t.Priority = todo.Medium
+	case "L":
+		t.Priority = todo.Low
+	default:
+		return nil, fmt.Errorf("unknown priority %q", tw.Priority)
+	}
+
+	switch tw.Status {
+	case "pending", "waiting", "":
+	case "completed":
+		t.Status = todo.Done
+	default:
+		return nil, fmt.Errorf("unknown status %q", tw.Status)
+	}
+
+	t.Tag = tw.Project
+	if t.Tag == "" && len(tw.Tags) > 0 {
+		t.Tag = tw.Tags[0]
+		if len(tw.Tags) > 1 {
+			result.SkippedFields["tags"]++
+		}
+	} else if len(tw.Tags) > 0 {
+		result.SkippedFields["project-tags"]++
+	}
+
+	var err error
+	if t.CreatedAt, err = parseTime(tw.Entry, "entry"); err != nil {
+		return nil, err
+	}
+	if t.UpdatedAt, err = parseTime(tw.Modified, "modified"); err != nil {
+		return nil, err
+	}
+	if t.UpdatedAt.IsZero() {
+		t.UpdatedAt = t.CreatedAt
+	}
+	if tw.Due != "" {
+		due, err := parseTime(tw.Due, "due")
+		if err != nil {
+			return nil, err
+		}
+		t.DueAt = &due
+	}
+	if t.Status == todo.Done {
+		end, err := parseTime(tw.End, "end")
+		if err != nil {
+			return nil, err
+		}
+		if end.IsZero() {
+			end = t.UpdatedAt
+		}
+		t.CompletedAt = &end
+	}
+
+	var notes []string
+	description := strings.Join(strings.Fields(tw.Description), " ")
+	if len(description) > maxDescription {
+		notes = append(notes, description)
+		description = truncate(description, maxDescription)
+		result.Shortened++
+	}
+	t.Description = description
+	if err := todo.ValidateDescription(t.Description); err != nil {
+		return nil, err
+	}
+
+	for _, a := range tw.Annotations {
+		line := a.Description
+		if at, err := parseTime(a.Entry, "annotation entry"); err == nil && !at.IsZero() {
+			line = at.Local().Format("2006-01-02") + ": " + line
+		}
+		notes = append(notes, line)
+	}
+	t.Notes = strings.Join(notes, "\n")
+
+	return t, nil
+}
+
+// parseTime parses a Taskwarrior timestamp; an empty value is the zero time
+func parseTime(s, field string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(timeLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s timestamp %q", field, s)
+	}
+	return t, nil
+}
+
+// truncate shortens s to at most n bytes without splitting a character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[:n]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return strings.TrimSpace(s)
+}
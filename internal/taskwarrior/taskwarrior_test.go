@@ -0,0 +1,131 @@
+package taskwarrior
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/negadras/tada/internal/todo"
+)
+
+const export = `[
+{"id":1,"description":"Fix login bug","entry":"20240420T090000Z","modified":"20240421T100000Z","priority":"H","project":"backend","status":"pending","tags":["work","urgent"],"uuid":"a1","urgency":8.1,
+ "annotations":[{"entry":"20240421T100000Z","description":"Repro steps in ticket 42"}]},
+{"id":0,"description":"Ship release","end":"20240501T120000Z","entry":"20240420T090000Z","modified":"20240501T120000Z","status":"completed","tags":["launch"],"uuid":"b2","urgency":0},
+{"id":0,"description":"Old idea","entry":"20240101T090000Z","status":"deleted","uuid":"c3"},
+{"id":3,"description":"Water plants","entry":"20240420T090000Z","recur":"weekly","status":"recurring","uuid":"d4"},
+{"id":4,"description":"Renew passport","due":"20240601T000000Z","entry":"20240420T090000Z","priority":"L","status":"waiting","wait":"20240520T000000Z","uuid":"e5"}
+]`
+
+func TestParse(t *testing.T) {
+	result, err := Parse(strings.NewReader(export))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(result.Todos) != 3 {
+		t.Fatalf("Parse() returned %d todos, want 3", len(result.Todos))
+	}
+	if result.Deleted != 1 || result.Recurring != 1 {
+		t.Errorf("Parse() deleted = %d, recurring = %d; want 1 and 1", result.Deleted, result.Recurring)
+	}
+
+	fix := result.Todos[0]
+	if fix.Description != "Fix login bug" || fix.Priority != todo.High || fix.Status != todo.Open || fix.Tag != "backend" {
+		t.Errorf("first todo = %+v", fix)
+	}
+	if want := time.Date(2024, 4, 20, 9, 0, 0, 0, time.UTC); !fix.CreatedAt.Equal(want) {
+		t.Errorf("CreatedAt = %v, want %v", fix.CreatedAt, want)
+	}
+	if !strings.HasSuffix(fix.Notes, ": Repro steps in ticket 42") {
+		t.Errorf("Notes = %q, want the annotation", fix.Notes)
+	}
+
+	ship := result.Todos[1]
+	if ship.Status != todo.Done || ship.Priority != todo.Medium || ship.Tag != "launch" {
+		t.Errorf("second todo = %+v", ship)
+	}
+	if want := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC); ship.CompletedAt == nil || !ship.CompletedAt.Equal(want) {
+		t.Errorf("CompletedAt = %v, want %v", ship.CompletedAt, want)
+	}
+
+	passport := result.Todos[2]
+	if passport.Status != todo.Open || passport.Priority != todo.Low {
+		t.Errorf("third todo = %+v", passport)
+	}
+	if want := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC); passport.DueAt == nil || !passport.DueAt.Equal(want) {
+		t.Errorf("DueAt = %v, want %v", passport.DueAt, want)
+	}
+
+	if fix.UID != "a1" {
+		t.Errorf("UID = %q, want the task uuid", fix.UID)
+	}
+
+	want := map[string]int{"project-tags": 1, "wait": 1}
+	for name, n := range want {
+		if result.SkippedFields[name] != n {
+			t.Errorf("SkippedFields[%q] = %d, want %d", name, result.SkippedFields[name], n)
+		}
+	}
+	if summary := result.SkippedSummary(); summary != "tags of tasks with a project (1 task), wait (1 task)" {
+		t.Errorf("SkippedSummary() = %q", summary)
+	}
+}
+
+func TestParse_LineFormat(t *testing.T) {
+	input := `{"description":"One","entry":"20240420T090000Z","status":"pending"},
+{"description":"Two","entry":"20240420T090000Z","status":"pending"}
+`
+	result, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(result.Todos) != 2 {
+		t.Errorf("Parse() returned %d todos, want 2", len(result.Todos))
+	}
+}
+
+func TestParse_LongDescription(t *testing.T) {
+	long := strings.Repeat("word ", 80)
+	input := `[{"description":"` + long + `","entry":"20240420T090000Z","status":"pending"}]`
+
+	result, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if result.Shortened != 1 {
+		t.Errorf("Shortened = %d, want 1", result.Shortened)
+	}
+	got := result.Todos[0]
+	if len(got.Description) > 255 {
+		t.Errorf("Description has %d characters, want at most 255", len(got.Description))
+	}
+	if got.Notes != strings.TrimSpace(long) {
+		t.Errorf("Notes should keep the full description, got %q", got.Notes)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		errContains string
+	}{
+		{"not json", "(A) todo.txt line", "not a Taskwarrior export"},
+		{"bad priority", `[{"description":"x","priority":"X","status":"pending"}]`, "unknown priority"},
+		{"bad timestamp", `[{"description":"x","entry":"yesterday","status":"pending"}]`, "invalid entry timestamp"},
+		{"empty description", `[{"description":"","status":"pending"}]`, "description cannot be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.input))
+			if err == nil {
+				t.Fatal("Parse() expected error")
+			}
+			if !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("Parse() error = %v, want error containing %q", err, tt.errContains)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package todo
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxRange is the most IDs a range such as 3-9 may cover, so that a typo
+// does not touch the whole database
+const maxRange = 10000
+
+// querier runs statements on the connection or on a batch's transaction
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// q returns where the DB's statements run
+func (db *DB) q() querier {
+	if db.tx != nil {
+		return db.tx
+	}
+	return db.conn
+}
+
+// Result is the outcome of a batch operation on one todo
+type Result struct {
+	ID int
+	// Todo is the todo after the operation, or before it for deletions
+	Todo *Todo
+	Err  error
+}
+
+// BatchError reports that some todos of a batch failed; the others were
+// changed
+type BatchError struct {
+	Failed int
+	Total  int
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d of %d todos failed", e.Failed, e.Total)
+}
+
+// Transaction runs fn in a single transaction: the changes fn makes through
+// the DB it is given are all kept if it returns nil, and none are otherwise
+func (db *DB) Transaction(fn func(db *DB) error) error {
+	if db.tx != nil {
+		return fn(db)
+	}
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	batch := *db
+	batch.tx = tx
+	if err := fn(&batch); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Tx returns the transaction of a DB given by Transaction, for other stores
+// of the same database file to join, or nil outside of one
+func (db *DB) Tx() *sql.Tx {
+	return db.tx
+}
+
+// Batch runs fn for the todo of each ID in a single transaction and reports
+// the result for each. The changes for a todo whose fn fails are undone and
+// the others are kept; IDs without a todo fail. The error is a *BatchError
+// if any ID failed.
+func (db *DB) Batch(ids []int, fn func(db *DB, t *Todo) (*Todo, error)) ([]Result, error) {
+	results := make([]Result, 0, len(ids))
+	failed := 0
+	err := db.Transaction(func(batch *DB) error {
+		for _, id := range ids {
+			if _, err := batch.tx.Exec(`SAVEPOINT item`); err != nil {
+				return err
+			}
+			t, err := batch.Get(id)
+			if errors.Is(err, sql.ErrNoRows) {
+				err = fmt.Errorf("todo #%d not found", id)
+			}
+			if err == nil {
+				if t, err = fn(batch, t); err != nil {
+					err = fmt.Errorf("todo #%d: %w", id, err)
+				}
+			}
+			if err != nil {
+				failed++
+				if _, err := batch.tx.Exec(`ROLLBACK TO item`); err != nil {
+					return err
+				}
+			}
+			if _, err := batch.tx.Exec(`RELEASE item`); err != nil {
+				return err
+			}
+			results = append(results, Result{ID: id, Todo: t, Err: err})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if failed > 0 {
+		return results, &BatchError{Failed: failed, Total: len(ids)}
+	}
+	return results, nil
+}
+
+// ParseIDs parses todo IDs given as arguments: single IDs such as 5 or #5,
+// ranges such as 3-9, and - to read more from stdin, separated by spaces,
+// commas or new lines. Each ID is returned once, in the order given.
+func ParseIDs(args []string, stdin io.Reader) ([]int, error) {
+	var ids []int
+	seen := map[int]bool{}
+	add := func(field string) error {
+		first, last, err := parseIDRange(field)
+		if err != nil {
+			return err
+		}
+		for id := first; id <= last; id++ {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		return nil
+	}
+
+	for _, arg := range args {
+		fields := strings.FieldsFunc(arg, isIDSeparator)
+		if arg == "-" {
+			var err error
+			if fields, err = readIDFields(stdin); err != nil {
+				return nil, err
+			}
+		}
+		for _, field := range fields {
+			if err := add(field); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no todo IDs given")
+	}
+	return ids, nil
+}
+
+// parseIDRange parses an ID or a range of IDs
+func parseIDRange(field string) (int, int, error) {
+	first, last, isRange := strings.Cut(field, "-")
+	if !isRange {
+		last = first
+	}
+	from, err := parseID(first)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid todo ID %q", field)
+	}
+	to, err := parseID(last)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid todo ID %q", field)
+	}
+	if from > to {
+		return 0, 0, fmt.Errorf("invalid range %q, the first ID must come first", field)
+	}
+	if to-from >= maxRange {
+		return 0, 0, fmt.Errorf("range %q covers more than %d todos", field, maxRange)
+	}
+	return from, to, nil
+}
+
+// parseID parses a positive ID, with an optional #
+func parseID(s string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(s, "#"))
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid todo ID %q", s)
+	}
+	return id, nil
+}
+
+// readIDFields reads the IDs and ranges piped to a command
+func readIDFields(stdin io.Reader) ([]string, error) {
+	var fields []string
+	s := bufio.NewScanner(stdin)
+	for s.Scan() {
+		fields = append(fields, strings.FieldsFunc(s.Text(), isIDSeparator)...)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read IDs: %w", err)
+	}
+	return fields, nil
+}
+
+func isIDSeparator(r rune) bool {
+	return r == ',' || r == ' ' || r == '\t'
+}
@@ -0,0 +1,140 @@
+package todo
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		stdin   string
+		want    []int
+		wantErr bool
+	}{
+		{"single", []string{"5"}, "", []int{5}, false},
+		{"several", []string{"5", "#2", "9"}, "", []int{5, 2, 9}, false},
+		{"range", []string{"3-6"}, "", []int{3, 4, 5, 6}, false},
+		{"commas", []string{"1,3-4"}, "", []int{1, 3, 4}, false},
+		{"duplicates", []string{"2-4", "3", "2"}, "", []int{2, 3, 4}, false},
+		{"stdin", []string{"-"}, "7\n8\n\n10-11\n", []int{7, 8, 10, 11}, false},
+		{"stdin and args", []string{"1", "-"}, "2 3", []int{1, 2, 3}, false},
+		{"empty stdin", []string{"-"}, "", nil, true},
+		{"not a number", []string{"abc"}, "", nil, true},
+		{"zero", []string{"0"}, "", nil, true},
+		{"reversed range", []string{"9-3"}, "", nil, true},
+		{"open range", []string{"3-"}, "", nil, true},
+		{"huge range", []string{"1-100000"}, "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseIDs(tt.args, strings.NewReader(tt.stdin))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseIDs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDB_Batch(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	defer db.Close()
+
+	for _, d := range []string{"First", "Second", "Third"} {
+		if _, err := db.Create(d, Medium); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := db.Batch([]int{1, 2, 99, 3}, func(db *DB, t *Todo) (*Todo, error) {
+		if err := db.UpdatePriority(t.ID, High); err != nil {
+			return nil, err
+		}
+		// The change already made to the second todo must be undone
+		if t.ID == 2 {
+			return nil, errors.New("refused")
+		}
+		return db.Get(t.ID)
+	})
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || batchErr.Failed != 2 || batchErr.Total != 4 {
+		t.Fatalf("Batch() error = %v, want 2 of 4 failed", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("Batch() returned %d results, want 4", len(results))
+	}
+	for i, wantErr := range []bool{false, true, true, false} {
+		if (results[i].Err != nil) != wantErr {
+			t.Errorf("result for #%d error = %v, wantErr %v", results[i].ID, results[i].Err, wantErr)
+		}
+	}
+	if results[0].Todo.Priority != High {
+		t.Errorf("result for #1 priority = %v, want the updated todo", results[0].Todo.Priority)
+	}
+	if msg := results[2].Err.Error(); msg != "todo #99 not found" {
+		t.Errorf("result for #99 error = %q", msg)
+	}
+
+	for id, want := range map[int]Priority{1: High, 2: Medium, 3: High} {
+		got, err := db.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Priority != want {
+			t.Errorf("todo #%d priority = %v, want %v", id, got.Priority, want)
+		}
+	}
+
+	if _, err := db.Batch([]int{1}, func(db *DB, t *Todo) (*Todo, error) {
+		return t, db.Delete(t.ID)
+	}); err != nil {
+		t.Errorf("Batch() delete error = %v", err)
+	}
+	if _, err := db.Get(1); err == nil {
+		t.Error("todo #1 should be deleted")
+	}
+}
+
+func TestDB_Transaction(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	defer db.Close()
+
+	err = db.Transaction(func(db *DB) error {
+		if _, err := db.Create("Kept out", Medium); err != nil {
+			return err
+		}
+		return errors.New("refused")
+	})
+	if err == nil || err.Error() != "refused" {
+		t.Fatalf("Transaction() error = %v, want refused", err)
+	}
+	if todos, _ := db.List(nil, nil, nil); len(todos) != 0 {
+		t.Errorf("Transaction() kept %d todos after failing, want none", len(todos))
+	}
+
+	err = db.Transaction(func(db *DB) error {
+		_, err := db.Create("Kept", Medium)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Transaction() error = %v", err)
+	}
+	if todos, _ := db.List(nil, nil, nil); len(todos) != 1 {
+		t.Errorf("Transaction() kept %d todos, want 1", len(todos))
+	}
+}
@@ -0,0 +1,89 @@
+package todo
+
+import "time"
+
+// Changes holds new values for some fields of a todo. Nil fields are left
+// as they are; Due is only written when DueSet is true, and nil clears it.
+type Changes struct {
+	Description *string
+	Priority    *Priority
+	Status      *Status
+	Tag         *string
+	Due         *time.Time
+	DueSet      bool
+	Notes       *string
+}
+
+// Apply writes the changes to the todo with the given ID
+func (c *Changes) Apply(db *DB, id int) error {
+	if c.Status != nil {
+		if err := db.UpdateStatus(id, *c.Status); err != nil {
+			return err
+		}
+	}
+
+	if c.Priority != nil {
+		if err := db.UpdatePriority(id, *c.Priority); err != nil {
+			return err
+		}
+	}
+
+	if c.Description != nil {
+		if err := db.UpdateDescription(id, *c.Description); err != nil {
+			return err
+		}
+	}
+
+	if c.Tag != nil {
+		if err := db.UpdateTag(id, *c.Tag); err != nil {
+			return err
+		}
+	}
+
+	if c.DueSet {
+		if err := db.UpdateDue(id, c.Due); err != nil {
+			return err
+		}
+	}
+
+	if c.Notes != nil {
+		if err := db.UpdateNotes(id, *c.Notes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Diff returns the changes that turn old into t, for the fields that differ
+func Diff(old, t *Todo) *Changes {
+	c := &Changes{}
+	if t.Status != old.Status {
+		c.Status = &t.Status
+	}
+	if t.Priority != old.Priority {
+		c.Priority = &t.Priority
+	}
+	if t.Description != old.Description {
+		c.Description = &t.Description
+	}
+	if t.Tag != old.Tag {
+		c.Tag = &t.Tag
+	}
+	if !sameTime(t.DueAt, old.DueAt) {
+		c.Due = t.DueAt
+		c.DueSet = true
+	}
+	if t.Notes != old.Notes {
+		c.Notes = &t.Notes
+	}
+	return c
+}
+
+// sameTime reports whether two optional times are equal
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
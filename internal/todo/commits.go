@@ -0,0 +1,74 @@
+package todo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Commit is a git commit linked to a todo
+type Commit struct {
+	SHA string `json:"sha"`
+	// Repo is the top-level directory of the commit's repository
+	Repo     string    `json:"repo"`
+	Subject  string    `json:"subject,omitempty"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+// ShortSHA returns the abbreviated hash git shows
+func (c *Commit) ShortSHA() string {
+	if len(c.SHA) > 7 {
+		return c.SHA[:7]
+	}
+	return c.SHA
+}
+
+// LinkCommit links a commit to a todo. It reports whether the link is new.
+func (db *DB) LinkCommit(id int, c *Commit) (bool, error) {
+	var uid string
+	err := db.q().QueryRow(`SELECT uid FROM todos WHERE id = ?`, id).Scan(&uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("todo #%d not found", id)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	res, err := db.q().Exec(`
+		INSERT OR IGNORE INTO todo_commits (uid, sha, repo, subject)
+		VALUES (?, ?, ?, ?)
+	`, uid, c.SHA, c.Repo, db.cipher.Seal(c.Subject))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Commits returns the commits linked to a todo, oldest first
+func (db *DB) Commits(id int) ([]*Commit, error) {
+	rows, err := db.q().Query(`
+		SELECT c.sha, c.repo, c.subject, c.linked_at
+		FROM todo_commits c JOIN todos t ON t.uid = c.uid
+		WHERE t.id = ?
+		ORDER BY c.linked_at, c.rowid
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var commits []*Commit
+	for rows.Next() {
+		c := &Commit{}
+		if err := rows.Scan(&c.SHA, &c.Repo, &c.Subject, &c.LinkedAt); err != nil {
+			return nil, err
+		}
+		if c.Subject, err = db.cipher.Open(c.Subject); err != nil {
+			return nil, err
+		}
+		commits = append(commits, c)
+	}
+	return commits, rows.Err()
+}
@@ -0,0 +1,791 @@
+package todo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Filter is a compiled filter expression such as
+//
+//	priority>=medium and (tag:work or tag:oncall) and created<7d and desc~"deploy"
+//
+// A filter can be turned into a parameterised SQL WHERE clause or evaluated
+// against a Todo in memory. A nil or empty filter matches every todo.
+type Filter struct {
+	expr string
+	root filterNode
+}
+
+// filterNode is a node of a parsed filter expression
+type filterNode interface {
+	sql() (string, []interface{})
+	match(t *Todo) bool
+	fields() []string
+}
+
+// fieldKind describes how values of a filterable field are parsed and compared
+type fieldKind int
+
+const (
+	kindInt fieldKind = iota
+	kindPriority
+	kindStatus
+	kindString
+	kindText
+	kindTime
+)
+
+// filterField describes a todo field that can be used in filter expressions
+type filterField struct {
+	name   string
+	column string
+	kind   fieldKind
+}
+
+// filterFields maps every accepted field name (including aliases) to its definition
+var filterFields = map[string]filterField{
+	"id":          {name: "id", column: "id", kind: kindInt},
+	"priority":    {name: "priority", column: "priority", kind: kindPriority},
+	"pri":         {name: "priority", column: "priority", kind: kindPriority},
+	"status":      {name: "status", column: "status", kind: kindStatus},
+	"tag":         {name: "tag", column: "tag", kind: kindString},
+	"description": {name: "description", column: "description", kind: kindText},
+	"desc":        {name: "description", column: "description", kind: kindText},
+	"created":     {name: "created", column: "created_at", kind: kindTime},
+	"updated":     {name: "updated", column: "updated_at", kind: kindTime},
+	"completed":   {name: "completed", column: "completed_at", kind: kindTime},
+	"due":         {name: "due", column: "due_at", kind: kindTime},
+}
+
+// sqlTimeFormat is the layout used to bind time values in filter queries
+const sqlTimeFormat = "2006-01-02 15:04:05"
+
+// ParseFilter parses a filter expression.
+//
+// Expressions are made of comparisons joined with "and", "or" and "not" and
+// grouped with parentheses; adjacent comparisons are implicitly joined with
+// "and". A comparison is a field, an operator and a value:
+//
+//	id, priority (pri), status, tag, description (desc), created, updated, completed, due
+//	=  exact match       :  match (contains for description)   ~  contains
+//	!= not equal         <, <=, >, >=  ordering (id, priority and dates)
+//
+// Dates accept YYYY-MM-DD, "today", "yesterday", "tomorrow" or an age such as
+// 30m, 12h, 7d or 2w, so created<7d means "created less than seven days ago".
+// For due dates an age looks ahead instead: due<3d means "due within three
+// days". A bare word or quoted string searches the description.
+func ParseFilter(expr string) (*Filter, error) {
+	return parseFilterAt(expr, time.Now())
+}
+
+// parseFilterAt parses a filter expression resolving relative dates against now
+func parseFilterAt(expr string, now time.Time) (*Filter, error) {
+	p := &filterParser{input: expr, now: now}
+
+	p.skipSpace()
+	if p.eof() {
+		return &Filter{expr: expr}, nil
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if !p.eof() {
+		return nil, p.errorf("unexpected %q", p.input[p.pos:])
+	}
+
+	return &Filter{expr: expr, root: root}, nil
+}
+
+// NewFieldFilter returns a filter matching the given status, priority and
+// tag. Nil arguments leave the corresponding field unconstrained.
+func NewFieldFilter(status *Status, priority *Priority, tag *string) *Filter {
+	f := &Filter{}
+
+	if status != nil {
+		f = f.And(&Filter{expr: "status:" + strings.ToLower(status.String()),
+			root: &cmpNode{field: filterFields["status"], op: "=", num: int(*status)}})
+	}
+
+	if priority != nil {
+		f = f.And(&Filter{expr: "priority:" + strings.ToLower(priority.String()),
+			root: &cmpNode{field: filterFields["priority"], op: "=", num: int(*priority)}})
+	}
+
+	if tag != nil {
+		f = f.And(&Filter{expr: "tag=" + strconv.Quote(*tag),
+			root: &cmpNode{field: filterFields["tag"], op: "=", str: *tag}})
+	}
+
+	return f
+}
+
+// ParseListFilter builds the filter of 'tada list' from the values of its
+// status, priority and tag flags and a filter expression, for callers taking
+// the same options elsewhere. An empty status means open todos unless the
+// expression filters on status; "all" or an empty priority match any.
+func ParseListFilter(status, priority, tag, expr string) (*Filter, error) {
+	exprFilter, err := ParseFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	if status == "" {
+		status = "open"
+		if exprFilter.Uses("status") {
+			status = "all"
+		}
+	}
+	var statusFilter *Status
+	if status != "all" && status != "a" {
+		s, err := ParseStatus(status)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status: %w", err)
+		}
+		statusFilter = &s
+	}
+
+	var priorityFilter *Priority
+	if priority != "" && priority != "all" && priority != "a" {
+		p, err := ParsePriority(priority)
+		if err != nil {
+			return nil, fmt.Errorf("invalid priority: %w", err)
+		}
+		priorityFilter = &p
+	}
+
+	var tagFilter *string
+	if tag != "" {
+		tagFilter = &tag
+	}
+
+	return NewFieldFilter(statusFilter, priorityFilter, tagFilter).And(exprFilter), nil
+}
+
+// And returns a filter matching todos that match both f and other
+func (f *Filter) And(other *Filter) *Filter {
+	if f.IsEmpty() {
+		return other
+	}
+	if other.IsEmpty() {
+		return f
+	}
+
+	return &Filter{
+		expr: fmt.Sprintf("(%s) and (%s)", f.expr, other.expr),
+		root: &andNode{left: f.root, right: other.root},
+	}
+}
+
+// IsEmpty reports whether the filter matches every todo
+func (f *Filter) IsEmpty() bool {
+	return f == nil || f.root == nil
+}
+
+// String returns the filter expression
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.expr
+}
+
+// SQL returns the filter as a WHERE clause and its positional arguments
+func (f *Filter) SQL() (string, []interface{}) {
+	if f.IsEmpty() {
+		return "1=1", nil
+	}
+	return f.root.sql()
+}
+
+// Match reports whether the todo satisfies the filter
+func (f *Filter) Match(t *Todo) bool {
+	if f.IsEmpty() {
+		return true
+	}
+	return f.root.match(t)
+}
+
+// Uses reports whether the filter constrains the named field
+func (f *Filter) Uses(field string) bool {
+	if f.IsEmpty() {
+		return false
+	}
+
+	def, ok := filterFields[field]
+	if !ok {
+		return false
+	}
+
+	for _, name := range f.root.fields() {
+		if name == def.name {
+			return true
+		}
+	}
+	return false
+}
+
+type andNode struct {
+	left, right filterNode
+}
+
+func (n *andNode) sql() (string, []interface{}) {
+	l, la := n.left.sql()
+	r, ra := n.right.sql()
+	return "(" + l + " AND " + r + ")", append(la, ra...)
+}
+
+func (n *andNode) match(t *Todo) bool {
+	return n.left.match(t) && n.right.match(t)
+}
+
+func (n *andNode) fields() []string {
+	return append(n.left.fields(), n.right.fields()...)
+}
+
+type orNode struct {
+	left, right filterNode
+}
+
+func (n *orNode) sql() (string, []interface{}) {
+	l, la := n.left.sql()
+	r, ra := n.right.sql()
+	return "(" + l + " OR " + r + ")", append(la, ra...)
+}
+
+func (n *orNode) match(t *Todo) bool {
+	return n.left.match(t) || n.right.match(t)
+}
+
+func (n *orNode) fields() []string {
+	return append(n.left.fields(), n.right.fields()...)
+}
+
+type notNode struct {
+	inner filterNode
+}
+
+func (n *notNode) sql() (string, []interface{}) {
+	s, args := n.inner.sql()
+	return "(NOT " + s + ")", args
+}
+
+func (n *notNode) match(t *Todo) bool {
+	return !n.inner.match(t)
+}
+
+func (n *notNode) fields() []string {
+	return n.inner.fields()
+}
+
+// cmpNode compares a single field against a value. Only the member matching
+// the field kind is set: num for ids, priorities and statuses, str for
+// strings and from/to for dates (to is exclusive, zero means unbounded).
+type cmpNode struct {
+	field    filterField
+	op       string
+	num      int
+	str      string
+	from, to time.Time
+}
+
+func (n *cmpNode) fields() []string {
+	return []string{n.field.name}
+}
+
+func (n *cmpNode) sql() (string, []interface{}) {
+	col := n.field.column
+
+	switch n.field.kind {
+	case kindInt, kindPriority, kindStatus:
+		return fmt.Sprintf("%s %s ?", col, sqlOperator(n.op)), []interface{}{n.num}
+
+	case kindString, kindText:
+		switch n.op {
+		case "~":
+			return col + ` LIKE ? ESCAPE '\'`, []interface{}{"%" + escapeLike(n.str) + "%"}
+		case "!=":
+			return col + " != ?", []interface{}{n.str}
+		default:
+			return col + " = ?", []interface{}{n.str}
+		}
+
+	default:
+		var conds []string
+		var args []interface{}
+		if !n.from.IsZero() {
+			conds = append(conds, fmt.Sprintf("julianday(%s) >= julianday(?)", col))
+			args = append(args, n.from.UTC().Format(sqlTimeFormat))
+		}
+		if !n.to.IsZero() {
+			conds = append(conds, fmt.Sprintf("julianday(%s) < julianday(?)", col))
+			args = append(args, n.to.UTC().Format(sqlTimeFormat))
+		}
+
+		inRange := strings.Join(conds, " AND ")
+		if n.op == "!=" {
+			inRange = "NOT (" + inRange + ")"
+		}
+		return fmt.Sprintf("(%s IS NOT NULL AND %s)", col, inRange), args
+	}
+}
+
+func (n *cmpNode) match(t *Todo) bool {
+	switch n.field.kind {
+	case kindInt, kindPriority, kindStatus:
+		var v int
+		switch n.field.name {
+		case "id":
+			v = t.ID
+		case "priority":
+			v = int(t.Priority)
+		case "status":
+			v = int(t.Status)
+		}
+		return compareInts(v, n.op, n.num)
+
+	case kindString, kindText:
+		v := t.Tag
+		if n.field.name == "description" {
+			v = t.Description
+		}
+		switch n.op {
+		case "~":
+			return strings.Contains(strings.ToLower(v), strings.ToLower(n.str))
+		case "!=":
+			return v != n.str
+		default:
+			return v == n.str
+		}
+
+	default:
+		var v *time.Time
+		switch n.field.name {
+		case "created":
+			v = &t.CreatedAt
+		case "updated":
+			v = &t.UpdatedAt
+		case "completed":
+			v = t.CompletedAt
+		case "due":
+			v = t.DueAt
+		}
+		if v == nil {
+			return false
+		}
+
+		inRange := (n.from.IsZero() || !v.Before(n.from)) && (n.to.IsZero() || v.Before(n.to))
+		if n.op == "!=" {
+			return !inRange
+		}
+		return inRange
+	}
+}
+
+// sqlOperator maps a filter operator to its SQL equivalent
+func sqlOperator(op string) string {
+	switch op {
+	case ":", "=":
+		return "="
+	default:
+		return op
+	}
+}
+
+// compareInts applies a filter operator to two integers
+func compareInts(a int, op string, b int) bool {
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "!=":
+		return a != b
+	default:
+		return a == b
+	}
+}
+
+// escapeLike escapes the LIKE wildcards in s
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
+
+// filterParser is a recursive descent parser for filter expressions
+type filterParser struct {
+	input string
+	pos   int
+	now   time.Time
+}
+
+func (p *filterParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid filter at position %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *filterParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *filterParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *filterParser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+// keyword consumes the given keyword if it is the next word in the input
+func (p *filterParser) keyword(kw string) bool {
+	p.skipSpace()
+	end := p.pos + len(kw)
+	if end > len(p.input) || !strings.EqualFold(p.input[p.pos:end], kw) {
+		return false
+	}
+	if end < len(p.input) && !isBoundary(p.input[end]) {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+// isBoundary reports whether c terminates a word
+func isBoundary(c byte) bool {
+	return unicode.IsSpace(rune(c)) || c == '(' || c == ')'
+}
+
+// isOperatorChar reports whether c can start a comparison operator
+func isOperatorChar(c byte) bool {
+	return strings.IndexByte(":=!<>~", c) >= 0
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if !p.keyword("and") {
+			// Adjacent terms are implicitly joined with "and"
+			p.skipSpace()
+			if p.eof() || p.peek() == ')' {
+				return left, nil
+			}
+			save := p.pos
+			if p.keyword("or") {
+				p.pos = save
+				return left, nil
+			}
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.keyword("not") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{inner: inner}, nil
+	}
+
+	p.skipSpace()
+	switch {
+	case p.eof():
+		return nil, p.errorf("unexpected end of expression")
+
+	case p.peek() == '(':
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.peek() != ')' {
+			return nil, p.errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return inner, nil
+
+	case p.peek() == ')':
+		return nil, p.errorf("unexpected closing parenthesis")
+	}
+
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterNode, error) {
+	start := p.pos
+
+	// A quoted string on its own searches the description
+	if c := p.peek(); c == '"' || c == '\'' {
+		s, err := p.parseQuoted()
+		if err != nil {
+			return nil, err
+		}
+		return &cmpNode{field: filterFields["description"], op: "~", str: s}, nil
+	}
+
+	for !p.eof() && !isBoundary(p.peek()) && !isOperatorChar(p.peek()) {
+		p.pos++
+	}
+	name := p.input[start:p.pos]
+
+	op := p.parseOperator()
+	if op == "" {
+		// A bare word searches the description
+		for !p.eof() && !isBoundary(p.peek()) {
+			p.pos++
+		}
+		return &cmpNode{field: filterFields["description"], op: "~", str: p.input[start:p.pos]}, nil
+	}
+
+	field, ok := filterFields[strings.ToLower(name)]
+	if !ok {
+		p.pos = start
+		return nil, p.errorf("unknown field %q", name)
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.compile(field, op, value)
+}
+
+func (p *filterParser) parseOperator() string {
+	for _, op := range []string{"<=", ">=", "!=", ":", "=", "<", ">", "~"} {
+		if strings.HasPrefix(p.input[p.pos:], op) {
+			p.pos += len(op)
+			return op
+		}
+	}
+	return ""
+}
+
+func (p *filterParser) parseValue() (string, error) {
+	if c := p.peek(); c == '"' || c == '\'' {
+		return p.parseQuoted()
+	}
+
+	start := p.pos
+	for !p.eof() && !isBoundary(p.peek()) {
+		p.pos++
+	}
+	if start == p.pos {
+		return "", p.errorf("missing value")
+	}
+	return p.input[start:p.pos], nil
+}
+
+func (p *filterParser) parseQuoted() (string, error) {
+	quote := p.peek()
+	p.pos++
+
+	var b strings.Builder
+	for !p.eof() {
+		c := p.input[p.pos]
+		p.pos++
+		switch {
+		case c == '\\' && !p.eof():
+			b.WriteByte(p.input[p.pos])
+			p.pos++
+		case c == quote:
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// compile resolves a comparison value according to the field kind
+func (p *filterParser) compile(field filterField, op, value string) (filterNode, error) {
+	node := &cmpNode{field: field, op: op}
+
+	switch field.kind {
+	case kindInt:
+		if op == "~" {
+			return nil, p.errorf("operator %q is not supported for %s", op, field.name)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, p.errorf("invalid %s %q", field.name, value)
+		}
+		node.num = n
+
+	case kindPriority:
+		if op == "~" {
+			return nil, p.errorf("operator %q is not supported for %s", op, field.name)
+		}
+		priority, err := ParsePriority(value)
+		if err != nil {
+			return nil, p.errorf("invalid priority %q: %v", value, err)
+		}
+		node.num = int(priority)
+
+	case kindStatus:
+		if op != ":" && op != "=" && op != "!=" {
+			return nil, p.errorf("operator %q is not supported for %s", op, field.name)
+		}
+		status, err := ParseStatus(value)
+		if err != nil {
+			return nil, p.errorf("invalid status %q: %v", value, err)
+		}
+		node.num = int(status)
+
+	case kindString, kindText:
+		switch op {
+		case "<", "<=", ">", ">=":
+			return nil, p.errorf("operator %q is not supported for %s", op, field.name)
+		case ":":
+			if field.kind == kindText {
+				node.op = "~"
+			} else {
+				node.op = "="
+			}
+		}
+		node.str = value
+
+	case kindTime:
+		if op == "~" {
+			return nil, p.errorf("operator %q is not supported for %s", op, field.name)
+		}
+		if err := p.compileTime(node, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return node, nil
+}
+
+// compileTime turns a date or age comparison into a time range on the node
+func (p *filterParser) compileTime(node *cmpNode, value string) error {
+	if age, ok := parseAge(value); ok && node.field.name == "due" {
+		// Due dates lie ahead, so due<3d means due before now+3d
+		at := p.now.Add(age)
+		switch node.op {
+		case "<", "<=", ":", "=":
+			node.to = at
+		case ">", ">=":
+			node.from = at
+		case "!=":
+			node.to = at
+		}
+		return nil
+	} else if ok {
+		// Ages are compared by how long ago something happened, so
+		// created<7d means the creation time is after now-7d.
+		at := p.now.Add(-age)
+		switch node.op {
+		case "<", "<=", ":", "=":
+			node.from = at
+		case ">", ">=":
+			node.to = at
+		case "!=":
+			node.from = at
+		}
+		return nil
+	}
+
+	day, err := parseDay(value, p.now)
+	if err != nil {
+		return p.errorf("invalid date %q (use YYYY-MM-DD, today, yesterday or an age like 7d)", value)
+	}
+	next := day.AddDate(0, 0, 1)
+
+	switch node.op {
+	case "<":
+		node.to = day
+	case "<=":
+		node.to = next
+	case ">":
+		node.from = next
+	case ">=":
+		node.from = day
+	default:
+		node.from, node.to = day, next
+	}
+	return nil
+}
+
+// parseAge parses ages such as 30m, 12h, 7d and 2w
+func parseAge(s string) (time.Duration, bool) {
+	if len(s) < 2 {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return 0, false
+	}
+
+	switch s[len(s)-1] {
+	case 'm':
+		return time.Duration(n) * time.Minute, true
+	case 'h':
+		return time.Duration(n) * time.Hour, true
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, true
+	case 'w':
+		return time.Duration(n) * 7 * 24 * time.Hour, true
+	default:
+		return 0, false
+	}
+}
+
+// parseDay parses a calendar day in the local time zone
+func parseDay(s string, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch strings.ToLower(s) {
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	}
+
+	return time.ParseInLocation("2006-01-02", s, now.Location())
+}
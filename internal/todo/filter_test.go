@@ -0,0 +1,251 @@
+package todo
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFilter_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		expr        string
+		errContains string
+	}{
+		{"unknown field", "owner:me", "unknown field"},
+		{"invalid priority", "priority>=urgent", "invalid priority"},
+		{"invalid status", "status:later", "invalid status"},
+		{"ordering on status", "status>open", "not supported"},
+		{"ordering on tag", "tag<work", "not supported"},
+		{"invalid id", "id=abc", "invalid id"},
+		{"invalid date", "created<soon", "invalid date"},
+		{"missing value", "tag: ", "missing value"},
+		{"unterminated string", `desc~"deploy`, "unterminated string"},
+		{"missing parenthesis", "(tag:work or tag:home", "missing closing parenthesis"},
+		{"stray parenthesis", "tag:work)", "unexpected"},
+		{"dangling operator", "tag:work and", "unexpected end"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFilter(tt.expr)
+			if err == nil {
+				t.Fatalf("ParseFilter(%q) expected error", tt.expr)
+			}
+			if !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("ParseFilter(%q) error = %v, want error containing %q", tt.expr, err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestParseFilter_SQLIsParameterised(t *testing.T) {
+	f, err := ParseFilter(`desc~"'; DROP TABLE todos; --" or tag:"x' OR 1=1"`)
+	if err != nil {
+		t.Fatalf("ParseFilter() error = %v", err)
+	}
+
+	where, args := f.SQL()
+	if strings.Contains(where, "DROP") || strings.Contains(where, "1=1") {
+		t.Errorf("SQL() leaked values into the query: %s", where)
+	}
+	if len(args) != 2 {
+		t.Errorf("SQL() args = %v, want 2 arguments", args)
+	}
+}
+
+func TestFilter_Match(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.Local)
+	completed := now.Add(-2 * time.Hour)
+
+	todo := &Todo{
+		ID:          7,
+		Description: "Deploy the hotfix",
+		Priority:    High,
+		Status:      Done,
+		Tag:         "oncall",
+		CreatedAt:   now.Add(-3 * 24 * time.Hour),
+		UpdatedAt:   now.Add(-2 * time.Hour),
+		CompletedAt: &completed,
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"", true},
+		{"priority>=medium", true},
+		{"priority<high", false},
+		{"pri:h", true},
+		{"status:done", true},
+		{"status!=done", false},
+		{"tag:oncall", true},
+		{"tag:work or tag:oncall", true},
+		{"tag~CALL", true},
+		{`desc~"deploy"`, true},
+		{"desc:hotfix", true},
+		{"description=hotfix", false},
+		{"hotfix", true},
+		{`"the hotfix"`, true},
+		{"created<7d", true},
+		{"created<2d", false},
+		{"created>2d", true},
+		{"created:2025-06-12", true},
+		{"created>=2025-06-13", false},
+		{"completed:today", true},
+		{"not completed:today", false},
+		{"id>=5 id<10", true},
+		{"priority>=medium and (tag:work or tag:oncall) and created<7d and desc~\"deploy\"", true},
+		{"not (tag:oncall and status:done)", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := parseFilterAt(tt.expr, now)
+			if err != nil {
+				t.Fatalf("parseFilterAt(%q) error = %v", tt.expr, err)
+			}
+			if got := f.Match(todo); got != tt.want {
+				t.Errorf("Filter(%q).Match() = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_Uses(t *testing.T) {
+	f, err := ParseFilter("pri:h and not (status:done or deploy)")
+	if err != nil {
+		t.Fatalf("ParseFilter() error = %v", err)
+	}
+
+	for _, field := range []string{"priority", "status", "desc"} {
+		if !f.Uses(field) {
+			t.Errorf("Uses(%q) = false, want true", field)
+		}
+	}
+	if f.Uses("tag") {
+		t.Error("Uses(\"tag\") = true, want false")
+	}
+}
+
+func TestDB_Find(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	defer db.Close()
+
+	deploy, _ := db.Create("Deploy service", High, "work")
+	_, _ = db.Create("Page the on-call engineer", Medium, "oncall")
+	_, _ = db.Create("Buy groceries", Low, "home")
+	_, _ = db.Create("100% done_ish", Low)
+	if err := db.UpdateStatus(deploy.ID, Done); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+
+	tests := []struct {
+		expr string
+		want int
+	}{
+		{"", 4},
+		{"priority>=medium", 2},
+		{"priority>=medium and (tag:work or tag:oncall)", 2},
+		{"status:open", 3},
+		{"completed<1h", 1},
+		{"created<7d", 4},
+		{"created>7d", 0},
+		{`desc~"deploy"`, 1},
+		{`desc~"%"`, 1},
+		{`desc~"_"`, 1},
+		{`tag=""`, 1},
+		{"not tag:home", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := ParseFilter(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseFilter(%q) error = %v", tt.expr, err)
+			}
+
+			todos, err := db.Find(f)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			if len(todos) != tt.want {
+				t.Errorf("Find(%q) returned %d todos, want %d", tt.expr, len(todos), tt.want)
+			}
+
+			// The in-memory matcher must agree with the SQL translation
+			all, _ := db.Find(nil)
+			matched := 0
+			for _, todo := range all {
+				if f.Match(todo) {
+					matched++
+				}
+			}
+			if matched != tt.want {
+				t.Errorf("Match(%q) matched %d todos, want %d", tt.expr, matched, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFieldFilter(t *testing.T) {
+	status := Open
+	priority := High
+	tag := "work"
+
+	f := NewFieldFilter(&status, &priority, &tag)
+	if f.IsEmpty() {
+		t.Fatal("NewFieldFilter() returned an empty filter")
+	}
+
+	if !f.Match(&Todo{Status: Open, Priority: High, Tag: "work"}) {
+		t.Error("NewFieldFilter() should match todo with all fields equal")
+	}
+	if f.Match(&Todo{Status: Open, Priority: High, Tag: "home"}) {
+		t.Error("NewFieldFilter() should not match todo with a different tag")
+	}
+
+	if !NewFieldFilter(nil, nil, nil).IsEmpty() {
+		t.Error("NewFieldFilter(nil, nil, nil) should be empty")
+	}
+}
+
+func TestParseListFilter(t *testing.T) {
+	tests := []struct {
+		name                   string
+		status, priority, tag  string
+		expr                   string
+		openHigh, doneHighWork bool
+		wantErr                bool
+	}{
+		{name: "defaults to open", openHigh: true},
+		{name: "all statuses", status: "all", openHigh: true, doneHighWork: true},
+		{name: "expression on status", expr: "status:done", doneHighWork: true},
+		{name: "fields and expression", status: "a", priority: "high", tag: "work", expr: "priority:high", doneHighWork: true},
+		{name: "invalid status", status: "maybe", wantErr: true},
+		{name: "invalid priority", priority: "urgent", wantErr: true},
+		{name: "invalid expression", expr: "colour:red", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseListFilter(tt.status, tt.priority, tt.tag, tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseListFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := f.Match(&Todo{Status: Open, Priority: High}); got != tt.openHigh {
+				t.Errorf("Match(open high) = %v, want %v", got, tt.openHigh)
+			}
+			if got := f.Match(&Todo{Status: Done, Priority: High, Tag: "work"}); got != tt.doneHighWork {
+				t.Errorf("Match(done high work) = %v, want %v", got, tt.doneHighWork)
+			}
+		})
+	}
+}
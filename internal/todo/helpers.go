@@ -45,6 +45,72 @@ func ParseStatus(status string) (Status, error) {
 	}
 }
 
+// ParseDueDate parses a due date given as YYYY-MM-DD, today, tomorrow, a
+// day of the week such as fri, or a number of days ahead such as 3d. "none"
+// or an empty string clears the date.
+func ParseDueDate(s string) (*time.Time, error) {
+	return parseDueDate(s, time.Now())
+}
+
+// parseDueDate parses a due date relative to now. A day of the week is the
+// next one after today.
+func parseDueDate(s string, now time.Time) (*time.Time, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "none" {
+		return nil, nil
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	if age, ok := parseAge(s); ok && age%(24*time.Hour) == 0 {
+		due := today.AddDate(0, 0, int(age.Hours()/24))
+		return &due, nil
+	}
+
+	if day, ok := parseWeekday(s); ok {
+		days := (int(day)-int(today.Weekday())+6)%7 + 1
+		due := today.AddDate(0, 0, days)
+		return &due, nil
+	}
+
+	due, err := parseDay(s, now)
+	if err != nil {
+		return nil, fmt.Errorf("must be YYYY-MM-DD, today, tomorrow, a day like fri, a number of days like 3d, or none")
+	}
+	return &due, nil
+}
+
+// parseWeekday parses a day of the week, in full or its first three letters
+func parseWeekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// FormatDue describes a due date relative to today
+func FormatDue(due time.Time) string {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	day := time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, now.Location())
+	days := int(day.Sub(today).Hours() / 24)
+
+	date := due.Format("2006-01-02")
+	switch {
+	case days < 0:
+		return fmt.Sprintf("%s (overdue)", date)
+	case days == 0:
+		return fmt.Sprintf("%s (today)", date)
+	case days == 1:
+		return fmt.Sprintf("%s (tomorrow)", date)
+	default:
+		return fmt.Sprintf("%s (in %d days)", date, days)
+	}
+}
+
 func PrintTodo(cmd *cobra.Command, todo *Todo) {
 	priorityIcon := GetPriorityIcon(todo.Priority)
 	age := FormatAge(todo.Age())
@@ -59,10 +125,27 @@ func PrintTodo(cmd *cobra.Command, todo *Todo) {
 		cmd.Printf("   Tag: %s\n", todo.Tag)
 	}
 
+	if todo.DueAt != nil && todo.Status == Open {
+		cmd.Printf("   Due: %s\n", FormatDue(*todo.DueAt))
+	}
+
 	if todo.Status == Done && todo.CompletedAt != nil {
 		completedAge := FormatAge(*todo.CompletedAge())
 		cmd.Printf("   Completed: %s ago\n", completedAge)
 	}
+
+	printNotes(cmd, todo.Notes)
+}
+
+// printNotes prints notes indented below a todo
+func printNotes(cmd *cobra.Command, notes string) {
+	if notes == "" {
+		return
+	}
+	cmd.Println("   Notes:")
+	for _, line := range strings.Split(notes, "\n") {
+		cmd.Printf("     %s\n", line)
+	}
 }
 
 func PrintCreated(cmd *cobra.Command, todo *Todo) {
@@ -72,6 +155,28 @@ func PrintCreated(cmd *cobra.Command, todo *Todo) {
 	if todo.Tag != "" {
 		cmd.Printf("   Tag: %s\n", todo.Tag)
 	}
+	if todo.DueAt != nil {
+		cmd.Printf("   Due: %s\n", FormatDue(*todo.DueAt))
+	}
+	printNotes(cmd, todo.Notes)
+}
+
+// PrintGroups prints todos in sections with a subtotal per group
+func PrintGroups(cmd *cobra.Command, groups []Group) {
+	total := 0
+	for i, g := range groups {
+		if i > 0 {
+			cmd.Println()
+		}
+		cmd.Printf("── %s (%d) ──\n", g.Key, g.Count)
+		for _, t := range g.Todos {
+			PrintTodo(cmd, t)
+		}
+		total += g.Count
+	}
+
+	cmd.Println()
+	cmd.Printf("%d todos in %d groups\n", total, len(groups))
 }
 
 func PrintError(cmd *cobra.Command, err error) {
@@ -142,3 +247,24 @@ func GetPriorityIcon(priority Priority) string {
 		return "⚪"
 	}
 }
+
+// Tally counts the changes of a sync or an edit to describe them, such as
+// "2 added, 1 closed"
+type Tally struct {
+	parts []string
+}
+
+// Add counts n todos that had what done to them
+func (t *Tally) Add(n int, what string) {
+	if n > 0 {
+		t.parts = append(t.parts, fmt.Sprintf("%d %s", n, what))
+	}
+}
+
+// Describe returns the counts that are not zero, or none if they all are
+func (t *Tally) Describe(none string) string {
+	if len(t.parts) == 0 {
+		return none
+	}
+	return strings.Join(t.parts, ", ")
+}
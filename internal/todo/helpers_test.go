@@ -3,6 +3,7 @@ package todo
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestValidateDescription(t *testing.T) {
@@ -148,3 +149,56 @@ func TestParseStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDueDate(t *testing.T) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	tests := []struct {
+		input   string
+		want    *time.Time
+		wantErr bool
+	}{
+		{input: "", want: nil},
+		{input: "none", want: nil},
+		{input: "today", want: &today},
+		{input: "tomorrow", want: func() *time.Time { d := today.AddDate(0, 0, 1); return &d }()},
+		{input: "3d", want: func() *time.Time { d := today.AddDate(0, 0, 3); return &d }()},
+		{input: "1w", want: func() *time.Time { d := today.AddDate(0, 0, 7); return &d }()},
+		{input: "2025-12-24", want: func() *time.Time {
+			d := time.Date(2025, 12, 24, 0, 0, 0, 0, now.Location())
+			return &d
+		}()},
+		{input: "30m", wantErr: true},
+		{input: "someday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDueDate(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDueDate(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (got == nil) != (tt.want == nil) || got != nil && !got.Equal(*tt.want) {
+				t.Errorf("ParseDueDate(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatDue(t *testing.T) {
+	now := time.Now()
+
+	if got := FormatDue(now.AddDate(0, 0, -2)); !strings.Contains(got, "overdue") {
+		t.Errorf("FormatDue(past) = %q, want overdue", got)
+	}
+	if got := FormatDue(now); !strings.Contains(got, "today") {
+		t.Errorf("FormatDue(now) = %q, want today", got)
+	}
+	if got := FormatDue(now.AddDate(0, 0, 5)); !strings.Contains(got, "in 5 days") {
+		t.Errorf("FormatDue(+5d) = %q, want in 5 days", got)
+	}
+}
@@ -0,0 +1,220 @@
+package todo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// estimateNote and tagNote are how an estimate, and a #tag given along with
+// a +project, are kept at the top of a todo's notes
+const (
+	estimateNote = "Estimate: %s"
+	tagNote      = "Tag: %s"
+)
+
+// QuickAdd is a todo typed as a single line, with its fields given as
+// tokens in the description:
+//
+//	deploy hotfix !h #oncall @today ~30m
+//
+// !priority takes the words of ParsePriority, #tag and +project set the
+// tag, @date and due:date the due date, and ~estimate an estimate such as
+// 30m or 2h. Words that only look like tokens, such as #42, @alice or
+// due:someday, stay in the description.
+//
+// A todo has a single tag. Given both, the +project becomes the tag, as
+// for Taskwarrior imports, and the #tag is kept in the notes.
+type QuickAdd struct {
+	// Description is the text left once the tokens are taken out
+	Description string
+	Priority    *Priority
+	// Tag is the #tag
+	Tag string
+	// Project is the +project
+	Project string
+	Due     *time.Time
+	// Estimate is as written, such as 30m
+	Estimate string
+}
+
+// ParseQuickAdd takes the tokens out of a description. Each kind of token
+// may be given once. Tokens of the kinds in keep, such as "priority", "tag",
+// "project", "due date" or "estimate", stay in the description, for fields
+// that were given another way.
+func ParseQuickAdd(text string, now time.Time, keep ...string) (*QuickAdd, error) {
+	q := &QuickAdd{}
+	var words []string
+	given := map[string]string{}
+	kept := map[string]bool{}
+	for _, kind := range keep {
+		kept[kind] = true
+	}
+
+	for _, word := range strings.Fields(text) {
+		var token QuickAdd
+		kind, err := token.token(word, now)
+		if err != nil {
+			return nil, err
+		}
+		if kind == "" || kept[kind] {
+			words = append(words, word)
+			continue
+		}
+		q.take(&token, kind)
+		if previous, ok := given[kind]; ok {
+			return nil, fmt.Errorf("%s given twice: %s and %s", kind, previous, word)
+		}
+		given[kind] = word
+	}
+
+	q.Description = strings.Join(words, " ")
+	return q, nil
+}
+
+// take copies the field of a token of the given kind
+func (q *QuickAdd) take(token *QuickAdd, kind string) {
+	switch kind {
+	case "priority":
+		q.Priority = token.Priority
+	case "tag":
+		q.Tag = token.Tag
+	case "project":
+		q.Project = token.Project
+	case "due date":
+		q.Due = token.Due
+	case "estimate":
+		q.Estimate = token.Estimate
+	}
+}
+
+// token sets the field of a token and returns its kind, or "" for a word
+// of the description
+func (q *QuickAdd) token(word string, now time.Time) (string, error) {
+	switch {
+	case strings.HasPrefix(strings.ToLower(word), "due:"):
+		due, err := parseDueDate(word[len("due:"):], now)
+		if err != nil || due == nil {
+			return "", nil
+		}
+		q.Due = due
+		return "due date", nil
+
+	case len(word) < 2:
+		return "", nil
+
+	case word[0] == '!':
+		p, err := ParsePriority(word[1:])
+		if err != nil {
+			return "", nil
+		}
+		q.Priority = &p
+		return "priority", nil
+
+	case word[0] == '#':
+		if !isTagName(word[1:]) {
+			return "", nil
+		}
+		q.Tag = word[1:]
+		return "tag", nil
+
+	case word[0] == '+':
+		if !isTagName(word[1:]) {
+			return "", nil
+		}
+		q.Project = word[1:]
+		return "project", nil
+
+	case word[0] == '@':
+		due, err := parseDueDate(word[1:], now)
+		if err != nil || due == nil {
+			return "", nil
+		}
+		q.Due = due
+		return "due date", nil
+
+	case word[0] == '~':
+		if !isEstimate(word[1:]) {
+			return "", nil
+		}
+		q.Estimate = strings.ToLower(word[1:])
+		return "estimate", nil
+	}
+	return "", nil
+}
+
+// isTagName reports whether a #tag or +project names one: it starts with a
+// letter, so that #42 stays an issue number, and has no punctuation other
+// than - _ and .
+func isTagName(s string) bool {
+	for i, r := range s {
+		if i == 0 && !unicode.IsLetter(r) {
+			return false
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-_.", r) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// isEstimate reports whether s is a positive duration such as 30m, 1h30m
+// or 2d
+func isEstimate(s string) bool {
+	if d, err := time.ParseDuration(s); err == nil {
+		return d > 0
+	}
+	d, ok := parseAge(strings.ToLower(s))
+	return ok && d > 0
+}
+
+// TodoTag returns the tag of the todo: the +project if given, or else the
+// #tag
+func (q *QuickAdd) TodoTag() string {
+	if q.Project != "" {
+		return q.Project
+	}
+	return q.Tag
+}
+
+// Notes returns notes with the estimate and a #tag left out of the todo's
+// tag, if any, at the top. tada has no estimate field and a single tag, so
+// they are kept in the notes.
+func (q *QuickAdd) Notes(notes string) string {
+	var lines []string
+	if q.Estimate != "" {
+		lines = append(lines, fmt.Sprintf(estimateNote, q.Estimate))
+	}
+	if q.Project != "" && q.Tag != "" {
+		lines = append(lines, fmt.Sprintf(tagNote, q.Tag))
+	}
+	if notes != "" {
+		lines = append(lines, notes)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Understood describes the tokens taken out of the description, such as
+// "priority HIGH, tag oncall, due 2025-06-15 (today), estimate 30m"
+func (q *QuickAdd) Understood() string {
+	var parts []string
+	if q.Priority != nil {
+		parts = append(parts, "priority "+q.Priority.String())
+	}
+	if q.Project != "" {
+		parts = append(parts, "project "+q.Project)
+	}
+	if q.Tag != "" && q.Project != "" {
+		parts = append(parts, "tag "+q.Tag+" kept in the notes")
+	} else if q.Tag != "" {
+		parts = append(parts, "tag "+q.Tag)
+	}
+	if q.Due != nil {
+		parts = append(parts, "due "+FormatDue(*q.Due))
+	}
+	if q.Estimate != "" {
+		parts = append(parts, "estimate "+q.Estimate)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,125 @@
+package todo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseQuickAdd(t *testing.T) {
+	// A Sunday
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.Local)
+	today := time.Date(2025, 6, 15, 0, 0, 0, 0, time.Local)
+	high, low := High, Low
+	day := func(days int) *time.Time { d := today.AddDate(0, 0, days); return &d }
+
+	tests := []struct {
+		name    string
+		text    string
+		keep    []string
+		want    QuickAdd
+		wantErr bool
+	}{
+		{
+			name: "every token",
+			text: "deploy hotfix !h #oncall @today ~30m",
+			want: QuickAdd{Description: "deploy hotfix", Priority: &high, Tag: "oncall", Due: day(0), Estimate: "30m"},
+		},
+		{
+			name: "project and due key",
+			text: "+website update the footer due:fri !low",
+			want: QuickAdd{Description: "update the footer", Priority: &low, Project: "website", Due: day(5)},
+		},
+		{
+			name: "words that look like tokens",
+			text: "fix #42 for @alice !important ~ + C++",
+			want: QuickAdd{Description: "fix #42 for @alice !important ~ + C++"},
+		},
+		{
+			name: "days ahead and longer estimate",
+			text: "write report @3d ~1h30m",
+			want: QuickAdd{Description: "write report", Due: day(3), Estimate: "1h30m"},
+		},
+		{name: "no description", text: "!h #work", want: QuickAdd{Priority: &high, Tag: "work"}},
+		{name: "two priorities", text: "x !h !l", wantErr: true},
+		{name: "tag and project", text: "fix login #bug +api", want: QuickAdd{Description: "fix login", Tag: "bug", Project: "api"}},
+		{name: "two projects", text: "x +site +api", wantErr: true},
+		{name: "invalid due key", text: "ship it due:someday", want: QuickAdd{Description: "ship it due:someday"}},
+		{name: "empty due key", text: "x due:", want: QuickAdd{Description: "x due:"}},
+		{
+			name: "kept kinds stay in the description",
+			text: "fix #oncall thing !h @today",
+			keep: []string{"priority", "tag"},
+			want: QuickAdd{Description: "fix #oncall thing !h", Due: day(0)},
+		},
+		{name: "kept kinds may repeat", text: "x !h !l", keep: []string{"priority"}, want: QuickAdd{Description: "x !h !l"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQuickAdd(tt.text, now, tt.keep...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQuickAdd(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Description != tt.want.Description || got.Tag != tt.want.Tag || got.Project != tt.want.Project || got.Estimate != tt.want.Estimate {
+				t.Errorf("ParseQuickAdd(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+			if (got.Priority == nil) != (tt.want.Priority == nil) || got.Priority != nil && *got.Priority != *tt.want.Priority {
+				t.Errorf("ParseQuickAdd(%q) Priority = %v, want %v", tt.text, got.Priority, tt.want.Priority)
+			}
+			if (got.Due == nil) != (tt.want.Due == nil) || got.Due != nil && !got.Due.Equal(*tt.want.Due) {
+				t.Errorf("ParseQuickAdd(%q) Due = %v, want %v", tt.text, got.Due, tt.want.Due)
+			}
+		})
+	}
+}
+
+func TestQuickAdd_Notes(t *testing.T) {
+	q := &QuickAdd{Estimate: "30m"}
+	if got := q.Notes("see ticket"); got != "Estimate: 30m\nsee ticket" {
+		t.Errorf("Notes() = %q, want the estimate above the notes", got)
+	}
+	if got := (&QuickAdd{}).Notes("see ticket"); got != "see ticket" {
+		t.Errorf("Notes() without an estimate = %q, want the notes", got)
+	}
+	q = &QuickAdd{Tag: "bug", Project: "api", Estimate: "1h"}
+	if got := q.Notes(""); got != "Estimate: 1h\nTag: bug" {
+		t.Errorf("Notes() with a tag and a project = %q, want the estimate and the tag", got)
+	}
+	if got := q.TodoTag(); got != "api" {
+		t.Errorf("TodoTag() = %q, want the project", got)
+	}
+	if got := q.Understood(); got != "project api, tag bug kept in the notes, estimate 1h" {
+		t.Errorf("Understood() = %q", got)
+	}
+}
+
+func TestParseDueDate_Weekday(t *testing.T) {
+	// A Sunday
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"mon", 16},
+		{"friday", 20},
+		{"Sat", 21},
+		// Today's weekday is next week's
+		{"sun", 22},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseDueDate(tt.input, now)
+			if err != nil {
+				t.Fatalf("parseDueDate(%q) error = %v", tt.input, err)
+			}
+			if got.Day() != tt.want || got.Month() != time.June {
+				t.Errorf("parseDueDate(%q) = %v, want June %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
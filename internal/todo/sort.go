@@ -0,0 +1,232 @@
+package todo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SortKey is a single key of a sort specification
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// sortFields lists the accepted sort keys and their aliases
+var sortFields = map[string]string{
+	"priority":    "priority",
+	"pri":         "priority",
+	"created":     "created",
+	"updated":     "updated",
+	"completed":   "completed",
+	"due":         "due",
+	"tag":         "tag",
+	"id":          "id",
+	"status":      "status",
+	"description": "description",
+	"desc":        "description",
+}
+
+// ParseSort parses a comma separated sort specification such as
+// "priority,-created". Keys sort in their natural order — most important
+// priority first, open before done, earliest dates and lowest IDs first,
+// tags alphabetically — and a leading "-" reverses a key. Todos without a
+// due or completion date, and untagged todos, always sort last.
+func ParseSort(spec string) ([]SortKey, error) {
+	var keys []SortKey
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+
+		key := SortKey{}
+		if strings.HasPrefix(part, "-") {
+			key.Desc = true
+			part = part[1:]
+		} else {
+			part = strings.TrimPrefix(part, "+")
+		}
+
+		field, ok := sortFields[part]
+		if !ok {
+			return nil, fmt.Errorf("unknown sort key %q (must be one of: priority, created, updated, completed, due, tag, id, status, description; priority sorts highest first and a - prefix reverses a key)", part)
+		}
+		key.Field = field
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// FormatSort returns the sort specification for the given keys
+func FormatSort(keys []SortKey) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.Field
+		if k.Desc {
+			parts[i] = "-" + k.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// SortTodos sorts todos in place by the given keys. Ties are broken by the
+// order of the input, so sorting the result of DB.List keeps the newest
+// todos first when all keys are equal.
+func SortTodos(todos []*Todo, keys []SortKey) {
+	if len(keys) == 0 {
+		return
+	}
+
+	sort.SliceStable(todos, func(i, j int) bool {
+		for _, k := range keys {
+			c, missing := compareTodos(todos[i], todos[j], k.Field)
+			if c == 0 {
+				continue
+			}
+			if k.Desc && !missing {
+				c = -c
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+// compareTodos compares a and b on a single field in its natural order. The
+// second result reports that exactly one of the values is missing, in which
+// case the comparison must not be reversed so missing values stay last.
+func compareTodos(a, b *Todo, field string) (int, bool) {
+	switch field {
+	case "priority":
+		return compareInt(int(b.Priority), int(a.Priority)), false
+	case "status":
+		return compareInt(int(a.Status), int(b.Status)), false
+	case "id":
+		return compareInt(a.ID, b.ID), false
+	case "created":
+		return compareTime(a.CreatedAt, b.CreatedAt), false
+	case "updated":
+		return compareTime(a.UpdatedAt, b.UpdatedAt), false
+	case "completed":
+		return compareOptionalTime(a.CompletedAt, b.CompletedAt)
+	case "due":
+		return compareOptionalTime(a.DueAt, b.DueAt)
+	case "tag":
+		if (a.Tag == "") != (b.Tag == "") {
+			if a.Tag == "" {
+				return 1, true
+			}
+			return -1, true
+		}
+		return strings.Compare(strings.ToLower(a.Tag), strings.ToLower(b.Tag)), false
+	case "description":
+		return strings.Compare(strings.ToLower(a.Description), strings.ToLower(b.Description)), false
+	default:
+		return 0, false
+	}
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareOptionalTime(a, b *time.Time) (int, bool) {
+	switch {
+	case a == nil && b == nil:
+		return 0, false
+	case a == nil:
+		return 1, true
+	case b == nil:
+		return -1, true
+	default:
+		return compareTime(*a, *b), false
+	}
+}
+
+// Group is a named set of todos produced by GroupTodos
+type Group struct {
+	Key   string  `json:"key"`
+	Count int     `json:"count"`
+	Todos []*Todo `json:"todos"`
+}
+
+// groupFields lists the accepted --group-by values
+var groupFields = []string{"tag", "priority", "status", "day"}
+
+// ValidateGroupBy checks that todos can be grouped by the given field
+func ValidateGroupBy(by string) error {
+	for _, f := range groupFields {
+		if by == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of: %s", strings.Join(groupFields, ", "))
+}
+
+// GroupTodos splits todos into groups by tag, priority, status or creation
+// day. Groups appear in the order their first todo appears in the input, so
+// sorting before grouping also orders the groups.
+func GroupTodos(todos []*Todo, by string) ([]Group, error) {
+	if err := ValidateGroupBy(by); err != nil {
+		return nil, err
+	}
+
+	var groups []Group
+	index := map[string]int{}
+
+	for _, t := range todos {
+		key := GroupKey(t, by)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, Group{Key: key})
+		}
+		groups[i].Todos = append(groups[i].Todos, t)
+		groups[i].Count++
+	}
+
+	return groups, nil
+}
+
+// GroupKey returns the name of the group a todo belongs to
+func GroupKey(t *Todo, by string) string {
+	switch by {
+	case "tag":
+		if t.Tag == "" {
+			return "(no tag)"
+		}
+		return t.Tag
+	case "priority":
+		return t.Priority.String()
+	case "status":
+		return t.Status.String()
+	case "day":
+		return t.CreatedAt.Local().Format("2006-01-02")
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,120 @@
+package todo
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSort(t *testing.T) {
+	keys, err := ParseSort("priority, -created,pri")
+	if err != nil {
+		t.Fatalf("ParseSort() error = %v", err)
+	}
+
+	want := []SortKey{{Field: "priority"}, {Field: "created", Desc: true}, {Field: "priority"}}
+	if len(keys) != len(want) {
+		t.Fatalf("ParseSort() = %v, want %v", keys, want)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("ParseSort()[%d] = %v, want %v", i, keys[i], want[i])
+		}
+	}
+
+	if got := FormatSort(keys); got != "priority,-created,priority" {
+		t.Errorf("FormatSort() = %q", got)
+	}
+
+	if _, err := ParseSort("priority,owner"); err == nil || !strings.Contains(err.Error(), "owner") {
+		t.Errorf("ParseSort() error = %v, want unknown sort key error", err)
+	}
+
+	if keys, err := ParseSort(""); err != nil || len(keys) != 0 {
+		t.Errorf("ParseSort(\"\") = %v, %v, want no keys", keys, err)
+	}
+}
+
+func TestSortTodos(t *testing.T) {
+	now := time.Now()
+	due := now.Add(24 * time.Hour)
+
+	todos := []*Todo{
+		{ID: 1, Priority: Low, CreatedAt: now.Add(-1 * time.Hour)},
+		{ID: 2, Priority: High, CreatedAt: now.Add(-2 * time.Hour), Tag: "work"},
+		{ID: 3, Priority: High, CreatedAt: now.Add(-3 * time.Hour), DueAt: &due, Tag: "home"},
+		{ID: 4, Priority: Medium, CreatedAt: now.Add(-4 * time.Hour)},
+	}
+
+	ids := func() []int {
+		var out []int
+		for _, t := range todos {
+			out = append(out, t.ID)
+		}
+		return out
+	}
+
+	tests := []struct {
+		spec string
+		want []int
+	}{
+		{"priority,created", []int{3, 2, 4, 1}},
+		{"-priority,-created", []int{1, 4, 2, 3}},
+		{"id", []int{1, 2, 3, 4}},
+		{"-id", []int{4, 3, 2, 1}},
+		{"due,id", []int{3, 1, 2, 4}},
+		{"-due,id", []int{3, 1, 2, 4}},
+		{"tag,id", []int{3, 2, 1, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			keys, err := ParseSort(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseSort() error = %v", err)
+			}
+
+			SortTodos(todos, keys)
+
+			got := ids()
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("SortTodos(%q) = %v, want %v", tt.spec, got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestGroupTodos(t *testing.T) {
+	todos := []*Todo{
+		{ID: 1, Priority: High, Status: Open, Tag: "work"},
+		{ID: 2, Priority: Low, Status: Done},
+		{ID: 3, Priority: High, Status: Open, Tag: "work"},
+	}
+
+	groups, err := GroupTodos(todos, "tag")
+	if err != nil {
+		t.Fatalf("GroupTodos() error = %v", err)
+	}
+
+	if len(groups) != 2 {
+		t.Fatalf("GroupTodos() returned %d groups, want 2", len(groups))
+	}
+	if groups[0].Key != "work" || groups[0].Count != 2 {
+		t.Errorf("GroupTodos() first group = %s (%d), want work (2)", groups[0].Key, groups[0].Count)
+	}
+	if groups[1].Key != "(no tag)" || groups[1].Count != 1 {
+		t.Errorf("GroupTodos() second group = %s (%d), want (no tag) (1)", groups[1].Key, groups[1].Count)
+	}
+
+	groups, _ = GroupTodos(todos, "priority")
+	if groups[0].Key != "HIGH" {
+		t.Errorf("GroupTodos(priority) first group = %s, want HIGH", groups[0].Key)
+	}
+
+	if _, err := GroupTodos(todos, "owner"); err == nil {
+		t.Error("GroupTodos() expected error for unknown field")
+	}
+}
@@ -3,11 +3,13 @@ package todo
 import (
 	"database/sql"
 	"fmt"
-	"os"
 	"path/filepath"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/negadras/tada/internal/profile"
+	"github.com/negadras/tada/internal/replica"
+	"github.com/negadras/tada/internal/vault"
 )
 
 // Priority represents task priority levels
@@ -61,6 +63,9 @@ type Todo struct {
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	Notes       string     `json:"notes,omitempty"`
+	UID         string     `json:"uid"`
 }
 
 // Age returns how long ago the todo was created
@@ -80,18 +85,21 @@ func (t *Todo) CompletedAge() *time.Duration {
 // DB handles all database operations
 type DB struct {
 	conn *sql.DB
+	// tx is the transaction of the batch the DB runs in, if any
+	tx *sql.Tx
+	// node identifies this database in the clocks of changes made to it
+	node string
+	// cipher encrypts descriptions, tags and notes, or is nil if the
+	// database is not encrypted
+	cipher *vault.Cipher
 }
 
-// GetDatabasePath returns the path to the database file
+// GetDatabasePath returns the path to the database file of the current
+// profile
 func GetDatabasePath() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	tadaDir, err := profile.GetCurrentDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
-	}
-
-	tadaDir := filepath.Join(homeDir, ".tada")
-	if err := os.MkdirAll(tadaDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create .tada directory: %w", err)
+		return "", err
 	}
 
 	return filepath.Join(tadaDir, "todos.db"), nil
@@ -109,7 +117,18 @@ func NewDB(dbPath string) (*DB, error) {
 		return nil, fmt.Errorf("failed to create tables: %w", err)
 	}
 
-	return &DB{conn: db}, nil
+	node, err := replica.Node(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node ID: %w", err)
+	}
+
+	cipher, err := vault.Unlock(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &DB{conn: db, node: node, cipher: cipher}, nil
 }
 
 // createTables creates the database schema
@@ -145,7 +164,25 @@ func createTables(db *sql.DB) error {
 	CREATE INDEX IF NOT EXISTS idx_quotes_author ON quotes(author);
 	CREATE INDEX IF NOT EXISTS idx_quotes_category ON quotes(category);
 	CREATE INDEX IF NOT EXISTS idx_quotes_created_at ON quotes(created_at);
-	`
+
+	-- How each todo looked in a synced file at the last sync
+	CREATE TABLE IF NOT EXISTS sync_state (
+		target TEXT NOT NULL,
+		uid TEXT NOT NULL,
+		item TEXT NOT NULL,
+		PRIMARY KEY (target, uid)
+	);
+
+	-- Commits linked to a todo, by the todo's UID
+	CREATE TABLE IF NOT EXISTS todo_commits (
+		uid TEXT NOT NULL,
+		sha TEXT NOT NULL,
+		repo TEXT NOT NULL DEFAULT '',
+		subject TEXT NOT NULL DEFAULT '',
+		linked_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (uid, sha)
+	);
+	` + replica.Schema
 
 	if _, err := db.Exec(schema); err != nil {
 		return err
@@ -154,11 +191,64 @@ func createTables(db *sql.DB) error {
 	// Migrate: add tag column to existing databases (idempotent — error ignored if already exists)
 	_, _ = db.Exec(`ALTER TABLE todos ADD COLUMN tag TEXT NOT NULL DEFAULT ''`)
 
+	// Migrate: add due date column to existing databases (idempotent — error ignored if already exists)
+	_, _ = db.Exec(`ALTER TABLE todos ADD COLUMN due_at DATETIME NULL`)
+
+	// Migrate: add notes column to existing databases (idempotent — error ignored if already exists)
+	_, _ = db.Exec(`ALTER TABLE todos ADD COLUMN notes TEXT NOT NULL DEFAULT ''`)
+
+	// Migrate: add a stable unique identifier, used to recognise a todo in
+	// exports and imports, and give one to existing todos
+	_, _ = db.Exec(`ALTER TABLE todos ADD COLUMN uid TEXT NOT NULL DEFAULT ''`)
+	if _, err := db.Exec(`UPDATE todos SET uid = ` + replica.SQLNewUID + ` WHERE uid = ''`); err != nil {
+		return err
+	}
+	if _, err := db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_todos_uid ON todos(uid)`); err != nil {
+		return err
+	}
+
 	// Index on tag — created after migration so the column is guaranteed to exist
 	_, tagIdxErr := db.Exec(`CREATE INDEX IF NOT EXISTS idx_todos_tag ON todos(tag)`)
 	return tagIdxErr
 }
 
+// NewUID returns a random (version 4) UUID
+func NewUID() string {
+	return replica.NewUID()
+}
+
+// todoColumns lists the columns read by scanTodo, in order
+const todoColumns = `id, description, priority, status, tag, created_at, updated_at, completed_at, due_at, notes, uid`
+
+// scanTodo reads a todo from a row selected with todoColumns
+func (db *DB) scanTodo(row interface{ Scan(...interface{}) error }) (*Todo, error) {
+	todo := &Todo{}
+	var completedAt, dueAt sql.NullTime
+
+	err := row.Scan(
+		&todo.ID, &todo.Description, &todo.Priority, &todo.Status, &todo.Tag,
+		&todo.CreatedAt, &todo.UpdatedAt, &completedAt, &dueAt, &todo.Notes, &todo.UID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if completedAt.Valid {
+		todo.CompletedAt = &completedAt.Time
+	}
+	if dueAt.Valid {
+		todo.DueAt = &dueAt.Time
+	}
+
+	for _, field := range []*string{&todo.Description, &todo.Tag, &todo.Notes} {
+		if *field, err = db.cipher.Open(*field); err != nil {
+			return nil, err
+		}
+	}
+
+	return todo, nil
+}
+
 // Create creates a new todo task. An optional tag can be provided as the third argument.
 func (db *DB) Create(description string, priority Priority, tag ...string) (*Todo, error) {
 	t := ""
@@ -166,10 +256,10 @@ func (db *DB) Create(description string, priority Priority, tag ...string) (*Tod
 		t = tag[0]
 	}
 
-	result, err := db.conn.Exec(`
-		INSERT INTO todos (description, priority, tag)
-		VALUES (?, ?, ?)
-	`, description, int(priority), t)
+	result, err := db.q().Exec(`
+		INSERT INTO todos (description, priority, tag, uid)
+		VALUES (?, ?, ?, ?)
+	`, db.cipher.Seal(description), int(priority), db.cipher.Seal(t), NewUID())
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create todo: %w", err)
@@ -183,58 +273,207 @@ func (db *DB) Create(description string, priority Priority, tag ...string) (*Tod
 	return db.Get(int(id))
 }
 
-// Get retrieves a todo by ID
-func (db *DB) Get(id int) (*Todo, error) {
-	row := db.conn.QueryRow(`
-		SELECT id, description, priority, status, tag, created_at, updated_at, completed_at
-		FROM todos WHERE id = ?
-	`, id)
+// Insert stores todos with all of their fields, including timestamps, in a
+// single transaction. It is used by imports, which must keep the original
+// creation and completion dates. Todos with an ID or UID keep it and the
+// others are given a new one.
+func (db *DB) Insert(todos []*Todo) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		return db.insertTodos(tx, todos)
+	})
+}
 
-	todo := &Todo{}
-	var completedAt sql.NullTime
+// Replace deletes every todo and stores the given todos in their place, in
+// a single transaction
+func (db *DB) Replace(todos []*Todo) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		if err := replica.Forget(tx, "todos"); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM todos`); err != nil {
+			return fmt.Errorf("failed to delete todos: %w", err)
+		}
+		return db.insertTodos(tx, todos)
+	})
+}
 
-	err := row.Scan(
-		&todo.ID, &todo.Description, &todo.Priority, &todo.Status, &todo.Tag,
-		&todo.CreatedAt, &todo.UpdatedAt, &completedAt,
-	)
+// Load brings the todos in line with the given todos, the state of another
+// store such as the git repository, in a single transaction. Todos are
+// matched by UID: the fields that differ are updated as an edit would,
+// missing todos are added, and todos not given are deleted with a
+// tombstone, so that a later merge deletes them there too. Todos that are
+// kept keep when each of their fields last changed.
+func (db *DB) Load(todos []*Todo) error {
+	return db.Transaction(func(db *DB) error {
+		existing, err := db.Find(nil)
+		if err != nil {
+			return err
+		}
+
+		keep := make(map[string]bool, len(todos))
+		for _, t := range todos {
+			keep[t.UID] = true
+		}
+		byUID := make(map[string]*Todo, len(existing))
+		usedIDs := map[int]bool{}
+		for _, t := range existing {
+			if !keep[t.UID] {
+				if err := db.Delete(t.ID); err != nil {
+					return err
+				}
+				continue
+			}
+			byUID[t.UID] = t
+			usedIDs[t.ID] = true
+		}
+
+		var added []*Todo
+		for _, t := range todos {
+			old, ok := byUID[t.UID]
+			if !ok {
+				if usedIDs[t.ID] {
+					// The ID was taken by another todo here
+					t.ID = 0
+				}
+				added = append(added, t)
+				continue
+			}
+			if err := db.loadTodo(old, t); err != nil {
+				return err
+			}
+		}
+		return db.insertTodos(db.tx, added)
+	})
+}
+
+// loadTodo updates the fields of old that differ in t, and takes t's
+// timestamps when any did
+func (db *DB) loadTodo(old, t *Todo) error {
+	changes := Diff(old, t)
+	if *changes == (Changes{}) {
+		return nil
+	}
+	if err := changes.Apply(db, old.ID); err != nil {
+		return err
+	}
+
+	_, err := db.tx.Exec(`
+		UPDATE todos
+		SET updated_at = ?, completed_at = ?
+		WHERE id = ?
+	`, t.UpdatedAt.UTC().Format(sqlTimeFormat), optionalTime(t.CompletedAt), old.ID)
+	return err
+}
 
+// withTx runs fn in a transaction, committing if it returns nil. In a batch
+// it runs in the batch's transaction.
+func (db *DB) withTx(fn func(tx *sql.Tx) error) error {
+	if db.tx != nil {
+		return fn(db.tx)
+	}
+
+	tx, err := db.conn.Begin()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get todo: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	if completedAt.Valid {
-		todo.CompletedAt = &completedAt.Time
+	if err := fn(tx); err != nil {
+		return err
 	}
 
-	return todo, nil
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
 }
 
-// List retrieves todos with optional filtering
-func (db *DB) List(status *Status, priority *Priority, tag *string) ([]*Todo, error) {
-	query := `
-		SELECT id, description, priority, status, tag, created_at, updated_at, completed_at
-		FROM todos WHERE 1=1
-	`
-	args := []interface{}{}
+// insertTodos inserts todos inside a transaction and sets their IDs
+func (db *DB) insertTodos(tx *sql.Tx, todos []*Todo) error {
+	stmt, err := tx.Prepare(`
+		INSERT INTO todos (id, description, priority, status, tag, created_at, updated_at, completed_at, due_at, notes, uid)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, t := range todos {
+		now := time.Now()
+		created, updated := t.CreatedAt, t.UpdatedAt
+		if created.IsZero() {
+			created = now
+		}
+		if updated.IsZero() {
+			updated = created
+		}
+		if t.UID == "" {
+			t.UID = NewUID()
+		}
+
+		var id interface{}
+		if t.ID != 0 {
+			id = t.ID
+		}
+
+		result, err := stmt.Exec(
+			id, db.cipher.Seal(t.Description), int(t.Priority), int(t.Status), db.cipher.Seal(t.Tag),
+			created.UTC().Format(sqlTimeFormat), updated.UTC().Format(sqlTimeFormat),
+			optionalTime(t.CompletedAt), optionalTime(t.DueAt), db.cipher.Seal(t.Notes), t.UID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert todo %q: %w", t.Description, err)
+		}
 
-	if status != nil {
-		query += " AND status = ?"
-		args = append(args, int(*status))
+		newID, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get todo ID: %w", err)
+		}
+		t.ID = int(newID)
 	}
 
-	if priority != nil {
-		query += " AND priority = ?"
-		args = append(args, int(*priority))
+	return nil
+}
+
+// optionalTime converts an optional time into a value for a nullable column
+func optionalTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(sqlTimeFormat)
+}
+
+// Get retrieves a todo by ID
+func (db *DB) Get(id int) (*Todo, error) {
+	row := db.q().QueryRow(`SELECT `+todoColumns+` FROM todos WHERE id = ?`, id)
+
+	todo, err := db.scanTodo(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get todo: %w", err)
 	}
 
-	if tag != nil {
-		query += " AND tag = ?"
-		args = append(args, *tag)
+	return todo, nil
+}
+
+// List retrieves todos with optional filtering
+func (db *DB) List(status *Status, priority *Priority, tag *string) ([]*Todo, error) {
+	return db.Find(NewFieldFilter(status, priority, tag))
+}
+
+// Find retrieves the todos matching a filter expression
+func (db *DB) Find(filter *Filter) ([]*Todo, error) {
+	where, args := filter.SQL()
+	// Encrypted columns cannot be compared in SQL, so the todos are
+	// filtered once decrypted
+	encrypted := db.cipher != nil && (filter.Uses("description") || filter.Uses("tag"))
+	if encrypted {
+		where, args = "1=1", nil
 	}
 
-	query += " ORDER BY created_at DESC"
+	query := `SELECT ` + todoColumns + ` FROM todos WHERE ` + where + ` ORDER BY created_at DESC`
 
-	rows, err := db.conn.Query(query, args...)
+	rows, err := db.q().Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list todos: %w", err)
 	}
@@ -242,20 +481,12 @@ func (db *DB) List(status *Status, priority *Priority, tag *string) ([]*Todo, er
 
 	var todos []*Todo
 	for rows.Next() {
-		todo := &Todo{}
-		var completedAt sql.NullTime
-
-		err := rows.Scan(
-			&todo.ID, &todo.Description, &todo.Priority, &todo.Status, &todo.Tag,
-			&todo.CreatedAt, &todo.UpdatedAt, &completedAt,
-		)
-
+		todo, err := db.scanTodo(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan todo: %w", err)
 		}
-
-		if completedAt.Valid {
-			todo.CompletedAt = &completedAt.Time
+		if encrypted && !filter.Match(todo) {
+			continue
 		}
 
 		todos = append(todos, todo)
@@ -273,52 +504,187 @@ func (db *DB) UpdateStatus(id int, status Status) error {
 		completedAt = nil
 	}
 
-	_, err := db.conn.Exec(`
-		UPDATE todos 
-		SET status = ?, completed_at = ?, updated_at = CURRENT_TIMESTAMP
-		WHERE id = ?
-	`, int(status), completedAt, id)
+	return db.withTx(func(tx *sql.Tx) error {
+		if err := replica.Touch(tx, db.node, "todos", id, "status", "status", int(status)); err != nil {
+			return err
+		}
 
-	return err
+		_, err := tx.Exec(`
+			UPDATE todos 
+			SET status = ?, completed_at = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, int(status), completedAt, id)
+		return err
+	})
 }
 
 // UpdatePriority updates the priority of a todo
 func (db *DB) UpdatePriority(id int, priority Priority) error {
-	_, err := db.conn.Exec(`
-		UPDATE todos 
-		SET priority = ?, updated_at = CURRENT_TIMESTAMP
-		WHERE id = ?
-	`, int(priority), id)
+	return db.withTx(func(tx *sql.Tx) error {
+		if err := replica.Touch(tx, db.node, "todos", id, "priority", "priority", int(priority)); err != nil {
+			return err
+		}
 
-	return err
+		_, err := tx.Exec(`
+			UPDATE todos 
+			SET priority = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, int(priority), id)
+		return err
+	})
 }
 
 // UpdateDescription updates the description of a todo
 func (db *DB) UpdateDescription(id int, description string) error {
-	_, err := db.conn.Exec(`
-		UPDATE todos 
-		SET description = ?, updated_at = CURRENT_TIMESTAMP
-		WHERE id = ?
-	`, description, id)
+	return db.withTx(func(tx *sql.Tx) error {
+		description, err := db.seal(tx, id, "description", description)
+		if err != nil {
+			return err
+		}
+		if err := replica.Touch(tx, db.node, "todos", id, "description", "description", description); err != nil {
+			return err
+		}
 
-	return err
+		_, err = tx.Exec(`
+			UPDATE todos 
+			SET description = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, description, id)
+		return err
+	})
 }
 
 // UpdateTag updates the tag of a todo
 func (db *DB) UpdateTag(id int, tag string) error {
-	_, err := db.conn.Exec(`
-		UPDATE todos
-		SET tag = ?, updated_at = CURRENT_TIMESTAMP
-		WHERE id = ?
-	`, tag, id)
+	return db.withTx(func(tx *sql.Tx) error {
+		tag, err := db.seal(tx, id, "tag", tag)
+		if err != nil {
+			return err
+		}
+		if err := replica.Touch(tx, db.node, "todos", id, "tag", "tag", tag); err != nil {
+			return err
+		}
 
-	return err
+		_, err = tx.Exec(`
+			UPDATE todos
+			SET tag = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, tag, id)
+		return err
+	})
+}
+
+// UpdateDue sets or, when due is nil, clears the due date of a todo
+func (db *DB) UpdateDue(id int, due *time.Time) error {
+	var dueAt interface{}
+	if due != nil {
+		dueAt = *due
+	}
+
+	return db.withTx(func(tx *sql.Tx) error {
+		if err := replica.Touch(tx, db.node, "todos", id, "due", "due_at", dueAt); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(`
+			UPDATE todos
+			SET due_at = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, dueAt, id)
+		return err
+	})
+}
+
+// UpdateNotes updates the notes of a todo
+func (db *DB) UpdateNotes(id int, notes string) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		notes, err := db.seal(tx, id, "notes", notes)
+		if err != nil {
+			return err
+		}
+		if err := replica.Touch(tx, db.node, "todos", id, "notes", "notes", notes); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			UPDATE todos
+			SET notes = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, notes, id)
+		return err
+	})
+}
+
+// seal encrypts the new value of a column of a todo, keeping the stored
+// value if it is unchanged
+func (db *DB) seal(tx *sql.Tx, id int, column, value string) (string, error) {
+	if db.cipher == nil {
+		return value, nil
+	}
+	var stored string
+	err := tx.QueryRow(`SELECT `+column+` FROM todos WHERE id = ?`, id).Scan(&stored)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	return db.cipher.Reseal(stored, value), nil
 }
 
-// Delete deletes a todo by ID
+// Delete deletes a todo by ID, leaving a tombstone so that merging with
+// another database deletes it there too
 func (db *DB) Delete(id int) error {
-	_, err := db.conn.Exec("DELETE FROM todos WHERE id = ?", id)
-	return err
+	return db.withTx(func(tx *sql.Tx) error {
+		if err := replica.Bury(tx, db.node, "todos", "todo", id); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("DELETE FROM todo_commits WHERE uid = (SELECT uid FROM todos WHERE id = ?)", id); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DELETE FROM todos WHERE id = ?", id)
+		return err
+	})
+}
+
+// SyncState returns how each todo looked in a sync target, such as a
+// markdown file, at its last sync, by UID
+func (db *DB) SyncState(target string) (map[string]string, error) {
+	rows, err := db.q().Query(`SELECT uid, item FROM sync_state WHERE target = ?`, target)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	state := map[string]string{}
+	for rows.Next() {
+		var uid, item string
+		if err := rows.Scan(&uid, &item); err != nil {
+			return nil, err
+		}
+		if state[uid], err = db.cipher.Open(item); err != nil {
+			return nil, err
+		}
+	}
+	return state, rows.Err()
+}
+
+// SaveSyncState replaces the recorded state of a sync target
+func (db *DB) SaveSyncState(target string, state map[string]string) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM sync_state WHERE target = ?`, target); err != nil {
+			return err
+		}
+		for uid, item := range state {
+			if _, err := tx.Exec(`INSERT INTO sync_state (target, uid, item) VALUES (?, ?, ?)`, target, uid, db.cipher.Seal(item)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Encrypted reports whether the database is encrypted
+func (db *DB) Encrypted() bool {
+	return db.cipher != nil
 }
 
 // Close closes the database connection
@@ -1,10 +1,15 @@
 package todo
 
 import (
+	"database/sql"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/negadras/tada/internal/vault"
 )
 
 func TestPriority_String(t *testing.T) {
@@ -283,6 +288,103 @@ func TestDB_Integration(t *testing.T) {
 		}
 	})
 
+	t.Run("Update due date", func(t *testing.T) {
+		todo, err := db.Create("Due test", Medium)
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+
+		due := time.Date(2030, 1, 2, 0, 0, 0, 0, time.Local)
+		if err := db.UpdateDue(todo.ID, &due); err != nil {
+			t.Fatalf("UpdateDue() error = %v", err)
+		}
+
+		updated, err := db.Get(todo.ID)
+		if err != nil {
+			t.Fatalf("Get() after UpdateDue() error = %v", err)
+		}
+		if updated.DueAt == nil || !updated.DueAt.Equal(due) {
+			t.Errorf("UpdateDue() due = %v, want %v", updated.DueAt, due)
+		}
+
+		if err := db.UpdateDue(todo.ID, nil); err != nil {
+			t.Fatalf("UpdateDue(nil) error = %v", err)
+		}
+
+		cleared, _ := db.Get(todo.ID)
+		if cleared.DueAt != nil {
+			t.Errorf("UpdateDue(nil) due = %v, want nil", cleared.DueAt)
+		}
+	})
+
+	t.Run("Insert keeps timestamps", func(t *testing.T) {
+		created := time.Date(2024, 4, 20, 0, 0, 0, 0, time.Local)
+		completed := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+
+		todos := []*Todo{
+			{Description: "Imported open", Priority: High, Status: Open, Tag: "backend", CreatedAt: created},
+			{Description: "Imported done", Priority: Low, Status: Done, CreatedAt: created, CompletedAt: &completed},
+		}
+		if err := db.Insert(todos); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+
+		for _, want := range todos {
+			if want.ID == 0 {
+				t.Fatalf("Insert() did not set the ID of %q", want.Description)
+			}
+			got, err := db.Get(want.ID)
+			if err != nil {
+				t.Fatalf("Get() after Insert() error = %v", err)
+			}
+			if got.Description != want.Description || got.Priority != want.Priority || got.Status != want.Status || got.Tag != want.Tag {
+				t.Errorf("Insert() stored %+v, want %+v", got, want)
+			}
+			if !got.CreatedAt.Equal(created) {
+				t.Errorf("Insert() created_at = %v, want %v", got.CreatedAt, created)
+			}
+		}
+
+		done, _ := db.Get(todos[1].ID)
+		if done.CompletedAt == nil || !done.CompletedAt.Equal(completed) {
+			t.Errorf("Insert() completed_at = %v, want %v", done.CompletedAt, completed)
+		}
+	})
+
+	t.Run("Update notes", func(t *testing.T) {
+		todo, err := db.Create("Notes test", Medium)
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+
+		notes := "First line\nSecond line"
+		if err := db.UpdateNotes(todo.ID, notes); err != nil {
+			t.Fatalf("UpdateNotes() error = %v", err)
+		}
+
+		updated, err := db.Get(todo.ID)
+		if err != nil {
+			t.Fatalf("Get() after UpdateNotes() error = %v", err)
+		}
+		if updated.Notes != notes {
+			t.Errorf("UpdateNotes() notes = %q, want %q", updated.Notes, notes)
+		}
+	})
+
+	t.Run("Replace todos", func(t *testing.T) {
+		if err := db.Replace([]*Todo{{ID: 42, Description: "Restored", Priority: Medium, Status: Open}}); err != nil {
+			t.Fatalf("Replace() error = %v", err)
+		}
+
+		all, err := db.Find(nil)
+		if err != nil {
+			t.Fatalf("Find() after Replace() error = %v", err)
+		}
+		if len(all) != 1 || all[0].ID != 42 || all[0].Description != "Restored" {
+			t.Errorf("Replace() left %+v, want only todo #42", all)
+		}
+	})
+
 	t.Run("Delete todo", func(t *testing.T) {
 		// Create a todo
 		todo, err := db.Create("Delete test", Medium)
@@ -302,6 +404,34 @@ func TestDB_Integration(t *testing.T) {
 			t.Error("Get() after Delete() should return error")
 		}
 	})
+
+	t.Run("Sync state", func(t *testing.T) {
+		if err := db.SaveSyncState("/notes/a.md", map[string]string{"u1": "[ ] One", "u2": "[x] Two"}); err != nil {
+			t.Fatalf("SaveSyncState() error = %v", err)
+		}
+		if err := db.SaveSyncState("/notes/b.md", map[string]string{"u1": "[ ] Other"}); err != nil {
+			t.Fatalf("SaveSyncState() error = %v", err)
+		}
+		if err := db.SaveSyncState("/notes/a.md", map[string]string{"u2": "[ ] Two"}); err != nil {
+			t.Fatalf("SaveSyncState() error = %v", err)
+		}
+
+		state, err := db.SyncState("/notes/a.md")
+		if err != nil {
+			t.Fatalf("SyncState() error = %v", err)
+		}
+		if len(state) != 1 || state["u2"] != "[ ] Two" {
+			t.Errorf("SyncState() = %v, want only the last saved state", state)
+		}
+
+		other, err := db.SyncState("/notes/b.md")
+		if err != nil {
+			t.Fatalf("SyncState() error = %v", err)
+		}
+		if other["u1"] != "[ ] Other" {
+			t.Errorf("SyncState() of another target = %v", other)
+		}
+	})
 }
 
 func TestGetDatabasePath(t *testing.T) {
@@ -329,3 +459,174 @@ func TestGetDatabasePath(t *testing.T) {
 		t.Error("GetDatabasePath() should create .tada directory")
 	}
 }
+
+func TestNewUID(t *testing.T) {
+	uid := NewUID()
+	if !uuidPattern.MatchString(uid) {
+		t.Errorf("NewUID() = %q, want a version 4 UUID", uid)
+	}
+	if NewUID() == uid {
+		t.Error("NewUID() returned the same UID twice")
+	}
+}
+
+func TestNewDB_BackfillsUID(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "old.db")
+
+	// A database created before todos had a uid column
+	old, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	_, err = old.Exec(`
+		CREATE TABLE todos (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			description TEXT NOT NULL,
+			priority INTEGER NOT NULL DEFAULT 2,
+			status INTEGER NOT NULL DEFAULT 1,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			completed_at DATETIME NULL
+		);
+		INSERT INTO todos (description) VALUES ('first'), ('second');
+	`)
+	old.Close()
+	if err != nil {
+		t.Fatalf("creating old schema error = %v", err)
+	}
+
+	db, err := NewDB(dbPath)
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	defer db.Close()
+
+	todos, err := db.Find(nil)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(todos) != 2 {
+		t.Fatalf("Find() returned %d todos, want 2", len(todos))
+	}
+	for _, todo := range todos {
+		if !uuidPattern.MatchString(todo.UID) {
+			t.Errorf("todo %d UID = %q, want a version 4 UUID", todo.ID, todo.UID)
+		}
+	}
+	if todos[0].UID == todos[1].UID {
+		t.Error("backfilled UIDs should be unique")
+	}
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestDB_Encrypted(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(vault.PassphraseEnv, "correct horse")
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	db, err := NewDB(dbPath)
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	before, _ := db.Create("Prepare the review", Medium, "hr")
+	if err := vault.Enable(db.conn, "correct horse"); err != nil {
+		t.Fatalf("vault.Enable() error = %v", err)
+	}
+	db.Close()
+
+	db, err = NewDB(dbPath)
+	if err != nil {
+		t.Fatalf("NewDB() of an encrypted database error = %v", err)
+	}
+	defer db.Close()
+
+	created, err := db.Create("Write up the incident", High, "oncall")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := db.UpdateNotes(created.ID, "Timeline in the shared doc"); err != nil {
+		t.Fatalf("UpdateNotes() error = %v", err)
+	}
+
+	var description, tag, notes string
+	if err := db.conn.QueryRow(`SELECT description, tag, notes FROM todos WHERE id = ?`, created.ID).Scan(&description, &tag, &notes); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{description, tag, notes} {
+		if !strings.HasPrefix(v, "enc:") {
+			t.Errorf("stored value %q should be encrypted", v)
+		}
+	}
+
+	got, err := db.Get(created.ID)
+	if err != nil || got.Description != "Write up the incident" || got.Tag != "oncall" || got.Notes != "Timeline in the shared doc" {
+		t.Errorf("Get() = %+v, %v, want the decrypted todo", got, err)
+	}
+
+	// Filters on encrypted columns are applied once decrypted
+	filter, _ := ParseFilter(`tag:hr or desc~"incident"`)
+	found, err := db.Find(filter)
+	if err != nil || len(found) != 2 {
+		t.Errorf("Find(%s) = %d todos, %v, want 2", filter, len(found), err)
+	}
+	filter, _ = ParseFilter(`desc~"review" and priority:high`)
+	if found, _ := db.Find(filter); len(found) != 0 {
+		t.Errorf("Find(%s) = %d todos, want 0", filter, len(found))
+	}
+
+	// Saving an unchanged description is not a change
+	if err := db.UpdateDescription(before.ID, "Prepare the review"); err != nil {
+		t.Fatal(err)
+	}
+	var clocks int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM field_clocks WHERE uid = ? AND field = 'description'`, before.UID).Scan(&clocks); err != nil {
+		t.Fatal(err)
+	}
+	if clocks != 0 {
+		t.Error("UpdateDescription() with the same description should not record a change")
+	}
+}
+
+func TestDB_Commits(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	defer db.Close()
+
+	created, err := db.Create("Fix the login redirect", High)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Commit{SHA: "3f9c2ab0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6", Repo: "/src/api", Subject: "Fix the login redirect"}
+
+	if linked, err := db.LinkCommit(created.ID, c); err != nil || !linked {
+		t.Fatalf("LinkCommit() = %v, %v, want a new link", linked, err)
+	}
+	if linked, err := db.LinkCommit(created.ID, c); err != nil || linked {
+		t.Errorf("LinkCommit() again = %v, %v, want no new link", linked, err)
+	}
+	if _, err := db.LinkCommit(created.ID+100, c); err == nil {
+		t.Error("LinkCommit() of a missing todo should fail")
+	}
+
+	commits, err := db.Commits(created.ID)
+	if err != nil {
+		t.Fatalf("Commits() error = %v", err)
+	}
+	if len(commits) != 1 || commits[0].SHA != c.SHA || commits[0].Repo != c.Repo || commits[0].Subject != c.Subject {
+		t.Fatalf("Commits() = %+v, want the linked commit", commits)
+	}
+	if got := commits[0].ShortSHA(); got != "3f9c2ab" {
+		t.Errorf("ShortSHA() = %q, want 3f9c2ab", got)
+	}
+
+	if err := db.Delete(created.ID); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM todo_commits`).Scan(&n); err != nil || n != 0 {
+		t.Errorf("links after Delete() = %d, %v, want none", n, err)
+	}
+}
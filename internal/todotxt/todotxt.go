@@ -0,0 +1,215 @@
+package todotxt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/negadras/tada/internal/todo"
+)
+
+const dateLayout = "2006-01-02"
+
+// Parse reads todo.txt lines (https://github.com/todotxt/todo.txt) into
+// todos. Blank lines are skipped. The whole input is rejected if any line
+// cannot be imported, so an import is never partial.
+func Parse(r io.Reader) ([]*todo.Todo, error) {
+	return parse(r, time.Now())
+}
+
+func parse(r io.Reader, now time.Time) ([]*todo.Todo, error) {
+	var todos []*todo.Todo
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		t, err := ParseLine(line, now)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		todos = append(todos, t)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read todo.txt: %w", err)
+	}
+
+	return todos, nil
+}
+
+// ParseLine parses a single todo.txt line.
+//
+// Priorities (A), (B) and (C)-(Z) map to high, medium and low; tasks without
+// a priority are medium, and completed tasks may keep theirs in a pri: key.
+// The first +project, or the first @context if there is no project, becomes
+// the tag; other projects and contexts stay in the description. A due: key
+// sets the due date. Todos without a creation date are created at now.
+func ParseLine(line string, now time.Time) (*todo.Todo, error) {
+	t := &todo.Todo{Priority: todo.Medium, Status: todo.Open}
+	fields := strings.Fields(line)
+
+	if len(fields) > 0 && fields[0] == "x" {
+		t.Status = todo.Done
+		fields = fields[1:]
+		completed := now
+		if len(fields) > 0 {
+			if d, ok := parseDate(fields[0]); ok {
+				completed = d
+				fields = fields[1:]
+			}
+		}
+		t.CompletedAt = &completed
+	} else if len(fields) > 0 {
+		if p, ok := parsePriority(fields[0]); ok {
+			t.Priority = p
+			fields = fields[1:]
+		}
+	}
+
+	t.CreatedAt = now
+	if len(fields) > 0 {
+		if d, ok := parseDate(fields[0]); ok {
+			t.CreatedAt = d
+			fields = fields[1:]
+		}
+	}
+
+	tagIndex := -1
+	for i, f := range fields {
+		if isTagToken(f, '+') {
+			tagIndex = i
+			break
+		}
+	}
+	if tagIndex < 0 {
+		for i, f := range fields {
+			if isTagToken(f, '@') {
+				tagIndex = i
+				break
+			}
+		}
+	}
+
+	var words []string
+	for i, f := range fields {
+		if i == tagIndex {
+			t.Tag = f[1:]
+			continue
+		}
+
+		key, value, ok := strings.Cut(f, ":")
+		if ok && value != "" {
+			switch key {
+			case "due":
+				if d, ok := parseDate(value); ok {
+					t.DueAt = &d
+					continue
+				}
+			case "pri":
+				if p, ok := parsePriority("(" + value + ")"); ok && t.Status == todo.Done {
+					t.Priority = p
+					continue
+				}
+			}
+		}
+
+		words = append(words, f)
+	}
+
+	t.Description = strings.Join(words, " ")
+	if err := todo.ValidateDescription(t.Description); err != nil {
+		return nil, err
+	}
+
+	t.UpdatedAt = t.CreatedAt
+	if t.CompletedAt != nil && t.CompletedAt.After(t.UpdatedAt) {
+		t.UpdatedAt = *t.CompletedAt
+	}
+
+	return t, nil
+}
+
+// Format returns the todo.txt line for a todo. Completed todos keep their
+// priority in a pri: key, since todo.txt drops the (A) marker on completion.
+func Format(t *todo.Todo) string {
+	var parts []string
+
+	if t.Status == todo.Done {
+		parts = append(parts, "x")
+		if t.CompletedAt != nil {
+			parts = append(parts, t.CompletedAt.Local().Format(dateLayout))
+		}
+	} else {
+		parts = append(parts, formatPriority(t.Priority))
+	}
+
+	parts = append(parts, t.CreatedAt.Local().Format(dateLayout), t.Description)
+
+	if t.Tag != "" {
+		parts = append(parts, "+"+strings.Join(strings.Fields(t.Tag), "-"))
+	}
+	if t.DueAt != nil {
+		parts = append(parts, "due:"+t.DueAt.Local().Format(dateLayout))
+	}
+	if t.Status == todo.Done {
+		parts = append(parts, "pri:"+strings.Trim(formatPriority(t.Priority), "()"))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Write writes todos as todo.txt lines
+func Write(w io.Writer, todos []*todo.Todo) error {
+	for _, t := range todos {
+		if _, err := fmt.Fprintln(w, Format(t)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parsePriority parses a todo.txt priority marker such as (A)
+func parsePriority(s string) (todo.Priority, bool) {
+	if len(s) != 3 || s[0] != '(' || s[2] != ')' || s[1] < 'A' || s[1] > 'Z' {
+		return 0, false
+	}
+
+	switch s[1] {
+	case 'A':
+		return todo.High, true
+	case 'B':
+		return todo.Medium, true
+	default:
+		return todo.Low, true
+	}
+}
+
+func formatPriority(p todo.Priority) string {
+	switch p {
+	case todo.High:
+		return "(A)"
+	case todo.Low:
+		return "(C)"
+	default:
+		return "(B)"
+	}
+}
+
+// parseDate parses a todo.txt date as local midnight
+func parseDate(s string) (time.Time, bool) {
+	d, err := time.ParseInLocation(dateLayout, s, time.Local)
+	return d, err == nil
+}
+
+// isTagToken reports whether a word is a +project or @context
+func isTagToken(s string, sigil byte) bool {
+	return len(s) > 1 && s[0] == sigil
+}
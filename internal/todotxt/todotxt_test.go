@@ -0,0 +1,220 @@
+package todotxt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/negadras/tada/internal/todo"
+)
+
+func date(s string) time.Time {
+	d, _ := time.ParseInLocation(dateLayout, s, time.Local)
+	return d
+}
+
+func TestParseLine(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name        string
+		line        string
+		description string
+		priority    todo.Priority
+		status      todo.Status
+		tag         string
+		created     time.Time
+		completed   *time.Time
+		due         *time.Time
+	}{
+		{
+			name:        "plain task",
+			line:        "Call mom",
+			description: "Call mom",
+			priority:    todo.Medium,
+			status:      todo.Open,
+			created:     now,
+		},
+		{
+			name:        "priority, creation date, project and context",
+			line:        "(A) 2024-04-20 Fix login bug +backend @work",
+			description: "Fix login bug @work",
+			priority:    todo.High,
+			status:      todo.Open,
+			tag:         "backend",
+			created:     date("2024-04-20"),
+		},
+		{
+			name:        "context becomes the tag without a project",
+			line:        "(C) Buy milk @store",
+			description: "Buy milk",
+			priority:    todo.Low,
+			status:      todo.Open,
+			tag:         "store",
+			created:     now,
+		},
+		{
+			name:        "low priority letters",
+			line:        "(F) Someday task",
+			description: "Someday task",
+			priority:    todo.Low,
+			status:      todo.Open,
+			created:     now,
+		},
+		{
+			name:        "completed with dates and pri key",
+			line:        "x 2024-05-01 2024-04-20 Ship release +launch pri:A",
+			description: "Ship release",
+			priority:    todo.High,
+			status:      todo.Done,
+			tag:         "launch",
+			created:     date("2024-04-20"),
+			completed:   ptr(date("2024-05-01")),
+		},
+		{
+			name:        "completed without dates",
+			line:        "x Water plants",
+			description: "Water plants",
+			priority:    todo.Medium,
+			status:      todo.Done,
+			created:     now,
+			completed:   &now,
+		},
+		{
+			name:        "due date and other key-values",
+			line:        "(B) Pay rent due:2024-06-01 rec:1m",
+			description: "Pay rent rec:1m",
+			priority:    todo.Medium,
+			status:      todo.Open,
+			created:     now,
+			due:         ptr(date("2024-06-01")),
+		},
+		{
+			name:        "invalid due date stays in the description",
+			line:        "Renew passport due:soon",
+			description: "Renew passport due:soon",
+			priority:    todo.Medium,
+			status:      todo.Open,
+			created:     now,
+		},
+		{
+			name:        "priority marker not at the start",
+			line:        "Read (A) book",
+			description: "Read (A) book",
+			priority:    todo.Medium,
+			status:      todo.Open,
+			created:     now,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLine(tt.line, now)
+			if err != nil {
+				t.Fatalf("ParseLine(%q) error = %v", tt.line, err)
+			}
+
+			if got.Description != tt.description {
+				t.Errorf("Description = %q, want %q", got.Description, tt.description)
+			}
+			if got.Priority != tt.priority {
+				t.Errorf("Priority = %v, want %v", got.Priority, tt.priority)
+			}
+			if got.Status != tt.status {
+				t.Errorf("Status = %v, want %v", got.Status, tt.status)
+			}
+			if got.Tag != tt.tag {
+				t.Errorf("Tag = %q, want %q", got.Tag, tt.tag)
+			}
+			if !got.CreatedAt.Equal(tt.created) {
+				t.Errorf("CreatedAt = %v, want %v", got.CreatedAt, tt.created)
+			}
+			if !equalTime(got.CompletedAt, tt.completed) {
+				t.Errorf("CompletedAt = %v, want %v", got.CompletedAt, tt.completed)
+			}
+			if !equalTime(got.DueAt, tt.due) {
+				t.Errorf("DueAt = %v, want %v", got.DueAt, tt.due)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	_, err := Parse(strings.NewReader("Buy milk\n\n(A) 2024-04-20 +project\n"))
+	if err == nil {
+		t.Fatal("Parse() expected error for a line without a description")
+	}
+	if !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Parse() error = %v, want the line number", err)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	completed := date("2024-05-01")
+	due := date("2024-06-01")
+
+	tests := []struct {
+		name string
+		todo *todo.Todo
+		want string
+	}{
+		{
+			name: "open todo",
+			todo: &todo.Todo{Description: "Fix login bug", Priority: todo.High, Status: todo.Open, Tag: "backend", CreatedAt: date("2024-04-20"), DueAt: &due},
+			want: "(A) 2024-04-20 Fix login bug +backend due:2024-06-01",
+		},
+		{
+			name: "done todo",
+			todo: &todo.Todo{Description: "Ship release", Priority: todo.Low, Status: todo.Done, CreatedAt: date("2024-04-20"), CompletedAt: &completed},
+			want: "x 2024-05-01 2024-04-20 Ship release pri:C",
+		},
+		{
+			name: "tag with spaces",
+			todo: &todo.Todo{Description: "Plan", Priority: todo.Medium, Status: todo.Open, Tag: "platform team", CreatedAt: date("2024-04-20")},
+			want: "(B) 2024-04-20 Plan +platform-team",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(tt.todo); got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	input := "(A) 2024-04-20 Fix login bug @work +backend due:2024-06-01\n" +
+		"x 2024-05-01 2024-04-20 Ship release +launch pri:B\n" +
+		"(C) 2024-04-21 Buy milk +store\n"
+
+	todos, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, todos); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	want := "(A) 2024-04-20 Fix login bug @work +backend due:2024-06-01\n" +
+		"x 2024-05-01 2024-04-20 Ship release +launch pri:B\n" +
+		"(C) 2024-04-21 Buy milk +store\n"
+	if buf.String() != want {
+		t.Errorf("round trip =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
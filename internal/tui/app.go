@@ -7,6 +7,7 @@ import (
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/negadras/tada/internal/profile"
 	"github.com/negadras/tada/internal/tui/models"
 	"github.com/negadras/tada/internal/tui/styles"
 	"github.com/negadras/tada/internal/tui/utils"
@@ -40,6 +41,10 @@ type App struct {
 	// Navigation
 	screens     []Screen
 	screenIndex int
+
+	// profile names the store in use, shown in the status bar, or is empty
+	// for the default profile
+	profile string
 }
 
 // NewApp creates a new TUI application
@@ -56,6 +61,7 @@ func NewApp() *App {
 		showHelp:      false,
 		screens:       []Screen{ScreenDashboard, ScreenTodos, ScreenQuotes},
 		screenIndex:   0,
+		profile:       profile.Label(),
 	}
 }
 
@@ -91,6 +97,11 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return a, nil
 
 	case tea.KeyMsg:
+		// Let forms and prompts receive every key except ctrl+c
+		if a.currentScreen == ScreenTodos && a.todos.CapturingInput() && msg.String() != "ctrl+c" {
+			return a.updateCurrentScreen(msg)
+		}
+
 		// Global key bindings
 		switch {
 		case key.Matches(msg, a.keymap.Quit):
@@ -281,6 +292,9 @@ func (a *App) renderStatusBar() string {
 	}
 
 	subtitle := a.styles.Subtitle.Render(screenName)
+	if a.profile != "" {
+		subtitle = a.styles.Subtitle.Render(screenName + " · " + a.profile)
+	}
 
 	left := lipgloss.JoinHorizontal(lipgloss.Left, title, subtitle)
 	right := a.styles.Muted.Render("? for help")
@@ -34,6 +34,7 @@ type Form struct {
 	focused   int
 	submitted bool
 	cancelled bool
+	err       string
 	width     int
 	height    int
 }
@@ -123,6 +124,7 @@ func (f *Form) Update(msg tea.Msg) (*Form, tea.Cmd) {
 			}
 
 		default:
+			f.err = ""
 			if f.focused < len(f.fields) {
 				var cmd tea.Cmd
 				f.fields[f.focused].Input, cmd = f.fields[f.focused].Input.Update(msg)
@@ -173,6 +175,9 @@ func (f *Form) View() string {
 		errorText := f.styles.Error.Render("Please fill in all required fields")
 		content.WriteString(errorText)
 		content.WriteString("\n")
+	} else if f.err != "" {
+		content.WriteString(f.styles.Error.Render(f.err))
+		content.WriteString("\n")
 	}
 
 	instructions := f.styles.Help.Render("tab/↑↓: navigate • enter: submit • esc: cancel")
@@ -211,6 +216,13 @@ func (f *Form) IsCancelled() bool {
 	return f.cancelled
 }
 
+// SetError keeps the form open after a submit whose values could not be
+// used, showing err until a field is edited
+func (f *Form) SetError(err string) {
+	f.submitted = false
+	f.err = err
+}
+
 // GetValue returns the value of a field by index
 func (f *Form) GetValue(index int) string {
 	if index < len(f.fields) {
@@ -231,6 +243,7 @@ func (f *Form) SetValue(index int, value string) {
 func (f *Form) Reset() {
 	f.submitted = false
 	f.cancelled = false
+	f.err = ""
 	f.focused = 0
 
 	for i := range f.fields {
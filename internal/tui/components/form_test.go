@@ -1,8 +1,10 @@
 package components
 
 import (
+	"strings"
 	"testing"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/negadras/tada/internal/tui/styles"
 	"github.com/negadras/tada/internal/tui/utils"
 )
@@ -350,4 +352,29 @@ func TestForm_EmptyForm(t *testing.T) {
 	if form.focused != 0 {
 		t.Errorf("Expected focus to be 0 in empty form, got %d", form.focused)
 	}
-}
\ No newline at end of file
+}
+func TestForm_SetError(t *testing.T) {
+	form := NewForm(styles.DefaultStyles(), utils.DefaultKeyMap(), "Test Form")
+	form.AddField("Field1", "Placeholder1", true)
+	form.SetValue(0, "Value 1")
+	form.SetSize(80, 24)
+	form.submitted = true
+
+	form.SetError("priority given twice")
+
+	if form.IsSubmitted() {
+		t.Error("Expected form to not be submitted after an error")
+	}
+	if form.GetValue(0) != "Value 1" {
+		t.Errorf("Expected the value to be kept, got '%s'", form.GetValue(0))
+	}
+	if !strings.Contains(form.View(), "priority given twice") {
+		t.Error("Expected the error to be shown")
+	}
+
+	// Editing a field clears the error
+	form.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	if strings.Contains(form.View(), "priority given twice") {
+		t.Error("Expected the error to be cleared after an edit")
+	}
+}
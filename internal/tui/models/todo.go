@@ -3,9 +3,11 @@ package models
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/negadras/tada/internal/todo"
@@ -26,11 +28,24 @@ const (
 	todoTableReserved    = 10
 )
 
+// todoSortPresets are the sort orders cycled through with the sort key
+var todoSortPresets = []string{"-created", "priority,created", "due,priority", "-updated", "id"}
+
+// todoGroupPresets are the groupings cycled through with the group key
+var todoGroupPresets = []string{"", "tag", "priority", "status", "day"}
+
 // TodosLoadedMsg is sent when todos are loaded from the database
 type TodosLoadedMsg struct {
 	Todos []*todo.Todo
 }
 
+// TodoCreatedMsg is sent when a todo was added from the add form
+type TodoCreatedMsg struct {
+	Todos []*todo.Todo
+	// Understood describes the tokens read from the description
+	Understood string
+}
+
 // TodoErrorMsg is sent when there's an error loading todos
 type TodoErrorMsg struct {
 	Error error
@@ -46,6 +61,7 @@ type TodoManager struct {
 	db                *todo.DB
 	loading           bool
 	errorMessage      string
+	statusMessage     string
 	statusFilter      *todo.Status
 	addForm           *components.Form
 	showAddForm       bool
@@ -54,6 +70,13 @@ type TodoManager struct {
 	editingTodo       *todo.Todo
 	showDeleteConfirm bool
 	todoToDelete      *todo.Todo
+	searchInput       textinput.Model
+	showSearch        bool
+	searchError       string
+	filter            *todo.Filter
+	sortIndex         int
+	groupIndex        int
+	rowTodos          []*todo.Todo
 }
 
 // NewTodoManager creates a new todo manager model
@@ -85,7 +108,7 @@ func NewTodoManager(styles *styles.Styles, keymap utils.KeyMap) *TodoManager {
 
 	// Create add form
 	addForm := components.NewForm(styles, keymap, "Add New Todo")
-	addForm.AddField("Description", "Enter todo description", true)
+	addForm.AddField("Description", "Enter todo description, e.g. deploy hotfix !h #oncall @today ~30m", true)
 	addForm.AddField("Priority", "low, medium, or high (default: medium)", false)
 
 	// Create edit form
@@ -93,14 +116,21 @@ func NewTodoManager(styles *styles.Styles, keymap utils.KeyMap) *TodoManager {
 	editForm.AddField("Description", "Enter todo description", true)
 	editForm.AddField("Priority", "low, medium, or high", false)
 
+	// Create filter prompt
+	searchInput := textinput.New()
+	searchInput.Prompt = "/ "
+	searchInput.Placeholder = `priority>=medium and (tag:work or tag:oncall) and desc~"deploy"`
+	searchInput.CharLimit = 255
+
 	return &TodoManager{
-		styles:   styles,
-		keymap:   keymap,
-		table:    t,
-		todos:    []*todo.Todo{},
-		loading:  true,
-		addForm:  addForm,
-		editForm: editForm,
+		styles:      styles,
+		keymap:      keymap,
+		table:       t,
+		todos:       []*todo.Todo{},
+		loading:     true,
+		addForm:     addForm,
+		editForm:    editForm,
+		searchInput: searchInput,
 	}
 }
 
@@ -121,9 +151,20 @@ func (t *TodoManager) Update(msg tea.Msg) (*TodoManager, tea.Cmd) {
 		t.updateTable()
 		return t, nil
 
+	case TodoCreatedMsg:
+		t.todos = msg.Todos
+		t.errorMessage = ""
+		t.statusMessage = ""
+		if msg.Understood != "" {
+			t.statusMessage = "💡 Understood: " + msg.Understood
+		}
+		t.updateTable()
+		return t, nil
+
 	case TodoErrorMsg:
 		t.loading = false
 		t.errorMessage = msg.Error.Error()
+		t.statusMessage = ""
 		return t, nil
 
 	case tea.KeyMsg:
@@ -135,8 +176,17 @@ func (t *TodoManager) Update(msg tea.Msg) (*TodoManager, tea.Cmd) {
 				description := t.addForm.GetValue(0)
 				priorityStr := t.addForm.GetValue(1)
 
-				// Parse priority
+				quick, err := todo.ParseQuickAdd(description, time.Now())
+				if err != nil {
+					t.addForm.SetError(err.Error())
+					return t, nil
+				}
+
+				// Parse priority; the field takes precedence over a !priority token
 				priority := todo.Medium // default
+				if quick.Priority != nil {
+					priority = *quick.Priority
+				}
 				if priorityStr != "" {
 					if p, err := todo.ParsePriority(priorityStr); err == nil {
 						priority = p
@@ -145,7 +195,7 @@ func (t *TodoManager) Update(msg tea.Msg) (*TodoManager, tea.Cmd) {
 
 				t.showAddForm = false
 				t.addForm.Reset()
-				return t, t.createTodo(description, priority)
+				return t, t.createTodo(quick, priority)
 			}
 
 			if t.addForm.IsCancelled() {
@@ -191,6 +241,22 @@ func (t *TodoManager) Update(msg tea.Msg) (*TodoManager, tea.Cmd) {
 			return t, cmd
 		}
 
+		// Handle filter prompt mode
+		if t.showSearch {
+			switch {
+			case key.Matches(msg, t.keymap.Enter):
+				return t, t.applySearch()
+			case key.Matches(msg, t.keymap.Escape):
+				t.showSearch = false
+				t.searchError = ""
+				t.searchInput.Blur()
+				return t, nil
+			}
+
+			t.searchInput, cmd = t.searchInput.Update(msg)
+			return t, cmd
+		}
+
 		// Handle delete confirmation mode
 		if t.showDeleteConfirm {
 			switch {
@@ -213,6 +279,7 @@ func (t *TodoManager) Update(msg tea.Msg) (*TodoManager, tea.Cmd) {
 		switch {
 		case key.Matches(msg, t.keymap.Add):
 			t.showAddForm = true
+			t.statusMessage = ""
 			t.addForm.SetSize(t.width, t.height)
 			return t, t.addForm.Init()
 
@@ -228,6 +295,22 @@ func (t *TodoManager) Update(msg tea.Msg) (*TodoManager, tea.Cmd) {
 		case key.Matches(msg, t.keymap.Filter):
 			return t, t.cycleStatusFilter()
 
+		case key.Matches(msg, t.keymap.Sort):
+			t.sortIndex = (t.sortIndex + 1) % len(todoSortPresets)
+			return t, t.reloadTodos()
+
+		case key.Matches(msg, t.keymap.Group):
+			t.groupIndex = (t.groupIndex + 1) % len(todoGroupPresets)
+			t.updateTable()
+			return t, nil
+
+		case key.Matches(msg, t.keymap.Search):
+			t.showSearch = true
+			t.searchError = ""
+			t.searchInput.SetValue(t.filter.String())
+			t.searchInput.CursorEnd()
+			return t, t.searchInput.Focus()
+
 		case key.Matches(msg, t.keymap.Enter):
 			return t, t.toggleTodoStatus()
 
@@ -270,6 +353,33 @@ func (t *TodoManager) View() string {
 		content.WriteString("\n")
 	}
 
+	if t.showSearch {
+		content.WriteString(t.styles.InputActive.Render(t.searchInput.View()))
+		content.WriteString("\n")
+		if t.searchError != "" {
+			content.WriteString(t.styles.Error.Render(t.searchError))
+			content.WriteString("\n")
+		}
+	} else if !t.filter.IsEmpty() {
+		queryText := t.styles.Info.Render(fmt.Sprintf("Query: %s", t.filter.String()))
+		content.WriteString(queryText)
+		content.WriteString("\n")
+	}
+
+	if t.sortIndex != 0 || t.groupIndex != 0 {
+		orderText := fmt.Sprintf("Sort: %s", todoSortPresets[t.sortIndex])
+		if group := todoGroupPresets[t.groupIndex]; group != "" {
+			orderText += fmt.Sprintf(" • Group: %s", group)
+		}
+		content.WriteString(t.styles.Info.Render(orderText))
+		content.WriteString("\n")
+	}
+
+	if t.statusMessage != "" {
+		content.WriteString(t.styles.Info.Render(t.statusMessage))
+		content.WriteString("\n")
+	}
+
 	if t.errorMessage != "" {
 		errorText := t.styles.Error.Render(fmt.Sprintf("Error: %s", t.errorMessage))
 		content.WriteString(errorText)
@@ -326,6 +436,9 @@ func (t *TodoManager) renderInstructions() string {
 		"d: delete",
 		"t/enter: toggle status",
 		"f: filter",
+		"/: search",
+		"s: sort",
+		"g: group",
 		"esc: back",
 	}
 
@@ -334,21 +447,51 @@ func (t *TodoManager) renderInstructions() string {
 
 // updateTable updates the table with current todos.
 // Formats each todo's data to fit within the designated column widths.
+// When a grouping is active every group is preceded by a header row.
 func (t *TodoManager) updateTable() {
-	rows := make([]table.Row, len(t.todos))
-	for i, todo := range t.todos {
-		rows[i] = table.Row{
-			fmt.Sprintf("#%d", todo.ID),
-			strings.ToUpper(todo.Priority.String()),
-			strings.ToUpper(todo.Status.String()),
-			utils.FormatDuration(todo.Age()),
-			todo.Description,
+	var rows []table.Row
+	t.rowTodos = t.rowTodos[:0]
+
+	addRow := func(item *todo.Todo) {
+		rows = append(rows, table.Row{
+			fmt.Sprintf("#%d", item.ID),
+			strings.ToUpper(item.Priority.String()),
+			strings.ToUpper(item.Status.String()),
+			utils.FormatDuration(item.Age()),
+			item.Description,
+		})
+		t.rowTodos = append(t.rowTodos, item)
+	}
+
+	groupBy := todoGroupPresets[t.groupIndex]
+	if groupBy == "" {
+		for _, item := range t.todos {
+			addRow(item)
+		}
+	} else {
+		groups, _ := todo.GroupTodos(t.todos, groupBy)
+		for _, g := range groups {
+			rows = append(rows, table.Row{"", "", "", "", fmt.Sprintf("▸ %s (%d)", g.Key, g.Count)})
+			t.rowTodos = append(t.rowTodos, nil)
+			for _, item := range g.Todos {
+				addRow(item)
+			}
 		}
 	}
 
 	t.table.SetRows(rows)
 }
 
+// selectedTodo returns the todo under the cursor, or nil when the table is
+// empty or a group header is selected
+func (t *TodoManager) selectedTodo() *todo.Todo {
+	cursor := t.table.Cursor()
+	if cursor < 0 || cursor >= len(t.rowTodos) {
+		return nil
+	}
+	return t.rowTodos[cursor]
+}
+
 // loadTodos loads todos from the database and applies the current status filter.
 // Returns a command that will send either TodosLoadedMsg or TodoErrorMsg.
 func (t *TodoManager) loadTodos() tea.Cmd {
@@ -365,7 +508,7 @@ func (t *TodoManager) loadTodos() tea.Cmd {
 
 		t.db = db
 
-		todos, err := t.db.List(t.statusFilter, nil, nil)
+		todos, err := t.fetchTodos()
 		if err != nil {
 			return TodoErrorMsg{Error: fmt.Errorf("failed to load todos: %w", err)}
 		}
@@ -374,18 +517,64 @@ func (t *TodoManager) loadTodos() tea.Cmd {
 	}
 }
 
-// toggleTodoStatus toggles the status of the selected todo
-func (t *TodoManager) toggleTodoStatus() tea.Cmd {
-	if len(t.todos) == 0 || t.db == nil {
+// fetchTodos lists the todos matching the current status filter and query
+// in the current sort order
+func (t *TodoManager) fetchTodos() ([]*todo.Todo, error) {
+	todos, err := t.db.Find(todo.NewFieldFilter(t.statusFilter, nil, nil).And(t.filter))
+	if err != nil {
+		return nil, err
+	}
+
+	keys, _ := todo.ParseSort(todoSortPresets[t.sortIndex])
+	todo.SortTodos(todos, keys)
+	return todos, nil
+}
+
+// reloadTodos returns a command reloading the todos from the database
+func (t *TodoManager) reloadTodos() tea.Cmd {
+	if t.db == nil {
 		return nil
 	}
 
-	selectedIndex := t.table.Cursor()
-	if selectedIndex >= len(t.todos) {
+	return func() tea.Msg {
+		todos, err := t.fetchTodos()
+		if err != nil {
+			return TodoErrorMsg{Error: err}
+		}
+
+		return TodosLoadedMsg{Todos: todos}
+	}
+}
+
+// applySearch parses the filter prompt and reloads the todos with it.
+// The prompt stays open with an error message if the expression is invalid.
+func (t *TodoManager) applySearch() tea.Cmd {
+	filter, err := todo.ParseFilter(t.searchInput.Value())
+	if err != nil {
+		t.searchError = err.Error()
 		return nil
 	}
 
-	selectedTodo := t.todos[selectedIndex]
+	t.filter = filter
+	t.showSearch = false
+	t.searchError = ""
+	t.searchInput.Blur()
+
+	return t.reloadTodos()
+}
+
+// CapturingInput reports whether a form or prompt is receiving keystrokes,
+// in which case global key bindings should not be applied.
+func (t *TodoManager) CapturingInput() bool {
+	return t.showAddForm || t.showEditForm || t.showSearch
+}
+
+// toggleTodoStatus toggles the status of the selected todo
+func (t *TodoManager) toggleTodoStatus() tea.Cmd {
+	selectedTodo := t.selectedTodo()
+	if selectedTodo == nil || t.db == nil {
+		return nil
+	}
 
 	return func() tea.Msg {
 		var newStatus todo.Status
@@ -400,7 +589,7 @@ func (t *TodoManager) toggleTodoStatus() tea.Cmd {
 			return TodoErrorMsg{Error: err}
 		}
 
-		todos, err := t.db.List(t.statusFilter, nil, nil)
+		todos, err := t.fetchTodos()
 		if err != nil {
 			return TodoErrorMsg{Error: err}
 		}
@@ -423,7 +612,7 @@ func (t *TodoManager) cycleStatusFilter() tea.Cmd {
 		}
 
 		// Reload todos with new filter
-		todos, err := t.db.List(t.statusFilter, nil, nil)
+		todos, err := t.fetchTodos()
 		if err != nil {
 			return TodoErrorMsg{Error: err}
 		}
@@ -432,25 +621,38 @@ func (t *TodoManager) cycleStatusFilter() tea.Cmd {
 	}
 }
 
-// createTodo creates a new todo with the given description and priority.
-// Returns a command that will send either TodosLoadedMsg or TodoErrorMsg.
-func (t *TodoManager) createTodo(description string, priority todo.Priority) tea.Cmd {
+// createTodo creates a new todo from a quick-add line with the given priority.
+// Returns a command that will send either TodoCreatedMsg or TodoErrorMsg.
+func (t *TodoManager) createTodo(quick *todo.QuickAdd, priority todo.Priority) tea.Cmd {
 	if t.db == nil {
 		return nil
 	}
 
 	return func() tea.Msg {
-		_, err := t.db.Create(description, priority)
+		if err := todo.ValidateDescription(quick.Description); err != nil {
+			return TodoErrorMsg{Error: err}
+		}
+		created, err := t.db.Create(quick.Description, priority, quick.TodoTag())
 		if err != nil {
 			return TodoErrorMsg{Error: err}
 		}
+		if quick.Due != nil {
+			if err := t.db.UpdateDue(created.ID, quick.Due); err != nil {
+				return TodoErrorMsg{Error: err}
+			}
+		}
+		if notes := quick.Notes(""); notes != "" {
+			if err := t.db.UpdateNotes(created.ID, notes); err != nil {
+				return TodoErrorMsg{Error: err}
+			}
+		}
 
-		todos, err := t.db.List(t.statusFilter, nil, nil)
+		todos, err := t.fetchTodos()
 		if err != nil {
 			return TodoErrorMsg{Error: err}
 		}
 
-		return TodosLoadedMsg{Todos: todos}
+		return TodoCreatedMsg{Todos: todos, Understood: quick.Understood()}
 	}
 }
 
@@ -458,16 +660,11 @@ func (t *TodoManager) createTodo(description string, priority todo.Priority) tea
 // Pre-populates the form with the todo's current description and priority.
 // Returns nil if no todos exist or selection is invalid.
 func (t *TodoManager) openEditForm() tea.Cmd {
-	if len(t.todos) == 0 {
+	selectedTodo := t.selectedTodo()
+	if selectedTodo == nil {
 		return nil
 	}
 
-	selectedIndex := t.table.Cursor()
-	if selectedIndex >= len(t.todos) {
-		return nil
-	}
-
-	selectedTodo := t.todos[selectedIndex]
 	t.editingTodo = selectedTodo
 
 	t.editForm.Reset()
@@ -499,7 +696,7 @@ func (t *TodoManager) updateTodo(id int, description string, priority todo.Prior
 			return TodoErrorMsg{Error: err}
 		}
 
-		todos, err := t.db.List(t.statusFilter, nil, nil)
+		todos, err := t.fetchTodos()
 		if err != nil {
 			return TodoErrorMsg{Error: err}
 		}
@@ -511,16 +708,12 @@ func (t *TodoManager) updateTodo(id int, description string, priority todo.Prior
 // showDeleteConfirmation shows the delete confirmation dialog for the selected todo.
 // Returns nil if no todos exist or selection is invalid.
 func (t *TodoManager) showDeleteConfirmation() tea.Cmd {
-	if len(t.todos) == 0 {
-		return nil
-	}
-
-	selectedIndex := t.table.Cursor()
-	if selectedIndex >= len(t.todos) {
+	selectedTodo := t.selectedTodo()
+	if selectedTodo == nil {
 		return nil
 	}
 
-	t.todoToDelete = t.todos[selectedIndex]
+	t.todoToDelete = selectedTodo
 	t.showDeleteConfirm = true
 	return nil
 }
@@ -539,7 +732,7 @@ func (t *TodoManager) confirmDeleteTodo(todoToDelete *todo.Todo) tea.Cmd {
 		}
 
 		// Reload todos
-		todos, err := t.db.List(t.statusFilter, nil, nil)
+		todos, err := t.fetchTodos()
 		if err != nil {
 			return TodoErrorMsg{Error: fmt.Errorf("failed to reload todos: %w", err)}
 		}
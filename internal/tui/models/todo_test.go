@@ -2,8 +2,10 @@ package models
 
 import (
 	"path/filepath"
+	"strings"
 	"testing"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/negadras/tada/internal/todo"
 	"github.com/negadras/tada/internal/tui/styles"
 	"github.com/negadras/tada/internal/tui/utils"
@@ -387,4 +389,97 @@ func TestTodoManager_EmptyState(t *testing.T) {
 	if completed != 0 {
 		t.Errorf("Expected 0 completed todos, got %d", completed)
 	}
-}
\ No newline at end of file
+}
+
+func TestTodoManager_applySearch(t *testing.T) {
+	manager := NewTodoManager(styles.DefaultStyles(), utils.DefaultKeyMap())
+	manager.showSearch = true
+
+	manager.searchInput.SetValue("priority>=urgent")
+	manager.applySearch()
+
+	if manager.searchError == "" {
+		t.Error("Expected an error for an invalid filter expression")
+	}
+	if !manager.showSearch {
+		t.Error("Expected filter prompt to stay open after an invalid expression")
+	}
+
+	manager.searchInput.SetValue("priority>=medium and tag:work")
+	manager.applySearch()
+
+	if manager.searchError != "" {
+		t.Errorf("Expected no error, got %q", manager.searchError)
+	}
+	if manager.showSearch {
+		t.Error("Expected filter prompt to close after a valid expression")
+	}
+	if manager.filter.String() != "priority>=medium and tag:work" {
+		t.Errorf("Expected filter to be applied, got %q", manager.filter.String())
+	}
+	if manager.CapturingInput() {
+		t.Error("CapturingInput should be false when no prompt or form is open")
+	}
+}
+
+func TestTodoManager_GroupedTable(t *testing.T) {
+	manager := NewTodoManager(styles.DefaultStyles(), utils.DefaultKeyMap())
+	manager.todos = []*todo.Todo{
+		{ID: 1, Description: "Deploy", Priority: todo.High, Status: todo.Open, Tag: "work"},
+		{ID: 2, Description: "Groceries", Priority: todo.Low, Status: todo.Open, Tag: "home"},
+		{ID: 3, Description: "Review", Priority: todo.Medium, Status: todo.Open, Tag: "work"},
+	}
+
+	// Group by tag
+	manager.groupIndex = 1
+	manager.updateTable()
+
+	rows := manager.table.Rows()
+	if len(rows) != 5 { // 2 group headers + 3 todos
+		t.Fatalf("Expected 5 table rows, got %d", len(rows))
+	}
+
+	if rows[0][4] != "▸ work (2)" {
+		t.Errorf("Expected first row to be the work group header, got %q", rows[0][4])
+	}
+
+	manager.table.SetCursor(0)
+	if manager.selectedTodo() != nil {
+		t.Error("Expected no todo to be selected on a group header")
+	}
+
+	manager.table.SetCursor(1)
+	if selected := manager.selectedTodo(); selected == nil || selected.ID != 1 {
+		t.Errorf("Expected todo #1 to be selected, got %v", selected)
+	}
+}
+
+func TestTodoManager_AddFormKeepsAnInvalidLine(t *testing.T) {
+	manager := NewTodoManager(styles.DefaultStyles(), utils.DefaultKeyMap())
+	manager.SetSize(100, 30)
+	manager.showAddForm = true
+	manager.addForm.SetValue(0, "ship it !h !l")
+
+	manager.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if !manager.showAddForm {
+		t.Fatal("Expected the add form to stay open after a parse error")
+	}
+	if got := manager.addForm.GetValue(0); got != "ship it !h !l" {
+		t.Errorf("Expected the line to be kept, got %q", got)
+	}
+}
+
+func TestTodoManager_ShowsWhatWasUnderstood(t *testing.T) {
+	manager := NewTodoManager(styles.DefaultStyles(), utils.DefaultKeyMap())
+	manager.SetSize(100, 30)
+
+	manager.Update(TodoCreatedMsg{
+		Todos:      []*todo.Todo{{ID: 1, Description: "ship it", Priority: todo.High, Tag: "api"}},
+		Understood: "priority HIGH, project api",
+	})
+
+	if !strings.Contains(manager.View(), "Understood: priority HIGH, project api") {
+		t.Error("Expected the view to show what was understood")
+	}
+}
@@ -20,6 +20,8 @@ type KeyMap struct {
 	Edit     key.Binding
 	Search   key.Binding
 	Filter   key.Binding
+	Sort     key.Binding
+	Group    key.Binding
 	Add      key.Binding
 	Toggle   key.Binding
 	Save     key.Binding
@@ -93,6 +95,14 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("f"),
 			key.WithHelp("f", "filter"),
 		),
+		Sort: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "sort"),
+		),
+		Group: key.NewBinding(
+			key.WithKeys("g"),
+			key.WithHelp("g", "group"),
+		),
 		Add: key.NewBinding(
 			key.WithKeys("a", "n"),
 			key.WithHelp("a", "add"),
@@ -123,7 +133,8 @@ func (k KeyMap) FullHelp() [][]key.Binding {
 		{k.Up, k.Down, k.Left, k.Right},
 		{k.Enter, k.Space, k.Tab, k.ShiftTab},
 		{k.Add, k.Edit, k.Delete, k.Toggle},
-		{k.Search, k.Filter, k.Save, k.Cancel},
+		{k.Search, k.Filter, k.Sort, k.Group},
+		{k.Save, k.Cancel},
 		{k.Help, k.Back, k.Quit},
 	}
 }
\ No newline at end of file
@@ -23,6 +23,8 @@ var (
 type TableModel struct {
 	table table.Model
 	todos []*todo.Todo
+	// profile is shown in the title, unless it is empty
+	profile string
 }
 
 // NewTableModel creates a new table model with todos
@@ -111,7 +113,11 @@ func (m TableModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m TableModel) View() string {
 	var b strings.Builder
 
-	b.WriteString("\n📋 Todo List\n\n")
+	if m.profile != "" {
+		b.WriteString("\n📋 Todo List · " + m.profile + "\n\n")
+	} else {
+		b.WriteString("\n📋 Todo List\n\n")
+	}
 	b.WriteString(baseStyle.Render(m.table.View()))
 	b.WriteString("\n")
 	b.WriteString("  ↑/↓: Navigate • Enter: Select • q: Quit\n")
@@ -119,13 +125,16 @@ func (m TableModel) View() string {
 	return b.String()
 }
 
-func ShowTable(todos []*todo.Todo) error {
+// ShowTable shows todos in an interactive table, titled with the profile
+// unless it is empty
+func ShowTable(todos []*todo.Todo, profile string) error {
 	if len(todos) == 0 {
 		fmt.Println("📝 No todos found matching your criteria.")
 		return nil
 	}
 
 	m := NewTableModel(todos)
+	m.profile = profile
 	p := tea.NewProgram(m)
 	_, err := p.Run()
 	return err
@@ -0,0 +1,162 @@
+package vault
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout is how long the agent keeps the passphrase
+const DefaultTimeout = 8 * time.Hour
+
+// GetAgentPath returns the path of the agent's socket, next to the database
+func GetAgentPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".tada", "agent.sock"), nil
+}
+
+// agentRequest sends a request to the agent and returns its answer
+func agentRequest(request string) (string, error) {
+	path, err := GetAgentPath()
+	if err != nil {
+		return "", err
+	}
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	if _, err := fmt.Fprintln(conn, request); err != nil {
+		return "", err
+	}
+	answer, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	answer = strings.TrimSpace(answer)
+	if msg, ok := strings.CutPrefix(answer, "err "); ok {
+		return "", errors.New(msg)
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(answer, "ok"), " "), nil
+}
+
+// agentKey asks the agent for the key derived with kdf and salt
+func agentKey(kdf, salt string) ([]byte, error) {
+	answer, err := agentRequest("key " + kdf + " " + salt)
+	if err != nil {
+		return nil, err
+	}
+	return base64.RawStdEncoding.DecodeString(answer)
+}
+
+// AgentRunning reports whether an agent holds the passphrase
+func AgentRunning() bool {
+	_, err := agentRequest("ping")
+	return err == nil
+}
+
+// StopAgent makes the agent forget the passphrase and exit. It reports
+// whether one was running.
+func StopAgent() bool {
+	_, err := agentRequest("stop")
+	return err == nil
+}
+
+// ListenAgent creates the agent's socket, readable by the current user only
+func ListenAgent() (net.Listener, error) {
+	path, err := GetAgentPath()
+	if err != nil {
+		return nil, err
+	}
+	if AgentRunning() {
+		return nil, errors.New("an agent is already running; run 'tada lock' first")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create .tada directory: %w", err)
+	}
+	// Left behind by an agent that did not exit cleanly
+	_ = os.Remove(path)
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start agent: %w", err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// ServeAgent answers requests for keys derived from passphrase on l, until
+// it is stopped or timeout has passed
+func ServeAgent(l net.Listener, passphrase string, timeout time.Duration) error {
+	var once sync.Once
+	stop := func() { once.Do(func() { l.Close() }) }
+	timer := time.AfterFunc(timeout, stop)
+	defer timer.Stop()
+
+	// Deriving a key is slow, so each one is derived once
+	var mu sync.Mutex
+	keys := map[string][]byte{}
+	derive := func(kdf, salt string) ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if key, ok := keys[kdf+" "+salt]; ok {
+			return key, nil
+		}
+		key, err := deriveKey(passphrase, kdf, salt)
+		if err == nil {
+			keys[kdf+" "+salt] = key
+		}
+		return key, err
+	}
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+			line, err := bufio.NewReader(conn).ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			fields := strings.Fields(line)
+			switch {
+			case len(fields) == 1 && fields[0] == "ping":
+				fmt.Fprintln(conn, "ok")
+			case len(fields) == 1 && fields[0] == "stop":
+				fmt.Fprintln(conn, "ok")
+				stop()
+			case len(fields) == 3 && fields[0] == "key":
+				key, err := derive(fields[1], fields[2])
+				if err != nil {
+					fmt.Fprintln(conn, "err", err)
+					return
+				}
+				fmt.Fprintln(conn, "ok", base64.RawStdEncoding.EncodeToString(key))
+			default:
+				fmt.Fprintln(conn, "err unknown request")
+			}
+		}()
+	}
+}
@@ -0,0 +1,399 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Schema creates the table holding how the key is derived from the
+// passphrase, when the database is encrypted
+const Schema = `
+	CREATE TABLE IF NOT EXISTS encryption (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		kdf TEXT NOT NULL,
+		salt TEXT NOT NULL,
+		check_value TEXT NOT NULL
+	);
+`
+
+// Columns are the columns encrypted, by table. Other columns, such as
+// priorities, dates and quote authors, stay in plain text so that they can
+// be queried.
+var Columns = map[string][]string{
+	"todos":              {"description", "tag", "notes"},
+	"quotes":             {"text"},
+	"sync_state":         {"item"},
+	"todo_commits":       {"subject"},
+	"webhook_state":      {"item"},
+	"webhook_deliveries": {"payload"},
+}
+
+// PassphraseEnv is the environment variable that holds the passphrase for
+// scripts
+const PassphraseEnv = "TADA_PASSPHRASE"
+
+var (
+	// ErrLocked is returned when opening an encrypted database without
+	// the passphrase
+	ErrLocked = errors.New("todos are encrypted; run 'tada unlock' or set " + PassphraseEnv)
+	// ErrWrongPassphrase is returned when the passphrase does not open the
+	// database
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+)
+
+// IsLocked reports whether err means the database could not be unlocked
+func IsLocked(err error) bool {
+	return errors.Is(err, ErrLocked) || errors.Is(err, ErrWrongPassphrase)
+}
+
+// kdf is how keys are derived: Argon2id with 3 passes over 64 MiB
+const kdf = "argon2id:t=3,m=65536,p=4"
+
+// prefix marks encrypted values
+const prefix = "enc:v1:"
+
+// checkText is encrypted with the key, so that a wrong passphrase is told
+// apart from a right one
+const checkText = "tada"
+
+// Cipher encrypts and decrypts values. A nil Cipher leaves them as they are,
+// so that unencrypted databases need no special case.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+func newCipher(key []byte) (*Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Seal encrypts a value with AES-256-GCM. Empty values stay empty.
+func (c *Cipher) Seal(value string) string {
+	if c == nil || value == "" {
+		return value
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	_, _ = rand.Read(nonce)
+	sealed := c.aead.Seal(nonce, nonce, []byte(value), nil)
+	return prefix + base64.RawStdEncoding.EncodeToString(sealed)
+}
+
+// Open decrypts a value encrypted by Seal. Values that are not encrypted are
+// returned as they are.
+func (c *Cipher) Open(value string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, prefix)
+	if !ok {
+		return value, nil
+	}
+	if c == nil {
+		return "", ErrLocked
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", errors.New("failed to decrypt: corrupt value")
+	}
+	n := c.aead.NonceSize()
+	plain, err := c.aead.Open(nil, sealed[:n], sealed[n:], nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt: wrong key or corrupt value")
+	}
+	return string(plain), nil
+}
+
+// Reseal encrypts value to replace stored, the value currently in the
+// database. If it decrypts to value, stored is returned unchanged, so that
+// saving an unchanged field does not look like a change.
+func (c *Cipher) Reseal(stored, value string) string {
+	if plain, err := c.Open(stored); err == nil && plain == value {
+		return stored
+	}
+	return c.Seal(value)
+}
+
+// settings are how the database's key is derived
+type settings struct {
+	kdf   string
+	salt  string
+	check string
+}
+
+// deriveKey derives the key of a database from the passphrase
+func deriveKey(passphrase, kdfSpec, salt string) ([]byte, error) {
+	var t, m uint32
+	var p uint8
+	if _, err := fmt.Sscanf(kdfSpec, "argon2id:t=%d,m=%d,p=%d", &t, &m, &p); err != nil {
+		return nil, fmt.Errorf("unknown key derivation %q", kdfSpec)
+	}
+	rawSalt, err := base64.RawStdEncoding.DecodeString(salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %w", err)
+	}
+	return argon2.IDKey([]byte(passphrase), rawSalt, t, m, p, 32), nil
+}
+
+func readSettings(q interface {
+	QueryRow(string, ...interface{}) *sql.Row
+}) (*settings, error) {
+	s := &settings{}
+	err := q.QueryRow(`SELECT kdf, salt, check_value FROM encryption WHERE id = 1`).Scan(&s.kdf, &s.salt, &s.check)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return s, err
+}
+
+// ciphers caches the ciphers unlocked by this process, by salt, so that the
+// key is derived once
+var ciphers sync.Map
+
+// cipherFor returns the cipher of a key if it opens the database
+func (s *settings) cipherFor(key []byte) (*Cipher, error) {
+	c, err := newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if text, err := c.Open(s.check); err != nil || text != checkText {
+		return nil, ErrWrongPassphrase
+	}
+	ciphers.Store(s.salt, c)
+	return c, nil
+}
+
+// Unlock returns the cipher of a database, or nil if it is not encrypted.
+// The passphrase is taken from TADA_PASSPHRASE, or else the key from the
+// agent started by 'tada unlock'.
+func Unlock(conn *sql.DB) (*Cipher, error) {
+	if _, err := conn.Exec(Schema); err != nil {
+		return nil, err
+	}
+	s, err := readSettings(conn)
+	if err != nil || s == nil {
+		return nil, err
+	}
+	if c, ok := ciphers.Load(s.salt); ok {
+		return c.(*Cipher), nil
+	}
+
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		key, err := deriveKey(passphrase, s.kdf, s.salt)
+		if err != nil {
+			return nil, err
+		}
+		c, err := s.cipherFor(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w in %s", err, PassphraseEnv)
+		}
+		return c, nil
+	}
+
+	key, err := agentKey(s.kdf, s.salt)
+	if err != nil {
+		return nil, ErrLocked
+	}
+	c, err := s.cipherFor(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: the passphrase given to 'tada unlock' does not open this database", err)
+	}
+	return c, nil
+}
+
+// Locked reports whether the database at dbPath is encrypted and cannot be
+// unlocked
+func Locked(dbPath string) bool {
+	if _, err := os.Stat(dbPath); err != nil {
+		return false
+	}
+	conn, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	_, err = Unlock(conn)
+	return IsLocked(err)
+}
+
+// Check reports whether passphrase opens the database, which must be
+// encrypted
+func Check(conn *sql.DB, passphrase string) error {
+	if _, err := conn.Exec(Schema); err != nil {
+		return err
+	}
+	s, err := readSettings(conn)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		return errors.New("todos are not encrypted; run 'tada encrypt' first")
+	}
+	key, err := deriveKey(passphrase, s.kdf, s.salt)
+	if err != nil {
+		return err
+	}
+	_, err = s.cipherFor(key)
+	return err
+}
+
+// Encrypted reports whether a database is encrypted
+func Encrypted(conn *sql.DB) (bool, error) {
+	if _, err := conn.Exec(Schema); err != nil {
+		return false, err
+	}
+	s, err := readSettings(conn)
+	return s != nil, err
+}
+
+// Enable encrypts a database with a key derived from passphrase, including
+// every value already stored
+func Enable(conn *sql.DB, passphrase string) error {
+	if _, err := conn.Exec(Schema); err != nil {
+		return err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	s := &settings{kdf: kdf, salt: base64.RawStdEncoding.EncodeToString(salt)}
+	key, err := deriveKey(passphrase, s.kdf, s.salt)
+	if err != nil {
+		return err
+	}
+	c, err := newCipher(key)
+	if err != nil {
+		return err
+	}
+	s.check = c.Seal(checkText)
+
+	err = withTx(conn, func(tx *sql.Tx) error {
+		if existing, err := readSettings(tx); err != nil {
+			return err
+		} else if existing != nil {
+			return errors.New("todos are already encrypted")
+		}
+		if _, err := tx.Exec(`INSERT INTO encryption (id, kdf, salt, check_value) VALUES (1, ?, ?, ?)`, s.kdf, s.salt, s.check); err != nil {
+			return err
+		}
+		if err := reseal(tx, nil, c); err != nil {
+			return err
+		}
+		ciphers.Store(s.salt, c)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Rewrite the file, so that the plain text left in its free pages is
+	// gone too
+	_, err = conn.Exec(`VACUUM`)
+	return err
+}
+
+// Disable decrypts every value of a database, which must be unlocked, and
+// stops encrypting it
+func Disable(conn *sql.DB) error {
+	c, err := Unlock(conn)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		return errors.New("todos are not encrypted")
+	}
+
+	return withTx(conn, func(tx *sql.Tx) error {
+		if err := reseal(tx, c, nil); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM encryption`)
+		return err
+	})
+}
+
+// reseal decrypts every encrypted column with from and encrypts it again
+// with to
+func reseal(tx *sql.Tx, from, to *Cipher) error {
+	for table, columns := range Columns {
+		var exists int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&exists); err != nil {
+			return err
+		}
+		if exists == 0 {
+			continue
+		}
+
+		rows, err := tx.Query(`SELECT rowid, ` + strings.Join(columns, ", ") + ` FROM ` + table)
+		if err != nil {
+			return err
+		}
+		type row struct {
+			id     int64
+			values []sql.NullString
+		}
+		var all []row
+		for rows.Next() {
+			r := row{values: make([]sql.NullString, len(columns))}
+			dest := []interface{}{&r.id}
+			for i := range r.values {
+				dest = append(dest, &r.values[i])
+			}
+			if err := rows.Scan(dest...); err != nil {
+				rows.Close()
+				return err
+			}
+			all = append(all, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		sets := strings.Join(columns, " = ?, ") + " = ?"
+		for _, r := range all {
+			args := make([]interface{}, 0, len(columns)+1)
+			for _, v := range r.values {
+				if !v.Valid {
+					args = append(args, nil)
+					continue
+				}
+				plain, err := from.Open(v.String)
+				if err != nil {
+					return fmt.Errorf("%s: %w", table, err)
+				}
+				args = append(args, to.Seal(plain))
+			}
+			args = append(args, r.id)
+			if _, err := tx.Exec(`UPDATE `+table+` SET `+sets+` WHERE rowid = ?`, args...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func withTx(conn *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
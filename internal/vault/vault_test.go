@@ -0,0 +1,206 @@
+package vault
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// newTestDB returns a database with a todos and a quotes table
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "todos.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if _, err := conn.Exec(`
+		CREATE TABLE todos (id INTEGER PRIMARY KEY, description TEXT NOT NULL, tag TEXT NOT NULL, notes TEXT NOT NULL, priority INTEGER);
+		CREATE TABLE quotes (id INTEGER PRIMARY KEY, text TEXT NOT NULL, author TEXT);
+		INSERT INTO todos (description, tag, notes, priority) VALUES ('Write the incident report', 'hr', '', 3);
+		INSERT INTO quotes (text, author) VALUES ('Simplicity is prerequisite for reliability', 'Dijkstra');
+	`); err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// lock forgets the ciphers unlocked by earlier tests and hides any agent
+// and passphrase
+func lock(t *testing.T) {
+	t.Helper()
+	ciphers.Clear()
+	t.Cleanup(ciphers.Clear)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(PassphraseEnv, "")
+}
+
+func TestCipher(t *testing.T) {
+	c, err := newCipher(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed := c.Seal("call the landlord")
+	if !strings.HasPrefix(sealed, prefix) || strings.Contains(sealed, "landlord") {
+		t.Fatalf("Seal() = %q, want an encrypted value", sealed)
+	}
+	if c.Seal("call the landlord") == sealed {
+		t.Error("Seal() should use a new nonce each time")
+	}
+	if got, err := c.Open(sealed); err != nil || got != "call the landlord" {
+		t.Errorf("Open() = %q, %v, want the plain text", got, err)
+	}
+
+	if got := c.Seal(""); got != "" {
+		t.Errorf("Seal(\"\") = %q, want empty", got)
+	}
+	if got, err := c.Open("not encrypted"); err != nil || got != "not encrypted" {
+		t.Errorf("Open() of a plain value = %q, %v, want it unchanged", got, err)
+	}
+	if _, err := c.Open(sealed[:len(sealed)-2]); err == nil {
+		t.Error("Open() of a corrupt value should fail")
+	}
+
+	if got := c.Reseal(sealed, "call the landlord"); got != sealed {
+		t.Error("Reseal() of an unchanged value should keep the stored value")
+	}
+	if got, _ := c.Open(c.Reseal(sealed, "call the bank")); got != "call the bank" {
+		t.Errorf("Reseal() of a changed value = %q, want the new value", got)
+	}
+
+	var none *Cipher
+	if got := none.Seal("plain"); got != "plain" {
+		t.Errorf("nil Cipher Seal() = %q, want the value unchanged", got)
+	}
+	if _, err := none.Open(sealed); !errors.Is(err, ErrLocked) {
+		t.Errorf("nil Cipher Open() of an encrypted value error = %v, want ErrLocked", err)
+	}
+}
+
+func TestEnableDisable(t *testing.T) {
+	lock(t)
+	conn := newTestDB(t)
+
+	if c, err := Unlock(conn); c != nil || err != nil {
+		t.Fatalf("Unlock() of an unencrypted database = %v, %v, want nil, nil", c, err)
+	}
+	if err := Enable(conn, "correct horse"); err != nil {
+		t.Fatalf("Enable() error = %v", err)
+	}
+	if err := Enable(conn, "correct horse"); err == nil {
+		t.Error("Enable() of an encrypted database should fail")
+	}
+
+	var description, tag, notes, text, author string
+	if err := conn.QueryRow(`SELECT description, tag, notes FROM todos`).Scan(&description, &tag, &notes); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.QueryRow(`SELECT text, author FROM quotes`).Scan(&text, &author); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{description, tag, text} {
+		if !strings.HasPrefix(v, prefix) {
+			t.Errorf("stored value %q should be encrypted", v)
+		}
+	}
+	if notes != "" || author != "Dijkstra" {
+		t.Errorf("notes = %q, author = %q, want empty notes and the author in plain text", notes, author)
+	}
+
+	// Another process has neither the passphrase nor an agent
+	ciphers.Clear()
+	if _, err := Unlock(conn); !errors.Is(err, ErrLocked) {
+		t.Errorf("Unlock() without a passphrase error = %v, want ErrLocked", err)
+	}
+	t.Setenv(PassphraseEnv, "wrong horse")
+	if _, err := Unlock(conn); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Unlock() with a wrong passphrase error = %v, want ErrWrongPassphrase", err)
+	}
+	if err := Disable(conn); !IsLocked(err) {
+		t.Errorf("Disable() while locked error = %v, want it locked", err)
+	}
+
+	t.Setenv(PassphraseEnv, "correct horse")
+	c, err := Unlock(conn)
+	if err != nil || c == nil {
+		t.Fatalf("Unlock() with the passphrase = %v, %v", c, err)
+	}
+	if got, _ := c.Open(description); got != "Write the incident report" {
+		t.Errorf("decrypted description = %q", got)
+	}
+
+	if err := Disable(conn); err != nil {
+		t.Fatalf("Disable() error = %v", err)
+	}
+	if err := conn.QueryRow(`SELECT description, tag FROM todos`).Scan(&description, &tag); err != nil {
+		t.Fatal(err)
+	}
+	if description != "Write the incident report" || tag != "hr" {
+		t.Errorf("after Disable() description = %q, tag = %q, want plain text", description, tag)
+	}
+	if encrypted, _ := Encrypted(conn); encrypted {
+		t.Error("Encrypted() after Disable() = true")
+	}
+}
+
+func TestAgent(t *testing.T) {
+	lock(t)
+	conn := newTestDB(t)
+	if err := Enable(conn, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	ciphers.Clear()
+
+	if AgentRunning() {
+		t.Fatal("AgentRunning() = true before starting one")
+	}
+	l, err := ListenAgent()
+	if err != nil {
+		t.Fatalf("ListenAgent() error = %v", err)
+	}
+	done := make(chan error)
+	go func() { done <- ServeAgent(l, "correct horse", time.Minute) }()
+
+	if _, err := ListenAgent(); err == nil {
+		t.Error("ListenAgent() with an agent running should fail")
+	}
+	if c, err := Unlock(conn); err != nil || c == nil {
+		t.Errorf("Unlock() with an agent = %v, %v", c, err)
+	}
+
+	if !StopAgent() {
+		t.Error("StopAgent() = false, want true")
+	}
+	if err := <-done; err != nil {
+		t.Errorf("ServeAgent() error = %v", err)
+	}
+	if StopAgent() {
+		t.Error("StopAgent() without an agent = true")
+	}
+
+	ciphers.Clear()
+	if _, err := Unlock(conn); !errors.Is(err, ErrLocked) {
+		t.Errorf("Unlock() after the agent stopped error = %v, want ErrLocked", err)
+	}
+}
+
+func TestAgentTimeout(t *testing.T) {
+	lock(t)
+	l, err := ListenAgent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ServeAgent(l, "correct horse", 50*time.Millisecond); err != nil {
+		t.Errorf("ServeAgent() error = %v", err)
+	}
+	if AgentRunning() {
+		t.Error("AgentRunning() after the timeout = true")
+	}
+}
@@ -0,0 +1,77 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Interval is how often files are checked for changes
+var Interval = time.Second
+
+// File returns a channel that receives a value each time the file at path
+// changes, whichever program changed it, until ctx is done. Changes made
+// while the previous value is still unread are merged into it.
+func File(ctx context.Context, path string) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		last := version(path)
+		ticker := time.NewTicker(Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				v := version(path)
+				if v == last {
+					continue
+				}
+				last = v
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changes
+}
+
+// version identifies the contents of a file by its modification time and
+// size
+func version(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+}
+
+// Changed reports whether the file at path was modified after the file at
+// stampPath, a cache or a stamp written once the file was last handled, or
+// whether there is no stamp yet. File times are coarse, so a stamp written
+// right after a change usually has the same time as the file; that stamp
+// is up to date.
+func Changed(path, stampPath string) bool {
+	stamp, err := os.Stat(stampPath)
+	if err != nil {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err == nil && stamp.ModTime().Before(info.ModTime())
+}
+
+// Stamp records that the file at path was handled as it is now, for
+// Changed, by touching the file at stampPath
+func Stamp(stampPath string) error {
+	now := time.Now()
+	if err := os.Chtimes(stampPath, now, now); err == nil {
+		return nil
+	}
+	return os.WriteFile(stampPath, nil, 0644)
+}
@@ -0,0 +1,54 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFile(t *testing.T) {
+	Interval = 10 * time.Millisecond
+	path := filepath.Join(t.TempDir(), "todos.db")
+	os.WriteFile(path, []byte("v1"), 0644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := File(ctx, path)
+
+	select {
+	case <-changes:
+		t.Fatal("change reported before the file changed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	os.WriteFile(path, []byte("v2, longer"), 0644)
+	select {
+	case <-changes:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change reported after the file changed")
+	}
+}
+
+func TestChanged(t *testing.T) {
+	dir := t.TempDir()
+	path, stamp := filepath.Join(dir, "todos.db"), filepath.Join(dir, "stamp")
+	os.WriteFile(path, []byte("v1"), 0644)
+
+	if !Changed(path, stamp) {
+		t.Error("Changed() without a stamp = false, want true")
+	}
+	if err := Stamp(stamp); err != nil {
+		t.Fatalf("Stamp() error = %v", err)
+	}
+	if Changed(path, stamp) {
+		t.Error("Changed() right after Stamp() = true, want false")
+	}
+
+	later := time.Now().Add(time.Second)
+	os.Chtimes(path, later, later)
+	if !Changed(path, stamp) {
+		t.Error("Changed() after the file changed = false, want true")
+	}
+}
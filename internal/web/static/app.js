@@ -0,0 +1,242 @@
+// tada web interface: a thin client of the REST API served under /api
+"use strict";
+
+const PRIORITIES = { 1: ["low", "🟢"], 2: ["medium", "🟡"], 3: ["high", "🔴"] };
+const DONE = 2;
+
+const $ = (selector) => document.querySelector(selector);
+
+let editingTodo = null;
+let editingQuote = null;
+
+async function api(method, path, body) {
+  const response = await fetch("/api" + path, {
+    method,
+    headers: body ? { "Content-Type": "application/json" } : {},
+    body: body ? JSON.stringify(body) : undefined,
+  });
+  if (response.status === 204) {
+    return null;
+  }
+  const data = await response.json();
+  if (!response.ok) {
+    throw new Error(data.error || response.statusText);
+  }
+  return data;
+}
+
+function showError(err) {
+  const el = $("#error");
+  el.textContent = err ? "❌ " + err.message : "";
+  el.hidden = !err;
+}
+
+// run calls an API action, shows its error if any and refreshes the view
+async function run(action) {
+  try {
+    await action();
+    showError(null);
+  } catch (err) {
+    showError(err);
+  }
+  await refresh();
+}
+
+function element(tag, className, text) {
+  const el = document.createElement(tag);
+  if (className) el.className = className;
+  if (text !== undefined) el.textContent = text;
+  return el;
+}
+
+function button(label, title, onClick) {
+  const el = element("button", "icon", label);
+  el.type = "button";
+  el.title = title;
+  el.addEventListener("click", onClick);
+  return el;
+}
+
+// Todos
+
+async function loadTodos() {
+  const params = new URLSearchParams({
+    status: $("#status-filter").value,
+    priority: $("#priority-filter").value,
+    sort: "priority,created",
+  });
+  const tag = $("#tag-filter").value.trim();
+  if (tag) params.set("tag", tag);
+
+  const todos = await api("GET", "/todos?" + params);
+  const list = $("#todo-list");
+  list.replaceChildren(...todos.map(todoItem));
+  $("#todo-count").textContent = todos.length === 1 ? "1 todo" : todos.length + " todos";
+}
+
+function todoItem(t) {
+  const [priority, icon] = PRIORITIES[t.priority] || ["", "⚪"];
+  const li = element("li", t.status === DONE ? "done" : "");
+
+  const toggle = element("input");
+  toggle.type = "checkbox";
+  toggle.checked = t.status === DONE;
+  toggle.title = toggle.checked ? "Mark as open" : "Mark as done";
+  toggle.addEventListener("change", () =>
+    run(() => api("PATCH", "/todos/" + t.id, { status: toggle.checked ? "done" : "open", updated_at: t.updated_at })));
+  li.append(toggle);
+
+  const body = element("div", "body");
+  body.append(element("span", "priority " + priority, icon), element("span", "id", "#" + t.id), element("span", "description", t.description));
+  if (t.tag) body.append(element("span", "tag", "#" + t.tag));
+  if (t.due_at) body.append(element("span", "due", "📅 " + t.due_at.slice(0, 10)));
+  if (t.notes) body.append(element("div", "notes", t.notes));
+  li.append(body);
+
+  li.append(
+    button("✏️", "Edit", () => editTodo(t)),
+    button("🗑️", "Delete", () => {
+      if (confirm("Delete todo #" + t.id + "?")) run(() => api("DELETE", "/todos/" + t.id));
+    }),
+  );
+  return li;
+}
+
+function editTodo(t) {
+  const form = $("#todo-form");
+  editingTodo = t;
+  form.description.value = t.description;
+  form.priority.value = (PRIORITIES[t.priority] || ["medium"])[0];
+  form.tag.value = t.tag || "";
+  form.due.value = t.due_at ? t.due_at.slice(0, 10) : "";
+  form.querySelector("[type=submit]").textContent = "Save #" + t.id;
+  form.querySelector(".cancel").hidden = false;
+  form.description.focus();
+}
+
+function resetTodoForm() {
+  const form = $("#todo-form");
+  editingTodo = null;
+  form.reset();
+  form.querySelector("[type=submit]").textContent = "Add";
+  form.querySelector(".cancel").hidden = true;
+}
+
+$("#todo-form").addEventListener("submit", (event) => {
+  event.preventDefault();
+  const form = event.target;
+  const fields = {
+    description: form.description.value,
+    priority: form.priority.value,
+    tag: form.tag.value.trim(),
+    due: form.due.value.trim() || null,
+  };
+  const t = editingTodo;
+  resetTodoForm();
+  run(() => (t ? api("PATCH", "/todos/" + t.id, { ...fields, updated_at: t.updated_at }) : api("POST", "/todos", fields)));
+});
+$("#todo-form .cancel").addEventListener("click", resetTodoForm);
+
+for (const id of ["#status-filter", "#priority-filter", "#tag-filter"]) {
+  $(id).addEventListener("input", () => run(() => null));
+}
+
+// Quotes
+
+async function loadQuotes() {
+  const quotes = await api("GET", "/quotes");
+  $("#quote-list").replaceChildren(...quotes.map(quoteItem));
+  if (!$("#random-quote .text").textContent) {
+    await randomQuote();
+  }
+}
+
+async function randomQuote() {
+  let q = null;
+  try {
+    q = await api("GET", "/quotes/random");
+  } catch (err) {
+    // No quotes yet
+  }
+  $("#random-quote .text").textContent = q ? "“" + q.text + "”" : "No quotes yet. Add one below.";
+  $("#random-quote .author").textContent = q && q.author ? "— " + q.author : "";
+}
+
+function quoteItem(q) {
+  const li = element("li");
+  const body = element("div", "body");
+  body.append(element("span", "id", "#" + q.id), element("span", "description", q.text));
+  if (q.author) body.append(element("span", "author", "— " + q.author));
+  if (q.category) body.append(element("span", "tag", q.category));
+  li.append(body);
+
+  li.append(
+    button("✏️", "Edit", () => editQuote(q)),
+    button("🗑️", "Delete", () => {
+      if (confirm("Delete quote #" + q.id + "?")) run(() => api("DELETE", "/quotes/" + q.id));
+    }),
+  );
+  return li;
+}
+
+function editQuote(q) {
+  const form = $("#quote-form");
+  editingQuote = q;
+  form.text.value = q.text;
+  form.author.value = q.author || "";
+  form.category.value = q.category || "";
+  form.querySelector("[type=submit]").textContent = "Save #" + q.id;
+  form.querySelector(".cancel").hidden = false;
+  form.text.focus();
+}
+
+function resetQuoteForm() {
+  const form = $("#quote-form");
+  editingQuote = null;
+  form.reset();
+  form.querySelector("[type=submit]").textContent = "Add";
+  form.querySelector(".cancel").hidden = true;
+}
+
+$("#quote-form").addEventListener("submit", (event) => {
+  event.preventDefault();
+  const form = event.target;
+  const fields = { text: form.text.value, author: form.author.value, category: form.category.value };
+  const q = editingQuote;
+  resetQuoteForm();
+  run(() => (q ? api("PATCH", "/quotes/" + q.id, fields) : api("POST", "/quotes", fields)));
+});
+$("#quote-form .cancel").addEventListener("click", resetQuoteForm);
+$("#another-quote").addEventListener("click", randomQuote);
+
+// Views and live updates
+
+let view = "todos";
+
+async function refresh() {
+  try {
+    await (view === "todos" ? loadTodos() : loadQuotes());
+  } catch (err) {
+    showError(err);
+  }
+}
+
+for (const tab of document.querySelectorAll(".tab")) {
+  tab.addEventListener("click", () => {
+    view = tab.dataset.view;
+    for (const other of document.querySelectorAll(".tab")) {
+      other.classList.toggle("active", other === tab);
+    }
+    for (const section of document.querySelectorAll(".view")) {
+      section.hidden = section.id !== view;
+    }
+    refresh();
+  });
+}
+
+const events = new EventSource("/api/events");
+events.addEventListener("change", refresh);
+events.onopen = () => $("#live").classList.remove("offline");
+events.onerror = () => $("#live").classList.add("offline");
+
+refresh();
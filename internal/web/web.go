@@ -0,0 +1,56 @@
+package web
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/http"
+
+	"github.com/negadras/tada/internal/server"
+	"github.com/negadras/tada/internal/watch"
+)
+
+//go:embed static
+var static embed.FS
+
+// Handler serves the web interface at / and the REST API under /api/.
+// /api/events is a stream of server-sent events with a "change" event each
+// time the database file at dbPath changes, whichever program changed it.
+// Requests must be addressed to addr, the address listened on.
+func Handler(api http.Handler, dbPath, addr string) http.Handler {
+	files, _ := fs.Sub(static, "static")
+
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(files)))
+	mux.Handle("/api/", http.StripPrefix("/api", api))
+	mux.HandleFunc("GET /api/events", func(w http.ResponseWriter, r *http.Request) {
+		events(w, r, dbPath)
+	})
+	return server.Guard(addr, mux)
+}
+
+// events sends a "change" event whenever the database file changes, until
+// the client goes away
+func events(w http.ResponseWriter, r *http.Request, dbPath string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprint(w, ": watching for changes\n\n")
+	flusher.Flush()
+
+	changes := watch.File(r.Context(), dbPath)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changes:
+			fmt.Fprint(w, "event: change\ndata: {}\n\n")
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package web
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/negadras/tada/internal/watch"
+)
+
+func TestHandler(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	os.WriteFile(dbPath, []byte("v1"), 0644)
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "api "+r.URL.Path)
+	})
+
+	watch.Interval = 10 * time.Millisecond
+	ts := httptest.NewUnstartedServer(nil)
+	ts.Config.Handler = Handler(api, dbPath, ts.Listener.Addr().String())
+	ts.Start()
+	defer ts.Close()
+
+	t.Run("serves the page", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "<title>tada</title>") {
+			t.Errorf("GET / = %d, %.60q", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("serves the API under /api", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/api/todos")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if body, _ := io.ReadAll(resp.Body); string(body) != "api /todos" {
+			t.Errorf("GET /api/todos = %q, want the API at /todos", body)
+		}
+	})
+
+	t.Run("refuses other hosts and origins", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			host   string
+			origin string
+			want   int
+		}{
+			{"same origin", "", ts.URL, http.StatusOK},
+			{"rebound host", "evil.example:80", "", http.StatusMisdirectedRequest},
+			{"other origin", "", "http://evil.example", http.StatusForbidden},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				req, _ := http.NewRequest("POST", ts.URL+"/api/todos", strings.NewReader("{}"))
+				if tt.host != "" {
+					req.Host = tt.host
+				}
+				if tt.origin != "" {
+					req.Header.Set("Origin", tt.origin)
+				}
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != tt.want {
+					t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("sends an event when the database changes", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/api/events")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+
+		lines := bufio.NewScanner(resp.Body)
+		lines.Scan() // the comment sent on connecting
+		os.WriteFile(dbPath, []byte("v2, longer"), 0644)
+
+		done := make(chan string)
+		go func() {
+			for lines.Scan() {
+				if strings.HasPrefix(lines.Text(), "event:") {
+					done <- lines.Text()
+					return
+				}
+			}
+		}()
+		select {
+		case got := <-done:
+			if got != "event: change" {
+				t.Errorf("event = %q, want change", got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Error("no event after the database changed")
+		}
+	})
+}
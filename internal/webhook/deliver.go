@@ -0,0 +1,271 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MaxAttempts is how many times a delivery is tried before it is given up
+const MaxAttempts = 8
+
+// Delivery is a change queued for a webhook
+type Delivery struct {
+	ID            int        `json:"id"`
+	WebhookID     int        `json:"webhook_id"`
+	URL           string     `json:"url"`
+	Event         string     `json:"event"`
+	Payload       string     `json:"payload"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+
+	secret string
+}
+
+// State is "delivered", "pending" while it will be retried, or "failed"
+// once it was tried MaxAttempts times
+func (d *Delivery) State() string {
+	switch {
+	case d.DeliveredAt != nil:
+		return "delivered"
+	case d.Attempts >= MaxAttempts:
+		return "failed"
+	default:
+		return "pending"
+	}
+}
+
+// Sign returns the signature of a payload, sent in the X-Tada-Signature
+// header so that receivers can check it came from tada
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff returns how long to wait after a failed attempt before the next:
+// 30 seconds, doubling with each attempt up to an hour
+func backoff(attempts int) time.Duration {
+	wait := 30 * time.Second
+	for i := 1; i < attempts && wait < time.Hour; i++ {
+		wait *= 2
+	}
+	return min(wait, time.Hour)
+}
+
+const deliveryColumns = `d.id, d.webhook_id, w.url, w.secret, d.event, d.payload, d.attempts, d.last_error,
+	d.next_attempt_at, d.delivered_at, d.created_at`
+
+func (s *Store) scanDelivery(row interface{ Scan(...interface{}) error }) (*Delivery, error) {
+	d := &Delivery{}
+	var deliveredAt sql.NullTime
+	if err := row.Scan(&d.ID, &d.WebhookID, &d.URL, &d.secret, &d.Event, &d.Payload, &d.Attempts, &d.LastError,
+		&d.NextAttemptAt, &deliveredAt, &d.CreatedAt); err != nil {
+		return nil, err
+	}
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	var err error
+	if d.Payload, err = s.cipher.Open(d.Payload); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Deliveries returns the most recent deliveries, newest first. With
+// undelivered, only the pending and failed ones are returned.
+func (s *Store) Deliveries(limit int, undelivered bool) ([]*Delivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id`
+	if undelivered {
+		query += ` WHERE d.delivered_at IS NULL`
+	}
+	query += ` ORDER BY d.id DESC LIMIT ?`
+
+	rows, err := s.conn.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*Delivery
+	for rows.Next() {
+		d, err := s.scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// Attempt is the outcome of sending a delivery
+type Attempt struct {
+	Delivery *Delivery
+	// Err is why the attempt failed, or nil
+	Err error
+}
+
+// Deliver sends the deliveries that are due, oldest first, and returns the
+// outcome of each. With retryAll, every undelivered one is sent now, even
+// those that were given up. Once a webhook fails, its other deliveries wait
+// for the next Deliver, so that they arrive in order.
+func (s *Store) Deliver(client *http.Client, retryAll bool) ([]*Attempt, error) {
+	pending, err := s.pending(retryAll)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	blocked := map[int]bool{}
+	var attempts []*Attempt
+	for _, d := range pending {
+		if blocked[d.WebhookID] {
+			continue
+		}
+		if !retryAll && d.NextAttemptAt.After(now) {
+			blocked[d.WebhookID] = true
+			continue
+		}
+
+		claimed, err := s.claim(d)
+		if err != nil {
+			return attempts, err
+		}
+		if !claimed {
+			// Another tada is sending it
+			blocked[d.WebhookID] = true
+			continue
+		}
+
+		sendErr := send(client, d)
+		if err := s.finish(d, sendErr); err != nil {
+			return attempts, err
+		}
+		attempts = append(attempts, &Attempt{Delivery: d, Err: sendErr})
+		if sendErr != nil {
+			blocked[d.WebhookID] = true
+		}
+	}
+	return attempts, nil
+}
+
+// Due reports whether Deliver, without retryAll, would send anything now
+func (s *Store) Due() (bool, error) {
+	pending, err := s.pending(false)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	blocked := map[int]bool{}
+	for _, d := range pending {
+		if blocked[d.WebhookID] {
+			continue
+		}
+		if d.NextAttemptAt.After(now) {
+			// Its webhook's later deliveries wait for it
+			blocked[d.WebhookID] = true
+			continue
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// pending returns the undelivered deliveries, oldest first; with retryAll,
+// those that were given up too
+func (s *Store) pending(retryAll bool) ([]*Delivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.delivered_at IS NULL`
+	if !retryAll {
+		query += ` AND d.attempts < ` + strconv.Itoa(MaxAttempts)
+	}
+	query += ` ORDER BY d.id`
+
+	rows, err := s.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var pending []*Delivery
+	for rows.Next() {
+		d, err := s.scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, d)
+	}
+	return pending, rows.Err()
+}
+
+// claim counts an attempt of a delivery and schedules the next one, in case
+// this one fails or tada is stopped while sending. It reports false if
+// another tada claimed it first.
+func (s *Store) claim(d *Delivery) (bool, error) {
+	next := time.Now().UTC().Add(backoff(d.Attempts + 1))
+	result, err := s.conn.Exec(`UPDATE webhook_deliveries SET attempts = attempts + 1, next_attempt_at = ?
+		WHERE id = ? AND attempts = ? AND delivered_at IS NULL`, next, d.ID, d.Attempts)
+	if err != nil {
+		return false, fmt.Errorf("failed to update delivery: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	d.Attempts++
+	d.NextAttemptAt = next
+	return true, nil
+}
+
+// finish records the outcome of an attempt
+func (s *Store) finish(d *Delivery, sendErr error) error {
+	var err error
+	if sendErr == nil {
+		now := time.Now().UTC()
+		d.DeliveredAt, d.LastError = &now, ""
+		_, err = s.conn.Exec(`UPDATE webhook_deliveries SET delivered_at = ?, last_error = '' WHERE id = ?`, now, d.ID)
+	} else {
+		d.LastError = sendErr.Error()
+		_, err = s.conn.Exec(`UPDATE webhook_deliveries SET last_error = ? WHERE id = ?`, d.LastError, d.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update delivery: %w", err)
+	}
+	return nil
+}
+
+// send posts a delivery to its webhook. Any 2xx response is a success.
+func send(client *http.Client, d *Delivery) error {
+	body := []byte(d.Payload)
+	req, err := http.NewRequest(http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "tada-webhook")
+	req.Header.Set("X-Tada-Event", d.Event)
+	req.Header.Set("X-Tada-Delivery", strconv.Itoa(d.ID))
+	req.Header.Set("X-Tada-Signature", Sign(d.secret, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("HTTP %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,365 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/todo"
+	"github.com/negadras/tada/internal/vault"
+)
+
+// Events are the changes a webhook can subscribe to
+var Events = []string{"add", "update", "done", "delete", "quote.add", "quote.update", "quote.delete"}
+
+// schema creates the webhooks, the outbox of deliveries, and how each todo
+// and quote looked when changes were last recorded
+const schema = `
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		url TEXT NOT NULL,
+		events TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		webhook_id INTEGER NOT NULL,
+		event TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		next_attempt_at DATETIME NOT NULL,
+		delivered_at DATETIME NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries(delivered_at, webhook_id);
+
+	CREATE TABLE IF NOT EXISTS webhook_state (
+		uid TEXT PRIMARY KEY,
+		kind TEXT NOT NULL,
+		item TEXT NOT NULL
+	);
+`
+
+// Webhook is a URL that receives changes
+type Webhook struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Wants reports whether the webhook subscribed to event
+func (w *Webhook) Wants(event string) bool {
+	return slices.Contains(w.Events, event)
+}
+
+// Store holds the webhooks and their deliveries, in the todo database
+type Store struct {
+	conn *sql.DB
+	// cipher encrypts payloads and recorded todos and quotes, or is nil if
+	// the database is not encrypted
+	cipher *vault.Cipher
+}
+
+// Open opens the webhook tables of the database at dbPath, creating them if
+// needed
+func Open(dbPath string) (*Store, error) {
+	conn, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if _, err := conn.Exec(schema); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create webhook tables: %w", err)
+	}
+	cipher, err := vault.Unlock(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Store{conn: conn, cipher: cipher}, nil
+}
+
+// Close closes the database connection
+func (s *Store) Close() error {
+	return s.conn.Close()
+}
+
+// ParseEvents parses a comma-separated list of events. An empty list is
+// every event.
+func ParseEvents(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return slices.Clone(Events), nil
+	}
+
+	var events []string
+	for _, event := range strings.Split(list, ",") {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if !slices.Contains(Events, event) {
+			return nil, fmt.Errorf("unknown event %q, must be one of %s", event, strings.Join(Events, ", "))
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// ValidateURL checks that a webhook URL is an absolute http or https URL
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL %q, must start with http:// or https://", rawURL)
+	}
+	return nil
+}
+
+// Add registers a webhook. Record the current todos and quotes first, so
+// that the webhook only receives the changes made after it was added.
+func (s *Store) Add(rawURL string, events []string, secret string) (*Webhook, error) {
+	if err := ValidateURL(rawURL); err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		var b [24]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(b[:])
+	}
+
+	w := &Webhook{URL: rawURL, Events: events, Secret: secret, CreatedAt: time.Now().UTC().Truncate(time.Second)}
+	result, err := s.conn.Exec(`INSERT INTO webhooks (url, events, secret, created_at) VALUES (?, ?, ?, ?)`,
+		w.URL, strings.Join(w.Events, ","), w.Secret, w.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add webhook: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	w.ID = int(id)
+	return w, nil
+}
+
+// List returns the webhooks
+func (s *Store) List() ([]*Webhook, error) {
+	rows, err := s.conn.Query(`SELECT id, url, events, secret, created_at FROM webhooks ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var webhooks []*Webhook
+	for rows.Next() {
+		w := &Webhook{}
+		var events string
+		if err := rows.Scan(&w.ID, &w.URL, &events, &w.Secret, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		w.Events = strings.Split(events, ",")
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+// Remove deletes a webhook and its deliveries. It returns sql.ErrNoRows if
+// there is no webhook with that ID.
+func (s *Store) Remove(id int) error {
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	if _, err := tx.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// payload is the JSON body a webhook receives
+type payload struct {
+	Event     string          `json:"event"`
+	CreatedAt time.Time       `json:"created_at"`
+	Todo      json.RawMessage `json:"todo,omitempty"`
+	Quote     json.RawMessage `json:"quote,omitempty"`
+}
+
+// change is a todo or quote that changed since the last Record
+type change struct {
+	uid, kind, event string
+	// item is the todo or quote as JSON, as it was before it was deleted
+	item string
+}
+
+// Record compares the todos and quotes with how they were at the last
+// Record and queues a delivery of each change to the webhooks that want it.
+// Changes between two Records are combined, so a todo added and completed
+// since the last one is a single add. It returns the number of deliveries
+// queued.
+func (s *Store) Record(todos []*todo.Todo, quotes []*quote.Quote) (int, error) {
+	// Lists are newest first; changes are sent oldest first
+	var current []change
+	for i := len(todos) - 1; i >= 0; i-- {
+		data, err := json.Marshal(todos[i])
+		if err != nil {
+			return 0, err
+		}
+		current = append(current, change{uid: todos[i].UID, kind: "todo", item: string(data)})
+	}
+	for i := len(quotes) - 1; i >= 0; i-- {
+		data, err := json.Marshal(quotes[i])
+		if err != nil {
+			return 0, err
+		}
+		current = append(current, change{uid: quotes[i].UID, kind: "quote", item: string(data)})
+	}
+
+	webhooks, err := s.List()
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	previous, err := s.loadState(tx)
+	if err != nil {
+		return 0, err
+	}
+	changes := diff(previous, current)
+	if len(changes) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now().UTC()
+	queued := 0
+	for _, c := range changes {
+		if c.event == "delete" || c.event == "quote.delete" {
+			_, err = tx.Exec(`DELETE FROM webhook_state WHERE uid = ?`, c.uid)
+		} else {
+			_, err = tx.Exec(`INSERT OR REPLACE INTO webhook_state (uid, kind, item) VALUES (?, ?, ?)`, c.uid, c.kind, s.cipher.Seal(c.item))
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to record changes: %w", err)
+		}
+
+		p := payload{Event: c.event, CreatedAt: now.Truncate(time.Second)}
+		if c.kind == "todo" {
+			p.Todo = json.RawMessage(c.item)
+		} else {
+			p.Quote = json.RawMessage(c.item)
+		}
+		body, err := json.Marshal(p)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, w := range webhooks {
+			if !w.Wants(c.event) {
+				continue
+			}
+			if _, err := tx.Exec(`INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt_at, created_at) VALUES (?, ?, ?, ?, ?)`,
+				w.ID, c.event, s.cipher.Seal(string(body)), now, now); err != nil {
+				return 0, fmt.Errorf("failed to queue delivery: %w", err)
+			}
+			queued++
+		}
+	}
+
+	return queued, tx.Commit()
+}
+
+// loadState returns the todos and quotes as they were at the last Record
+func (s *Store) loadState(tx *sql.Tx) (map[string]change, error) {
+	rows, err := tx.Query(`SELECT uid, kind, item FROM webhook_state`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	state := map[string]change{}
+	for rows.Next() {
+		var c change
+		if err := rows.Scan(&c.uid, &c.kind, &c.item); err != nil {
+			return nil, err
+		}
+		if c.item, err = s.cipher.Open(c.item); err != nil {
+			return nil, err
+		}
+		state[c.uid] = c
+	}
+	return state, rows.Err()
+}
+
+// diff returns the changes from previous to current: deletions, by UID,
+// then the rest in the order of current
+func diff(previous map[string]change, current []change) []change {
+	seen := map[string]bool{}
+	for _, c := range current {
+		seen[c.uid] = true
+	}
+
+	var changes []change
+	for uid, old := range previous {
+		if !seen[uid] {
+			old.event = eventName(old.kind, "delete")
+			changes = append(changes, old)
+		}
+	}
+	slices.SortFunc(changes, func(a, b change) int { return strings.Compare(a.uid, b.uid) })
+
+	for _, c := range current {
+		old, ok := previous[c.uid]
+		switch {
+		case !ok:
+			c.event = eventName(c.kind, "add")
+		case old.item == c.item:
+			continue
+		case c.kind == "todo" && todoStatus(old.item) == todo.Open && todoStatus(c.item) == todo.Done:
+			c.event = "done"
+		default:
+			c.event = eventName(c.kind, "update")
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+func eventName(kind, action string) string {
+	if kind == "quote" {
+		return "quote." + action
+	}
+	return action
+}
+
+func todoStatus(item string) todo.Status {
+	var t struct {
+		Status todo.Status `json:"status"`
+	}
+	_ = json.Unmarshal([]byte(item), &t)
+	return t.Status
+}
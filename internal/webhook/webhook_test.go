@@ -0,0 +1,212 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/negadras/tada/internal/quote"
+	"github.com/negadras/tada/internal/todo"
+)
+
+// received is what a stub webhook got
+type received struct {
+	event, signature string
+	body             []byte
+}
+
+// newStub starts a webhook that fails the first failures requests
+func newStub(t *testing.T, failures int) (*httptest.Server, func() []received) {
+	t.Helper()
+	var mu sync.Mutex
+	var got []received
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, received{r.Header.Get("X-Tada-Event"), r.Header.Get("X-Tada-Signature"), body})
+	}))
+	t.Cleanup(ts.Close)
+	return ts, func() []received {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]received(nil), got...)
+	}
+}
+
+func setup(t *testing.T) (*Store, *todo.DB, *quote.DB) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	todoDB, err := todo.NewDB(path)
+	if err != nil {
+		t.Fatalf("todo.NewDB() error = %v", err)
+	}
+	t.Cleanup(func() { todoDB.Close() })
+	quoteDB, err := quote.NewDB(path)
+	if err != nil {
+		t.Fatalf("quote.NewDB() error = %v", err)
+	}
+	t.Cleanup(func() { quoteDB.Close() })
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store, todoDB, quoteDB
+}
+
+func record(t *testing.T, store *Store, todoDB *todo.DB, quoteDB *quote.DB) int {
+	t.Helper()
+	todos, _ := todoDB.List(nil, nil, nil)
+	quotes, _ := quoteDB.List(nil, nil)
+	n, err := store.Record(todos, quotes)
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	return n
+}
+
+func TestRecordAndDeliver(t *testing.T) {
+	store, todoDB, quoteDB := setup(t)
+	ts, got := newStub(t, 0)
+
+	// Todos from before the webhook are not sent
+	old, _ := todoDB.Create("Existing", todo.Low)
+	record(t, store, todoDB, quoteDB)
+	if _, err := store.Add(ts.URL, []string{"add", "done", "delete", "quote.add"}, "s3cret"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	added, _ := todoDB.Create("Buy milk", todo.High)
+	todoDB.UpdateTag(added.ID, "shop") // seen as part of the add
+	if n := record(t, store, todoDB, quoteDB); n != 1 {
+		t.Errorf("Record() queued %d deliveries, want 1", n)
+	}
+
+	todoDB.UpdateTag(old.ID, "home") // update, not subscribed
+	todoDB.UpdateStatus(added.ID, todo.Done)
+	todoDB.Delete(old.ID)
+	quoteDB.Create("Less is more", "Mies", "")
+	if n := record(t, store, todoDB, quoteDB); n != 3 {
+		t.Errorf("Record() queued %d deliveries, want 3", n)
+	}
+	if n := record(t, store, todoDB, quoteDB); n != 0 {
+		t.Errorf("Record() without changes queued %d deliveries, want 0", n)
+	}
+
+	attempts, err := store.Deliver(http.DefaultClient, false)
+	if err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	if len(attempts) != 4 {
+		t.Fatalf("Deliver() made %d attempts, want 4", len(attempts))
+	}
+
+	want := []string{"add", "delete", "done", "quote.add"}
+	for i, r := range got() {
+		if r.event != want[i] {
+			t.Errorf("delivery %d event = %q, want %q", i, r.event, want[i])
+		}
+		if r.signature != Sign("s3cret", r.body) {
+			t.Errorf("delivery %d signature = %q, want %q", i, r.signature, Sign("s3cret", r.body))
+		}
+		var p struct {
+			Event string          `json:"event"`
+			Todo  json.RawMessage `json:"todo"`
+			Quote json.RawMessage `json:"quote"`
+		}
+		if err := json.Unmarshal(r.body, &p); err != nil || p.Event != want[i] || (p.Todo == nil) == (p.Quote == nil) {
+			t.Errorf("delivery %d payload = %s", i, r.body)
+		}
+	}
+}
+
+func TestDeliver_Retry(t *testing.T) {
+	store, todoDB, quoteDB := setup(t)
+	ts, got := newStub(t, 1)
+	store.Add(ts.URL, Events, "")
+
+	todoDB.Create("First", todo.Medium)
+	todoDB.Create("Second", todo.Medium)
+	record(t, store, todoDB, quoteDB)
+	if due, err := store.Due(); err != nil || !due {
+		t.Errorf("Due() = %v, %v, want true once recorded", due, err)
+	}
+
+	// The first fails, and the second waits for it
+	attempts, err := store.Deliver(http.DefaultClient, false)
+	if err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	if len(attempts) != 1 || attempts[0].Err == nil {
+		t.Fatalf("Deliver() attempts = %+v, want one failure", attempts)
+	}
+	d := attempts[0].Delivery
+	if d.State() != "pending" || d.Attempts != 1 || time.Until(d.NextAttemptAt) < 25*time.Second {
+		t.Errorf("failed delivery = %+v, want pending with a retry in 30s", d)
+	}
+
+	// Not due yet
+	if attempts, _ := store.Deliver(http.DefaultClient, false); len(attempts) != 0 {
+		t.Errorf("Deliver() before the retry made %d attempts", len(attempts))
+	}
+	if due, _ := store.Due(); due {
+		t.Error("Due() before the retry = true, want false")
+	}
+
+	attempts, _ = store.Deliver(http.DefaultClient, true)
+	if len(attempts) != 2 || len(got()) != 2 {
+		t.Fatalf("Deliver(retryAll) made %d attempts, stub got %d, want 2", len(attempts), len(got()))
+	}
+
+	deliveries, err := store.Deliveries(10, true)
+	if err != nil || len(deliveries) != 0 {
+		t.Errorf("Deliveries(undelivered) = %d, %v, want none", len(deliveries), err)
+	}
+	deliveries, _ = store.Deliveries(10, false)
+	if len(deliveries) != 2 || deliveries[1].Attempts != 2 || deliveries[1].State() != "delivered" {
+		t.Errorf("Deliveries() = %+v, want the first delivered after 2 attempts", deliveries)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestParseEvents(t *testing.T) {
+	events, err := ParseEvents(" add, DONE,add")
+	if err != nil || len(events) != 2 || events[0] != "add" || events[1] != "done" {
+		t.Errorf("ParseEvents() = %v, %v, want [add done]", events, err)
+	}
+	if events, _ := ParseEvents(""); len(events) != len(Events) {
+		t.Errorf("ParseEvents(\"\") = %v, want every event", events)
+	}
+	if _, err := ParseEvents("add,finish"); err == nil {
+		t.Error("ParseEvents() with an unknown event should fail")
+	}
+}